@@ -1,6 +1,8 @@
 package main
 
 import (
+	_ "time/tzdata"
+
 	running "github.com/bllooop/pvzservice/internal/server"
 
 	_ "github.com/jackc/pgx/v5/pgxpool"
@@ -0,0 +1,291 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bllooop/pvzservice/internal/delivery/api"
+	"github.com/bllooop/pvzservice/internal/domain"
+	"github.com/bllooop/pvzservice/internal/ratelimit"
+	"github.com/bllooop/pvzservice/internal/repository"
+	"github.com/bllooop/pvzservice/internal/usecase"
+	logger "github.com/bllooop/pvzservice/pkg/logging"
+	"github.com/bllooop/pvzservice/pkg/tracing"
+	"github.com/joho/godotenv"
+	"github.com/spf13/viper"
+)
+
+const (
+	// DefaultPath - файл конфигурации, если путь не задан ни аргументом, ни
+	// переменной PVZ_CONFIG.
+	DefaultPath = "config/config.yml"
+	// EnvPrefix - префикс переменных окружения: ключ db.host переопределяется
+	// переменной PVZ_DB_HOST, portGrpc - PVZ_PORTGRPC.
+	EnvPrefix = "PVZ"
+)
+
+// Config - полная конфигурация сервиса. Имена полей совпадают с ключами
+// config.yml без учета регистра.
+type Config struct {
+	Env                string
+	Logging            logger.Config
+	Port               string
+	PortGrpc           string `mapstructure:"portGrpc"`
+	HTTP               HTTP
+	Health             Health
+	GRPCProbe          GRPCProbe `mapstructure:"grpcProbe"`
+	DB                 DB
+	Auth               Auth
+	Password           usecase.PasswordPolicy
+	PasswordReset      PasswordReset `mapstructure:"passwordReset"`
+	Idempotency        Idempotency
+	ReceptionAutoClose ReceptionAutoClose `mapstructure:"receptionAutoClose"`
+	PvzLimits          PvzLimits          `mapstructure:"pvzLimits"`
+	PvzLifecycle       PvzLifecycle       `mapstructure:"pvzLifecycle"`
+	Metrics            Metrics
+	Audit              Audit
+	Pagination         Pagination
+	RateLimit          ratelimit.Policy `mapstructure:"rateLimit"`
+	Cache              Cache
+	Tracing            tracing.Config
+}
+
+type HTTP struct {
+	ReadTimeout     time.Duration `mapstructure:"readTimeout"`
+	WriteTimeout    time.Duration `mapstructure:"writeTimeout"`
+	IdleTimeout     time.Duration `mapstructure:"idleTimeout"`
+	ShutdownTimeout time.Duration `mapstructure:"shutdownTimeout"`
+	// CORSOrigins - источники, которым разрешены запросы из браузера, "*" -
+	// любые. В переменной окружения перечисляются через запятую.
+	CORSOrigins []string `mapstructure:"corsOrigins"`
+	// TrustedProxies - адреса и подсети прокси, которым разрешено передавать
+	// IP клиента в X-Forwarded-For. По умолчанию не доверяем никому.
+	TrustedProxies []string `mapstructure:"trustedProxies"`
+}
+
+type Health struct {
+	Timeout  time.Duration
+	Interval time.Duration
+}
+
+type GRPCProbe struct {
+	Enabled bool
+	Address string
+}
+
+type DB struct {
+	repository.Config `mapstructure:",squash"`
+	MigrationsPath    string `mapstructure:"migrationsPath"`
+	Timeouts          repository.Timeouts
+	Replica           repository.ReplicaConfig
+}
+
+type Auth struct {
+	AttemptsStore       string        `mapstructure:"attemptsStore"`
+	TokenTTL            time.Duration `mapstructure:"tokenTTL"`
+	usecase.LoginPolicy `mapstructure:",squash"`
+}
+
+type PasswordReset struct {
+	TokenTTL              time.Duration `mapstructure:"tokenTTL"`
+	Notifier              string
+	File                  string
+	usecase.ResetThrottle `mapstructure:",squash"`
+}
+
+type Idempotency struct {
+	TTL             time.Duration
+	CleanupInterval time.Duration `mapstructure:"cleanupInterval"`
+}
+
+type ReceptionAutoClose struct {
+	Enabled                bool
+	Interval               time.Duration
+	domain.AutoClosePolicy `mapstructure:",squash"`
+}
+
+type PvzLimits struct {
+	Mode string
+}
+
+// PvzLifecycle - архивирование ПВЗ, вывод из работы которых вступил в силу.
+type PvzLifecycle struct {
+	ArchiveInterval time.Duration `mapstructure:"archiveInterval"`
+}
+
+// Audit - проверка цепочки журнала аудита командой auditverify.
+type Audit struct {
+	// VerifyTimeout ограничивает всю проверку, 0 - без ограничения.
+	VerifyTimeout time.Duration `mapstructure:"verifyTimeout"`
+}
+
+// Metrics - сбор метрик для /metrics.
+type Metrics struct {
+	// StockTimeout ограничивает чтение остатков ПВЗ при каждом сборе метрик.
+	StockTimeout time.Duration `mapstructure:"stockTimeout"`
+}
+
+type Pagination struct {
+	MaxLimit int `mapstructure:"maxLimit"`
+}
+
+// Cache - кэш списков ПВЗ в памяти экземпляра.
+type Cache struct {
+	Enabled bool
+	TTL     time.Duration
+	Size    int
+}
+
+// Load читает конфигурацию: значения по умолчанию, затем файл path, затем
+// переменные окружения с префиксом PVZ, и проверяет результат. Пустой path
+// заменяется значением PVZ_CONFIG или DefaultPath; отсутствие файла по
+// умолчанию не ошибка. Файл .env, если есть, загружается в окружение.
+func Load(path string) (*Config, error) {
+	v, err := newViper(path)
+	if err != nil {
+		return nil, err
+	}
+	return decode(v)
+}
+
+func newViper(path string) (*viper.Viper, error) {
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("ошибка чтения .env: %w", err)
+	}
+	v := viper.New()
+	setDefaults(v)
+	v.SetEnvPrefix(EnvPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+	// DB_PASSWORD поддерживается для совместимости с прежним .env.
+	if err := v.BindEnv("db.password", EnvPrefix+"_DB_PASSWORD", "DB_PASSWORD"); err != nil {
+		return nil, err
+	}
+
+	explicit := path != ""
+	if !explicit {
+		path = os.Getenv(EnvPrefix + "_CONFIG")
+		explicit = path != ""
+	}
+	if !explicit {
+		path = DefaultPath
+	}
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		if !explicit && errors.Is(err, fs.ErrNotExist) {
+			return v, nil
+		}
+		return nil, fmt.Errorf("ошибка чтения файла конфигурации %s: %w", path, err)
+	}
+	return v, nil
+}
+
+func decode(v *viper.Viper) (*Config, error) {
+	var cfg Config
+	if err := v.Unmarshal(&cfg); err != nil {
+		return nil, fmt.Errorf("ошибка разбора конфигурации: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+func setDefaults(v *viper.Viper) {
+	// Без явного профиля сервис работает как prod: /dummyLogin не регистрируется.
+	v.SetDefault("env", api.EnvProd)
+	v.SetDefault("logging.level", "info")
+	v.SetDefault("logging.format", logger.FormatJSON)
+	v.SetDefault("port", "8080")
+	v.SetDefault("portGrpc", ":3000")
+
+	v.SetDefault("http.readTimeout", 10*time.Second)
+	v.SetDefault("http.writeTimeout", 10*time.Second)
+	v.SetDefault("http.idleTimeout", 60*time.Second)
+	v.SetDefault("http.shutdownTimeout", 5*time.Second)
+	v.SetDefault("http.corsOrigins", []string{"*"})
+	v.SetDefault("http.trustedProxies", []string{})
+
+	v.SetDefault("health.timeout", 2*time.Second)
+	v.SetDefault("health.interval", 10*time.Second)
+	v.SetDefault("grpcProbe.enabled", false)
+	v.SetDefault("grpcProbe.address", "")
+
+	v.SetDefault("db.host", "localhost")
+	v.SetDefault("db.port", "5432")
+	v.SetDefault("db.username", "postgres")
+	v.SetDefault("db.password", "")
+	v.SetDefault("db.dbname", "postgres")
+	v.SetDefault("db.sslmode", "disable")
+	v.SetDefault("db.migrationsPath", "./migrations")
+	v.SetDefault("db.timeouts.read", repository.DefaultTimeouts.Read)
+	v.SetDefault("db.timeouts.write", repository.DefaultTimeouts.Write)
+	v.SetDefault("db.timeouts.report", repository.DefaultTimeouts.Report)
+	v.SetDefault("db.replica.dsn", "")
+	v.SetDefault("db.replica.maxLag", repository.DefaultReplicaMaxLag)
+	v.SetDefault("db.replica.checkInterval", repository.DefaultReplicaCheckInterval)
+	v.SetDefault("db.replica.readYourWritesWindow", repository.DefaultReadYourWritesWindow)
+
+	login := usecase.DefaultLoginPolicy
+	v.SetDefault("auth.attemptsStore", "postgres")
+	v.SetDefault("auth.tokenTTL", usecase.DefaultTokenTTL)
+	v.SetDefault("auth.maxFailures", login.MaxFailures)
+	v.SetDefault("auth.maxFailuresPerIp", login.MaxFailuresPerIP)
+	v.SetDefault("auth.freeAttempts", login.FreeAttempts)
+	v.SetDefault("auth.baseDelay", login.BaseDelay)
+	v.SetDefault("auth.maxDelay", login.MaxDelay)
+	v.SetDefault("auth.lockDuration", login.LockDuration)
+	v.SetDefault("auth.failureWindow", login.FailureWindow)
+
+	password := usecase.DefaultPasswordPolicy
+	v.SetDefault("password.minLength", password.MinLength)
+	v.SetDefault("password.requireUpper", password.RequireUpper)
+	v.SetDefault("password.requireLower", password.RequireLower)
+	v.SetDefault("password.requireDigit", password.RequireDigit)
+	v.SetDefault("password.requireSpecial", password.RequireSpecial)
+	v.SetDefault("password.checkBanned", password.CheckBanned)
+
+	v.SetDefault("passwordReset.tokenTTL", 30*time.Minute)
+	v.SetDefault("passwordReset.notifier", "file")
+	v.SetDefault("passwordReset.file", "./password_reset.log")
+	v.SetDefault("passwordReset.maxPerEmail", usecase.DefaultResetThrottle.MaxPerEmail)
+	v.SetDefault("passwordReset.maxPerIp", usecase.DefaultResetThrottle.MaxPerIP)
+	v.SetDefault("passwordReset.throttleWindow", usecase.DefaultResetThrottle.Window)
+
+	v.SetDefault("idempotency.ttl", usecase.DefaultIdempotencyTTL)
+	v.SetDefault("idempotency.cleanupInterval", time.Hour)
+
+	v.SetDefault("receptionAutoClose.enabled", true)
+	v.SetDefault("receptionAutoClose.interval", 5*time.Minute)
+	v.SetDefault("receptionAutoClose.idleAfter", usecase.DefaultAutoClosePolicy.IdleAfter)
+	v.SetDefault("receptionAutoClose.emptyAction", usecase.DefaultAutoClosePolicy.EmptyAction)
+
+	v.SetDefault("pvzLimits.mode", domain.LimitReject)
+	v.SetDefault("pvzLifecycle.archiveInterval", time.Minute)
+	v.SetDefault("metrics.stockTimeout", 5*time.Second)
+	v.SetDefault("audit.verifyTimeout", time.Duration(0))
+	v.SetDefault("pagination.maxLimit", api.DefaultMaxPageLimit)
+
+	v.SetDefault("rateLimit.enabled", true)
+	v.SetDefault("rateLimit.auth.rps", 1)
+	v.SetDefault("rateLimit.auth.burst", 10)
+	v.SetDefault("rateLimit.write.rps", 5)
+	v.SetDefault("rateLimit.write.burst", 20)
+	v.SetDefault("rateLimit.read.rps", 20)
+	v.SetDefault("rateLimit.read.burst", 50)
+
+	v.SetDefault("cache.enabled", true)
+	v.SetDefault("cache.ttl", repository.DefaultCacheTTL)
+	v.SetDefault("cache.size", repository.DefaultCacheSize)
+
+	v.SetDefault("tracing.exporter", tracing.ExporterNone)
+	v.SetDefault("tracing.endpoint", "")
+	v.SetDefault("tracing.insecure", false)
+	v.SetDefault("tracing.file", "")
+	v.SetDefault("tracing.serviceName", tracing.DefaultServiceName)
+	v.SetDefault("tracing.sampleRatio", 1.0)
+}
@@ -0,0 +1,131 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfig(t *testing.T, body string) string {
+	t.Helper()
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "migrations"), 0o755))
+	path := filepath.Join(dir, "config.yml")
+	body = "db:\n    migrationsPath: \"" + filepath.Join(dir, "migrations") + "\"\n" + body
+	require.NoError(t, os.WriteFile(path, []byte(body), 0o644))
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	path := writeConfig(t, `
+env: "prod"
+port: "9090"
+logging:
+    level: "debug"
+auth:
+    maxFailures: 7
+pagination:
+    maxLimit: 50
+`)
+	t.Setenv("PVZ_PORT", "8081")
+	t.Setenv("PVZ_AUTH_TOKENTTL", "1h")
+	t.Setenv("PVZ_HTTP_CORSORIGINS", "https://a.example,https://b.example")
+	t.Setenv("DB_PASSWORD", "secret")
+
+	cfg, err := Load(path)
+
+	require.NoError(t, err)
+	assert.Equal(t, "prod", cfg.Env)
+	assert.Equal(t, "8081", cfg.Port)
+	assert.Equal(t, "debug", cfg.Logging.Level)
+	assert.Equal(t, 7, cfg.Auth.MaxFailures)
+	assert.Equal(t, 20, cfg.Auth.MaxFailuresPerIP)
+	assert.Equal(t, time.Hour, cfg.Auth.TokenTTL)
+	assert.Equal(t, []string{"https://a.example", "https://b.example"}, cfg.HTTP.CORSOrigins)
+	assert.Equal(t, 10*time.Second, cfg.HTTP.ReadTimeout)
+	assert.Equal(t, 50, cfg.Pagination.MaxLimit)
+	assert.Equal(t, "secret", cfg.DB.Password)
+	assert.Equal(t, ":3000", cfg.PortGrpc)
+}
+
+func TestLoad_invalid(t *testing.T) {
+	testTable := []struct {
+		name          string
+		body          string
+		expectedError string
+	}{
+		{
+			name:          "Неизвестный профиль",
+			body:          `env: "stage"`,
+			expectedError: `env: недопустимое значение "stage", допустимы dev, test, prod`,
+		},
+		{
+			name:          "Некорректный порт",
+			body:          `port: "http"`,
+			expectedError: `port: ожидается номер порта от 1 до 65535, получено "http"`,
+		},
+		{
+			name:          "Нулевой таймаут",
+			body:          "http:\n    readTimeout: \"0s\"",
+			expectedError: "http.readTimeout: должно быть больше нуля, получено 0s",
+		},
+		{
+			name:          "Неверный доверенный прокси",
+			body:          "http:\n    trustedProxies: [\"proxy.local\"]",
+			expectedError: `http.trustedProxies: неверный адрес или подсеть "proxy.local"`,
+		},
+		{
+			name:          "Неизвестный уровень логирования",
+			body:          "logging:\n    level: \"verbose\"",
+			expectedError: `logging: неизвестный уровень логирования "verbose"`,
+		},
+		{
+			name:          "OTLP без адреса",
+			body:          "tracing:\n    exporter: \"otlp\"",
+			expectedError: "tracing.endpoint: не задано",
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, err := Load(writeConfig(t, testCase.body))
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), testCase.expectedError)
+		})
+	}
+}
+
+func TestLoad_defaultEnv(t *testing.T) {
+	cfg, err := Load(writeConfig(t, ""))
+
+	require.NoError(t, err)
+	assert.Equal(t, "prod", cfg.Env, "без явного профиля /dummyLogin недоступен")
+}
+
+func TestLoad_missingExplicitFile(t *testing.T) {
+	_, err := Load(filepath.Join(t.TempDir(), "absent.yml"))
+	assert.Error(t, err)
+}
+
+func TestConfig_Reload(t *testing.T) {
+	cfg, err := Load(writeConfig(t, ""))
+	require.NoError(t, err)
+
+	next := *cfg
+	next.Logging.Level = "warn"
+	next.Auth.MaxFailures = 10
+	updated, restartRequired := cfg.Reload(&next)
+	assert.Equal(t, "warn", updated.Logging.Level)
+	assert.Equal(t, 10, updated.Auth.MaxFailures)
+	assert.False(t, restartRequired)
+
+	next.Port = "9999"
+	updated, restartRequired = cfg.Reload(&next)
+	assert.Equal(t, cfg.Port, updated.Port)
+	assert.True(t, restartRequired)
+}
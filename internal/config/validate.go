@@ -0,0 +1,176 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bllooop/pvzservice/internal/delivery/api"
+	"github.com/bllooop/pvzservice/internal/domain"
+	"github.com/bllooop/pvzservice/internal/ratelimit"
+	logger "github.com/bllooop/pvzservice/pkg/logging"
+	"github.com/bllooop/pvzservice/pkg/tracing"
+)
+
+// Validate проверяет конфигурацию и возвращает все найденные ошибки сразу,
+// каждую с именем ключа.
+func (c *Config) Validate() error {
+	var errs []error
+	fail := func(key, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+	}
+	oneOf := func(key, value string, allowed ...string) {
+		for _, a := range allowed {
+			if value == a {
+				return
+			}
+		}
+		fail(key, "недопустимое значение %q, допустимы %s", value, strings.Join(allowed, ", "))
+	}
+	positive := func(key string, d time.Duration) {
+		if d <= 0 {
+			fail(key, "должно быть больше нуля, получено %s", d)
+		}
+	}
+	required := func(key, value string) {
+		if strings.TrimSpace(value) == "" {
+			fail(key, "не задано")
+		}
+	}
+
+	oneOf("env", c.Env, api.EnvDev, api.EnvTest, api.EnvProd)
+	if err := logger.ValidateConfig(c.Logging); err != nil {
+		fail("logging", "%v", err)
+	}
+
+	if port, err := strconv.Atoi(c.Port); err != nil || port < 1 || port > 65535 {
+		fail("port", "ожидается номер порта от 1 до 65535, получено %q", c.Port)
+	}
+	if _, _, err := net.SplitHostPort(c.PortGrpc); err != nil {
+		fail("portGrpc", "ожидается адрес вида :3000, получено %q", c.PortGrpc)
+	}
+	positive("http.readTimeout", c.HTTP.ReadTimeout)
+	positive("http.writeTimeout", c.HTTP.WriteTimeout)
+	positive("http.idleTimeout", c.HTTP.IdleTimeout)
+	positive("http.shutdownTimeout", c.HTTP.ShutdownTimeout)
+	if len(c.HTTP.CORSOrigins) == 0 {
+		fail("http.corsOrigins", "не задано ни одного источника")
+	}
+	for _, proxy := range c.HTTP.TrustedProxies {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				fail("http.trustedProxies", "неверный адрес или подсеть %q", proxy)
+			}
+		}
+	}
+	positive("health.timeout", c.Health.Timeout)
+	positive("health.interval", c.Health.Interval)
+
+	required("db.host", c.DB.Host)
+	required("db.port", c.DB.Port)
+	required("db.username", c.DB.Username)
+	required("db.dbname", c.DB.DBname)
+	if info, err := os.Stat(c.DB.MigrationsPath); err != nil || !info.IsDir() {
+		fail("db.migrationsPath", "каталог миграций %q не найден", c.DB.MigrationsPath)
+	}
+	for key, d := range map[string]time.Duration{
+		"db.timeouts.read":   c.DB.Timeouts.Read,
+		"db.timeouts.write":  c.DB.Timeouts.Write,
+		"db.timeouts.report": c.DB.Timeouts.Report,
+	} {
+		if d < 0 {
+			fail(key, "не может быть отрицательным")
+		}
+	}
+	if c.DB.Replica.DSN != "" {
+		positive("db.replica.maxLag", c.DB.Replica.MaxLag)
+		positive("db.replica.checkInterval", c.DB.Replica.CheckInterval)
+		if c.DB.Replica.ReadYourWritesWindow < 0 {
+			fail("db.replica.readYourWritesWindow", "не может быть отрицательным")
+		}
+	}
+
+	oneOf("auth.attemptsStore", c.Auth.AttemptsStore, "postgres", "memory")
+	positive("auth.tokenTTL", c.Auth.TokenTTL)
+	if c.Auth.MaxFailures < 0 || c.Auth.MaxFailuresPerIP < 0 || c.Auth.FreeAttempts < 0 {
+		fail("auth", "число попыток не может быть отрицательным")
+	}
+	if c.Auth.BaseDelay < 0 || c.Auth.MaxDelay < c.Auth.BaseDelay {
+		fail("auth.maxDelay", "должно быть не меньше auth.baseDelay")
+	}
+	positive("auth.lockDuration", c.Auth.LockDuration)
+	positive("auth.failureWindow", c.Auth.FailureWindow)
+	if c.Password.MinLength < 1 {
+		fail("password.minLength", "должно быть больше нуля")
+	}
+
+	positive("passwordReset.tokenTTL", c.PasswordReset.TokenTTL)
+	oneOf("passwordReset.notifier", c.PasswordReset.Notifier, "file", "log")
+	if c.PasswordReset.Notifier == "file" {
+		required("passwordReset.file", c.PasswordReset.File)
+	}
+	if c.PasswordReset.MaxPerEmail < 0 || c.PasswordReset.MaxPerIP < 0 {
+		fail("passwordReset", "число запросов не может быть отрицательным")
+	}
+	positive("passwordReset.throttleWindow", c.PasswordReset.Window)
+	positive("idempotency.ttl", c.Idempotency.TTL)
+	positive("idempotency.cleanupInterval", c.Idempotency.CleanupInterval)
+	positive("receptionAutoClose.interval", c.ReceptionAutoClose.Interval)
+	positive("receptionAutoClose.idleAfter", c.ReceptionAutoClose.IdleAfter)
+	oneOf("receptionAutoClose.emptyAction", c.ReceptionAutoClose.EmptyAction, domain.EmptyReceptionFlag, domain.EmptyReceptionCancel)
+	oneOf("pvzLimits.mode", c.PvzLimits.Mode, domain.LimitReject, domain.LimitWarn)
+	positive("pvzLifecycle.archiveInterval", c.PvzLifecycle.ArchiveInterval)
+	positive("metrics.stockTimeout", c.Metrics.StockTimeout)
+	if c.Audit.VerifyTimeout < 0 {
+		fail("audit.verifyTimeout", "не может быть отрицательным, получено %s", c.Audit.VerifyTimeout)
+	}
+	if c.Pagination.MaxLimit < 1 {
+		fail("pagination.maxLimit", "должно быть больше нуля")
+	}
+
+	for group, g := range map[string]ratelimit.Group{
+		ratelimit.GroupAuth:  c.RateLimit.Auth,
+		ratelimit.GroupWrite: c.RateLimit.Write,
+		ratelimit.GroupRead:  c.RateLimit.Read,
+	} {
+		key := "rateLimit." + group
+		if g.Rate < 0 || g.Burst < 0 {
+			fail(key, "rps и burst не могут быть отрицательными")
+		}
+		for role, limit := range g.Roles {
+			if !api.IsRole(role) {
+				fail(key+".roles", "неизвестная роль %q, допустимы employee, moderator, admin", role)
+			}
+			if limit.Rate < 0 || limit.Burst < 0 {
+				fail(key+".roles."+role, "rps и burst не могут быть отрицательными")
+			}
+		}
+	}
+
+	if c.Cache.Enabled {
+		positive("cache.ttl", c.Cache.TTL)
+		if c.Cache.Size < 1 {
+			fail("cache.size", "должно быть больше нуля")
+		}
+	}
+
+	oneOf("tracing.exporter", c.Tracing.Exporter, tracing.ExporterNone, tracing.ExporterOTLP, tracing.ExporterStdout, tracing.ExporterFile)
+	switch c.Tracing.Exporter {
+	case tracing.ExporterOTLP:
+		required("tracing.endpoint", c.Tracing.Endpoint)
+	case tracing.ExporterFile:
+		required("tracing.file", c.Tracing.File)
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		fail("tracing.sampleRatio", "должно быть от 0 до 1, получено %v", c.Tracing.SampleRatio)
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("некорректная конфигурация: %w", errors.Join(errs...))
+}
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"os"
+	"reflect"
+
+	logger "github.com/bllooop/pvzservice/pkg/logging"
+	"github.com/fsnotify/fsnotify"
+)
+
+// Watch следит за файлом конфигурации и при каждом изменении вызывает
+// onChange с новой проверенной конфигурацией. Конфигурация с ошибками
+// отклоняется, и сервис продолжает работать с прежней. Без файла
+// конфигурации Watch ничего не делает.
+func Watch(path string, onChange func(*Config)) error {
+	v, err := newViper(path)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(v.ConfigFileUsed()); err != nil {
+		return nil
+	}
+	v.OnConfigChange(func(e fsnotify.Event) {
+		cfg, err := decode(v)
+		if err != nil {
+			logger.Log.Error().Err(err).Str("file", e.Name).Msg("Измененная конфигурация отклонена")
+			return
+		}
+		onChange(cfg)
+	})
+	v.WatchConfig()
+	return nil
+}
+
+// Reload возвращает копию c, в которую из next перенесены поля, применяемые
+// без перезапуска: logging.level, ограничения попыток входа auth.* и лимиты
+// частоты запросов rateLimit. Второй результат сообщает, что next отличается
+// и в других полях - они вступят в силу только после перезапуска.
+func (c *Config) Reload(next *Config) (*Config, bool) {
+	updated := *c
+	updated.Logging.Level = next.Logging.Level
+	updated.Auth.LoginPolicy = next.Auth.LoginPolicy
+	updated.RateLimit = next.RateLimit
+	return &updated, !reflect.DeepEqual(updated, *next)
+}
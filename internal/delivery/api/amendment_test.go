@@ -0,0 +1,292 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bllooop/pvzservice/internal/domain"
+	"github.com/bllooop/pvzservice/internal/repository"
+	"github.com/bllooop/pvzservice/internal/usecase"
+	mock_usecase "github.com/bllooop/pvzservice/internal/usecase/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestHandler_requestAmendment(t *testing.T) {
+	type mockBehavior func(s *mock_usecase.MockAmendments)
+	fixedTime := time.Date(2025, 4, 10, 15, 5, 17, 0, time.UTC)
+	receptionId := uuid.New()
+	amendmentId := uuid.New()
+	pvzId := uuid.New()
+	input := domain.AmendmentRequest{
+		Reason: "найдена коробка на складе",
+		Items:  []domain.AmendmentItem{{Action: "add", Type: "обувь"}},
+	}
+	testTable := []struct {
+		name                 string
+		inputBody            string
+		inputUserRole        int
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:          "OK",
+			inputBody:     `{"reason":"найдена коробка на складе","items":[{"action":"add","type":"обувь"}]}`,
+			inputUserRole: 1,
+			mockBehavior: func(s *mock_usecase.MockAmendments) {
+				s.EXPECT().RequestAmendment(gomock.Any(), testScope, receptionId, "u1", input).Return(domain.ReceptionAmendment{
+					Id:          amendmentId,
+					ReceptionId: receptionId,
+					PVZId:       pvzId,
+					Status:      domain.AmendmentPending,
+					Reason:      input.Reason,
+					Items:       input.Items,
+					RequestedBy: "u1",
+					CreatedAt:   fixedTime,
+				}, nil)
+			},
+			expectedStatusCode: 200,
+			expectedResponseBody: fmt.Sprintf(`{"message":"Заявка на изменение создана","content":{"id":"%s","receptionId":"%s","pvzId":"%s","status":"pending","reason":"найдена коробка на складе","items":[{"action":"add","type":"обувь"}],"requestedBy":"u1","createdAt":"2025-04-10T15:05:17Z"}}`,
+				amendmentId, receptionId, pvzId),
+		},
+		{
+			name:                 "Неизвестное действие",
+			inputBody:            `{"reason":"ошибка","items":[{"action":"replace","type":"обувь"}]}`,
+			inputUserRole:        1,
+			mockBehavior:         func(s *mock_usecase.MockAmendments) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"Неверный запрос"}`,
+		},
+		{
+			name:                 "Без позиций",
+			inputBody:            `{"reason":"ошибка","items":[]}`,
+			inputUserRole:        1,
+			mockBehavior:         func(s *mock_usecase.MockAmendments) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"Неверный запрос"}`,
+		},
+		{
+			name:          "Приемка не закрыта",
+			inputBody:     `{"reason":"найдена коробка на складе","items":[{"action":"add","type":"обувь"}]}`,
+			inputUserRole: 1,
+			mockBehavior: func(s *mock_usecase.MockAmendments) {
+				s.EXPECT().RequestAmendment(gomock.Any(), testScope, receptionId, "u1", input).Return(domain.ReceptionAmendment{}, repository.ErrReceptionNotClosed)
+			},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"Ошибка выполнения запроса изменять через заявку можно только закрытую приемку"}`,
+		},
+		{
+			name:          "Приемка не найдена",
+			inputBody:     `{"reason":"найдена коробка на складе","items":[{"action":"add","type":"обувь"}]}`,
+			inputUserRole: 1,
+			mockBehavior: func(s *mock_usecase.MockAmendments) {
+				s.EXPECT().RequestAmendment(gomock.Any(), testScope, receptionId, "u1", input).Return(domain.ReceptionAmendment{}, repository.ErrReceptionNotFound)
+			},
+			expectedStatusCode:   404,
+			expectedResponseBody: `{"message":"Ошибка выполнения запроса приемка не найдена"}`,
+		},
+		{
+			name:                 "Запрещен доступ",
+			inputBody:            `{"reason":"найдена коробка на складе","items":[{"action":"add","type":"обувь"}]}`,
+			inputUserRole:        2,
+			mockBehavior:         func(s *mock_usecase.MockAmendments) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"Доступ запрещен"}`,
+		},
+	}
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			amendments := mock_usecase.NewMockAmendments(c)
+			testCase.mockBehavior(amendments)
+			audit := mock_usecase.NewMockAudit(c)
+			audit.EXPECT().RecordAudit(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+			handler := NewHandlerWithFixedTime(&usecase.Usecase{Amendments: amendments, Audit: audit}, fixedTime)
+			r := gin.New()
+			r.POST("/receptions/:receptionId/amendments", func(c *gin.Context) {
+				c.Set(userCtx, testCase.inputUserRole)
+				c.Set(userId, "u1")
+				handler.RequestAmendment(c)
+			})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/receptions/"+receptionId.String()+"/amendments", bytes.NewBufferString(testCase.inputBody))
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.JSONEq(t, testCase.expectedResponseBody, w.Body.String())
+		})
+	}
+}
+
+func TestHandler_reviewAmendment(t *testing.T) {
+	type mockBehavior func(s *mock_usecase.MockAmendments)
+	amendmentId := uuid.New()
+	receptionId := uuid.New()
+	testTable := []struct {
+		name                 string
+		path                 string
+		inputBody            string
+		inputUserRole        int
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:          "Одобрение",
+			path:          "approve",
+			inputBody:     `{"comment":"подтверждено"}`,
+			inputUserRole: 2,
+			mockBehavior: func(s *mock_usecase.MockAmendments) {
+				s.EXPECT().ReviewAmendment(gomock.Any(), testScope, amendmentId, "m1", true, "подтверждено").
+					Return(domain.ReceptionAmendment{Id: amendmentId, ReceptionId: receptionId, Status: domain.AmendmentApproved}, nil)
+			},
+			expectedStatusCode: 200,
+		},
+		{
+			name:          "Отклонение без комментария",
+			path:          "reject",
+			inputUserRole: 2,
+			mockBehavior: func(s *mock_usecase.MockAmendments) {
+				s.EXPECT().ReviewAmendment(gomock.Any(), testScope, amendmentId, "m1", false, "").
+					Return(domain.ReceptionAmendment{Id: amendmentId, ReceptionId: receptionId, Status: domain.AmendmentRejected}, nil)
+			},
+			expectedStatusCode: 200,
+		},
+		{
+			name:          "Уже рассмотрена",
+			path:          "approve",
+			inputUserRole: 2,
+			mockBehavior: func(s *mock_usecase.MockAmendments) {
+				s.EXPECT().ReviewAmendment(gomock.Any(), testScope, amendmentId, "m1", true, "").
+					Return(domain.ReceptionAmendment{}, repository.ErrAmendmentNotPending)
+			},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"Ошибка выполнения запроса заявка на изменение уже рассмотрена"}`,
+		},
+		{
+			name:                 "Запрещен доступ",
+			path:                 "approve",
+			inputUserRole:        1,
+			mockBehavior:         func(s *mock_usecase.MockAmendments) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"Доступ запрещен"}`,
+		},
+	}
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			amendments := mock_usecase.NewMockAmendments(c)
+			testCase.mockBehavior(amendments)
+			audit := mock_usecase.NewMockAudit(c)
+			audit.EXPECT().RecordAudit(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+			handler := Handler{Usecases: &usecase.Usecase{Amendments: amendments, Audit: audit}}
+			r := gin.New()
+			setUser := func(c *gin.Context) {
+				c.Set(userCtx, testCase.inputUserRole)
+				c.Set(userId, "m1")
+			}
+			r.POST("/amendments/:amendmentId/approve", setUser, handler.ApproveAmendment)
+			r.POST("/amendments/:amendmentId/reject", setUser, handler.RejectAmendment)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/amendments/"+amendmentId.String()+"/"+testCase.path, bytes.NewBufferString(testCase.inputBody))
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			if testCase.expectedResponseBody != "" {
+				assert.JSONEq(t, testCase.expectedResponseBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandler_amendmentHistory(t *testing.T) {
+	type mockBehavior func(s *mock_usecase.MockAmendments)
+	receptionId := uuid.New()
+	testTable := []struct {
+		name                 string
+		path                 string
+		inputUserRole        int
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:          "Заявки",
+			path:          "amendments",
+			inputUserRole: 2,
+			mockBehavior: func(s *mock_usecase.MockAmendments) {
+				s.EXPECT().GetAmendments(gomock.Any(), testScope, receptionId).Return([]domain.ReceptionAmendment{}, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"message":"Заявки на изменение приемки","content":[]}`,
+		},
+		{
+			name:          "История версий",
+			path:          "history",
+			inputUserRole: 2,
+			mockBehavior: func(s *mock_usecase.MockAmendments) {
+				s.EXPECT().GetReceptionHistory(gomock.Any(), testScope, receptionId).Return([]domain.ReceptionVersion{}, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"message":"История версий приемки","content":[]}`,
+		},
+		{
+			name:                 "Заявки недоступны сотруднику",
+			path:                 "amendments",
+			inputUserRole:        1,
+			mockBehavior:         func(s *mock_usecase.MockAmendments) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"Доступ запрещен"}`,
+		},
+		{
+			name:                 "История недоступна сотруднику",
+			path:                 "history",
+			inputUserRole:        1,
+			mockBehavior:         func(s *mock_usecase.MockAmendments) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"Доступ запрещен"}`,
+		},
+	}
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			amendments := mock_usecase.NewMockAmendments(c)
+			testCase.mockBehavior(amendments)
+
+			handler := Handler{Usecases: &usecase.Usecase{Amendments: amendments}}
+			r := gin.New()
+			setUser := func(c *gin.Context) {
+				c.Set(userCtx, testCase.inputUserRole)
+				c.Set(userId, "m1")
+			}
+			r.GET("/receptions/:receptionId/amendments", setUser, handler.GetAmendments)
+			r.GET("/receptions/:receptionId/history", setUser, handler.GetReceptionHistory)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/receptions/"+receptionId.String()+"/"+testCase.path, nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.JSONEq(t, testCase.expectedResponseBody, w.Body.String())
+		})
+	}
+}
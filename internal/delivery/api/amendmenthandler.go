@@ -0,0 +1,174 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/bllooop/pvzservice/internal/domain"
+	"github.com/bllooop/pvzservice/internal/repository"
+	"github.com/bllooop/pvzservice/internal/usecase"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func (h *Handler) RequestAmendment(c *gin.Context) {
+	reqLog(c).Info().Msg("Получен запрос на изменение закрытой приемки")
+	receptionId, err := uuid.Parse(c.Param("receptionId"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "Некорректный UUID приемки")
+		return
+	}
+	userRole, err := getUserRole(c)
+	if err != nil {
+		reqLog(c).Error().Err(err).Msg("")
+		newErrorResponse(c, http.StatusInternalServerError, "Ошибка получения роли "+err.Error())
+		return
+	}
+	if userRole != 1 {
+		reqLog(c).Error().Msg("Данный запрос доступен только сотруднику ПВЗ")
+		newErrorResponse(c, http.StatusBadRequest, "Доступ запрещен")
+		return
+	}
+	var input domain.AmendmentRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		reqLog(c).Error().Err(err).Msg(err.Error())
+		newErrorResponse(c, http.StatusBadRequest, "Неверный запрос")
+		return
+	}
+	actorId, _ := getUserId(c)
+	result, err := h.Usecases.Amendments.RequestAmendment(h.auditContext(c, "reception.amendment.request", "reception"), tenantScope(c), receptionId, actorId, input)
+	if err != nil {
+		reqLog(c).Error().Err(err).Msg("")
+		newErrorResponse(c, amendmentErrorStatus(err), "Ошибка выполнения запроса "+err.Error())
+		return
+	}
+	reqLog(c).Info().Msg("Получен ответ на заявку на изменение приемки")
+	c.JSON(http.StatusOK, map[string]any{
+		"message": "Заявка на изменение создана",
+		"content": result,
+	})
+}
+
+func (h *Handler) GetAmendments(c *gin.Context) {
+	reqLog(c).Info().Msg("Получен запрос на получение заявок на изменение приемки")
+	receptionId, err := uuid.Parse(c.Param("receptionId"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "Некорректный UUID приемки")
+		return
+	}
+	userRole, err := getUserRole(c)
+	if err != nil {
+		reqLog(c).Error().Err(err).Msg("")
+		newErrorResponse(c, http.StatusInternalServerError, "Ошибка получения роли "+err.Error())
+		return
+	}
+	if userRole != 2 {
+		reqLog(c).Error().Msg("Данный запрос доступен только модератору")
+		newErrorResponse(c, http.StatusBadRequest, "Доступ запрещен")
+		return
+	}
+	result, err := h.Usecases.Amendments.GetAmendments(c.Request.Context(), tenantScope(c), receptionId)
+	if err != nil {
+		reqLog(c).Error().Err(err).Msg("")
+		newErrorResponse(c, http.StatusInternalServerError, "Ошибка выполнения запроса "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, map[string]any{
+		"message": "Заявки на изменение приемки",
+		"content": result,
+	})
+}
+
+func (h *Handler) ApproveAmendment(c *gin.Context) {
+	h.reviewAmendment(c, true)
+}
+
+func (h *Handler) RejectAmendment(c *gin.Context) {
+	h.reviewAmendment(c, false)
+}
+
+func (h *Handler) reviewAmendment(c *gin.Context, approve bool) {
+	reqLog(c).Info().Bool("approve", approve).Msg("Получен запрос на рассмотрение заявки на изменение приемки")
+	amendmentId, err := uuid.Parse(c.Param("amendmentId"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "Некорректный UUID заявки")
+		return
+	}
+	userRole, err := getUserRole(c)
+	if err != nil {
+		reqLog(c).Error().Err(err).Msg("")
+		newErrorResponse(c, http.StatusInternalServerError, "Ошибка получения роли "+err.Error())
+		return
+	}
+	if userRole != 2 {
+		reqLog(c).Error().Msg("Данный запрос доступен только модератору")
+		newErrorResponse(c, http.StatusBadRequest, "Доступ запрещен")
+		return
+	}
+	var input domain.AmendmentReview
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			reqLog(c).Error().Err(err).Msg(err.Error())
+			newErrorResponse(c, http.StatusBadRequest, "Неверный запрос")
+			return
+		}
+	}
+	action, message := "reception.amendment.reject", "Заявка на изменение отклонена"
+	if approve {
+		action, message = "reception.amendment.approve", "Заявка на изменение одобрена и применена"
+	}
+	reviewer, _ := getUserId(c)
+	result, err := h.Usecases.Amendments.ReviewAmendment(h.auditContext(c, action, "reception"), tenantScope(c), amendmentId, reviewer, approve, input.Comment)
+	if err != nil {
+		reqLog(c).Error().Err(err).Msg("")
+		newErrorResponse(c, amendmentErrorStatus(err), "Ошибка выполнения запроса "+err.Error())
+		return
+	}
+	reqLog(c).Info().Msg("Получен ответ на рассмотрение заявки на изменение приемки")
+	c.JSON(http.StatusOK, map[string]any{
+		"message": message,
+		"content": result,
+	})
+}
+
+func (h *Handler) GetReceptionHistory(c *gin.Context) {
+	reqLog(c).Info().Msg("Получен запрос на получение истории версий приемки")
+	receptionId, err := uuid.Parse(c.Param("receptionId"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "Некорректный UUID приемки")
+		return
+	}
+	userRole, err := getUserRole(c)
+	if err != nil {
+		reqLog(c).Error().Err(err).Msg("")
+		newErrorResponse(c, http.StatusInternalServerError, "Ошибка получения роли "+err.Error())
+		return
+	}
+	if userRole != 2 {
+		reqLog(c).Error().Msg("Данный запрос доступен только модератору")
+		newErrorResponse(c, http.StatusBadRequest, "Доступ запрещен")
+		return
+	}
+	result, err := h.Usecases.Amendments.GetReceptionHistory(c.Request.Context(), tenantScope(c), receptionId)
+	if err != nil {
+		reqLog(c).Error().Err(err).Msg("")
+		newErrorResponse(c, http.StatusInternalServerError, "Ошибка выполнения запроса "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, map[string]any{
+		"message": "История версий приемки",
+		"content": result,
+	})
+}
+
+func amendmentErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, repository.ErrReceptionNotFound), errors.Is(err, repository.ErrAmendmentNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, usecase.ErrInvalidAmendment), errors.Is(err, repository.ErrReceptionNotClosed),
+		errors.Is(err, repository.ErrAmendmentNotPending), errors.Is(err, repository.ErrProductNotFound):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
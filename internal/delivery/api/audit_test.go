@@ -0,0 +1,134 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bllooop/pvzservice/internal/domain"
+	"github.com/bllooop/pvzservice/internal/repository"
+	"github.com/bllooop/pvzservice/internal/usecase"
+	mock_usecase "github.com/bllooop/pvzservice/internal/usecase/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestHandler_getAudit(t *testing.T) {
+	type mockBehavior func(s *mock_usecase.MockAudit)
+	fixedTime := time.Date(2025, 4, 10, 15, 5, 17, 0, time.UTC)
+	testTable := []struct {
+		name                 string
+		query                string
+		inputUserRole        int
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:          "OK",
+			query:         "?action=pvz.create&from=2025-04-10T00:00:00Z&limit=100",
+			inputUserRole: 2,
+			mockBehavior: func(s *mock_usecase.MockAudit) {
+				s.EXPECT().GetAudit(gomock.Any(), domain.AuditFilter{
+					Scope:  testScope,
+					Action: "pvz.create",
+					From:   time.Date(2025, 4, 10, 0, 0, 0, 0, time.UTC),
+					Page:   1,
+					Limit:  30,
+				}).Return([]domain.AuditEntry{{Id: 1, CreatedAt: fixedTime, ActorId: "u1", ActorRole: "moderator", Action: "pvz.create", EntityType: "pvz", EntityId: "p1", After: json.RawMessage(`{"city":"Москва"}`), Hash: "h1"}}, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"content":[{"id":1,"createdAt":"2025-04-10T15:05:17Z","actorId":"u1","actorRole":"moderator","action":"pvz.create","entityType":"pvz","entityId":"p1","after":{"city":"Москва"},"prevHash":"","hash":"h1"}],"message":"Журнал аудита"}`,
+		},
+		{
+			name:                 "Не модератор",
+			inputUserRole:        1,
+			mockBehavior:         func(s *mock_usecase.MockAudit) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"Доступ запрещен"}`,
+		},
+		{
+			name:                 "Неверная дата",
+			query:                "?from=yesterday",
+			inputUserRole:        2,
+			mockBehavior:         func(s *mock_usecase.MockAudit) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"Неверный запрос"}`,
+		},
+		{
+			name:          "Ошибка сервера",
+			inputUserRole: 2,
+			mockBehavior: func(s *mock_usecase.MockAudit) {
+				s.EXPECT().GetAudit(gomock.Any(), domain.AuditFilter{Scope: testScope, Page: 1, Limit: 10}).Return(nil, errors.New("Internal Server Error"))
+			},
+			expectedStatusCode:   500,
+			expectedResponseBody: `{"message":"Ошибка выполнения запроса Internal Server Error"}`,
+		},
+	}
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			audit := mock_usecase.NewMockAudit(c)
+			testCase.mockBehavior(audit)
+
+			usecases := &usecase.Usecase{Audit: audit}
+			handler := NewHandlerWithFixedTime(usecases, fixedTime)
+			r := gin.New()
+			r.GET("/audit", func(c *gin.Context) {
+				c.Set(userCtx, testCase.inputUserRole)
+				handler.GetAudit(c)
+			})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/audit"+testCase.query, nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedResponseBody, w.Body.String())
+		})
+	}
+}
+
+func TestHandler_auditContext(t *testing.T) {
+	fixedTime := time.Date(2025, 4, 10, 15, 5, 17, 0, time.UTC)
+	actorId := uuid.NewString()
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	pvz := mock_usecase.NewMockPvz(c)
+	pvz.EXPECT().DeleteLastProduct(gomock.Any(), testScope, gomock.Any()).DoAndReturn(func(ctx context.Context, _ domain.TenantScope, _ domain.ProductDeletion) (domain.Product, error) {
+		entry, ok := repository.AuditFromContext(ctx)
+		assert.True(t, ok)
+		assert.Equal(t, fixedTime, entry.CreatedAt)
+		assert.Equal(t, actorId, entry.ActorId)
+		assert.Equal(t, "employee", entry.ActorRole)
+		assert.Equal(t, "product.delete", entry.Action)
+		assert.Equal(t, "product", entry.EntityType)
+		assert.Equal(t, "req-1", entry.RequestId)
+		return domain.Product{}, errors.New("audit unavailable")
+	})
+
+	handler := NewHandlerWithFixedTime(&usecase.Usecase{Pvz: pvz}, fixedTime)
+	r := gin.New()
+	r.POST("/pvz/:pvzId/delete_last_product", func(c *gin.Context) {
+		c.Set(userCtx, 1)
+		c.Set(userId, actorId)
+		handler.DeleteLast(c)
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/pvz/"+uuid.NewString()+"/delete_last_product", nil)
+	req.Header.Set(requestIdHeader, "req-1")
+
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, 500, w.Code, "изменение без записи в журнале не выполняется")
+}
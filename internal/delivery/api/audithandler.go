@@ -0,0 +1,140 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bllooop/pvzservice/internal/domain"
+	"github.com/bllooop/pvzservice/internal/repository"
+	logger "github.com/bllooop/pvzservice/pkg/logging"
+	"github.com/bllooop/pvzservice/prometheus"
+	"github.com/gin-gonic/gin"
+)
+
+// auditContext возвращает контекст запроса с черновиком записи журнала аудита
+// от имени пользователя из токена. Запись сохраняет репозиторий в транзакции
+// изменения, поэтому изменение без записи в журнале не выполняется.
+func (h *Handler) auditContext(c *gin.Context, action, entityType string) context.Context {
+	return repository.WithAudit(c.Request.Context(), h.userAuditDraft(c, action, entityType))
+}
+
+// anonymousAuditContext — то же для запросов без токена: регистрации и сброса
+// пароля. Автор записи не указывается.
+func (h *Handler) anonymousAuditContext(c *gin.Context, action, tenantId string) context.Context {
+	return repository.WithAudit(c.Request.Context(), h.auditDraft(c, action, "user", tenantId))
+}
+
+// recordAudit записывает в журнал изменение, которое хранится вне базы,
+// например снятие блокировки входа. Операция к этому моменту уже выполнена,
+// поэтому ошибка записи только логируется и учитывается в метрике.
+func (h *Handler) recordAudit(c *gin.Context, action, entityType, entityId string, before, after any) {
+	entry := h.userAuditDraft(c, action, entityType)
+	entry.EntityId = entityId
+	entry.Before = auditSnapshot(before)
+	entry.After = auditSnapshot(after)
+	if err := h.Usecases.Audit.RecordAudit(c.Request.Context(), entry); err != nil {
+		prometheus.AuditWriteFailures.Inc()
+		reqLog(c).Error().Err(err).Str("action", action).Str("entity", entityId).Msg("Ошибка записи в журнал аудита")
+	}
+}
+
+func (h *Handler) userAuditDraft(c *gin.Context, action, entityType string) domain.AuditEntry {
+	actorId, err := getUserId(c)
+	if err != nil {
+		reqLog(c).Error().Err(err).Msg("Не удалось определить автора изменения")
+	}
+	role, _ := getUserRole(c)
+	entry := h.auditDraft(c, action, entityType, tenantScope(c).TenantId)
+	entry.ActorId = actorId
+	entry.ActorRole = getRoleName(role)
+	return entry
+}
+
+func (h *Handler) auditDraft(c *gin.Context, action, entityType, tenantId string) domain.AuditEntry {
+	entry := domain.AuditEntry{
+		Action:     action,
+		EntityType: entityType,
+		RequestId:  getRequestId(c),
+		ClientIP:   c.ClientIP(),
+		TenantId:   tenantId,
+	}
+	if h.Now != nil {
+		entry.CreatedAt = h.Now()
+	}
+	return entry
+}
+
+func auditSnapshot(v any) json.RawMessage {
+	if v == nil {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Ошибка сериализации снимка для аудита")
+		return nil
+	}
+	return data
+}
+
+func (h *Handler) GetAudit(c *gin.Context) {
+	reqLog(c).Info().Msg("Получен запрос на получение журнала аудита")
+	userRole, err := getUserRole(c)
+	if err != nil {
+		reqLog(c).Error().Err(err).Msg("")
+		newErrorResponse(c, http.StatusInternalServerError, "Ошибка получения роли "+err.Error())
+		return
+	}
+	if !isReader(userRole) {
+		reqLog(c).Error().Msg("Данный запрос доступен только модератору или администратору")
+		newErrorResponse(c, http.StatusBadRequest, "Доступ запрещен")
+		return
+	}
+	filter := domain.AuditFilter{
+		Scope:      tenantScope(c),
+		ActorId:    c.Query("actorId"),
+		Action:     c.Query("action"),
+		EntityType: c.Query("entityType"),
+		EntityId:   c.Query("entityId"),
+	}
+	if from := c.Query("from"); from != "" {
+		filter.From, err = time.Parse(time.RFC3339, from)
+		if err != nil {
+			reqLog(c).Error().Err(err).Msg("")
+			newErrorResponse(c, http.StatusBadRequest, "Неверный запрос")
+			return
+		}
+	}
+	if to := c.Query("to"); to != "" {
+		filter.To, err = time.Parse(time.RFC3339, to)
+		if err != nil {
+			reqLog(c).Error().Err(err).Msg("")
+			newErrorResponse(c, http.StatusBadRequest, "Неверный запрос")
+			return
+		}
+	}
+	filter.Page, err = strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || filter.Page < 1 {
+		filter.Page = 1
+	}
+	filter.Limit, err = strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || filter.Limit < 1 {
+		filter.Limit = 10
+	} else if filter.Limit > h.maxPageLimit() {
+		filter.Limit = h.maxPageLimit()
+	}
+	reqLog(c).Debug().Any("filter", filter).Msg("Успешно прочитаны параметры из запроса")
+	result, err := h.Usecases.Audit.GetAudit(c.Request.Context(), filter)
+	if err != nil {
+		reqLog(c).Error().Err(err).Msg("")
+		newErrorResponse(c, http.StatusInternalServerError, "Ошибка выполнения запроса "+err.Error())
+		return
+	}
+	reqLog(c).Info().Msg("Получен ответ на запрос журнала аудита")
+	c.JSON(http.StatusOK, map[string]any{
+		"message": "Журнал аудита",
+		"content": result,
+	})
+}
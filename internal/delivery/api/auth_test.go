@@ -7,8 +7,10 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/bllooop/pvzservice/internal/domain"
+	"github.com/bllooop/pvzservice/internal/repository"
 	"github.com/bllooop/pvzservice/internal/usecase"
 	mock_usecase "github.com/bllooop/pvzservice/internal/usecase/mocks"
 	"github.com/gin-gonic/gin"
@@ -32,7 +34,7 @@ func TestHandler_dummyLogin(t *testing.T) {
 			inputBody: `{"role":"moderator"}`,
 			role:      "moderator",
 			mockBehavior: func(s *mock_usecase.MockAuthorization, role string) {
-				s.EXPECT().GenerateToken(uuid.MustParse("22222222-2222-2222-2222-222222222222"), 2).Return("valid.jwt.token", nil)
+				s.EXPECT().GenerateDummyToken(uuid.MustParse("22222222-2222-2222-2222-222222222222"), 2, domain.DefaultTenant).Return("valid.jwt.token", nil)
 			},
 			expectedStatusCode: 200,
 			expectedResponseBody: `{		"message": "Успешная авторизация",
@@ -75,6 +77,30 @@ func TestHandler_dummyLogin(t *testing.T) {
 	}
 }
 
+func TestHandler_dummyLoginRoute(t *testing.T) {
+	testTable := []struct {
+		name               string
+		env                string
+		expectedStatusCode int
+	}{
+		{name: "dev", env: EnvDev, expectedStatusCode: 400},
+		{name: "test", env: EnvTest, expectedStatusCode: 400},
+		{name: "prod", env: EnvProd, expectedStatusCode: 404},
+	}
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			handler := NewHandler(&usecase.Usecase{}, Config{Env: testCase.env})
+			r := handler.InitRoutes()
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/dummyLogin", bytes.NewBufferString(`{}`))
+
+			r.ServeHTTP(w, req)
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+		})
+	}
+}
+
 func TestHandler_signUp(t *testing.T) {
 	type mockBehavior func(s *mock_usecase.MockAuthorization, user domain.User)
 
@@ -93,9 +119,10 @@ func TestHandler_signUp(t *testing.T) {
 				Email:    "test",
 				Password: "12345",
 				Role:     "employee",
+				TenantId: domain.DefaultTenant,
 			},
 			mockBehavior: func(s *mock_usecase.MockAuthorization, user domain.User) {
-				s.EXPECT().CreateUser(user).Return(domain.User{
+				s.EXPECT().CreateUser(gomock.Any(), user).Return(domain.User{
 					Email: "test",
 					Role:  "employee",
 				}, nil)
@@ -105,18 +132,60 @@ func TestHandler_signUp(t *testing.T) {
 		},
 		{
 			name:      "Ошибка выполнения запроса",
-			inputBody: `{"email": "test", "password":"12345","role":"moderator"}`,
+			inputBody: `{"email": "test", "password":"12345","role":"employee"}`,
 			inputUser: domain.User{
 				Email:    "test",
 				Password: "12345",
-				Role:     "moderator",
+				Role:     "employee",
+				TenantId: domain.DefaultTenant,
 			},
 			mockBehavior: func(s *mock_usecase.MockAuthorization, user domain.User) {
-				s.EXPECT().CreateUser(user).Return(domain.User{}, errors.New("Internal Server Error"))
+				s.EXPECT().CreateUser(gomock.Any(), user).Return(domain.User{}, errors.New("Internal Server Error"))
 			},
 			expectedStatusCode:   500,
 			expectedResponseBody: `{"message":"Internal Server Error"}`,
 		},
+		{
+			name:      "Слабый пароль",
+			inputBody: `{"email":"test", "password":"12345", "role":"employee"}`,
+			inputUser: domain.User{
+				Email:    "test",
+				Password: "12345",
+				Role:     "employee",
+				TenantId: domain.DefaultTenant,
+			},
+			mockBehavior: func(s *mock_usecase.MockAuthorization, user domain.User) {
+				s.EXPECT().CreateUser(gomock.Any(), user).Return(domain.User{}, &usecase.PasswordPolicyError{Reasons: []string{"слишком короткий"}})
+			},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"пароль не соответствует требованиям: слишком короткий"}`,
+		},
+		{
+			name:      "Чужая компания в запросе",
+			inputBody: `{"email":"test", "password":"12345", "role":"employee", "tenant":"acme"}`,
+			inputUser: domain.User{
+				Email:    "test",
+				Password: "12345",
+				Role:     "employee",
+				TenantId: domain.DefaultTenant,
+			},
+			mockBehavior: func(s *mock_usecase.MockAuthorization, user domain.User) {
+				s.EXPECT().CreateUser(gomock.Any(), user).Return(domain.User{
+					Email: "test",
+					Role:  "employee",
+				}, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"message":"Пользователь создан", "content":{"email":"test", "role":"employee"}}`,
+		},
+		{
+			name:                 "Регистрация модератора",
+			inputBody:            `{"email": "test", "password":"12345","role":"moderator"}`,
+			inputUser:            domain.User{},
+			mockBehavior:         func(s *mock_usecase.MockAuthorization, user domain.User) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"Регистрация доступна только для роли employee"}`,
+		},
 		{
 			name:                 "Плохой ввод",
 			inputBody:            `{"email":1000}`,
@@ -156,7 +225,7 @@ func TestHandler_signUp(t *testing.T) {
 }
 
 func TestHandler_signIn(t *testing.T) {
-	type mockBehavior func(s *mock_usecase.MockAuthorization, email, password string)
+	type mockBehavior func(s *mock_usecase.MockAuthorization, l *mock_usecase.MockLoginProtection, email, password string)
 	userID, err := uuid.NewRandom()
 	if err != nil {
 		panic(err)
@@ -169,15 +238,18 @@ func TestHandler_signIn(t *testing.T) {
 		mockBehavior         mockBehavior
 		expectedStatusCode   int
 		expectedResponseBody string
+		expectedRetryAfter   string
 	}{
 		{
 			name:      "OK",
 			inputBody: `{"email":"name", "password":"12345"}`,
 			email:     "name",
 			password:  "12345",
-			mockBehavior: func(s *mock_usecase.MockAuthorization, email, password string) {
-				s.EXPECT().SignUser("name", "12345").Return(domain.User{Id: userID, Role: "moderator"}, nil)
-				s.EXPECT().GenerateToken(userID, 2).Return("valid.jwt.token", nil)
+			mockBehavior: func(s *mock_usecase.MockAuthorization, l *mock_usecase.MockLoginProtection, email, password string) {
+				l.EXPECT().CheckLogin(gomock.Any(), "", "name", "192.0.2.1").Return(nil)
+				s.EXPECT().SignUser(gomock.Any(), "", "name", "12345").Return(domain.User{Id: userID, Role: "moderator"}, nil)
+				s.EXPECT().GenerateToken(userID, 2, "").Return("valid.jwt.token", nil)
+				l.EXPECT().RegisterLoginSuccess(gomock.Any(), "", "name", "192.0.2.1").Return(nil)
 			},
 			expectedStatusCode:   200,
 			expectedResponseBody: `{"message": "Успешная авторизация","token":"valid.jwt.token"}`,
@@ -187,16 +259,49 @@ func TestHandler_signIn(t *testing.T) {
 			inputBody: `{"email":"notname", "password":"password123"}`,
 			email:     "name",
 			password:  "password123",
-			mockBehavior: func(s *mock_usecase.MockAuthorization, email, password string) {
-				s.EXPECT().SignUser("notname", "password123").Return(domain.User{}, errors.New("пользователь не найден"))
+			mockBehavior: func(s *mock_usecase.MockAuthorization, l *mock_usecase.MockLoginProtection, email, password string) {
+				l.EXPECT().CheckLogin(gomock.Any(), "", "notname", "192.0.2.1").Return(nil)
+				s.EXPECT().SignUser(gomock.Any(), "", "notname", "password123").Return(domain.User{}, repository.ErrUserNotFound)
 			},
 			expectedStatusCode:   500,
 			expectedResponseBody: `{"message":"Ошибка авторизации"}`,
 		},
+		{
+			name:      "Неверный пароль",
+			inputBody: `{"email":"name", "password":"wrong"}`,
+			mockBehavior: func(s *mock_usecase.MockAuthorization, l *mock_usecase.MockLoginProtection, email, password string) {
+				l.EXPECT().CheckLogin(gomock.Any(), "", "name", "192.0.2.1").Return(nil)
+				s.EXPECT().SignUser(gomock.Any(), "", "name", "wrong").Return(domain.User{}, usecase.ErrInvalidCredentials)
+			},
+			expectedStatusCode:   500,
+			expectedResponseBody: `{"message":"Ошибка авторизации"}`,
+		},
+		{
+			name:      "Вход заблокирован",
+			inputBody: `{"email":"name", "password":"12345"}`,
+			mockBehavior: func(s *mock_usecase.MockAuthorization, l *mock_usecase.MockLoginProtection, email, password string) {
+				l.EXPECT().CheckLogin(gomock.Any(), "", "name", "192.0.2.1").Return(&usecase.LoginBlockedError{RetryAfter: 90 * time.Second, Locked: true})
+			},
+			expectedStatusCode:   429,
+			expectedResponseBody: `{"message":"вход временно заблокирован, повторите через 1m30s"}`,
+			expectedRetryAfter:   "90",
+		},
+		{
+			name:      "Пользователь заблокирован",
+			inputBody: `{"email":"name", "password":"12345"}`,
+			email:     "name",
+			password:  "12345",
+			mockBehavior: func(s *mock_usecase.MockAuthorization, l *mock_usecase.MockLoginProtection, email, password string) {
+				l.EXPECT().CheckLogin(gomock.Any(), "", "name", "192.0.2.1").Return(nil)
+				s.EXPECT().SignUser(gomock.Any(), "", "name", "12345").Return(domain.User{}, usecase.ErrUserDisabled)
+			},
+			expectedStatusCode:   403,
+			expectedResponseBody: `{"message":"Пользователь заблокирован"}`,
+		},
 		{
 			name:                 "Invalid JSON Input",
 			inputBody:            `{"email":1000}`,
-			mockBehavior:         func(s *mock_usecase.MockAuthorization, email, password string) {},
+			mockBehavior:         func(s *mock_usecase.MockAuthorization, l *mock_usecase.MockLoginProtection, email, password string) {},
 			expectedStatusCode:   400,
 			expectedResponseBody: `{"message":"Неверный запрос"}`,
 		},
@@ -205,8 +310,9 @@ func TestHandler_signIn(t *testing.T) {
 			inputBody: `{"email":"test", "password":"12345","role":"employee"}`,
 			email:     "test",
 			password:  "12345",
-			mockBehavior: func(s *mock_usecase.MockAuthorization, email, password string) {
-				s.EXPECT().SignUser("test", "12345").Return(domain.User{}, errors.New("Internal Server Error"))
+			mockBehavior: func(s *mock_usecase.MockAuthorization, l *mock_usecase.MockLoginProtection, email, password string) {
+				l.EXPECT().CheckLogin(gomock.Any(), "", "test", "192.0.2.1").Return(nil)
+				s.EXPECT().SignUser(gomock.Any(), "", "test", "12345").Return(domain.User{}, errors.New("Internal Server Error"))
 			},
 			expectedStatusCode:   500,
 			expectedResponseBody: `{"message":"Ошибка авторизации"}`,
@@ -219,9 +325,10 @@ func TestHandler_signIn(t *testing.T) {
 			defer c.Finish()
 
 			repo := mock_usecase.NewMockAuthorization(c)
-			testCase.mockBehavior(repo, testCase.email, testCase.password)
+			login := mock_usecase.NewMockLoginProtection(c)
+			testCase.mockBehavior(repo, login, testCase.email, testCase.password)
 
-			usecases := &usecase.Usecase{Authorization: repo}
+			usecases := &usecase.Usecase{Authorization: repo, LoginProtection: login}
 			handler := Handler{Usecases: usecases}
 			r := gin.New()
 			r.POST("/api/auth/sign-in", handler.SignIn)
@@ -231,6 +338,7 @@ func TestHandler_signIn(t *testing.T) {
 
 			r.ServeHTTP(w, req)
 			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedRetryAfter, w.Header().Get("Retry-After"))
 
 			if json.Valid([]byte(testCase.expectedResponseBody)) {
 				assert.JSONEq(t, testCase.expectedResponseBody, w.Body.String())
@@ -240,3 +348,133 @@ func TestHandler_signIn(t *testing.T) {
 		})
 	}
 }
+
+func TestHandler_resetPassword(t *testing.T) {
+	type mockBehavior func(s *mock_usecase.MockPasswordReset)
+	testTable := []struct {
+		name                 string
+		inputBody            string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:      "OK",
+			inputBody: `{"token":"abc", "password":"Str0ngPassword"}`,
+			mockBehavior: func(s *mock_usecase.MockPasswordReset) {
+				s.EXPECT().ResetPassword(gomock.Any(), "abc", "Str0ngPassword").Return(nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"message":"Пароль изменен"}`,
+		},
+		{
+			name:      "Слабый пароль",
+			inputBody: `{"token":"abc", "password":"12345"}`,
+			mockBehavior: func(s *mock_usecase.MockPasswordReset) {
+				s.EXPECT().ResetPassword(gomock.Any(), "abc", "12345").Return(&usecase.PasswordPolicyError{Reasons: []string{"слишком короткий"}})
+			},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"пароль не соответствует требованиям: слишком короткий"}`,
+		},
+		{
+			name:      "Недействительный токен",
+			inputBody: `{"token":"abc", "password":"Str0ngPassword"}`,
+			mockBehavior: func(s *mock_usecase.MockPasswordReset) {
+				s.EXPECT().ResetPassword(gomock.Any(), "abc", "Str0ngPassword").Return(repository.ErrResetTokenInvalid)
+			},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"токен сброса пароля недействителен или истек"}`,
+		},
+		{
+			name:                 "Плохой ввод",
+			inputBody:            `{"token":"abc"}`,
+			mockBehavior:         func(s *mock_usecase.MockPasswordReset) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"Неверный запрос"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			reset := mock_usecase.NewMockPasswordReset(c)
+			testCase.mockBehavior(reset)
+
+			usecases := &usecase.Usecase{PasswordReset: reset}
+			handler := Handler{Usecases: usecases}
+			r := gin.New()
+			r.POST("/password/reset/confirm", handler.ResetPassword)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/password/reset/confirm", bytes.NewBufferString(testCase.inputBody))
+
+			r.ServeHTTP(w, req)
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.JSONEq(t, testCase.expectedResponseBody, w.Body.String())
+		})
+	}
+}
+
+func TestHandler_requestPasswordReset(t *testing.T) {
+	type mockBehavior func(s *mock_usecase.MockPasswordReset)
+	testTable := []struct {
+		name                 string
+		inputBody            string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+		expectedRetryAfter   string
+	}{
+		{
+			name:      "OK",
+			inputBody: `{"email":"name"}`,
+			mockBehavior: func(s *mock_usecase.MockPasswordReset) {
+				s.EXPECT().RequestPasswordReset(gomock.Any(), "", "name", "192.0.2.1").Return(nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"message":"Если пользователь существует, ему отправлена инструкция по сбросу пароля"}`,
+		},
+		{
+			name:      "Слишком частые запросы",
+			inputBody: `{"email":"name"}`,
+			mockBehavior: func(s *mock_usecase.MockPasswordReset) {
+				s.EXPECT().RequestPasswordReset(gomock.Any(), "", "name", "192.0.2.1").Return(&usecase.ResetThrottledError{RetryAfter: 10 * time.Minute})
+			},
+			expectedStatusCode:   429,
+			expectedResponseBody: `{"message":"слишком частые запросы сброса пароля, повторите через 10m0s"}`,
+			expectedRetryAfter:   "600",
+		},
+		{
+			name:                 "Плохой ввод",
+			inputBody:            `{}`,
+			mockBehavior:         func(s *mock_usecase.MockPasswordReset) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"Неверный запрос"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			reset := mock_usecase.NewMockPasswordReset(c)
+			testCase.mockBehavior(reset)
+
+			usecases := &usecase.Usecase{PasswordReset: reset}
+			handler := Handler{Usecases: usecases}
+			r := gin.New()
+			r.POST("/password/reset/request", handler.RequestPasswordReset)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/password/reset/request", bytes.NewBufferString(testCase.inputBody))
+
+			r.ServeHTTP(w, req)
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedRetryAfter, w.Header().Get("Retry-After"))
+			assert.JSONEq(t, testCase.expectedResponseBody, w.Body.String())
+		})
+	}
+}
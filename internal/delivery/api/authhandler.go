@@ -1,38 +1,49 @@
 package api
 
 import (
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
 
 	"github.com/bllooop/pvzservice/internal/domain"
-	logger "github.com/bllooop/pvzservice/pkg/logging"
+	"github.com/bllooop/pvzservice/internal/repository"
+	"github.com/bllooop/pvzservice/internal/usecase"
+	"github.com/bllooop/pvzservice/prometheus"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
+// roleAdmin — суперадминистратор, который видит данные всех компаний, но не
+// может их менять.
+const roleAdmin = "admin"
+
 var roleMap = map[string]int{
 	"employee":  1,
 	"moderator": 2,
+	roleAdmin:   3,
 }
 
 var dummyUserIds = map[string]string{
 	"employee":  "11111111-1111-1111-1111-111111111111",
 	"moderator": "22222222-2222-2222-2222-222222222222",
+	roleAdmin:   "33333333-3333-3333-3333-333333333333",
 }
 
 func (h *Handler) DummyLogin(c *gin.Context) {
-	logger.Log.Info().Msg("Получили запрос на получение токена")
+	reqLog(c).Info().Msg("Получили запрос на получение токена")
 	if c.Request.Method != http.MethodPost {
 		newErrorResponse(c, http.StatusBadRequest, "Требуется запрос POST")
-		logger.Log.Error().Msg("Требуется запрос POST")
+		reqLog(c).Error().Msg("Требуется запрос POST")
 		return
 	}
 	var input domain.DummyLogin
 	if err := c.ShouldBindJSON(&input); err != nil {
-		logger.Log.Error().Err(err).Msg(err.Error())
+		reqLog(c).Error().Err(err).Msg(err.Error())
 		newErrorResponse(c, http.StatusBadRequest, "Неверный запрос")
 		return
 	}
-	logger.Log.Debug().Msgf("Успешно прочитана роль: %s", input.Role)
+	reqLog(c).Debug().Msgf("Успешно прочитана роль: %s", input.Role)
 	userRole, ok := roleMap[input.Role]
 	if !ok {
 		newErrorResponse(c, http.StatusBadRequest, "Неверная роль")
@@ -47,15 +58,17 @@ func (h *Handler) DummyLogin(c *gin.Context) {
 	userId, err := uuid.Parse(userIdStr)
 	if err != nil {
 		newErrorResponse(c, http.StatusInternalServerError, "Ошибка UUID: "+err.Error())
-		logger.Log.Error().Err(err).Msg("Невалидный UUID")
+		reqLog(c).Error().Err(err).Msg("Невалидный UUID")
 		return
 	}
-	logger.Log.Debug().Any("AAAAA", userRole)
-
-	token, err := h.Usecases.Authorization.GenerateToken(userId, userRole)
+	tenantId := input.TenantId
+	if tenantId == "" {
+		tenantId = domain.DefaultTenant
+	}
+	token, err := h.Usecases.Authorization.GenerateDummyToken(userId, userRole, tenantId)
 	if err != nil {
 		newErrorResponse(c, http.StatusInternalServerError, "Ошибка создания токена: "+err.Error())
-		logger.Log.Error().Err(err).Msg("")
+		reqLog(c).Error().Err(err).Msg("")
 		return
 	}
 
@@ -63,25 +76,38 @@ func (h *Handler) DummyLogin(c *gin.Context) {
 		"message": "Успешная авторизация",
 		"token":   token,
 	})
-	logger.Log.Info().Msg("Получили токен")
+	reqLog(c).Info().Msg("Получили токен")
 }
 func (h *Handler) SignUp(c *gin.Context) {
-	logger.Log.Info().Msg("Получили запрос на создание пользователя")
+	reqLog(c).Info().Msg("Получили запрос на создание пользователя")
 	if c.Request.Method != http.MethodPost {
 		newErrorResponse(c, http.StatusBadRequest, "Требуется запрос POST")
-		logger.Log.Error().Msg("Требуется запрос POST")
+		reqLog(c).Error().Msg("Требуется запрос POST")
 		return
 	}
 	var input domain.User
 	if err := c.ShouldBindJSON(&input); err != nil {
-		logger.Log.Error().Err(err).Msg(err.Error())
+		reqLog(c).Error().Err(err).Msg(err.Error())
 		newErrorResponse(c, http.StatusBadRequest, "Неверный запрос")
 		return
 	}
-	logger.Log.Debug().Msgf("Успешно прочитаны почта: %s, пароль: %s, роль: %s", input.Email, input.Password, input.Role)
-	result, err := h.Usecases.Authorization.CreateUser(input)
+	reqLog(c).Debug().Msgf("Успешно прочитаны почта: %s, роль: %s", input.Email, input.Role)
+	if input.Role != "employee" {
+		reqLog(c).Error().Msg("Самостоятельная регистрация доступна только сотруднику ПВЗ")
+		newErrorResponse(c, http.StatusBadRequest, "Регистрация доступна только для роли employee")
+		return
+	}
+	// Компания из тела запроса игнорируется: сотрудников других компаний заводит администратор.
+	input.TenantId = domain.DefaultTenant
+	result, err := h.Usecases.Authorization.CreateUser(h.anonymousAuditContext(c, "user.register", domain.DefaultTenant), input)
+	var policyErr *usecase.PasswordPolicyError
+	if errors.As(err, &policyErr) {
+		reqLog(c).Error().Err(err).Msg("")
+		newErrorResponse(c, http.StatusBadRequest, policyErr.Error())
+		return
+	}
 	if err != nil {
-		logger.Log.Error().Err(err).Msg("")
+		reqLog(c).Error().Err(err).Msg("")
 		newErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -89,46 +115,125 @@ func (h *Handler) SignUp(c *gin.Context) {
 		"message": "Пользователь создан",
 		"content": result,
 	})
-	logger.Log.Info().Msg("Создали пользователя")
+	reqLog(c).Info().Msg("Создали пользователя")
 
 }
 
 func (h *Handler) SignIn(c *gin.Context) {
-	logger.Log.Info().Msg("Получили запрос на авторизацию пользователя")
+	reqLog(c).Info().Msg("Получили запрос на авторизацию пользователя")
 	var input domain.SignInInput
 	if c.Request.Method != http.MethodPost {
-		logger.Log.Error().Msg("Требуется запрос POST")
+		reqLog(c).Error().Msg("Требуется запрос POST")
 		newErrorResponse(c, http.StatusBadRequest, "Требуется запрос POST")
 		return
 	}
 	if err := c.ShouldBindJSON(&input); err != nil {
-		logger.Log.Error().Err(err).Msg(err.Error())
+		reqLog(c).Error().Err(err).Msg(err.Error())
 		newErrorResponse(c, http.StatusBadRequest, "Неверный запрос")
 		return
 	}
-	logger.Log.Debug().Msgf("Успешно прочитаны почта: %s, пароль: %s", input.Email, input.Password)
-	user, err := h.Usecases.Authorization.SignUser(input.Email, input.Password)
+	reqLog(c).Debug().Msgf("Успешно прочитана почта: %s", input.Email)
+	clientIP := c.ClientIP()
+	if err := h.Usecases.LoginProtection.CheckLogin(c.Request.Context(), input.TenantId, input.Email, clientIP); err != nil {
+		var blocked *usecase.LoginBlockedError
+		if errors.As(err, &blocked) {
+			prometheus.LoginAttemptsTotal.WithLabelValues("blocked").Inc()
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(blocked.RetryAfter.Seconds()))))
+			newErrorResponse(c, http.StatusTooManyRequests, blocked.Error())
+			return
+		}
+		reqLog(c).Error().Err(err).Msg("")
+		newErrorResponse(c, http.StatusInternalServerError, "Ошибка авторизации")
+		return
+	}
+	user, err := h.Usecases.Authorization.SignUser(c.Request.Context(), input.TenantId, input.Email, input.Password)
+	// Неудачная попытка уже учтена в CheckLogin.
+	if errors.Is(err, usecase.ErrInvalidCredentials) || errors.Is(err, repository.ErrUserNotFound) {
+		prometheus.LoginAttemptsTotal.WithLabelValues("failure").Inc()
+	}
+	if errors.Is(err, usecase.ErrUserDisabled) {
+		reqLog(c).Error().Err(err).Msg("")
+		newErrorResponse(c, http.StatusForbidden, "Пользователь заблокирован")
+		return
+	}
 	if err != nil {
-		logger.Log.Error().Err(err).Msg("")
+		reqLog(c).Error().Err(err).Msg("")
 		newErrorResponse(c, http.StatusInternalServerError, "Ошибка авторизации")
 		return
 	}
 	userRole, ok := roleMap[user.Role]
-	logger.Log.Debug().Msgf("Успешно получена роль: %v", userRole)
+	reqLog(c).Debug().Msgf("Успешно получена роль: %v", userRole)
 	if !ok {
 		newErrorResponse(c, http.StatusBadRequest, "Неверная роль")
 		return
 	}
-	token, err := h.Usecases.Authorization.GenerateToken(user.Id, userRole)
+	token, err := h.Usecases.Authorization.GenerateToken(user.Id, userRole, user.TenantId)
 	if err != nil {
-		logger.Log.Error().Err(err).Msg("")
+		reqLog(c).Error().Err(err).Msg("")
 		newErrorResponse(c, http.StatusInternalServerError, "Ошибка создания токена: "+err.Error())
 		return
 	}
+	prometheus.LoginAttemptsTotal.WithLabelValues("success").Inc()
+	if err := h.Usecases.LoginProtection.RegisterLoginSuccess(c.Request.Context(), input.TenantId, input.Email, clientIP); err != nil {
+		reqLog(c).Error().Err(err).Msg("Ошибка сброса счетчика попыток входа")
+	}
 
 	c.JSON(http.StatusOK, map[string]interface{}{
 		"message": "Успешная авторизация",
 		"token":   token,
 	})
-	logger.Log.Info().Msg("Получили токен")
+	reqLog(c).Info().Msg("Получили токен")
+}
+
+func (h *Handler) RequestPasswordReset(c *gin.Context) {
+	reqLog(c).Info().Msg("Получили запрос на сброс пароля")
+	var input domain.PasswordResetRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		reqLog(c).Error().Err(err).Msg(err.Error())
+		newErrorResponse(c, http.StatusBadRequest, "Неверный запрос")
+		return
+	}
+	if err := h.Usecases.PasswordReset.RequestPasswordReset(h.anonymousAuditContext(c, "user.password.reset_request", ""), input.TenantId, input.Email, c.ClientIP()); err != nil {
+		var throttled *usecase.ResetThrottledError
+		if errors.As(err, &throttled) {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+			newErrorResponse(c, http.StatusTooManyRequests, throttled.Error())
+			return
+		}
+		reqLog(c).Error().Err(err).Msg("")
+		newErrorResponse(c, http.StatusInternalServerError, "Ошибка выполнения запроса")
+		return
+	}
+	c.JSON(http.StatusOK, map[string]any{
+		"message": "Если пользователь существует, ему отправлена инструкция по сбросу пароля",
+	})
+	reqLog(c).Info().Msg("Обработали запрос на сброс пароля")
+}
+
+func (h *Handler) ResetPassword(c *gin.Context) {
+	reqLog(c).Info().Msg("Получили запрос на установку нового пароля")
+	var input domain.PasswordResetConfirm
+	if err := c.ShouldBindJSON(&input); err != nil {
+		reqLog(c).Error().Err(err).Msg(err.Error())
+		newErrorResponse(c, http.StatusBadRequest, "Неверный запрос")
+		return
+	}
+	err := h.Usecases.PasswordReset.ResetPassword(h.anonymousAuditContext(c, "user.password.reset", ""), input.Token, input.Password)
+	var policyErr *usecase.PasswordPolicyError
+	switch {
+	case errors.As(err, &policyErr):
+		newErrorResponse(c, http.StatusBadRequest, policyErr.Error())
+		return
+	case errors.Is(err, repository.ErrResetTokenInvalid):
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	case err != nil:
+		reqLog(c).Error().Err(err).Msg("")
+		newErrorResponse(c, http.StatusInternalServerError, "Ошибка выполнения запроса")
+		return
+	}
+	c.JSON(http.StatusOK, map[string]any{
+		"message": "Пароль изменен",
+	})
+	reqLog(c).Info().Msg("Установили новый пароль")
 }
@@ -1,7 +1,6 @@
 package api
 
 import (
-	logger "github.com/bllooop/pvzservice/pkg/logging"
 	"github.com/gin-gonic/gin"
 )
 
@@ -10,6 +9,6 @@ type errorResponse struct {
 }
 
 func newErrorResponse(c *gin.Context, statusCode int, message string) {
-	logger.Log.Error().Msg(message)
+	reqLog(c).Error().Msg(message)
 	c.AbortWithStatusJSON(statusCode, errorResponse{message})
 }
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"context"
+	"strings"
+
+	"github.com/bllooop/pvzservice/internal/domain"
+	"github.com/bllooop/pvzservice/internal/usecase"
+	logger "github.com/bllooop/pvzservice/pkg/logging"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	grpcAuthorization = "authorization"
+	grpcTenant        = "tenant"
+)
+
+type tenantScopeKey struct{}
+
+type claimsKey struct{}
+
+// AuthInterceptor — аналог authIdentity для gRPC: токен передается в
+// метаданных authorization в виде "Bearer <токен>". В контекст вызова
+// кладется компания, которой ограничены запросы к данным.
+func AuthInterceptor(auth usecase.Authorization, env string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		// Проверки состояния вызываются оркестратором без токена.
+		if strings.HasPrefix(info.FullMethod, "/"+healthpb.Health_ServiceDesc.ServiceName+"/") {
+			return handler(ctx, req)
+		}
+		md, _ := metadata.FromIncomingContext(ctx)
+		values := md.Get(grpcAuthorization)
+		if len(values) == 0 || values[0] == "" {
+			return nil, status.Error(codes.Unauthenticated, "Пустой заголовок авторизации")
+		}
+		token, ok := strings.CutPrefix(values[0], "Bearer ")
+		if !ok || token == "" {
+			return nil, status.Error(codes.Unauthenticated, "Некорректный ввод токена")
+		}
+		claims, err := auth.ParseToken(token)
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		if claims.Dummy && env == EnvProd {
+			return nil, status.Error(codes.Unauthenticated, "Тестовые токены не принимаются")
+		}
+		if err := auth.CheckUserActive(ctx, claims); err != nil {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		scope := domain.TenantOf(claims.TenantId)
+		if claims.UserRole == roleMap[roleAdmin] {
+			scope = domain.AllTenants()
+			if tenants := md.Get(grpcTenant); len(tenants) > 0 && tenants[0] != "" {
+				scope = domain.TenantOf(tenants[0])
+			}
+		}
+		l := logger.FromContext(ctx).With().Str("user_id", claims.UserId).Str("tenant_id", claims.TenantId).Logger()
+		ctx = logger.WithLogger(ctx, l)
+		ctx = context.WithValue(ctx, claimsKey{}, claims)
+		return handler(context.WithValue(ctx, tenantScopeKey{}, scope), req)
+	}
+}
+
+// grpcTenantScope возвращает компанию, которую AuthInterceptor положил в контекст.
+func grpcTenantScope(ctx context.Context) (domain.TenantScope, bool) {
+	scope, ok := ctx.Value(tenantScopeKey{}).(domain.TenantScope)
+	return scope, ok
+}
+
+// grpcClaims возвращает данные токена, проверенного AuthInterceptor.
+func grpcClaims(ctx context.Context) (domain.TokenClaims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(domain.TokenClaims)
+	return claims, ok
+}
+
+// ServiceToken выпускает токен суперадминистратора для внутренних вызовов
+// gRPC самим сервисом от имени служебной учетной записи.
+func ServiceToken(auth usecase.Authorization) (string, error) {
+	return auth.GenerateToken(domain.ServiceUserId, roleMap[roleAdmin], domain.ServiceTenant)
+}
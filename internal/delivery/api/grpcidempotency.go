@@ -0,0 +1,111 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/bllooop/pvzservice/internal/ratelimit"
+	"github.com/bllooop/pvzservice/internal/usecase"
+	logger "github.com/bllooop/pvzservice/pkg/logging"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+const (
+	grpcIdempotencyKey   = "idempotency-key"
+	grpcIdempotencyScope = "grpc"
+)
+
+// IdempotencyInterceptor — аналог заголовка Idempotency-Key для gRPC: ключ
+// передается в метаданных idempotency-key и действует только для изменяющих
+// методов (группа write, см. grpcMethodGroup). Сохраняются только успешные
+// ответы, они упаковываются в Any, чтобы при повторе восстановить исходный тип.
+func IdempotencyInterceptor(idem usecase.Idempotency) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		keys := md.Get(grpcIdempotencyKey)
+		if len(keys) == 0 || keys[0] == "" || grpcMethodGroup(info.FullMethod) != ratelimit.GroupWrite {
+			return handler(ctx, req)
+		}
+		key := keys[0]
+		if len(key) > maxIdempotencyKeyLength {
+			return nil, status.Error(codes.InvalidArgument, "Слишком длинный ключ идемпотентности")
+		}
+		// Как и в HTTP, ключи принадлежат пользователю: иначе повтор вернул бы
+		// ответ, выданный другому пользователю той же компании.
+		claims, ok := grpcClaims(ctx)
+		if !ok {
+			return handler(ctx, req)
+		}
+		scope := grpcIdempotencyScope + ":" + claims.UserId
+		msg, ok := req.(proto.Message)
+		if !ok {
+			return handler(ctx, req)
+		}
+		body, err := proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+
+		stored, err := idem.BeginIdempotent(ctx, scope, key, requestHash(http.MethodPost, info.FullMethod, body))
+		switch {
+		case errors.Is(err, usecase.ErrIdempotencyKeyReused):
+			return nil, status.Error(codes.FailedPrecondition, err.Error())
+		case errors.Is(err, usecase.ErrIdempotencyInProgress):
+			return nil, status.Error(codes.Aborted, err.Error())
+		case err != nil:
+			logger.FromContext(ctx).Error().Err(err).Msg("")
+			return nil, status.Error(codes.Internal, "Ошибка проверки ключа идемпотентности")
+		case stored != nil:
+			var packed anypb.Any
+			if err := proto.Unmarshal(stored.Response, &packed); err != nil {
+				return nil, status.Error(codes.Internal, err.Error())
+			}
+			logger.FromContext(ctx).Info().Str("key", key).Msg("Повторный gRPC запрос, возвращается сохраненный ответ")
+			return packed.UnmarshalNew()
+		}
+
+		// Ключ фиксируется и при отмене вызова клиентом, иначе он остался бы
+		// занятым до истечения срока хранения.
+		saveCtx := context.WithoutCancel(ctx)
+		defer func() {
+			if p := recover(); p != nil {
+				if err := idem.ReleaseIdempotent(saveCtx, scope, key); err != nil {
+					logger.FromContext(ctx).Error().Err(err).Msg("Не удалось освободить ключ идемпотентности")
+				}
+				panic(p)
+			}
+		}()
+		resp, err = handler(ctx, req)
+		ctx = saveCtx
+		if err != nil {
+			if err := idem.ReleaseIdempotent(ctx, scope, key); err != nil {
+				logger.FromContext(ctx).Error().Err(err).Msg("Не удалось освободить ключ идемпотентности")
+			}
+			return resp, err
+		}
+		if respMsg, ok := resp.(proto.Message); ok {
+			if err := completeGRPCIdempotent(ctx, idem, scope, key, respMsg); err != nil {
+				logger.FromContext(ctx).Error().Err(err).Msg("Не удалось сохранить ответ по ключу идемпотентности")
+			}
+		}
+		return resp, nil
+	}
+}
+
+func completeGRPCIdempotent(ctx context.Context, idem usecase.Idempotency, scope, key string, resp proto.Message) error {
+	packed, err := anypb.New(resp)
+	if err != nil {
+		return err
+	}
+	data, err := proto.Marshal(packed)
+	if err != nil {
+		return err
+	}
+	return idem.CompleteIdempotent(ctx, scope, key, http.StatusOK, data)
+}
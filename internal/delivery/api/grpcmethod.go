@@ -2,10 +2,14 @@ package api
 
 import (
 	"context"
+	"errors"
 
 	pb "github.com/bllooop/pvzservice/grpcpvz"
+	"github.com/bllooop/pvzservice/internal/domain"
 	"github.com/bllooop/pvzservice/internal/usecase"
 	logger "github.com/bllooop/pvzservice/pkg/logging"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
@@ -18,25 +22,67 @@ func NewPVZServiceServer(s *usecase.Usecase) *PVZServiceServerHandle {
 	return &PVZServiceServerHandle{usecase: s}
 }
 func (g *PVZServiceServerHandle) GetPVZList(ctx context.Context, req *pb.GetPVZListRequest) (*pb.GetPVZListResponse, error) {
-	pvzs, err := g.usecase.GetListOFpvz(ctx)
+	scope, ok := grpcTenantScope(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "Компания пользователя не найдена")
+	}
+	pvzs, err := g.usecase.GetListOFpvz(ctx, scope)
 	if err != nil {
-		logger.Log.Error().Err(err).Msg("")
+		logger.FromContext(ctx).Error().Err(err).Msg("")
 		return nil, err
 	}
 
 	var pvzList []*pb.PVZ
 	for _, pvz := range pvzs {
-		var registrationDate *timestamppb.Timestamp
-		if pvz.DateRegister != nil {
-			registrationDate = timestamppb.New(*pvz.DateRegister)
-		}
-		pvzList = append(pvzList, &pb.PVZ{
-			Id:               pvz.Id.String(),
-			RegistrationDate: registrationDate,
-			City:             pvz.City,
-		})
-	}
-	logger.Log.Debug().Any("pvz", pvzList).Msg("Получен список ПВЗ")
+		pvzList = append(pvzList, pvzToProto(pvz))
+	}
+	logger.FromContext(ctx).Debug().Any("pvz", pvzList).Msg("Получен список ПВЗ")
 
 	return &pb.GetPVZListResponse{Pvzs: pvzList}, nil
 }
+
+func (g *PVZServiceServerHandle) GetNearestPVZ(ctx context.Context, req *pb.GetNearestPVZRequest) (*pb.GetNearestPVZResponse, error) {
+	scope, ok := grpcTenantScope(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "Компания пользователя не найдена")
+	}
+	pvzs, err := g.usecase.GetNearestPvz(ctx, scope, domain.NearestPvzParams{
+		Latitude:  req.GetLatitude(),
+		Longitude: req.GetLongitude(),
+		RadiusKm:  req.GetRadiusKm(),
+		Limit:     int(req.GetLimit()),
+	})
+	if errors.Is(err, usecase.ErrInvalidGeoQuery) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err != nil {
+		logger.FromContext(ctx).Error().Err(err).Msg("")
+		return nil, err
+	}
+	var nearest []*pb.NearestPVZ
+	for _, pvz := range pvzs {
+		nearest = append(nearest, &pb.NearestPVZ{Pvz: pvzToProto(pvz.PVZ), DistanceKm: pvz.DistanceKm})
+	}
+	logger.FromContext(ctx).Debug().Int("count", len(nearest)).Msg("Получен список ближайших ПВЗ")
+	return &pb.GetNearestPVZResponse{Pvzs: nearest}, nil
+}
+
+func pvzToProto(pvz domain.PVZ) *pb.PVZ {
+	var registrationDate *timestamppb.Timestamp
+	if pvz.DateRegister != nil {
+		registrationDate = timestamppb.New(*pvz.DateRegister)
+	}
+	return &pb.PVZ{
+		Id:               pvz.Id.String(),
+		RegistrationDate: registrationDate,
+		City:             pvz.City,
+		Address:          pvz.Address,
+		PostalCode:       pvz.PostalCode,
+		WorkingHours:     pvz.WorkingHours,
+		Latitude:         pvz.Latitude,
+		Longitude:        pvz.Longitude,
+		Status:           pvz.Status,
+		Timezone:         pvz.Timezone,
+		TenantId:         pvz.TenantId,
+	}
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	pb "github.com/bllooop/pvzservice/grpcpvz"
+	"github.com/bllooop/pvzservice/internal/domain"
+	"github.com/bllooop/pvzservice/internal/usecase"
+	mock_usecase "github.com/bllooop/pvzservice/internal/usecase/mocks"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestPVZServiceServer_GetNearestPVZ(t *testing.T) {
+	type mockBehavior func(s *mock_usecase.MockPvz)
+	pvzId := uuid.New()
+	scoped := context.WithValue(context.Background(), tenantScopeKey{}, testScope)
+
+	testTable := []struct {
+		name         string
+		ctx          context.Context
+		req          *pb.GetNearestPVZRequest
+		mockBehavior mockBehavior
+		wantIds      []string
+		wantCode     codes.Code
+	}{
+		{
+			name: "OK",
+			ctx:  scoped,
+			req:  &pb.GetNearestPVZRequest{Latitude: 55.75, Longitude: 37.62, RadiusKm: 3, Limit: 5},
+			mockBehavior: func(s *mock_usecase.MockPvz) {
+				s.EXPECT().GetNearestPvz(gomock.Any(), testScope, domain.NearestPvzParams{Latitude: 55.75, Longitude: 37.62, RadiusKm: 3, Limit: 5}).
+					Return([]domain.PvzDistance{{PVZ: domain.PVZ{Id: &pvzId, City: "Москва"}, DistanceKm: 0.63}}, nil)
+			},
+			wantIds:  []string{pvzId.String()},
+			wantCode: codes.OK,
+		},
+		{
+			name: "Некорректные координаты",
+			ctx:  scoped,
+			req:  &pb.GetNearestPVZRequest{Latitude: math.NaN(), Longitude: 37.62},
+			mockBehavior: func(s *mock_usecase.MockPvz) {
+				s.EXPECT().GetNearestPvz(gomock.Any(), testScope, gomock.Any()).Return(nil, usecase.ErrInvalidGeoQuery)
+			},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:         "Нет компании пользователя",
+			ctx:          context.Background(),
+			req:          &pb.GetNearestPVZRequest{Latitude: 55.75, Longitude: 37.62},
+			mockBehavior: func(s *mock_usecase.MockPvz) {},
+			wantCode:     codes.Unauthenticated,
+		},
+		{
+			name: "Ошибка выполнения",
+			ctx:  scoped,
+			req:  &pb.GetNearestPVZRequest{Latitude: 55.75, Longitude: 37.62},
+			mockBehavior: func(s *mock_usecase.MockPvz) {
+				s.EXPECT().GetNearestPvz(gomock.Any(), testScope, gomock.Any()).Return(nil, errors.New("ошибка бд"))
+			},
+			wantCode: codes.Unknown,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			pvz := mock_usecase.NewMockPvz(c)
+			testCase.mockBehavior(pvz)
+
+			server := NewPVZServiceServer(&usecase.Usecase{Pvz: pvz})
+			resp, err := server.GetNearestPVZ(testCase.ctx, testCase.req)
+
+			assert.Equal(t, testCase.wantCode, status.Code(err))
+			if testCase.wantCode != codes.OK {
+				return
+			}
+			var ids []string
+			for _, nearest := range resp.GetPvzs() {
+				ids = append(ids, nearest.GetPvz().GetId())
+				assert.Equal(t, 0.63, nearest.GetDistanceKm())
+			}
+			assert.Equal(t, testCase.wantIds, ids)
+		})
+	}
+}
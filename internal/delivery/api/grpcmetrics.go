@@ -0,0 +1,23 @@
+package api
+
+import (
+	"context"
+	"time"
+
+	"github.com/bllooop/pvzservice/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// MetricsInterceptor — аналог PrometheusMiddleware для gRPC: считает вызовы и
+// их продолжительность по методу и коду ответа.
+func MetricsInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		code := status.Code(err).String()
+		prometheus.GRPCRequestTotal.WithLabelValues(info.FullMethod, code).Inc()
+		prometheus.GRPCRequestDuration.WithLabelValues(info.FullMethod, code).Observe(time.Since(start).Seconds())
+		return resp, err
+	}
+}
@@ -0,0 +1,32 @@
+package api
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bllooop/pvzservice/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestMetricsInterceptor(t *testing.T) {
+	const method = "/pvz.v1.PVZService/GetPVZList"
+	interceptor := MetricsInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: method}
+
+	ok := testutil.ToFloat64(prometheus.GRPCRequestTotal.WithLabelValues(method, "OK"))
+	denied := testutil.ToFloat64(prometheus.GRPCRequestTotal.WithLabelValues(method, "Unauthenticated"))
+
+	_, _ = interceptor(context.Background(), nil, info, func(ctx context.Context, req any) (any, error) {
+		return "ok", nil
+	})
+	_, _ = interceptor(context.Background(), nil, info, func(ctx context.Context, req any) (any, error) {
+		return nil, status.Error(codes.Unauthenticated, "Пустой заголовок авторизации")
+	})
+
+	assert.Equal(t, ok+1, testutil.ToFloat64(prometheus.GRPCRequestTotal.WithLabelValues(method, "OK")))
+	assert.Equal(t, denied+1, testutil.ToFloat64(prometheus.GRPCRequestTotal.WithLabelValues(method, "Unauthenticated")))
+}
@@ -1,22 +1,82 @@
 package api
 
 import (
+	"net/http"
 	"time"
 
+	"github.com/bllooop/pvzservice/internal/ratelimit"
 	"github.com/bllooop/pvzservice/internal/usecase"
+	logger "github.com/bllooop/pvzservice/pkg/logging"
+	"github.com/bllooop/pvzservice/pkg/tracing"
+	"github.com/bllooop/pvzservice/prometheus"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
+const (
+	EnvDev  = "dev"
+	EnvTest = "test"
+	EnvProd = "prod"
+)
+
+// DefaultMaxPageLimit - наибольший размер страницы списков, если в Config он
+// не задан.
+const DefaultMaxPageLimit = 30
+
+// Config задает параметры HTTP API, не зависящие от usecase.
+type Config struct {
+	// Env - профиль окружения (dev, test или prod).
+	Env string
+	// CORSOrigins - источники, которым разрешены запросы из браузера. Пустой
+	// список разрешает любые.
+	CORSOrigins []string
+	// TrustedProxies - адреса и подсети прокси, заголовкам X-Forwarded-For и
+	// X-Real-IP которых можно доверять. Пустой список - IP клиента всегда
+	// берется из адреса соединения.
+	TrustedProxies []string
+	// MaxPageLimit ограничивает параметр limit списков.
+	MaxPageLimit int
+	// RateLimit ограничивает частоту запросов. Если не задан, запросы не
+	// ограничиваются.
+	RateLimit *ratelimit.Guard
+}
+
 type Handler struct {
 	Usecases *usecase.Usecase
 	Now      func() time.Time
+	// Env - профиль окружения (dev, test или prod). В prod не регистрируется /dummyLogin
+	// и не принимаются выданные им токены.
+	Env            string
+	CORSOrigins    []string
+	TrustedProxies []string
+	MaxPageLimit   int
+	RateLimit      *ratelimit.Guard
+	Probes         Probes
+}
+
+func NewHandler(usecases *usecase.Usecase, cfg Config) *Handler {
+	return &Handler{
+		Usecases:       usecases,
+		Now:            func() time.Time { return time.Now().UTC() },
+		Env:            cfg.Env,
+		CORSOrigins:    cfg.CORSOrigins,
+		TrustedProxies: cfg.TrustedProxies,
+		MaxPageLimit:   cfg.MaxPageLimit,
+		RateLimit:      cfg.RateLimit,
+	}
 }
 
-func NewHandler(usecases *usecase.Usecase) *Handler {
-	return &Handler{Usecases: usecases, Now: func() time.Time { return time.Now() }}
+// maxPageLimit возвращает MaxPageLimit или DefaultMaxPageLimit, если он не
+// задан.
+func (h *Handler) maxPageLimit() int {
+	if h.MaxPageLimit > 0 {
+		return h.MaxPageLimit
+	}
+	return DefaultMaxPageLimit
 }
+
 func NewHandlerWithFixedTime(usecases *usecase.Usecase, fixedTime time.Time) *Handler {
 	return &Handler{
 		Usecases: usecases,
@@ -26,22 +86,73 @@ func NewHandlerWithFixedTime(usecases *usecase.Usecase, fixedTime time.Time) *Ha
 
 func (h *Handler) InitRoutes() *gin.Engine {
 	router := gin.New()
+	// Лимиты и блокировка входа считаются по IP клиента, поэтому заголовкам
+	// X-Forwarded-For доверяем только от явно указанных прокси.
+	if err := router.SetTrustedProxies(h.TrustedProxies); err != nil {
+		logger.Log.Error().Err(err).Msg("Неверный список доверенных прокси, IP клиента берется из адреса соединения")
+		router.SetTrustedProxies(nil)
+	}
+	// Спан запроса создается первым, чтобы в него попадало время всех
+	// остальных middleware. Запросы к /metrics и пробам не трассируются.
+	router.Use(otelgin.Middleware(tracing.DefaultServiceName, otelgin.WithFilter(func(r *http.Request) bool {
+		switch r.URL.Path {
+		case "/metrics", "/healthz", "/readyz":
+			return false
+		}
+		return true
+	})))
+	router.Use(h.requestId)
+	origins := h.CORSOrigins
+	if len(origins) == 0 {
+		origins = []string{"*"}
+	}
 	router.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"*"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
+		AllowOrigins:     origins,
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "Idempotency-Key", "X-Request-Id", "traceparent", "tracestate"},
+		ExposeHeaders:    []string{"X-Request-Id"},
 		AllowCredentials: true,
 	}))
 	router.Use(h.PrometheusMiddleware())
-	router.POST("/register", h.SignUp)
-	router.POST("/login", h.SignIn)
-	router.POST("/dummyLogin", h.DummyLogin)
-	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
-	router.POST("/pvz", h.authIdentity, h.CreatePvz)
+	router.Use(h.recovery)
+	authLimit := h.rateLimitByIP(ratelimit.GroupAuth)
+	router.POST("/register", authLimit, h.SignUp)
+	router.POST("/login", authLimit, h.SignIn)
+	if h.Env != EnvProd {
+		router.POST("/dummyLogin", authLimit, h.DummyLogin)
+	}
+	router.POST("/password/reset/request", authLimit, h.RequestPasswordReset)
+	router.POST("/password/reset/confirm", authLimit, h.ResetPassword)
+	router.GET("/healthz", h.Healthz)
+	router.GET("/readyz", h.Readyz)
+	router.GET("/version", h.Version)
+	router.GET("/metrics", gin.WrapH(promhttp.HandlerFor(prometheus.Registry, promhttp.HandlerOpts{ErrorHandling: promhttp.ContinueOnError})))
+	router.POST("/pvz", h.authIdentity, h.idempotency, h.CreatePvz)
 	router.GET("/pvz", h.authIdentity, h.GetPvz)
-	router.POST("/pvz/:pvzId/close_last_reception", h.authIdentity, h.CloseLast)
-	router.POST("/pvz/:pvzId/delete_last_product", h.authIdentity, h.DeleteLast)
-	router.POST("/receptions", h.authIdentity, h.CreateReceptions)
-	router.POST("/products", h.authIdentity, h.AddProducts)
+	router.GET("/pvz/nearest", h.authIdentity, h.GetNearestPvz)
+	router.GET("/pvz/occupancy", h.authIdentity, h.GetPvzOccupancy)
+	router.GET("/pvz/:pvzId/schedule", h.authIdentity, h.GetPvzSchedule)
+	router.GET("/pvz/:pvzId/report", h.authIdentity, h.GetPvzReport)
+	router.PUT("/pvz/:pvzId/schedule", h.authIdentity, h.idempotency, h.SetPvzSchedule)
+	router.PATCH("/pvz/:pvzId", h.authIdentity, h.idempotency, h.UpdatePvz)
+	router.POST("/pvz/:pvzId/close_last_reception", h.authIdentity, h.idempotency, h.CloseLast)
+	router.POST("/pvz/:pvzId/delete_last_product", h.authIdentity, h.idempotency, h.DeleteLast)
+	router.POST("/pvz/:pvzId/delete_product/:productId", h.authIdentity, h.idempotency, h.DeleteProduct)
+	router.POST("/pvz/:pvzId/issue_product/:productId", h.authIdentity, h.idempotency, h.IssueProduct)
+	router.POST("/pvz/:pvzId/transfer_product/:productId", h.authIdentity, h.idempotency, h.ShipProduct)
+	router.POST("/transfers/:transferId/accept", h.authIdentity, h.idempotency, h.AcceptTransfer)
+	router.GET("/transfers/in_transit", h.authIdentity, h.GetTransfersInTransit)
+	router.POST("/receptions", h.authIdentity, h.idempotency, h.CreateReceptions)
+	router.POST("/receptions/:receptionId/amendments", h.authIdentity, h.idempotency, h.RequestAmendment)
+	router.GET("/receptions/:receptionId/amendments", h.authIdentity, h.GetAmendments)
+	router.GET("/receptions/:receptionId/history", h.authIdentity, h.GetReceptionHistory)
+	router.POST("/amendments/:amendmentId/approve", h.authIdentity, h.idempotency, h.ApproveAmendment)
+	router.POST("/amendments/:amendmentId/reject", h.authIdentity, h.idempotency, h.RejectAmendment)
+	router.POST("/products", h.authIdentity, h.idempotency, h.AddProducts)
+	router.GET("/users", h.authIdentity, h.GetUsers)
+	router.POST("/users/unlock", h.authIdentity, h.idempotency, h.UnlockLogin)
+	router.PATCH("/users/:userId", h.authIdentity, h.idempotency, h.UpdateUser)
+	router.DELETE("/users/:userId", h.authIdentity, h.idempotency, h.DeleteUser)
+	router.GET("/audit", h.authIdentity, h.GetAudit)
 	return router
 }
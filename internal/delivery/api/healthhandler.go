@@ -0,0 +1,56 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/bllooop/pvzservice/internal/health"
+	"github.com/bllooop/pvzservice/pkg/buildinfo"
+	"github.com/gin-gonic/gin"
+)
+
+// Probes — зависимости служебных маршрутов /readyz и /version. Если Readiness
+// не задан, сервис считается готовым.
+type Probes struct {
+	Readiness         *health.Checker
+	MigrationVersion  func(ctx context.Context) (int64, error)
+	ExpectedMigration int64
+}
+
+// Healthz отвечает, что процесс жив. Зависимости не проверяются, чтобы сбой
+// базы не приводил к перезапуску контейнера.
+func (h *Handler) Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, map[string]any{"status": health.StatusUp})
+}
+
+// Readyz проверяет зависимости и отвечает 503, если хотя бы одна недоступна.
+func (h *Handler) Readyz(c *gin.Context) {
+	if h.Probes.Readiness == nil {
+		c.JSON(http.StatusOK, health.Report{Status: health.StatusUp, Components: map[string]health.Component{}})
+		return
+	}
+	report := h.Probes.Readiness.Check(c.Request.Context())
+	status := http.StatusOK
+	if report.Status != health.StatusUp {
+		reqLog(c).Warn().Any("components", report.Components).Msg("Сервис не готов обслуживать запросы")
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, report)
+}
+
+// Version возвращает сведения о сборке и версию схемы базы.
+func (h *Handler) Version(c *gin.Context) {
+	response := map[string]any{
+		"build":                    buildinfo.Get(),
+		"expectedMigrationVersion": h.Probes.ExpectedMigration,
+	}
+	if h.Probes.MigrationVersion != nil {
+		version, err := h.Probes.MigrationVersion(c.Request.Context())
+		if err != nil {
+			reqLog(c).Error().Err(err).Msg("Ошибка получения версии миграций")
+		} else {
+			response["migrationVersion"] = version
+		}
+	}
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bllooop/pvzservice/internal/health"
+	"github.com/bllooop/pvzservice/internal/usecase"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler_healthz(t *testing.T) {
+	handler := NewHandlerWithFixedTime(&usecase.Usecase{}, time.Now())
+	r := gin.New()
+	r.GET("/healthz", handler.Healthz)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/healthz", nil))
+
+	assert.Equal(t, 200, w.Code)
+	assert.JSONEq(t, `{"status":"up"}`, w.Body.String())
+}
+
+func TestHandler_readyz(t *testing.T) {
+	testTable := []struct {
+		name                 string
+		dbErr                error
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:                 "Ok",
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"status":"up","components":{"database":{"status":"up"}}}`,
+		},
+		{
+			name:                 "База недоступна",
+			dbErr:                errors.New("connection refused"),
+			expectedStatusCode:   503,
+			expectedResponseBody: `{"status":"down","components":{"database":{"status":"down","error":"connection refused"}}}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			readiness := health.NewChecker(time.Second)
+			readiness.Register("database", func(ctx context.Context) error { return testCase.dbErr })
+
+			handler := NewHandlerWithFixedTime(&usecase.Usecase{}, time.Now())
+			handler.Probes = Probes{Readiness: readiness}
+			r := gin.New()
+			r.GET("/readyz", handler.Readyz)
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest("GET", "/readyz", nil))
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.JSONEq(t, testCase.expectedResponseBody, w.Body.String())
+		})
+	}
+}
+
+func TestHandler_version(t *testing.T) {
+	handler := NewHandlerWithFixedTime(&usecase.Usecase{}, time.Now())
+	handler.Probes = Probes{
+		ExpectedMigration: 12,
+		MigrationVersion:  func(ctx context.Context) (int64, error) { return 11, nil },
+	}
+	r := gin.New()
+	r.GET("/version", handler.Version)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/version", nil))
+
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Body.String(), `"expectedMigrationVersion":12`)
+	assert.Contains(t, w.Body.String(), `"migrationVersion":11`)
+	assert.Contains(t, w.Body.String(), `"goVersion"`)
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/bllooop/pvzservice/internal/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	idempotencyKeyHeader      = "Idempotency-Key"
+	idempotencyReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength   = 255
+)
+
+// responseRecorder дублирует тело ответа, чтобы сохранить его по ключу идемпотентности.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// idempotency повторно отдает сохраненный ответ, если запрос с тем же
+// Idempotency-Key уже выполнялся от имени этого пользователя. Ответы 5xx не
+// сохраняются, чтобы клиент мог повторить запрос после сбоя. При панике
+// обработчика ключ освобождается, а паника передается дальше.
+func (h *Handler) idempotency(c *gin.Context) {
+	key := c.GetHeader(idempotencyKeyHeader)
+	if key == "" {
+		c.Next()
+		return
+	}
+	if len(key) > maxIdempotencyKeyLength {
+		newErrorResponse(c, http.StatusBadRequest, "Слишком длинный ключ идемпотентности")
+		return
+	}
+	scope, err := getUserId(c)
+	if err != nil {
+		reqLog(c).Error().Err(err).Msg("")
+		newErrorResponse(c, http.StatusInternalServerError, "Ошибка получения пользователя "+err.Error())
+		return
+	}
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		reqLog(c).Error().Err(err).Msg("")
+		newErrorResponse(c, http.StatusBadRequest, "Неверный запрос")
+		return
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	stored, err := h.Usecases.Idempotency.BeginIdempotent(c.Request.Context(), scope, key, requestHash(c.Request.Method, c.Request.URL.Path, body))
+	switch {
+	case errors.Is(err, usecase.ErrIdempotencyKeyReused), errors.Is(err, usecase.ErrIdempotencyInProgress):
+		newErrorResponse(c, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		reqLog(c).Error().Err(err).Msg("")
+		newErrorResponse(c, http.StatusInternalServerError, "Ошибка проверки ключа идемпотентности "+err.Error())
+		return
+	case stored != nil:
+		reqLog(c).Info().Str("key", key).Msg("Повторный запрос, возвращается сохраненный ответ")
+		c.Header(idempotencyReplayedHeader, "true")
+		c.Data(stored.StatusCode, "application/json; charset=utf-8", stored.Response)
+		c.Abort()
+		return
+	}
+
+	// Ключ фиксируется и при разрыве соединения клиентом, иначе он остался
+	// бы занятым до истечения срока хранения.
+	ctx := context.WithoutCancel(c.Request.Context())
+	defer func() {
+		if p := recover(); p != nil {
+			if err := h.Usecases.Idempotency.ReleaseIdempotent(ctx, scope, key); err != nil {
+				reqLog(c).Error().Err(err).Msg("Не удалось освободить ключ идемпотентности")
+			}
+			panic(p)
+		}
+	}()
+	recorder := &responseRecorder{ResponseWriter: c.Writer}
+	c.Writer = recorder
+	c.Next()
+
+	status := recorder.Status()
+	if status >= http.StatusInternalServerError {
+		if err := h.Usecases.Idempotency.ReleaseIdempotent(ctx, scope, key); err != nil {
+			reqLog(c).Error().Err(err).Msg("Не удалось освободить ключ идемпотентности")
+		}
+		return
+	}
+	if err := h.Usecases.Idempotency.CompleteIdempotent(ctx, scope, key, status, recorder.body.Bytes()); err != nil {
+		reqLog(c).Error().Err(err).Msg("Не удалось сохранить ответ по ключу идемпотентности")
+	}
+}
+
+func requestHash(method, path string, body []byte) string {
+	sum := sha256.New()
+	sum.Write([]byte(method))
+	sum.Write([]byte{0})
+	sum.Write([]byte(path))
+	sum.Write([]byte{0})
+	sum.Write(body)
+	return hex.EncodeToString(sum.Sum(nil))
+}
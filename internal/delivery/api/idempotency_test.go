@@ -0,0 +1,209 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	pb "github.com/bllooop/pvzservice/grpcpvz"
+	"github.com/bllooop/pvzservice/internal/domain"
+	"github.com/bllooop/pvzservice/internal/usecase"
+	mock_usecase "github.com/bllooop/pvzservice/internal/usecase/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+func TestHandler_idempotency(t *testing.T) {
+	type mockBehavior func(s *mock_usecase.MockIdempotency, hash string)
+	const body = `{"type":"обувь"}`
+	hash := requestHash(http.MethodPost, "/products", []byte(body))
+	testTable := []struct {
+		name                 string
+		key                  string
+		handlerStatus        int
+		handlerPanics        bool
+		mockBehavior         mockBehavior
+		expectedCalls        int
+		expectedStatusCode   int
+		expectedResponseBody string
+		expectedReplayed     string
+	}{
+		{
+			name:                 "Без ключа",
+			handlerStatus:        http.StatusOK,
+			mockBehavior:         func(s *mock_usecase.MockIdempotency, hash string) {},
+			expectedCalls:        1,
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"message":"ok"}`,
+		},
+		{
+			name:          "Первый запрос",
+			key:           "k1",
+			handlerStatus: http.StatusOK,
+			mockBehavior: func(s *mock_usecase.MockIdempotency, hash string) {
+				s.EXPECT().BeginIdempotent(gomock.Any(), "u1", "k1", hash).Return(nil, nil)
+				s.EXPECT().CompleteIdempotent(gomock.Any(), "u1", "k1", 200, []byte(`{"message":"ok"}`)).Return(nil)
+			},
+			expectedCalls:        1,
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"message":"ok"}`,
+		},
+		{
+			name: "Повторный запрос",
+			key:  "k1",
+			mockBehavior: func(s *mock_usecase.MockIdempotency, hash string) {
+				s.EXPECT().BeginIdempotent(gomock.Any(), "u1", "k1", hash).Return(&domain.IdempotencyRecord{StatusCode: 200, Response: []byte(`{"message":"ok"}`)}, nil)
+			},
+			expectedCalls:        0,
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"message":"ok"}`,
+			expectedReplayed:     "true",
+		},
+		{
+			name: "Ключ использован для другого запроса",
+			key:  "k1",
+			mockBehavior: func(s *mock_usecase.MockIdempotency, hash string) {
+				s.EXPECT().BeginIdempotent(gomock.Any(), "u1", "k1", hash).Return(nil, usecase.ErrIdempotencyKeyReused)
+			},
+			expectedCalls:        0,
+			expectedStatusCode:   409,
+			expectedResponseBody: `{"message":"ключ идемпотентности уже использован для другого запроса"}`,
+		},
+		{
+			name:          "Ошибка сервера освобождает ключ",
+			key:           "k1",
+			handlerStatus: http.StatusInternalServerError,
+			mockBehavior: func(s *mock_usecase.MockIdempotency, hash string) {
+				s.EXPECT().BeginIdempotent(gomock.Any(), "u1", "k1", hash).Return(nil, nil)
+				s.EXPECT().ReleaseIdempotent(gomock.Any(), "u1", "k1").Return(nil)
+			},
+			expectedCalls:        1,
+			expectedStatusCode:   500,
+			expectedResponseBody: `{"message":"ok"}`,
+		},
+		{
+			name:          "Паника освобождает ключ",
+			key:           "k1",
+			handlerPanics: true,
+			mockBehavior: func(s *mock_usecase.MockIdempotency, hash string) {
+				s.EXPECT().BeginIdempotent(gomock.Any(), "u1", "k1", hash).Return(nil, nil)
+				s.EXPECT().ReleaseIdempotent(gomock.Any(), "u1", "k1").Return(nil)
+			},
+			expectedCalls:        1,
+			expectedStatusCode:   500,
+			expectedResponseBody: `{"message":"Внутренняя ошибка сервера"}`,
+		},
+	}
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			idem := mock_usecase.NewMockIdempotency(c)
+			testCase.mockBehavior(idem, hash)
+
+			handler := Handler{Usecases: &usecase.Usecase{Idempotency: idem}}
+			calls := 0
+			r := gin.New()
+			r.POST("/products", handler.recovery, func(c *gin.Context) {
+				c.Set(userId, "u1")
+			}, handler.idempotency, func(c *gin.Context) {
+				calls++
+				if testCase.handlerPanics {
+					panic("сбой обработчика")
+				}
+				c.JSON(testCase.handlerStatus, map[string]any{"message": "ok"})
+			})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/products", strings.NewReader(body))
+			if testCase.key != "" {
+				req.Header.Set(idempotencyKeyHeader, testCase.key)
+			}
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedCalls, calls)
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedResponseBody, w.Body.String())
+			assert.Equal(t, testCase.expectedReplayed, w.Header().Get(idempotencyReplayedHeader))
+		})
+	}
+}
+
+func TestIdempotencyInterceptor(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	req := &pb.GetPVZListRequest{}
+	resp := &pb.GetPVZListResponse{Pvzs: []*pb.PVZ{{Id: "p1", City: "Москва"}}}
+	packed, err := anypb.New(resp)
+	assert.NoError(t, err)
+	stored, err := proto.Marshal(packed)
+	assert.NoError(t, err)
+
+	info := &grpc.UnaryServerInfo{FullMethod: "/pvz.v1.PVZService/CreatePVZ"}
+	hash := requestHash(http.MethodPost, info.FullMethod, nil)
+	scope := grpcIdempotencyScope + ":u1"
+	idem := mock_usecase.NewMockIdempotency(c)
+	gomock.InOrder(
+		idem.EXPECT().BeginIdempotent(gomock.Any(), scope, "k1", hash).Return(nil, nil),
+		idem.EXPECT().CompleteIdempotent(gomock.Any(), scope, "k1", http.StatusOK, stored).Return(nil),
+		idem.EXPECT().BeginIdempotent(gomock.Any(), scope, "k1", hash).Return(&domain.IdempotencyRecord{StatusCode: http.StatusOK, Response: stored}, nil),
+		idem.EXPECT().BeginIdempotent(gomock.Any(), scope, "k2", hash).Return(nil, nil),
+		idem.EXPECT().ReleaseIdempotent(gomock.Any(), scope, "k2").Return(nil),
+		idem.EXPECT().BeginIdempotent(gomock.Any(), grpcIdempotencyScope+":u2", "k1", hash).Return(nil, nil),
+		idem.EXPECT().ReleaseIdempotent(gomock.Any(), grpcIdempotencyScope+":u2", "k1").Return(nil),
+	)
+	interceptor := IdempotencyInterceptor(idem)
+	calls := 0
+	handler := func(ctx context.Context, req any) (any, error) {
+		calls++
+		if calls == 2 {
+			return nil, errors.New("unavailable")
+		}
+		return resp, nil
+	}
+	withKey := func(userId, key string) context.Context {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(grpcIdempotencyKey, key))
+		return context.WithValue(ctx, claimsKey{}, domain.TokenClaims{UserId: userId, TenantId: "t1"})
+	}
+
+	got, err := interceptor(withKey("u1", "k1"), req, info, handler)
+	assert.NoError(t, err)
+	assert.Same(t, resp, got)
+
+	got, err = interceptor(withKey("u1", "k1"), req, info, handler)
+	assert.NoError(t, err)
+	assert.True(t, proto.Equal(resp, got.(proto.Message)))
+	assert.Equal(t, 1, calls, "повтор не вызывает обработчик")
+
+	_, err = interceptor(withKey("u1", "k2"), req, info, handler)
+	assert.Error(t, err)
+
+	assert.Panics(t, func() {
+		_, _ = interceptor(withKey("u2", "k1"), req, info, func(ctx context.Context, req any) (any, error) {
+			panic("сбой обработчика")
+		})
+	}, "ключ освобождается, паника передается дальше")
+
+	readInfo := &grpc.UnaryServerInfo{FullMethod: "/pvz.v1.PVZService/GetPVZList"}
+	got, err = interceptor(withKey("u1", "k1"), req, readInfo, handler)
+	assert.NoError(t, err)
+	assert.Same(t, resp, got, "чтение выполняется без ключа идемпотентности")
+
+	_, err = interceptor(context.Background(), req, info, func(ctx context.Context, req any) (any, error) {
+		return nil, status.Error(codes.Unavailable, "")
+	})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
@@ -3,21 +3,48 @@ package api
 import (
 	"errors"
 	"net/http"
+	"runtime/debug"
 	"strconv"
 	"strings"
 	"time"
 
-	logger "github.com/bllooop/pvzservice/pkg/logging"
+	"github.com/bllooop/pvzservice/internal/domain"
+	"github.com/bllooop/pvzservice/internal/ratelimit"
 	"github.com/bllooop/pvzservice/prometheus"
 	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
 )
 
 const (
 	authorizationHeader = "Authorization"
 	userCtx             = "userRole"
 	userId              = "userId"
+	tenantCtx           = "tenantId"
+	tenantQuery         = "tenant"
 )
 
+// recovery отвечает 500 на панику обработчика и пишет ее в лог запроса.
+// Middleware, выполняющиеся после него, успевают обработать панику в своих
+// defer, например освободить ключ идемпотентности.
+func (h *Handler) recovery(c *gin.Context) {
+	defer func() {
+		p := recover()
+		if p == nil {
+			return
+		}
+		if p == http.ErrAbortHandler {
+			panic(p)
+		}
+		reqLog(c).Error().Any("panic", p).Str("stack", string(debug.Stack())).Msg("Паника при обработке запроса")
+		if c.Writer.Written() {
+			c.Abort()
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse{"Внутренняя ошибка сервера"})
+	}()
+	c.Next()
+}
+
 func (h *Handler) authIdentity(c *gin.Context) {
 	header := c.GetHeader(authorizationHeader)
 	if header == "" {
@@ -36,14 +63,33 @@ func (h *Handler) authIdentity(c *gin.Context) {
 		c.Abort()
 		return
 	}
-	parsedId, userRole, err := h.Usecases.Authorization.ParseToken(headerSplit[1])
+	claims, err := h.Usecases.Authorization.ParseToken(headerSplit[1])
 	if err != nil {
 		newErrorResponse(c, http.StatusUnauthorized, err.Error())
 		c.Abort()
 		return
 	}
-	c.Set(userCtx, userRole)
-	c.Set(userId, parsedId)
+	if claims.Dummy && h.Env == EnvProd {
+		newErrorResponse(c, http.StatusUnauthorized, "Тестовые токены не принимаются")
+		c.Abort()
+		return
+	}
+	if err := h.Usecases.Authorization.CheckUserActive(c.Request.Context(), claims); err != nil {
+		newErrorResponse(c, http.StatusUnauthorized, err.Error())
+		c.Abort()
+		return
+	}
+	c.Set(userCtx, claims.UserRole)
+	c.Set(userId, claims.UserId)
+	c.Set(tenantCtx, claims.TenantId)
+	withLogFields(c, func(l zerolog.Context) zerolog.Context {
+		return l.Str("user_id", claims.UserId).Str("tenant_id", claims.TenantId)
+	})
+	// Лимит частоты проверяется здесь, потому что только после проверки
+	// токена известны пользователь и его роль.
+	if !h.allowRequest(c, methodGroup(c.Request.Method), roleName(claims.UserRole), ratelimit.UserKey(claims.UserId)) {
+		c.Abort()
+	}
 }
 func getUserRole(c *gin.Context) (int, error) {
 	role, ok := c.Get(userCtx)
@@ -59,18 +105,39 @@ func getUserRole(c *gin.Context) (int, error) {
 	return roleInt, nil
 }
 
-func getUserId(c *gin.Context) (int, error) {
+func getUserId(c *gin.Context) (string, error) {
 	id, ok := c.Get(userId)
 	if !ok {
-		return 0, errors.New("ID пользователя не найдена")
+		return "", errors.New("ID пользователя не найдена")
 	}
 
-	idInt, ok := id.(int)
+	idStr, ok := id.(string)
 	if !ok {
-		return 0, errors.New("ID пользователя некорректного типа данных")
+		return "", errors.New("ID пользователя некорректного типа данных")
+	}
+
+	return idStr, nil
+}
+
+// tenantScope возвращает компанию, данными которой ограничен запрос: компанию
+// из токена пользователя. Суперадминистратор видит все компании или только
+// указанную в параметре tenant.
+func tenantScope(c *gin.Context) domain.TenantScope {
+	if role, _ := getUserRole(c); role == roleMap[roleAdmin] {
+		if tenantId := c.Query(tenantQuery); tenantId != "" {
+			return domain.TenantOf(tenantId)
+		}
+		return domain.AllTenants()
 	}
+	tenantId, _ := c.Get(tenantCtx)
+	tenantIdStr, _ := tenantId.(string)
+	return domain.TenantOf(tenantIdStr)
+}
 
-	return idInt, nil
+// isReader сообщает, что роль может читать отчеты: модератор компании или
+// суперадминистратор.
+func isReader(userRole int) bool {
+	return userRole == roleMap["moderator"] || userRole == roleMap[roleAdmin]
 }
 
 func (h *Handler) PrometheusMiddleware() gin.HandlerFunc {
@@ -98,7 +165,7 @@ func logMetricsOncePerMinute(c *gin.Context, path string, statusCode string, dur
 	currentTime := time.Now()
 
 	if currentTime.Sub(lastLogged) >= time.Minute {
-		logger.Log.Debug().Msgf("Recording metrics: method=%s path=%s status=%s duration=%f", c.Request.Method, path, statusCode, duration)
+		reqLog(c).Debug().Msgf("Recording metrics: method=%s path=%s status=%s duration=%f", c.Request.Method, path, statusCode, duration)
 		lastLogged = currentTime
 	}
 }
@@ -1,19 +1,27 @@
 package api
 
 import (
+	"context"
 	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
+	"github.com/bllooop/pvzservice/internal/domain"
 	"github.com/bllooop/pvzservice/internal/usecase"
 	mock_usecase "github.com/bllooop/pvzservice/internal/usecase/mocks"
 	"github.com/gin-gonic/gin"
-
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
+// testScope — компания, которой ограничены запросы пользователя без tenant_id в токене.
+var testScope = domain.TenantOf(domain.DefaultTenant)
+
 func TestHandler_authIdentity(t *testing.T) {
 	type mockBehavior func(r *mock_usecase.MockAuthorization, token string)
 
@@ -22,6 +30,7 @@ func TestHandler_authIdentity(t *testing.T) {
 		headerName           string
 		headerValue          string
 		token                string
+		env                  string
 		mockBehavior         mockBehavior
 		expectedStatusCode   int
 		expectedResponseBody string
@@ -32,11 +41,49 @@ func TestHandler_authIdentity(t *testing.T) {
 			headerValue: "Bearer token",
 			token:       "token",
 			mockBehavior: func(r *mock_usecase.MockAuthorization, token string) {
-				r.EXPECT().ParseToken(token).Return("1", 1, nil)
+				r.EXPECT().ParseToken(token).Return(domain.TokenClaims{UserId: "1", UserRole: 1}, nil)
+				r.EXPECT().CheckUserActive(gomock.Any(), domain.TokenClaims{UserId: "1", UserRole: 1}).Return(nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: "1",
+		},
+		{
+			name:        "Тестовый токен в prod",
+			headerName:  "Authorization",
+			headerValue: "Bearer token",
+			token:       "token",
+			env:         EnvProd,
+			mockBehavior: func(r *mock_usecase.MockAuthorization, token string) {
+				r.EXPECT().ParseToken(token).Return(domain.TokenClaims{UserId: "1", UserRole: 1, Dummy: true}, nil)
+			},
+			expectedStatusCode:   http.StatusUnauthorized,
+			expectedResponseBody: `{"message":"Тестовые токены не принимаются"}`,
+		},
+		{
+			name:        "Тестовый токен в dev",
+			headerName:  "Authorization",
+			headerValue: "Bearer token",
+			token:       "token",
+			env:         EnvDev,
+			mockBehavior: func(r *mock_usecase.MockAuthorization, token string) {
+				r.EXPECT().ParseToken(token).Return(domain.TokenClaims{UserId: "1", UserRole: 1, Dummy: true}, nil)
+				r.EXPECT().CheckUserActive(gomock.Any(), domain.TokenClaims{UserId: "1", UserRole: 1, Dummy: true}).Return(nil)
 			},
 			expectedStatusCode:   http.StatusOK,
 			expectedResponseBody: "1",
 		},
+		{
+			name:        "Пользователь заблокирован",
+			headerName:  "Authorization",
+			headerValue: "Bearer token",
+			token:       "token",
+			mockBehavior: func(r *mock_usecase.MockAuthorization, token string) {
+				r.EXPECT().ParseToken(token).Return(domain.TokenClaims{UserId: "1", UserRole: 1}, nil)
+				r.EXPECT().CheckUserActive(gomock.Any(), domain.TokenClaims{UserId: "1", UserRole: 1}).Return(errors.New("пользователь заблокирован"))
+			},
+			expectedStatusCode:   http.StatusUnauthorized,
+			expectedResponseBody: `{"message":"пользователь заблокирован"}`,
+		},
 		{
 			name:                 "Некорректное значение заголовка",
 			headerName:           "",
@@ -61,7 +108,7 @@ func TestHandler_authIdentity(t *testing.T) {
 			headerValue: "Bearer token",
 			token:       "token",
 			mockBehavior: func(r *mock_usecase.MockAuthorization, token string) {
-				r.EXPECT().ParseToken(token).Return("", 0, errors.New("Некорректный ввод токена"))
+				r.EXPECT().ParseToken(token).Return(domain.TokenClaims{}, errors.New("Некорректный ввод токена"))
 			},
 			expectedStatusCode:   http.StatusUnauthorized,
 			expectedResponseBody: `{"message":"Некорректный ввод токена"}`,
@@ -77,7 +124,7 @@ func TestHandler_authIdentity(t *testing.T) {
 			test.mockBehavior(repo, test.token)
 
 			usecases := &usecase.Usecase{Authorization: repo}
-			handler := Handler{Usecases: usecases}
+			handler := Handler{Usecases: usecases, Env: test.env}
 
 			r := gin.New()
 			r.GET("/identity", handler.authIdentity, func(c *gin.Context) {
@@ -137,3 +184,128 @@ func TestGetUserRole(t *testing.T) {
 		})
 	}
 }
+
+func TestTenantScope(t *testing.T) {
+	testTable := []struct {
+		name  string
+		role  int
+		query string
+		want  domain.TenantScope
+	}{
+		{
+			name: "Сотрудник компании",
+			role: 1,
+			want: domain.TenantOf("t1"),
+		},
+		{
+			name:  "Параметр tenant игнорируется не для администратора",
+			role:  2,
+			query: "?tenant=t2",
+			want:  domain.TenantOf("t1"),
+		},
+		{
+			name: "Администратор видит все компании",
+			role: 3,
+			want: domain.AllTenants(),
+		},
+		{
+			name:  "Администратор выбирает компанию",
+			role:  3,
+			query: "?tenant=t2",
+			want:  domain.TenantOf("t2"),
+		},
+	}
+
+	for _, test := range testTable {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("GET", "/pvz"+test.query, nil)
+			c.Set(userCtx, test.role)
+			c.Set(tenantCtx, "t1")
+			assert.Equal(t, test.want, tenantScope(c))
+		})
+	}
+}
+
+func TestAuthInterceptor(t *testing.T) {
+	type mockBehavior func(r *mock_usecase.MockAuthorization)
+
+	testTable := []struct {
+		name         string
+		md           metadata.MD
+		env          string
+		mockBehavior mockBehavior
+		wantCode     codes.Code
+		wantScope    domain.TenantScope
+	}{
+		{
+			name: "Ok",
+			md:   metadata.Pairs(grpcAuthorization, "Bearer token"),
+			mockBehavior: func(r *mock_usecase.MockAuthorization) {
+				claims := domain.TokenClaims{UserId: "1", UserRole: 1, TenantId: "t1"}
+				r.EXPECT().ParseToken("token").Return(claims, nil)
+				r.EXPECT().CheckUserActive(gomock.Any(), claims).Return(nil)
+			},
+			wantCode:  codes.OK,
+			wantScope: domain.TenantOf("t1"),
+		},
+		{
+			name: "Администратор выбирает компанию",
+			md:   metadata.Pairs(grpcAuthorization, "Bearer token", grpcTenant, "t2"),
+			mockBehavior: func(r *mock_usecase.MockAuthorization) {
+				claims := domain.TokenClaims{UserRole: 3}
+				r.EXPECT().ParseToken("token").Return(claims, nil)
+				r.EXPECT().CheckUserActive(gomock.Any(), claims).Return(nil)
+			},
+			wantCode:  codes.OK,
+			wantScope: domain.TenantOf("t2"),
+		},
+		{
+			name: "Администратор видит все компании",
+			md:   metadata.Pairs(grpcAuthorization, "Bearer token"),
+			mockBehavior: func(r *mock_usecase.MockAuthorization) {
+				claims := domain.TokenClaims{UserRole: 3}
+				r.EXPECT().ParseToken("token").Return(claims, nil)
+				r.EXPECT().CheckUserActive(gomock.Any(), claims).Return(nil)
+			},
+			wantCode:  codes.OK,
+			wantScope: domain.AllTenants(),
+		},
+		{
+			name:         "Нет токена",
+			md:           metadata.MD{},
+			mockBehavior: func(r *mock_usecase.MockAuthorization) {},
+			wantCode:     codes.Unauthenticated,
+		},
+		{
+			name: "Тестовый токен в prod",
+			md:   metadata.Pairs(grpcAuthorization, "Bearer token"),
+			env:  EnvProd,
+			mockBehavior: func(r *mock_usecase.MockAuthorization) {
+				r.EXPECT().ParseToken("token").Return(domain.TokenClaims{UserRole: 1, Dummy: true}, nil)
+			},
+			wantCode: codes.Unauthenticated,
+		},
+	}
+
+	for _, test := range testTable {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			auth := mock_usecase.NewMockAuthorization(c)
+			test.mockBehavior(auth)
+
+			var got domain.TenantScope
+			handler := func(ctx context.Context, req any) (any, error) {
+				got, _ = grpcTenantScope(ctx)
+				return nil, nil
+			}
+			ctx := metadata.NewIncomingContext(context.Background(), test.md)
+			_, err := AuthInterceptor(auth, test.env)(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+			assert.Equal(t, test.wantCode, status.Code(err))
+			assert.Equal(t, test.wantScope, got)
+		})
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/bllooop/pvzservice/internal/domain"
+	"github.com/bllooop/pvzservice/internal/repository"
 	"github.com/bllooop/pvzservice/internal/usecase"
 	mock_usecase "github.com/bllooop/pvzservice/internal/usecase/mocks"
 	"github.com/gin-gonic/gin"
@@ -44,7 +45,7 @@ func TestHandler_createPvz(t *testing.T) {
 			},
 			inputUserRole: 2,
 			mockBehavior: func(s *mock_usecase.MockPvz, pvz domain.PVZ) {
-				s.EXPECT().CreatePvz(pvz).Return(domain.PVZ{
+				s.EXPECT().CreatePvz(gomock.Any(), testScope, pvz).Return(domain.PVZ{
 					Id:           &userID,
 					DateRegister: &fixedTime,
 					City:         "Москва",
@@ -69,7 +70,7 @@ func TestHandler_createPvz(t *testing.T) {
 			},
 			inputUserRole: 2,
 			mockBehavior: func(s *mock_usecase.MockPvz, pvz domain.PVZ) {
-				s.EXPECT().CreatePvz(pvz).Return(domain.PVZ{}, errors.New("Internal Server Error"))
+				s.EXPECT().CreatePvz(gomock.Any(), testScope, pvz).Return(domain.PVZ{}, errors.New("Internal Server Error"))
 			},
 			expectedStatusCode:   500,
 			expectedResponseBody: `{"message":"Ошибка выполнения запроса Internal Server Error"}`,
@@ -80,11 +81,27 @@ func TestHandler_createPvz(t *testing.T) {
 			inputPVZ:      domain.PVZ{},
 			inputUserRole: 1,
 			mockBehavior: func(s *mock_usecase.MockPvz, pvz domain.PVZ) {
-				s.EXPECT().CreatePvz(gomock.Any()).Times(0)
+				s.EXPECT().CreatePvz(gomock.Any(), testScope, gomock.Any()).Times(0)
 			},
 			expectedStatusCode:   400,
 			expectedResponseBody: `{"message":"Доступ запрещен"}`,
 		},
+		{
+			name:                 "Только широта",
+			inputBody:            `{"city":"Москва","latitude":55.75}`,
+			inputUserRole:        2,
+			mockBehavior:         func(s *mock_usecase.MockPvz, pvz domain.PVZ) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"Неверный запрос"}`,
+		},
+		{
+			name:                 "Некорректный индекс",
+			inputBody:            `{"city":"Москва","postalCode":"12a"}`,
+			inputUserRole:        2,
+			mockBehavior:         func(s *mock_usecase.MockPvz, pvz domain.PVZ) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"Неверный запрос"}`,
+		},
 		{
 			name:      "Плохой ввод",
 			inputBody: `{"city":10000}`,
@@ -106,7 +123,10 @@ func TestHandler_createPvz(t *testing.T) {
 			repo := mock_usecase.NewMockPvz(c)
 			testCase.mockBehavior(repo, testCase.inputPVZ)
 
-			usecases := &usecase.Usecase{Pvz: repo}
+			audit := mock_usecase.NewMockAudit(c)
+			audit.EXPECT().RecordAudit(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+			usecases := &usecase.Usecase{Pvz: repo, Audit: audit}
 			handler := NewHandlerWithFixedTime(usecases, fixedTime)
 
 			r := gin.New()
@@ -152,7 +172,7 @@ func TestHandler_closeLast(t *testing.T) {
 			inputPvzId:    userID.String(),
 			inputUserRole: 1,
 			mockBehavior: func(s *mock_usecase.MockPvz, pvzId uuid.UUID) {
-				s.EXPECT().CloseReception(pvzId).Return(domain.ProductReception{
+				s.EXPECT().CloseReception(gomock.Any(), testScope, pvzId).Return(domain.ProductReception{
 					Id:           &userID,
 					DateReceived: &fixedTime,
 					PVZId:        &pvzId,
@@ -175,7 +195,7 @@ func TestHandler_closeLast(t *testing.T) {
 			inputPvzId:    userID.String(),
 			inputUserRole: 1,
 			mockBehavior: func(s *mock_usecase.MockPvz, pvzId uuid.UUID) {
-				s.EXPECT().CloseReception(pvzId).Return(domain.ProductReception{}, errors.New("Internal Server Error"))
+				s.EXPECT().CloseReception(gomock.Any(), testScope, pvzId).Return(domain.ProductReception{}, errors.New("Internal Server Error"))
 			},
 			expectedStatusCode:   500,
 			expectedResponseBody: `{"message":"Ошибка выполнения запроса Internal Server Error"}`,
@@ -224,7 +244,10 @@ func TestHandler_closeLast(t *testing.T) {
 				testCase.mockBehavior(repo, parsedPvzId)
 			}
 
-			usecases := &usecase.Usecase{Pvz: repo}
+			audit := mock_usecase.NewMockAudit(c)
+			audit.EXPECT().RecordAudit(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+			usecases := &usecase.Usecase{Pvz: repo, Audit: audit}
 			handler := NewHandlerWithFixedTime(usecases, fixedTime)
 
 			r := gin.New()
@@ -249,6 +272,124 @@ func TestHandler_closeLast(t *testing.T) {
 	}
 }
 
+func TestHandler_deleteProduct(t *testing.T) {
+	type mockBehavior func(s *mock_usecase.MockPvz, input domain.ProductDeletion)
+	pvzId := uuid.New()
+	productId := uuid.New()
+	testTable := []struct {
+		name                 string
+		inputProductId       string
+		inputBody            string
+		inputUserRole        int
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:           "OK",
+			inputProductId: productId.String(),
+			inputBody:      `{"reason":"mis_scan","comment":"не тот штрихкод"}`,
+			inputUserRole:  1,
+			mockBehavior: func(s *mock_usecase.MockPvz, input domain.ProductDeletion) {
+				s.EXPECT().DeleteProduct(gomock.Any(), testScope, input).Return(domain.Product{Id: &productId, Type: "обувь"}, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: fmt.Sprintf(`{"message":"Товар удален","content":{"id":"%s","type":"обувь","receptionId":null}}`, productId),
+		},
+		{
+			name:                 "Без причины",
+			inputProductId:       productId.String(),
+			inputBody:            `{}`,
+			inputUserRole:        1,
+			mockBehavior:         func(s *mock_usecase.MockPvz, input domain.ProductDeletion) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"Неверный запрос, укажите причину удаления"}`,
+		},
+		{
+			name:                 "Неизвестная причина",
+			inputProductId:       productId.String(),
+			inputBody:            `{"reason":"lost"}`,
+			inputUserRole:        1,
+			mockBehavior:         func(s *mock_usecase.MockPvz, input domain.ProductDeletion) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"Неверный запрос, укажите причину удаления"}`,
+		},
+		{
+			name:           "Товар не найден",
+			inputProductId: productId.String(),
+			inputBody:      `{"reason":"duplicate"}`,
+			inputUserRole:  1,
+			mockBehavior: func(s *mock_usecase.MockPvz, input domain.ProductDeletion) {
+				s.EXPECT().DeleteProduct(gomock.Any(), testScope, input).Return(domain.Product{}, repository.ErrProductNotFound)
+			},
+			expectedStatusCode:   404,
+			expectedResponseBody: `{"message":"Товар не найден"}`,
+		},
+		{
+			name:           "Приемка закрыта",
+			inputProductId: productId.String(),
+			inputBody:      `{"reason":"duplicate"}`,
+			inputUserRole:  1,
+			mockBehavior: func(s *mock_usecase.MockPvz, input domain.ProductDeletion) {
+				s.EXPECT().DeleteProduct(gomock.Any(), testScope, input).Return(domain.Product{}, repository.ErrReceptionClosed)
+			},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"Неверный запрос, приемка уже закрыта"}`,
+		},
+		{
+			name:                 "Некорректный UUID товара",
+			inputProductId:       "invalid-uuid",
+			inputBody:            `{"reason":"duplicate"}`,
+			inputUserRole:        1,
+			mockBehavior:         func(s *mock_usecase.MockPvz, input domain.ProductDeletion) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"Некорректный UUID товара"}`,
+		},
+		{
+			name:                 "Запрещен доступ",
+			inputProductId:       productId.String(),
+			inputBody:            `{"reason":"duplicate"}`,
+			inputUserRole:        2,
+			mockBehavior:         func(s *mock_usecase.MockPvz, input domain.ProductDeletion) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"Доступ запрещен"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			repo := mock_usecase.NewMockPvz(c)
+			var input domain.ProductDeletion
+			_ = json.Unmarshal([]byte(testCase.inputBody), &input)
+			input.PVZId = pvzId
+			input.ProductId = &productId
+			input.ActorId = "u1"
+			testCase.mockBehavior(repo, input)
+			audit := mock_usecase.NewMockAudit(c)
+			audit.EXPECT().RecordAudit(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+			handler := Handler{Usecases: &usecase.Usecase{Pvz: repo, Audit: audit}}
+			r := gin.New()
+			r.POST("/pvz/:pvzId/delete_product/:productId", func(c *gin.Context) {
+				c.Set(userCtx, testCase.inputUserRole)
+				c.Set(userId, "u1")
+				handler.DeleteProduct(c)
+			})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/pvz/"+pvzId.String()+"/delete_product/"+testCase.inputProductId, bytes.NewBufferString(testCase.inputBody))
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.JSONEq(t, testCase.expectedResponseBody, w.Body.String())
+		})
+	}
+}
+
 func TestHandler_deleteLast(t *testing.T) {
 	type mockBehavior func(s *mock_usecase.MockPvz, pvzId uuid.UUID)
 	userID, err := uuid.NewRandom()
@@ -268,7 +409,7 @@ func TestHandler_deleteLast(t *testing.T) {
 			inputPvzId:    userID.String(),
 			inputUserRole: 1,
 			mockBehavior: func(s *mock_usecase.MockPvz, pvzId uuid.UUID) {
-				s.EXPECT().DeleteLastProduct(pvzId).Return(nil)
+				s.EXPECT().DeleteLastProduct(gomock.Any(), testScope, domain.ProductDeletion{PVZId: pvzId}).Return(domain.Product{}, nil)
 			},
 			expectedStatusCode:   200,
 			expectedResponseBody: `{ "message": "Товар удален"}`,
@@ -278,7 +419,7 @@ func TestHandler_deleteLast(t *testing.T) {
 			inputPvzId:    userID.String(),
 			inputUserRole: 1,
 			mockBehavior: func(s *mock_usecase.MockPvz, pvzId uuid.UUID) {
-				s.EXPECT().DeleteLastProduct(pvzId).Return(errors.New("Internal Server Error"))
+				s.EXPECT().DeleteLastProduct(gomock.Any(), testScope, domain.ProductDeletion{PVZId: pvzId}).Return(domain.Product{}, errors.New("Internal Server Error"))
 			},
 			expectedStatusCode:   500,
 			expectedResponseBody: `{"message":"Ошибка выполнения запроса Internal Server Error"}`,
@@ -317,7 +458,10 @@ func TestHandler_deleteLast(t *testing.T) {
 				testCase.mockBehavior(repo, parsedPvzId)
 			}
 
-			usecases := &usecase.Usecase{Pvz: repo}
+			audit := mock_usecase.NewMockAudit(c)
+			audit.EXPECT().RecordAudit(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+			usecases := &usecase.Usecase{Pvz: repo, Audit: audit}
 			handler := Handler{
 				Usecases: usecases,
 			}
@@ -374,7 +518,7 @@ func TestHandler_createReception(t *testing.T) {
 			},
 			mockBehavior: func(s *mock_usecase.MockPvz, reception domain.ProductReception) {
 				reception.PVZId = &userID
-				s.EXPECT().CreateRecep(reception).Return(domain.ProductReception{
+				s.EXPECT().CreateRecep(gomock.Any(), testScope, reception).Return(domain.ProductReception{
 					Id:           &userID,
 					DateReceived: &fixedTime,
 					PVZId:        &userID,
@@ -404,11 +548,28 @@ func TestHandler_createReception(t *testing.T) {
 			},
 			inputUserRole: 1,
 			mockBehavior: func(s *mock_usecase.MockPvz, reception domain.ProductReception) {
-				s.EXPECT().CreateRecep(reception).Return(domain.ProductReception{}, errors.New("Internal Server Error"))
+				s.EXPECT().CreateRecep(gomock.Any(), testScope, reception).Return(domain.ProductReception{}, errors.New("Internal Server Error"))
 			},
 			expectedStatusCode:   500,
 			expectedResponseBody: `{"message":"Ошибка выполнения запроса Internal Server Error"}`,
 		},
+		{
+			name: "Есть незакрытая приемка",
+			inputBody: fmt.Sprintf(`{
+				"pvzId": "%s"
+			}`, userID.String()),
+			inputRecep: domain.ProductReception{
+				DateReceived: &fixedTime,
+				Status:       &stat,
+				PVZId:        &userID,
+			},
+			inputUserRole: 1,
+			mockBehavior: func(s *mock_usecase.MockPvz, reception domain.ProductReception) {
+				s.EXPECT().CreateRecep(gomock.Any(), testScope, reception).Return(domain.ProductReception{}, repository.ErrReceptionInProgress)
+			},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"Неверный запрос или есть незакрытая приемка"}`,
+		},
 		{
 			name: "Запрещен доступ",
 			inputBody: fmt.Sprintf(`{
@@ -421,7 +582,7 @@ func TestHandler_createReception(t *testing.T) {
 			},
 			inputUserRole: 2,
 			mockBehavior: func(s *mock_usecase.MockPvz, pvz domain.ProductReception) {
-				s.EXPECT().CreateRecep(gomock.Any()).Times(0)
+				s.EXPECT().CreateRecep(gomock.Any(), testScope, gomock.Any()).Times(0)
 			},
 			expectedStatusCode:   400,
 			expectedResponseBody: `{"message":"Доступ запрещен"}`,
@@ -441,6 +602,21 @@ func TestHandler_createReception(t *testing.T) {
 			expectedStatusCode:   400,
 			expectedResponseBody: `{"message":"Некорректный UUID ПВЗ"}`,
 		},*/
+		{
+			name:          "ПВЗ временно закрыт",
+			inputUserRole: 1,
+			inputBody:     fmt.Sprintf(`{"pvzId": "%s"}`, userID.String()),
+			inputRecep: domain.ProductReception{
+				DateReceived: &fixedTime,
+				Status:       &stat,
+				PVZId:        &userID,
+			},
+			mockBehavior: func(s *mock_usecase.MockPvz, reception domain.ProductReception) {
+				s.EXPECT().CreateRecep(gomock.Any(), testScope, reception).Return(domain.ProductReception{}, repository.ErrPvzNotActive)
+			},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"ПВЗ закрыт или выведен из работы"}`,
+		},
 	}
 
 	for _, testCase := range testTable {
@@ -450,7 +626,13 @@ func TestHandler_createReception(t *testing.T) {
 
 			repo := mock_usecase.NewMockPvz(c)
 			testCase.mockBehavior(repo, testCase.inputRecep)
-			usecases := &usecase.Usecase{Pvz: repo}
+			audit := mock_usecase.NewMockAudit(c)
+			audit.EXPECT().RecordAudit(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+			limits := mock_usecase.NewMockPvzCapacity(c)
+			limits.EXPECT().CheckPvzLimits(gomock.Any(), testScope, gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+
+			usecases := &usecase.Usecase{Pvz: repo, Audit: audit, PvzCapacity: limits}
 			handler := NewHandlerWithFixedTime(usecases, fixedTime)
 
 			r := gin.New()
@@ -507,7 +689,7 @@ func TestHandler_addProduct(t *testing.T) {
 				Type:         "электроника",
 			},
 			mockBehavior: func(s *mock_usecase.MockPvz, product domain.Product) {
-				s.EXPECT().AddProdToRecep(product).Return(domain.Product{
+				s.EXPECT().AddProdToRecep(gomock.Any(), testScope, product).Return(domain.Product{
 					Id:           &userID,
 					DateReceived: &fixedTime,
 					ReceptionId:  &userID,
@@ -538,7 +720,7 @@ func TestHandler_addProduct(t *testing.T) {
 			},
 			inputUserRole: 1,
 			mockBehavior: func(s *mock_usecase.MockPvz, product domain.Product) {
-				s.EXPECT().AddProdToRecep(product).Return(domain.Product{}, errors.New("Internal Server Error"))
+				s.EXPECT().AddProdToRecep(gomock.Any(), testScope, product).Return(domain.Product{}, errors.New("Internal Server Error"))
 			},
 			expectedStatusCode:   500,
 			expectedResponseBody: `{"message":"Ошибка выполнения запроса Internal Server Error"}`,
@@ -574,7 +756,7 @@ func TestHandler_addProduct(t *testing.T) {
 			},
 			inputUserRole: 2,
 			mockBehavior: func(s *mock_usecase.MockPvz, pvz domain.Product) {
-				s.EXPECT().AddProdToRecep(gomock.Any()).Times(0)
+				s.EXPECT().AddProdToRecep(gomock.Any(), testScope, gomock.Any()).Times(0)
 			},
 			expectedStatusCode:   400,
 			expectedResponseBody: `{"message":"Доступ запрещен"}`,
@@ -603,7 +785,13 @@ func TestHandler_addProduct(t *testing.T) {
 
 			repo := mock_usecase.NewMockPvz(c)
 			testCase.mockBehavior(repo, testCase.inputProd)
-			usecases := &usecase.Usecase{Pvz: repo}
+			audit := mock_usecase.NewMockAudit(c)
+			audit.EXPECT().RecordAudit(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+			limits := mock_usecase.NewMockPvzCapacity(c)
+			limits.EXPECT().CheckPvzLimits(gomock.Any(), testScope, gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+
+			usecases := &usecase.Usecase{Pvz: repo, Audit: audit, PvzCapacity: limits}
 			handler := NewHandlerWithFixedTime(usecases, fixedTime)
 
 			r := gin.New()
@@ -636,13 +824,15 @@ func TestHandler_getPvz(t *testing.T) {
 	testTable := []struct {
 		name                 string
 		inputUserRole        int
+		inputQuery           string
 		inputParams          domain.GettingPvzParams
 		mockBehavior         mockBehavior
 		expectedStatusCode   int
 		expectedResponseBody string
 	}{
 		{
-			name: "OK",
+			name:       "OK",
+			inputQuery: "startDate=2025-04-10T15:05:17Z&page=1&limit=10",
 			inputParams: domain.GettingPvzParams{
 				Start: fixedTime,
 				Page:  1,
@@ -650,7 +840,7 @@ func TestHandler_getPvz(t *testing.T) {
 			},
 			inputUserRole: 2,
 			mockBehavior: func(s *mock_usecase.MockPvz, gettingPvz domain.GettingPvzParams) {
-				s.EXPECT().GetPvz(gettingPvz).Return([]domain.PvzSummary{}, nil)
+				s.EXPECT().GetPvz(gomock.Any(), testScope, gettingPvz).Return([]domain.PvzSummary{}, nil)
 			},
 			expectedStatusCode: 200,
 			expectedResponseBody: `{
@@ -659,7 +849,33 @@ func TestHandler_getPvz(t *testing.T) {
 			  }`,
 		},
 		{
-			name: "Ошибка выполнения запроса",
+			name:       "Местные сутки ПВЗ",
+			inputQuery: "startDate=2025-04-10&endDate=2025-04-11&localDay=true",
+			inputParams: domain.GettingPvzParams{
+				Start:    time.Date(2025, 4, 10, 0, 0, 0, 0, time.UTC),
+				End:      time.Date(2025, 4, 11, 0, 0, 0, 0, time.UTC),
+				Page:     1,
+				Limit:    10,
+				LocalDay: true,
+			},
+			inputUserRole: 2,
+			mockBehavior: func(s *mock_usecase.MockPvz, gettingPvz domain.GettingPvzParams) {
+				s.EXPECT().GetPvz(gomock.Any(), testScope, gettingPvz).Return([]domain.PvzSummary{}, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"message":"Список ПВЗ","content":[]}`,
+		},
+		{
+			name:                 "Время вместо даты в режиме местных суток",
+			inputQuery:           "startDate=2025-04-10T15:05:17Z&localDay=true",
+			inputUserRole:        2,
+			mockBehavior:         func(s *mock_usecase.MockPvz, gettingPvz domain.GettingPvzParams) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"Неверный запрос"}`,
+		},
+		{
+			name:       "Ошибка выполнения запроса",
+			inputQuery: "startDate=2025-04-10T15:05:17Z&page=1&limit=10",
 			inputParams: domain.GettingPvzParams{
 				Start: fixedTime,
 				Page:  1,
@@ -667,7 +883,7 @@ func TestHandler_getPvz(t *testing.T) {
 			},
 			inputUserRole: 2,
 			mockBehavior: func(s *mock_usecase.MockPvz, gettingPvz domain.GettingPvzParams) {
-				s.EXPECT().GetPvz(gettingPvz).Return([]domain.PvzSummary{}, errors.New("Internal Server Error"))
+				s.EXPECT().GetPvz(gomock.Any(), testScope, gettingPvz).Return([]domain.PvzSummary{}, errors.New("Internal Server Error"))
 			},
 			expectedStatusCode:   500,
 			expectedResponseBody: `{"message":"Ошибка выполнения запроса Internal Server Error"}`,
@@ -681,7 +897,10 @@ func TestHandler_getPvz(t *testing.T) {
 			repo := mock_usecase.NewMockPvz(c)
 			testCase.mockBehavior(repo, testCase.inputParams)
 
-			usecases := &usecase.Usecase{Pvz: repo}
+			audit := mock_usecase.NewMockAudit(c)
+			audit.EXPECT().RecordAudit(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+			usecases := &usecase.Usecase{Pvz: repo, Audit: audit}
 			handler := Handler{
 				Usecases: usecases,
 			}
@@ -692,7 +911,7 @@ func TestHandler_getPvz(t *testing.T) {
 				handler.GetPvz(c)
 			})
 			w := httptest.NewRecorder()
-			req := httptest.NewRequest("GET", "/api/pvz?startDate=2025-04-10T15:05:17Z&page=1&limit=10", nil)
+			req := httptest.NewRequest("GET", "/api/pvz?"+testCase.inputQuery, nil)
 
 			r.ServeHTTP(w, req)
 			assert.Equal(t, w.Code, testCase.expectedStatusCode)
@@ -704,3 +923,286 @@ func TestHandler_getPvz(t *testing.T) {
 		})
 	}
 }
+
+func TestHandler_updatePvz(t *testing.T) {
+	type mockBehavior func(s *mock_usecase.MockPvz)
+	fixedTime := time.Date(2025, 4, 10, 15, 5, 17, 0, time.UTC)
+	until := time.Date(2025, 4, 12, 0, 0, 0, 0, time.UTC)
+	pvzId := uuid.New()
+	closed := domain.PvzTemporarilyClosed
+	decommissioned := domain.PvzDecommissioned
+	address := "ул. Ленина, 1"
+
+	testTable := []struct {
+		name                 string
+		inputUserRole        int
+		inputBody            string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:          "Временное закрытие",
+			inputUserRole: 2,
+			inputBody:     `{"status":"temporarily_closed","effectiveTo":"2025-04-12T00:00:00Z","reason":"ремонт"}`,
+			mockBehavior: func(s *mock_usecase.MockPvz) {
+				s.EXPECT().UpdatePvz(gomock.Any(), testScope, pvzId, domain.PvzUpdate{
+					Status:        &closed,
+					EffectiveFrom: &fixedTime,
+					EffectiveTo:   &until,
+					Reason:        "ремонт",
+					ActorId:       "u1",
+				}).Return(domain.PVZ{Id: &pvzId, DateRegister: &fixedTime, City: "Москва", Status: closed}, nil)
+			},
+			expectedStatusCode: 200,
+			expectedResponseBody: fmt.Sprintf(`{"message":"ПВЗ изменен","content":{"id":"%s","registrationDate":"2025-04-10T15:05:17Z","city":"Москва","status":"temporarily_closed"}}`,
+				pvzId),
+		},
+		{
+			name:          "Смена адреса",
+			inputUserRole: 2,
+			inputBody:     `{"address":"ул. Ленина, 1"}`,
+			mockBehavior: func(s *mock_usecase.MockPvz) {
+				s.EXPECT().UpdatePvz(gomock.Any(), testScope, pvzId, domain.PvzUpdate{Address: &address, ActorId: "u1"}).
+					Return(domain.PVZ{Id: &pvzId, DateRegister: &fixedTime, City: "Москва", Address: address, Status: domain.PvzActive}, nil)
+			},
+			expectedStatusCode: 200,
+			expectedResponseBody: fmt.Sprintf(`{"message":"ПВЗ изменен","content":{"id":"%s","registrationDate":"2025-04-10T15:05:17Z","city":"Москва","address":"ул. Ленина, 1","status":"active"}}`,
+				pvzId),
+		},
+		{
+			name:                 "Неизвестный статус",
+			inputUserRole:        2,
+			inputBody:            `{"status":"closed"}`,
+			mockBehavior:         func(s *mock_usecase.MockPvz) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"Неверный запрос"}`,
+		},
+		{
+			name:          "Недопустимый переход",
+			inputUserRole: 2,
+			inputBody:     `{"status":"decommissioned"}`,
+			mockBehavior: func(s *mock_usecase.MockPvz) {
+				s.EXPECT().UpdatePvz(gomock.Any(), testScope, pvzId, domain.PvzUpdate{Status: &decommissioned, EffectiveFrom: &fixedTime, ActorId: "u1"}).
+					Return(domain.PVZ{}, repository.ErrPvzTransitionNotAllowed)
+			},
+			expectedStatusCode:   409,
+			expectedResponseBody: `{"message":"Недопустимая смена статуса ПВЗ"}`,
+		},
+		{
+			name:          "Пустое изменение",
+			inputUserRole: 2,
+			inputBody:     `{}`,
+			mockBehavior: func(s *mock_usecase.MockPvz) {
+				s.EXPECT().UpdatePvz(gomock.Any(), testScope, pvzId, domain.PvzUpdate{ActorId: "u1"}).Return(domain.PVZ{}, usecase.ErrInvalidPvzUpdate)
+			},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"Неверный запрос"}`,
+		},
+		{
+			name:                 "Запрещен доступ",
+			inputUserRole:        1,
+			inputBody:            `{"address":"ул. Ленина, 1"}`,
+			mockBehavior:         func(s *mock_usecase.MockPvz) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"Доступ запрещен"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			repo := mock_usecase.NewMockPvz(c)
+			testCase.mockBehavior(repo)
+			audit := mock_usecase.NewMockAudit(c)
+			audit.EXPECT().RecordAudit(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+			handler := NewHandlerWithFixedTime(&usecase.Usecase{Pvz: repo, Audit: audit}, fixedTime)
+			r := gin.New()
+			r.PATCH("/pvz/:pvzId", func(c *gin.Context) {
+				c.Set(userCtx, testCase.inputUserRole)
+				c.Set(userId, "u1")
+				handler.UpdatePvz(c)
+			})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("PATCH", "/pvz/"+pvzId.String(), strings.NewReader(testCase.inputBody))
+			req.Header.Set("Content-Type", "application/json")
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.JSONEq(t, testCase.expectedResponseBody, w.Body.String())
+		})
+	}
+}
+
+func TestHandler_getNearestPvz(t *testing.T) {
+	type mockBehavior func(s *mock_usecase.MockPvz)
+	fixedTime := time.Date(2025, 4, 10, 15, 5, 17, 0, time.UTC)
+	pvzId := uuid.New()
+	lat, lon := 55.7558, 37.6173
+
+	testTable := []struct {
+		name                 string
+		query                string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:  "OK",
+			query: "lat=55.75&lon=37.62&radius=3",
+			mockBehavior: func(s *mock_usecase.MockPvz) {
+				s.EXPECT().GetNearestPvz(gomock.Any(), testScope, domain.NearestPvzParams{Latitude: 55.75, Longitude: 37.62, RadiusKm: 3}).
+					Return([]domain.PvzDistance{{
+						PVZ:        domain.PVZ{Id: &pvzId, DateRegister: &fixedTime, City: "Москва", Latitude: &lat, Longitude: &lon, Status: domain.PvzActive},
+						DistanceKm: 0.63,
+					}}, nil)
+			},
+			expectedStatusCode: 200,
+			expectedResponseBody: fmt.Sprintf(`{"message":"Ближайшие ПВЗ","content":[{"id":"%s","registrationDate":"2025-04-10T15:05:17Z","city":"Москва","latitude":55.7558,"longitude":37.6173,"status":"active","distanceKm":0.63}]}`,
+				pvzId),
+		},
+		{
+			name:  "Ничего не найдено",
+			query: "lat=55.75&lon=37.62",
+			mockBehavior: func(s *mock_usecase.MockPvz) {
+				s.EXPECT().GetNearestPvz(gomock.Any(), testScope, domain.NearestPvzParams{Latitude: 55.75, Longitude: 37.62}).Return(nil, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"message":"Ближайшие ПВЗ","content":[]}`,
+		},
+		{
+			name:                 "Нет координат",
+			query:                "lat=55.75",
+			mockBehavior:         func(s *mock_usecase.MockPvz) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"Неверный запрос, укажите lat и lon"}`,
+		},
+		{
+			name:                 "Координата NaN",
+			query:                "lat=NaN&lon=37.62",
+			mockBehavior:         func(s *mock_usecase.MockPvz) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"Неверный запрос, укажите lat и lon"}`,
+		},
+		{
+			name:                 "Бесконечная координата",
+			query:                "lat=55.75&lon=-Inf",
+			mockBehavior:         func(s *mock_usecase.MockPvz) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"Неверный запрос, укажите lat и lon"}`,
+		},
+		{
+			name:                 "Радиус NaN",
+			query:                "lat=55.75&lon=37.62&radius=nan",
+			mockBehavior:         func(s *mock_usecase.MockPvz) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"Неверный запрос, некорректный радиус"}`,
+		},
+		{
+			name:  "Слишком большой радиус",
+			query: "lat=55.75&lon=37.62&radius=1000",
+			mockBehavior: func(s *mock_usecase.MockPvz) {
+				s.EXPECT().GetNearestPvz(gomock.Any(), testScope, domain.NearestPvzParams{Latitude: 55.75, Longitude: 37.62, RadiusKm: 1000}).
+					Return(nil, usecase.ErrInvalidGeoQuery)
+			},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"Неверный запрос, некорректные координаты или радиус поиска"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			repo := mock_usecase.NewMockPvz(c)
+			testCase.mockBehavior(repo)
+
+			handler := NewHandlerWithFixedTime(&usecase.Usecase{Pvz: repo}, fixedTime)
+			r := gin.New()
+			r.GET("/pvz/nearest", handler.GetNearestPvz)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/pvz/nearest?"+testCase.query, nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.JSONEq(t, testCase.expectedResponseBody, w.Body.String())
+		})
+	}
+}
+
+func TestHandler_getPvzReport(t *testing.T) {
+	type mockBehavior func(s *mock_usecase.MockPvz)
+	pvzId := uuid.New()
+
+	testTable := []struct {
+		name                 string
+		inputQuery           string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:       "Ok",
+			inputQuery: "startDate=2025-04-10&endDate=2025-04-11&localDay=true",
+			mockBehavior: func(s *mock_usecase.MockPvz) {
+				s.EXPECT().GetPvzReport(gomock.Any(), testScope, domain.PvzReportParams{PvzId: pvzId, From: "2025-04-10", To: "2025-04-11", LocalDay: true}).
+					Return(domain.PvzReport{PvzId: pvzId, Timezone: "Asia/Yekaterinburg", Days: []domain.PvzReportDay{
+						{Day: "2025-04-10", Receptions: 1, Products: 12, Issued: 3},
+					}}, nil)
+			},
+			expectedStatusCode: 200,
+			expectedResponseBody: fmt.Sprintf(`{"message":"Отчет ПВЗ по дням","content":{"pvzId":"%s","timezone":"Asia/Yekaterinburg",
+				"days":[{"day":"2025-04-10","receptions":1,"products":12,"issued":3}]}}`, pvzId),
+		},
+		{
+			name:       "Некорректный период",
+			inputQuery: "startDate=2025-04-11&endDate=2025-04-10",
+			mockBehavior: func(s *mock_usecase.MockPvz) {
+				s.EXPECT().GetPvzReport(gomock.Any(), testScope, domain.PvzReportParams{PvzId: pvzId, From: "2025-04-11", To: "2025-04-10"}).
+					Return(domain.PvzReport{}, usecase.ErrInvalidReport)
+			},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"Неверный запрос, некорректный период отчета"}`,
+		},
+		{
+			name:       "ПВЗ не найден",
+			inputQuery: "startDate=2025-04-10&endDate=2025-04-11",
+			mockBehavior: func(s *mock_usecase.MockPvz) {
+				s.EXPECT().GetPvzReport(gomock.Any(), testScope, domain.PvzReportParams{PvzId: pvzId, From: "2025-04-10", To: "2025-04-11"}).
+					Return(domain.PvzReport{}, repository.ErrPvzNotFound)
+			},
+			expectedStatusCode:   404,
+			expectedResponseBody: `{"message":"ПВЗ не найден"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			repo := mock_usecase.NewMockPvz(c)
+			testCase.mockBehavior(repo)
+
+			handler := NewHandler(&usecase.Usecase{Pvz: repo}, Config{})
+			r := gin.New()
+			r.GET("/pvz/:pvzId/report", handler.GetPvzReport)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/pvz/"+pvzId.String()+"/report?"+testCase.inputQuery, nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.JSONEq(t, testCase.expectedResponseBody, w.Body.String())
+		})
+	}
+}
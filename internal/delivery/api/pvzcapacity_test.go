@@ -0,0 +1,373 @@
+package api
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bllooop/pvzservice/internal/domain"
+	"github.com/bllooop/pvzservice/internal/repository"
+	"github.com/bllooop/pvzservice/internal/usecase"
+	mock_usecase "github.com/bllooop/pvzservice/internal/usecase/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestHandler_setPvzSchedule(t *testing.T) {
+	type mockBehavior func(s *mock_usecase.MockPvzCapacity)
+	pvzId := uuid.New()
+	schedule := domain.PvzSchedule{
+		Week:     []domain.WorkingDay{{Weekday: 1, Opens: "09:00", Closes: "21:00"}},
+		Holidays: []domain.Holiday{{Date: "2025-05-01", Reason: "праздник"}},
+	}
+
+	testTable := []struct {
+		name                 string
+		inputUserRole        int
+		inputBody            string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:          "Ok",
+			inputUserRole: 2,
+			inputBody:     `{"week":[{"weekday":1,"opens":"09:00","closes":"21:00"}],"holidays":[{"date":"2025-05-01","reason":"праздник"}]}`,
+			mockBehavior: func(s *mock_usecase.MockPvzCapacity) {
+				s.EXPECT().SetPvzSchedule(gomock.Any(), testScope, pvzId, schedule).Return(schedule, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"message":"Расписание ПВЗ изменено","content":{"week":[{"weekday":1,"opens":"09:00","closes":"21:00"}],"holidays":[{"date":"2025-05-01","reason":"праздник"}]}}`,
+		},
+		{
+			name:                 "Некорректное время",
+			inputUserRole:        2,
+			inputBody:            `{"week":[{"weekday":1,"opens":"9 утра","closes":"21:00"}]}`,
+			mockBehavior:         func(s *mock_usecase.MockPvzCapacity) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"Неверный запрос"}`,
+		},
+		{
+			name:          "Закрытие раньше открытия",
+			inputUserRole: 2,
+			inputBody:     `{"week":[{"weekday":1,"opens":"21:00","closes":"09:00"}]}`,
+			mockBehavior: func(s *mock_usecase.MockPvzCapacity) {
+				s.EXPECT().SetPvzSchedule(gomock.Any(), testScope, pvzId, domain.PvzSchedule{Week: []domain.WorkingDay{{Weekday: 1, Opens: "21:00", Closes: "09:00"}}}).
+					Return(domain.PvzSchedule{}, fmt.Errorf("%w: день 1 закрывается раньше открытия", usecase.ErrInvalidSchedule))
+			},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"Неверный запрос, некорректное расписание ПВЗ: день 1 закрывается раньше открытия"}`,
+		},
+		{
+			name:          "ПВЗ не найден",
+			inputUserRole: 2,
+			inputBody:     `{"week":[]}`,
+			mockBehavior: func(s *mock_usecase.MockPvzCapacity) {
+				s.EXPECT().SetPvzSchedule(gomock.Any(), testScope, pvzId, domain.PvzSchedule{Week: []domain.WorkingDay{}}).
+					Return(domain.PvzSchedule{}, repository.ErrPvzNotFound)
+			},
+			expectedStatusCode:   404,
+			expectedResponseBody: `{"message":"ПВЗ не найден"}`,
+		},
+		{
+			name:                 "Запрещен доступ",
+			inputUserRole:        1,
+			inputBody:            `{"week":[]}`,
+			mockBehavior:         func(s *mock_usecase.MockPvzCapacity) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"Доступ запрещен"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			repo := mock_usecase.NewMockPvzCapacity(c)
+			testCase.mockBehavior(repo)
+			audit := mock_usecase.NewMockAudit(c)
+			audit.EXPECT().RecordAudit(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+			handler := NewHandler(&usecase.Usecase{PvzCapacity: repo, Audit: audit}, Config{})
+			r := gin.New()
+			r.PUT("/pvz/:pvzId/schedule", func(c *gin.Context) {
+				c.Set(userCtx, testCase.inputUserRole)
+				handler.SetPvzSchedule(c)
+			})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("PUT", "/pvz/"+pvzId.String()+"/schedule", strings.NewReader(testCase.inputBody))
+			req.Header.Set("Content-Type", "application/json")
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.JSONEq(t, testCase.expectedResponseBody, w.Body.String())
+		})
+	}
+}
+
+func TestHandler_getPvzOccupancy(t *testing.T) {
+	type mockBehavior func(s *mock_usecase.MockPvzCapacity)
+	pvzId := uuid.New()
+	capacity, fill := 200, 50.0
+
+	testTable := []struct {
+		name                 string
+		query                string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:  "Ok",
+			query: "?pvzId=" + pvzId.String(),
+			mockBehavior: func(s *mock_usecase.MockPvzCapacity) {
+				s.EXPECT().GetPvzOccupancy(gomock.Any(), testScope, &pvzId).Return([]domain.PvzOccupancy{
+					{PVZId: pvzId, City: "Москва", Capacity: &capacity, Stored: 100, FillPercent: &fill},
+				}, nil)
+			},
+			expectedStatusCode: 200,
+			expectedResponseBody: fmt.Sprintf(`{"message":"Заполненность ПВЗ","content":[{"pvzId":"%s","city":"Москва","capacity":200,"stored":100,"fillPercent":50}]}`,
+				pvzId),
+		},
+		{
+			name:  "Пустой результат",
+			query: "",
+			mockBehavior: func(s *mock_usecase.MockPvzCapacity) {
+				s.EXPECT().GetPvzOccupancy(gomock.Any(), testScope, nil).Return(nil, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"message":"Заполненность ПВЗ","content":[]}`,
+		},
+		{
+			name:                 "Некорректный UUID",
+			query:                "?pvzId=abc",
+			mockBehavior:         func(s *mock_usecase.MockPvzCapacity) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"Некорректный UUID ПВЗ"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			repo := mock_usecase.NewMockPvzCapacity(c)
+			testCase.mockBehavior(repo)
+
+			handler := NewHandler(&usecase.Usecase{PvzCapacity: repo}, Config{})
+			r := gin.New()
+			r.GET("/pvz/occupancy", handler.GetPvzOccupancy)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/pvz/occupancy"+testCase.query, nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.JSONEq(t, testCase.expectedResponseBody, w.Body.String())
+		})
+	}
+}
+
+func TestHandler_issueProduct(t *testing.T) {
+	type mockBehavior func(s *mock_usecase.MockPvzCapacity)
+	fixedTime := time.Date(2025, 4, 10, 15, 5, 17, 0, time.UTC)
+	pvzId, productId, recepId := uuid.New(), uuid.New(), uuid.New()
+
+	testTable := []struct {
+		name                 string
+		inputUserRole        int
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:          "Ok",
+			inputUserRole: 1,
+			mockBehavior: func(s *mock_usecase.MockPvzCapacity) {
+				s.EXPECT().IssueProduct(gomock.Any(), testScope, pvzId, productId, fixedTime).Return(domain.Product{
+					Id: &productId, DateReceived: &fixedTime, Type: "обувь", ReceptionId: &recepId, PVZId: &pvzId, IssuedAt: &fixedTime,
+				}, nil)
+			},
+			expectedStatusCode: 200,
+			expectedResponseBody: fmt.Sprintf(`{"message":"Товар выдан","content":{"id":"%s","dateTime":"2025-04-10T15:05:17Z","type":"обувь","receptionId":"%s","pvzId":"%s","issuedAt":"2025-04-10T15:05:17Z"}}`,
+				productId, recepId, pvzId),
+		},
+		{
+			name:          "Товар уже выдан",
+			inputUserRole: 1,
+			mockBehavior: func(s *mock_usecase.MockPvzCapacity) {
+				s.EXPECT().IssueProduct(gomock.Any(), testScope, pvzId, productId, fixedTime).Return(domain.Product{}, repository.ErrProductIssued)
+			},
+			expectedStatusCode:   400,
+			expectedResponseBody: fmt.Sprintf(`{"message":"Неверный запрос, %s"}`, repository.ErrProductIssued),
+		},
+		{
+			name:          "Товар не найден",
+			inputUserRole: 1,
+			mockBehavior: func(s *mock_usecase.MockPvzCapacity) {
+				s.EXPECT().IssueProduct(gomock.Any(), testScope, pvzId, productId, fixedTime).Return(domain.Product{}, repository.ErrProductNotFound)
+			},
+			expectedStatusCode:   404,
+			expectedResponseBody: `{"message":"Товар не найден"}`,
+		},
+		{
+			name:          "ПВЗ закрыт",
+			inputUserRole: 1,
+			mockBehavior: func(s *mock_usecase.MockPvzCapacity) {
+				s.EXPECT().IssueProduct(gomock.Any(), testScope, pvzId, productId, fixedTime).Return(domain.Product{}, repository.ErrPvzNotActive)
+			},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"ПВЗ закрыт или выведен из работы"}`,
+		},
+		{
+			name:                 "Запрещен доступ",
+			inputUserRole:        2,
+			mockBehavior:         func(s *mock_usecase.MockPvzCapacity) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"Доступ запрещен"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			repo := mock_usecase.NewMockPvzCapacity(c)
+			testCase.mockBehavior(repo)
+			audit := mock_usecase.NewMockAudit(c)
+			audit.EXPECT().RecordAudit(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+			handler := NewHandlerWithFixedTime(&usecase.Usecase{PvzCapacity: repo, Audit: audit}, fixedTime)
+			r := gin.New()
+			r.POST("/pvz/:pvzId/issue_product/:productId", func(c *gin.Context) {
+				c.Set(userCtx, testCase.inputUserRole)
+				handler.IssueProduct(c)
+			})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", fmt.Sprintf("/pvz/%s/issue_product/%s", pvzId, productId), nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.JSONEq(t, testCase.expectedResponseBody, w.Body.String())
+		})
+	}
+}
+
+func TestHandler_pvzLimits(t *testing.T) {
+	type mockBehavior func(pvz *mock_usecase.MockPvz, limits *mock_usecase.MockPvzCapacity)
+	fixedTime := time.Date(2025, 4, 10, 15, 5, 17, 0, time.UTC)
+	pvzId := uuid.New()
+	recepId := uuid.New()
+	stat := domain.ReceptionInProgress
+	reception := domain.ProductReception{Id: &recepId, DateReceived: &fixedTime, PVZId: &pvzId, Status: &stat}
+	product := domain.Product{Id: &recepId, DateReceived: &fixedTime, Type: "обувь", ReceptionId: &recepId, PVZId: &pvzId}
+
+	testTable := []struct {
+		name                 string
+		path                 string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name: "Приемка вне расписания",
+			path: "/receptions",
+			mockBehavior: func(pvz *mock_usecase.MockPvz, limits *mock_usecase.MockPvzCapacity) {
+				limits.EXPECT().CheckPvzLimits(gomock.Any(), testScope, pvzId, fixedTime).Return(nil, usecase.ErrPvzClosedNow)
+			},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"ПВЗ сейчас не работает по расписанию"}`,
+		},
+		{
+			name: "Товар вне расписания",
+			path: "/products",
+			mockBehavior: func(pvz *mock_usecase.MockPvz, limits *mock_usecase.MockPvzCapacity) {
+				limits.EXPECT().CheckPvzLimits(gomock.Any(), testScope, pvzId, fixedTime).Return(nil, usecase.ErrPvzClosedNow)
+			},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"ПВЗ сейчас не работает по расписанию"}`,
+		},
+		{
+			name: "Приемка в заполненный ПВЗ",
+			path: "/receptions",
+			mockBehavior: func(pvz *mock_usecase.MockPvz, limits *mock_usecase.MockPvzCapacity) {
+				limits.EXPECT().CheckPvzLimits(gomock.Any(), testScope, pvzId, fixedTime).Return(nil, nil)
+				pvz.EXPECT().CreateRecep(gomock.Any(), testScope, gomock.Any()).Return(domain.ProductReception{}, repository.ErrPvzFull)
+			},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"ПВЗ заполнен"}`,
+		},
+		{
+			name: "Товар в заполненный ПВЗ",
+			path: "/products",
+			mockBehavior: func(pvz *mock_usecase.MockPvz, limits *mock_usecase.MockPvzCapacity) {
+				limits.EXPECT().CheckPvzLimits(gomock.Any(), testScope, pvzId, fixedTime).Return(nil, nil)
+				pvz.EXPECT().AddProdToRecep(gomock.Any(), testScope, gomock.Any()).Return(domain.Product{}, repository.ErrPvzFull)
+			},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"ПВЗ заполнен"}`,
+		},
+		{
+			name: "Предупреждение при создании приемки",
+			path: "/receptions",
+			mockBehavior: func(pvz *mock_usecase.MockPvz, limits *mock_usecase.MockPvzCapacity) {
+				limits.EXPECT().CheckPvzLimits(gomock.Any(), testScope, pvzId, fixedTime).Return([]string{usecase.ErrPvzFull.Error()}, nil)
+				pvz.EXPECT().CreateRecep(gomock.Any(), testScope, gomock.Any()).Return(reception, nil)
+			},
+			expectedStatusCode: 200,
+			expectedResponseBody: fmt.Sprintf(`{"message":"Приемка создана","warnings":["ПВЗ заполнен"],
+				"content":{"id":"%s","dateTime":"2025-04-10T15:05:17Z","pvzId":"%s","status":"in_progress"}}`, recepId, pvzId),
+		},
+		{
+			name: "Предупреждение при добавлении товара",
+			path: "/products",
+			mockBehavior: func(pvz *mock_usecase.MockPvz, limits *mock_usecase.MockPvzCapacity) {
+				limits.EXPECT().CheckPvzLimits(gomock.Any(), testScope, pvzId, fixedTime).Return([]string{usecase.ErrPvzClosedNow.Error()}, nil)
+				pvz.EXPECT().AddProdToRecep(gomock.Any(), testScope, gomock.Any()).Return(product, nil)
+			},
+			expectedStatusCode: 200,
+			expectedResponseBody: fmt.Sprintf(`{"message":"Товар добавлен","warnings":["ПВЗ сейчас не работает по расписанию"],
+				"content":{"id":"%s","dateTime":"2025-04-10T15:05:17Z","type":"обувь","receptionId":"%s","pvzId":"%s"}}`, recepId, recepId, pvzId),
+		},
+	}
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			pvz := mock_usecase.NewMockPvz(c)
+			limits := mock_usecase.NewMockPvzCapacity(c)
+			testCase.mockBehavior(pvz, limits)
+
+			handler := NewHandlerWithFixedTime(&usecase.Usecase{Pvz: pvz, PvzCapacity: limits}, fixedTime)
+			r := gin.New()
+			setUser := func(c *gin.Context) {
+				c.Set(userCtx, 1)
+			}
+			r.POST("/receptions", setUser, handler.CreateReceptions)
+			r.POST("/products", setUser, handler.AddProducts)
+
+			w := httptest.NewRecorder()
+			body := fmt.Sprintf(`{"pvzId":"%s","type":"обувь"}`, pvzId)
+			req := httptest.NewRequest("POST", testCase.path, strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.JSONEq(t, testCase.expectedResponseBody, w.Body.String())
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/bllooop/pvzservice/internal/domain"
+	"github.com/bllooop/pvzservice/internal/repository"
+	"github.com/bllooop/pvzservice/internal/usecase"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func (h *Handler) GetPvzSchedule(c *gin.Context) {
+	reqLog(c).Info().Msg("Получен запрос на получение расписания ПВЗ")
+	pvzId, err := uuid.Parse(c.Param("pvzId"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "Некорректный UUID ПВЗ")
+		return
+	}
+	result, err := h.Usecases.PvzCapacity.GetPvzSchedule(c.Request.Context(), tenantScope(c), pvzId)
+	if errors.Is(err, repository.ErrPvzNotFound) {
+		newErrorResponse(c, http.StatusNotFound, "ПВЗ не найден")
+		return
+	}
+	if err != nil {
+		reqLog(c).Error().Err(err).Msg("")
+		newErrorResponse(c, http.StatusInternalServerError, "Ошибка выполнения запроса "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, map[string]any{
+		"message": "Расписание ПВЗ",
+		"content": result,
+	})
+}
+
+func (h *Handler) SetPvzSchedule(c *gin.Context) {
+	reqLog(c).Info().Msg("Получен запрос на изменение расписания ПВЗ")
+	pvzId, err := uuid.Parse(c.Param("pvzId"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "Некорректный UUID ПВЗ")
+		return
+	}
+	userRole, err := getUserRole(c)
+	if err != nil {
+		reqLog(c).Error().Err(err).Msg("")
+		newErrorResponse(c, http.StatusInternalServerError, "Ошибка получения роли "+err.Error())
+		return
+	}
+	if userRole != 2 {
+		reqLog(c).Error().Msg("Данный запрос доступен только модератору")
+		newErrorResponse(c, http.StatusBadRequest, "Доступ запрещен")
+		return
+	}
+	var input domain.PvzSchedule
+	if err := c.ShouldBindJSON(&input); err != nil {
+		reqLog(c).Error().Err(err).Msg(err.Error())
+		newErrorResponse(c, http.StatusBadRequest, "Неверный запрос")
+		return
+	}
+	result, err := h.Usecases.PvzCapacity.SetPvzSchedule(h.auditContext(c, "pvz.schedule", "pvz"), tenantScope(c), pvzId, input)
+	switch {
+	case errors.Is(err, usecase.ErrInvalidSchedule):
+		newErrorResponse(c, http.StatusBadRequest, "Неверный запрос, "+err.Error())
+		return
+	case errors.Is(err, repository.ErrPvzNotFound):
+		newErrorResponse(c, http.StatusNotFound, "ПВЗ не найден")
+		return
+	case err != nil:
+		reqLog(c).Error().Err(err).Msg("")
+		newErrorResponse(c, http.StatusInternalServerError, "Ошибка выполнения запроса "+err.Error())
+		return
+	}
+	reqLog(c).Info().Msg("Получен ответ на изменение расписания ПВЗ")
+	c.JSON(http.StatusOK, map[string]any{
+		"message": "Расписание ПВЗ изменено",
+		"content": result,
+	})
+}
+
+func (h *Handler) GetPvzOccupancy(c *gin.Context) {
+	reqLog(c).Info().Msg("Получен запрос на получение заполненности ПВЗ")
+	var pvzId *uuid.UUID
+	if param := c.Query("pvzId"); param != "" {
+		id, err := uuid.Parse(param)
+		if err != nil {
+			newErrorResponse(c, http.StatusBadRequest, "Некорректный UUID ПВЗ")
+			return
+		}
+		pvzId = &id
+	}
+	result, err := h.Usecases.PvzCapacity.GetPvzOccupancy(c.Request.Context(), tenantScope(c), pvzId)
+	if err != nil {
+		reqLog(c).Error().Err(err).Msg("")
+		newErrorResponse(c, http.StatusInternalServerError, "Ошибка выполнения запроса "+err.Error())
+		return
+	}
+	if result == nil {
+		result = []domain.PvzOccupancy{}
+	}
+	c.JSON(http.StatusOK, map[string]any{
+		"message": "Заполненность ПВЗ",
+		"content": result,
+	})
+}
+
+func (h *Handler) IssueProduct(c *gin.Context) {
+	reqLog(c).Info().Msg("Получен запрос на выдачу товара")
+	pvzId, err := uuid.Parse(c.Param("pvzId"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "Некорректный UUID ПВЗ")
+		return
+	}
+	productId, err := uuid.Parse(c.Param("productId"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "Некорректный UUID товара")
+		return
+	}
+	userRole, err := getUserRole(c)
+	if err != nil {
+		reqLog(c).Error().Err(err).Msg("")
+		newErrorResponse(c, http.StatusInternalServerError, "Ошибка получения роли "+err.Error())
+		return
+	}
+	if userRole != 1 {
+		reqLog(c).Error().Msg("Данный запрос доступен только сотруднику ПВЗ")
+		newErrorResponse(c, http.StatusBadRequest, "Доступ запрещен")
+		return
+	}
+	result, err := h.Usecases.PvzCapacity.IssueProduct(h.auditContext(c, "product.issue", "product"), tenantScope(c), pvzId, productId, h.Now())
+	if pvzUnavailable(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, repository.ErrProductNotFound):
+		newErrorResponse(c, http.StatusNotFound, "Товар не найден")
+		return
+	case errors.Is(err, repository.ErrProductNotReceived), errors.Is(err, repository.ErrProductIssued):
+		newErrorResponse(c, http.StatusBadRequest, "Неверный запрос, "+err.Error())
+		return
+	case err != nil:
+		reqLog(c).Error().Err(err).Msg("")
+		newErrorResponse(c, http.StatusInternalServerError, "Ошибка выполнения запроса "+err.Error())
+		return
+	}
+	reqLog(c).Info().Msg("Получен ответ на выдачу товара")
+	c.JSON(http.StatusOK, map[string]any{
+		"message": "Товар выдан",
+		"content": result,
+	})
+}
+
+// checkPvzLimits проверяет расписание и заполненность ПВЗ перед операцией с
+// приемкой. Если операцию нужно отклонить, ответ уже отправлен и ok равен false.
+func (h *Handler) checkPvzLimits(c *gin.Context, pvzId uuid.UUID) (warnings []string, ok bool) {
+	warnings, err := h.Usecases.PvzCapacity.CheckPvzLimits(c.Request.Context(), tenantScope(c), pvzId, h.Now())
+	if pvzUnavailable(c, err) {
+		return nil, false
+	}
+	switch {
+	case errors.Is(err, usecase.ErrPvzClosedNow), errors.Is(err, usecase.ErrPvzFull):
+		reqLog(c).Error().Err(err).Msg("")
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return nil, false
+	case err != nil:
+		reqLog(c).Error().Err(err).Msg("")
+		newErrorResponse(c, http.StatusInternalServerError, "Ошибка выполнения запроса "+err.Error())
+		return nil, false
+	}
+	for _, warning := range warnings {
+		reqLog(c).Warn().Str("pvz", pvzId.String()).Msg(warning)
+	}
+	return warnings, true
+}
+
+// withWarnings добавляет к ответу предупреждения, если они есть.
+func withWarnings(response map[string]any, warnings []string) map[string]any {
+	if len(warnings) > 0 {
+		response["warnings"] = warnings
+	}
+	return response
+}
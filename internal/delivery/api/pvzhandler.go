@@ -1,12 +1,15 @@
 package api
 
 import (
+	"errors"
+	"math"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/bllooop/pvzservice/internal/domain"
-	logger "github.com/bllooop/pvzservice/pkg/logging"
+	"github.com/bllooop/pvzservice/internal/repository"
+	"github.com/bllooop/pvzservice/internal/usecase"
 	prometheus "github.com/bllooop/pvzservice/prometheus"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -14,36 +17,36 @@ import (
 
 func (h *Handler) CreatePvz(c *gin.Context) {
 
-	logger.Log.Info().Msg("Получен запрос на заведение ПВЗ")
+	reqLog(c).Info().Msg("Получен запрос на заведение ПВЗ")
 	if c.Request.Method != http.MethodPost {
-		logger.Log.Error().Msg("Требуется запрос POST")
+		reqLog(c).Error().Msg("Требуется запрос POST")
 		newErrorResponse(c, http.StatusBadRequest, "Неверный запрос")
 		return
 	}
 	userRole, err := getUserRole(c)
 	if err != nil {
-		logger.Log.Error().Err(err).Msg("")
+		reqLog(c).Error().Err(err).Msg("")
 		newErrorResponse(c, http.StatusInternalServerError, "Ошибка получения роли "+err.Error())
 		return
 	}
-	logger.Log.Debug().Msgf("Успешно получена роль %v", getRoleName(userRole))
+	reqLog(c).Debug().Msgf("Успешно получена роль %v", getRoleName(userRole))
 	if userRole != 2 {
-		logger.Log.Error().Msg("Данный запрос доступен только модератору")
+		reqLog(c).Error().Msg("Данный запрос доступен только модератору")
 		newErrorResponse(c, http.StatusBadRequest, "Доступ запрещен")
 		return
 	}
 	var input domain.PVZ
 	if err := c.ShouldBindJSON(&input); err != nil {
-		logger.Log.Error().Err(err).Msg(err.Error())
+		reqLog(c).Error().Err(err).Msg(err.Error())
 		newErrorResponse(c, http.StatusBadRequest, "Неверный запрос")
 		return
 	}
-	logger.Log.Debug().Msgf("Успешно прочитаны данные из запроса  %s", input.City)
+	reqLog(c).Debug().Msgf("Успешно прочитаны данные из запроса  %s", input.City)
 	now := h.Now()
 	input.DateRegister = &now
-	result, err := h.Usecases.Pvz.CreatePvz(input)
+	result, err := h.Usecases.Pvz.CreatePvz(h.auditContext(c, "pvz.create", "pvz"), tenantScope(c), input)
 	if err != nil {
-		logger.Log.Error().Err(err).Msg("")
+		reqLog(c).Error().Err(err).Msg("")
 		newErrorResponse(c, http.StatusInternalServerError, "Ошибка выполнения запроса "+err.Error())
 		return
 	}
@@ -52,13 +55,134 @@ func (h *Handler) CreatePvz(c *gin.Context) {
 		"message": "ПВЗ создан",
 		"content": result,
 	})
-	logger.Log.Info().Msg("Получен ответ cоздание пвз")
+	reqLog(c).Info().Msg("Получен ответ cоздание пвз")
+}
+
+func (h *Handler) UpdatePvz(c *gin.Context) {
+	reqLog(c).Info().Msg("Получен запрос на изменение ПВЗ")
+	pvzId, err := uuid.Parse(c.Param("pvzId"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "Некорректный UUID ПВЗ")
+		return
+	}
+	userRole, err := getUserRole(c)
+	if err != nil {
+		reqLog(c).Error().Err(err).Msg("")
+		newErrorResponse(c, http.StatusInternalServerError, "Ошибка получения роли "+err.Error())
+		return
+	}
+	if userRole != 2 {
+		reqLog(c).Error().Msg("Данный запрос доступен только модератору")
+		newErrorResponse(c, http.StatusBadRequest, "Доступ запрещен")
+		return
+	}
+	var input domain.PvzUpdate
+	if err := c.ShouldBindJSON(&input); err != nil {
+		reqLog(c).Error().Err(err).Msg(err.Error())
+		newErrorResponse(c, http.StatusBadRequest, "Неверный запрос")
+		return
+	}
+	if input.Status != nil && input.EffectiveFrom == nil {
+		now := h.Now()
+		input.EffectiveFrom = &now
+	}
+	input.ActorId, _ = getUserId(c)
+	reqLog(c).Debug().Msgf("Успешно прочитаны данные из запроса %s", pvzId)
+	result, err := h.Usecases.Pvz.UpdatePvz(h.auditContext(c, "pvz.update", "pvz"), tenantScope(c), pvzId, input)
+	switch {
+	case errors.Is(err, usecase.ErrInvalidPvzUpdate):
+		newErrorResponse(c, http.StatusBadRequest, "Неверный запрос")
+		return
+	case errors.Is(err, repository.ErrPvzNotFound):
+		newErrorResponse(c, http.StatusNotFound, "ПВЗ не найден")
+		return
+	case errors.Is(err, repository.ErrPvzTransitionNotAllowed):
+		newErrorResponse(c, http.StatusConflict, "Недопустимая смена статуса ПВЗ")
+		return
+	case errors.Is(err, repository.ErrReceptionInProgress):
+		newErrorResponse(c, http.StatusConflict, "В ПВЗ есть незакрытая приемка")
+		return
+	case err != nil:
+		reqLog(c).Error().Err(err).Msg("")
+		newErrorResponse(c, http.StatusInternalServerError, "Ошибка выполнения запроса "+err.Error())
+		return
+	}
+	reqLog(c).Info().Msg("Получен ответ на изменение ПВЗ")
+	c.JSON(http.StatusOK, map[string]any{
+		"message": "ПВЗ изменен",
+		"content": result,
+	})
+}
+
+func (h *Handler) GetNearestPvz(c *gin.Context) {
+	reqLog(c).Info().Msg("Получен запрос на поиск ближайших ПВЗ")
+	lat, errLat := strconv.ParseFloat(c.Query("lat"), 64)
+	lon, errLon := strconv.ParseFloat(c.Query("lon"), 64)
+	if errLat != nil || errLon != nil || !finite(lat) || !finite(lon) {
+		reqLog(c).Error().Msg("Не указаны или некорректны координаты")
+		newErrorResponse(c, http.StatusBadRequest, "Неверный запрос, укажите lat и lon")
+		return
+	}
+	params := domain.NearestPvzParams{Latitude: lat, Longitude: lon}
+	if radius := c.Query("radius"); radius != "" {
+		radiusKm, err := strconv.ParseFloat(radius, 64)
+		if err != nil || !finite(radiusKm) {
+			newErrorResponse(c, http.StatusBadRequest, "Неверный запрос, некорректный радиус")
+			return
+		}
+		params.RadiusKm = radiusKm
+	}
+	if limit, err := strconv.Atoi(c.Query("limit")); err == nil {
+		params.Limit = limit
+	}
+	reqLog(c).Debug().Msgf("Успешно прочитаны параметры из запроса %v, %v, %v", lat, lon, params.RadiusKm)
+	result, err := h.Usecases.Pvz.GetNearestPvz(c.Request.Context(), tenantScope(c), params)
+	if errors.Is(err, usecase.ErrInvalidGeoQuery) {
+		newErrorResponse(c, http.StatusBadRequest, "Неверный запрос, "+err.Error())
+		return
+	}
+	if err != nil {
+		reqLog(c).Error().Err(err).Msg("")
+		newErrorResponse(c, http.StatusInternalServerError, "Ошибка выполнения запроса "+err.Error())
+		return
+	}
+	if result == nil {
+		result = []domain.PvzDistance{}
+	}
+	reqLog(c).Info().Msg("Получен ответ на поиск ближайших ПВЗ")
+	c.JSON(http.StatusOK, map[string]any{
+		"message": "Ближайшие ПВЗ",
+		"content": result,
+	})
+}
+
+// finite сообщает, что v - обычное число: strconv.ParseFloat принимает и
+// строки NaN и Inf.
+func finite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
+
+// pvzUnavailable отвечает клиенту, если операция с приемкой отклонена из-за
+// статуса, заполненности или расписания ПВЗ, и сообщает, был ли отправлен ответ.
+func pvzUnavailable(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, repository.ErrPvzNotFound):
+		newErrorResponse(c, http.StatusNotFound, "ПВЗ не найден")
+	case errors.Is(err, repository.ErrPvzNotActive):
+		newErrorResponse(c, http.StatusBadRequest, "ПВЗ закрыт или выведен из работы")
+	case errors.Is(err, repository.ErrPvzFull), errors.Is(err, repository.ErrPvzClosedNow):
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+	default:
+		return false
+	}
+	reqLog(c).Error().Err(err).Msg("")
+	return true
 }
 
 func (h *Handler) GetPvz(c *gin.Context) {
-	logger.Log.Info().Msg("Получен запрос на получение данны о ПВЗ")
+	reqLog(c).Info().Msg("Получен запрос на получение данны о ПВЗ")
 	if c.Request.Method != http.MethodGet {
-		logger.Log.Error().Msg("Требуется запрос GET")
+		reqLog(c).Error().Msg("Требуется запрос GET")
 		newErrorResponse(c, http.StatusBadRequest, "Требуется запрос GET")
 		return
 	}
@@ -74,50 +198,91 @@ func (h *Handler) GetPvz(c *gin.Context) {
 	limitInt, err := strconv.Atoi(limit)
 	if err != nil || limitInt < 1 {
 		limitInt = 10
-	} else if limitInt > 30 {
-		limitInt = 30
+	} else if limitInt > h.maxPageLimit() {
+		limitInt = h.maxPageLimit()
+	}
+	// При localDay=true даты задаются без времени и сравниваются с местными
+	// сутками каждого ПВЗ.
+	localDay := c.Query("localDay") == "true"
+	layout := time.RFC3339
+	if localDay {
+		layout = "2006-01-02"
 	}
 	if startDate != "" {
-		startParse, err = time.Parse(time.RFC3339, startDate)
+		startParse, err = time.Parse(layout, startDate)
 		if err != nil {
-			logger.Log.Error().Err(err).Msg("")
+			reqLog(c).Error().Err(err).Msg("")
 			newErrorResponse(c, http.StatusBadRequest, "Неверный запрос")
 			return
 		}
 	}
 	if endDate != "" {
-		endParse, err = time.Parse(time.RFC3339, endDate)
+		endParse, err = time.Parse(layout, endDate)
 		if err != nil {
-			logger.Log.Error().Err(err).Msg("")
+			reqLog(c).Error().Err(err).Msg("")
 			newErrorResponse(c, http.StatusBadRequest, "Неверный запрос")
 			return
 		}
 	}
 	input := domain.GettingPvzParams{
-		Start: startParse,
-		End:   endParse,
-		Page:  pageInt,
-		Limit: limitInt,
-	}
-	logger.Log.Debug().Msgf("Успешно прочитаны параметры из запроса %s, %s,%v,%v", startParse, endParse, pageInt, limitInt)
-	result, err := h.Usecases.GetPvz(input)
+		Start:    startParse,
+		End:      endParse,
+		Page:     pageInt,
+		Limit:    limitInt,
+		LocalDay: localDay,
+	}
+	reqLog(c).Debug().Msgf("Успешно прочитаны параметры из запроса %s, %s,%v,%v", startParse, endParse, pageInt, limitInt)
+	result, err := h.Usecases.GetPvz(c.Request.Context(), tenantScope(c), input)
 	if err != nil {
-		logger.Log.Error().Err(err).Msg("")
+		reqLog(c).Error().Err(err).Msg("")
 		newErrorResponse(c, http.StatusInternalServerError, "Ошибка выполнения запроса "+err.Error())
 		return
 	}
 
-	logger.Log.Info().Msg("Получен ответ на запрос информации о ПВЗ")
+	reqLog(c).Info().Msg("Получен ответ на запрос информации о ПВЗ")
 	c.JSON(http.StatusOK, map[string]any{
 		"message": "Список ПВЗ",
 		"content": result,
 	})
 }
 
+func (h *Handler) GetPvzReport(c *gin.Context) {
+	reqLog(c).Info().Msg("Получен запрос на получение отчета ПВЗ по дням")
+	pvzId, err := uuid.Parse(c.Param("pvzId"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "Некорректный UUID ПВЗ")
+		return
+	}
+	params := domain.PvzReportParams{
+		PvzId:    pvzId,
+		From:     c.Query("startDate"),
+		To:       c.Query("endDate"),
+		LocalDay: c.Query("localDay") == "true",
+	}
+	result, err := h.Usecases.GetPvzReport(c.Request.Context(), tenantScope(c), params)
+	switch {
+	case errors.Is(err, usecase.ErrInvalidReport):
+		newErrorResponse(c, http.StatusBadRequest, "Неверный запрос, "+err.Error())
+		return
+	case errors.Is(err, repository.ErrPvzNotFound):
+		newErrorResponse(c, http.StatusNotFound, "ПВЗ не найден")
+		return
+	case err != nil:
+		reqLog(c).Error().Err(err).Msg("")
+		newErrorResponse(c, http.StatusInternalServerError, "Ошибка выполнения запроса "+err.Error())
+		return
+	}
+	reqLog(c).Info().Msg("Получен ответ на запрос отчета ПВЗ по дням")
+	c.JSON(http.StatusOK, map[string]any{
+		"message": "Отчет ПВЗ по дням",
+		"content": result,
+	})
+}
+
 func (h *Handler) CloseLast(c *gin.Context) {
-	logger.Log.Info().Msg("Получен запрос на закрытие приёмки")
+	reqLog(c).Info().Msg("Получен запрос на закрытие приёмки")
 	if c.Request.Method != http.MethodPost {
-		logger.Log.Error().Msg("Требуется запрос POST")
+		reqLog(c).Error().Msg("Требуется запрос POST")
 		newErrorResponse(c, http.StatusBadRequest, "Требуется запрос POST")
 		return
 	}
@@ -128,35 +293,38 @@ func (h *Handler) CloseLast(c *gin.Context) {
 		newErrorResponse(c, http.StatusBadRequest, "Некорректный UUID ПВЗ")
 		return
 	}
-	logger.Log.Debug().Msgf("Успешно прочитан параметр из запроса %s", pvzId)
+	reqLog(c).Debug().Msgf("Успешно прочитан параметр из запроса %s", pvzId)
 	userRole, err := getUserRole(c)
 	if err != nil {
-		logger.Log.Error().Err(err).Msg("")
+		reqLog(c).Error().Err(err).Msg("")
 		newErrorResponse(c, http.StatusInternalServerError, "Ошибка получения роли "+err.Error())
 		return
 	}
-	logger.Log.Debug().Msgf("Успешно получена роль %v", userRole)
+	reqLog(c).Debug().Msgf("Успешно получена роль %v", userRole)
 	if userRole != 1 {
-		logger.Log.Error().Msg("Данный запрос доступен только сотруднику ПВЗ")
+		reqLog(c).Error().Msg("Данный запрос доступен только сотруднику ПВЗ")
 		newErrorResponse(c, http.StatusBadRequest, "Доступ запрещен")
 		return
 	}
-	result, err := h.Usecases.Pvz.CloseReception(pvzId)
+	result, err := h.Usecases.Pvz.CloseReception(h.auditContext(c, "reception.close", "reception"), tenantScope(c), pvzId)
+	if pvzUnavailable(c, err) {
+		return
+	}
 	if err != nil {
-		logger.Log.Error().Err(err).Msg("")
+		reqLog(c).Error().Err(err).Msg("")
 		newErrorResponse(c, http.StatusInternalServerError, "Ошибка выполнения запроса "+err.Error())
 		return
 	}
-	logger.Log.Info().Msg("Получен ответ на закрытие приемки")
+	reqLog(c).Info().Msg("Получен ответ на закрытие приемки")
 	c.JSON(http.StatusOK, map[string]any{
 		"message": "Приемка закрыта",
 		"content": result,
 	})
 }
 func (h *Handler) DeleteLast(c *gin.Context) {
-	logger.Log.Info().Msg("Получен запрос на удаление товаров в рамках не закрытой приёмки:")
+	reqLog(c).Info().Msg("Получен запрос на удаление товаров в рамках не закрытой приёмки:")
 	if c.Request.Method != http.MethodPost {
-		logger.Log.Error().Msg("Требуется запрос POST")
+		reqLog(c).Error().Msg("Требуется запрос POST")
 		newErrorResponse(c, http.StatusBadRequest, "Требуется запрос POST")
 		return
 	}
@@ -167,128 +335,207 @@ func (h *Handler) DeleteLast(c *gin.Context) {
 		newErrorResponse(c, http.StatusBadRequest, "Некорректный UUID ПВЗ")
 		return
 	}
-	logger.Log.Debug().Msgf("Успешно прочитан параметр из запроса %s", pvzId)
+	reqLog(c).Debug().Msgf("Успешно прочитан параметр из запроса %s", pvzId)
 	userRole, err := getUserRole(c)
 	if err != nil {
-		logger.Log.Error().Err(err).Msg("")
+		reqLog(c).Error().Err(err).Msg("")
 		newErrorResponse(c, http.StatusInternalServerError, "Ошибка получения роли "+err.Error())
 		return
 	}
-	logger.Log.Debug().Msgf("Успешно получена роль %v", userRole)
+	reqLog(c).Debug().Msgf("Успешно получена роль %v", userRole)
 	if userRole != 1 {
-		logger.Log.Error().Msg("Данный запрос доступен только сотруднику ПВЗ")
+		reqLog(c).Error().Msg("Данный запрос доступен только сотруднику ПВЗ")
 		newErrorResponse(c, http.StatusBadRequest, "Доступ запрещен")
 		return
 	}
-	err = h.Usecases.Pvz.DeleteLastProduct(pvzId)
+	actorId, _ := getUserId(c)
+	_, err = h.Usecases.Pvz.DeleteLastProduct(h.auditContext(c, "product.delete", "product"), tenantScope(c), domain.ProductDeletion{PVZId: pvzId, ActorId: actorId})
+	if pvzUnavailable(c, err) {
+		return
+	}
+	if err != nil {
+		reqLog(c).Error().Err(err).Msg("")
+		newErrorResponse(c, http.StatusInternalServerError, "Ошибка выполнения запроса "+err.Error())
+		return
+	}
+	reqLog(c).Info().Msg("Получен ответ на удаление товара")
+	c.JSON(http.StatusOK, map[string]any{
+		"message": "Товар удален",
+	})
+}
+func (h *Handler) DeleteProduct(c *gin.Context) {
+	reqLog(c).Info().Msg("Получен запрос на удаление товара из не закрытой приёмки")
+	pvzId, err := uuid.Parse(c.Param("pvzId"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "Некорректный UUID ПВЗ")
+		return
+	}
+	productId, err := uuid.Parse(c.Param("productId"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "Некорректный UUID товара")
+		return
+	}
+	userRole, err := getUserRole(c)
 	if err != nil {
-		logger.Log.Error().Err(err).Msg("")
+		reqLog(c).Error().Err(err).Msg("")
+		newErrorResponse(c, http.StatusInternalServerError, "Ошибка получения роли "+err.Error())
+		return
+	}
+	if userRole != 1 {
+		reqLog(c).Error().Msg("Данный запрос доступен только сотруднику ПВЗ")
+		newErrorResponse(c, http.StatusBadRequest, "Доступ запрещен")
+		return
+	}
+	var input domain.ProductDeletion
+	if err := c.ShouldBindJSON(&input); err != nil {
+		reqLog(c).Error().Err(err).Msg(err.Error())
+		newErrorResponse(c, http.StatusBadRequest, "Неверный запрос, укажите причину удаления")
+		return
+	}
+	input.PVZId = pvzId
+	input.ProductId = &productId
+	input.ActorId, _ = getUserId(c)
+	reqLog(c).Debug().Msgf("Успешно прочитаны данные из запроса %s, %s", productId, input.Reason)
+	deleted, err := h.Usecases.Pvz.DeleteProduct(h.auditContext(c, "product.delete", "product"), tenantScope(c), input)
+	if pvzUnavailable(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, repository.ErrProductNotFound):
+		newErrorResponse(c, http.StatusNotFound, "Товар не найден")
+		return
+	case errors.Is(err, repository.ErrReceptionClosed):
+		newErrorResponse(c, http.StatusBadRequest, "Неверный запрос, приемка уже закрыта")
+		return
+	case err != nil:
+		reqLog(c).Error().Err(err).Msg("")
 		newErrorResponse(c, http.StatusInternalServerError, "Ошибка выполнения запроса "+err.Error())
 		return
 	}
-	logger.Log.Info().Msg("Получен ответ на удаление товара")
+	reqLog(c).Info().Msg("Получен ответ на удаление товара")
 	c.JSON(http.StatusOK, map[string]any{
 		"message": "Товар удален",
+		"content": deleted,
 	})
 }
+
 func (h *Handler) CreateReceptions(c *gin.Context) {
-	logger.Log.Info().Msg("Получен запрос на добавление информации о приёмке товаров")
+	reqLog(c).Info().Msg("Получен запрос на добавление информации о приёмке товаров")
 	if c.Request.Method != http.MethodPost {
-		logger.Log.Error().Msg("Требуется запрос POST")
+		reqLog(c).Error().Msg("Требуется запрос POST")
 		newErrorResponse(c, http.StatusBadRequest, "Требуется запрос POST")
 		return
 	}
 	userRole, err := getUserRole(c)
 	if err != nil {
-		logger.Log.Error().Err(err).Msg("")
+		reqLog(c).Error().Err(err).Msg("")
 		newErrorResponse(c, http.StatusInternalServerError, "Ошибка получения роли "+err.Error())
 		return
 	}
-	logger.Log.Debug().Msgf("Успешно получена роль %v", userRole)
+	reqLog(c).Debug().Msgf("Успешно получена роль %v", userRole)
 	if userRole != 1 {
-		logger.Log.Error().Msg("Данный запрос доступен только сотруднику ПВЗ")
+		reqLog(c).Error().Msg("Данный запрос доступен только сотруднику ПВЗ")
 		newErrorResponse(c, http.StatusBadRequest, "Доступ запрещен")
 		return
 	}
 	var input domain.ProductReception
 	if err := c.ShouldBindJSON(&input); err != nil {
-		logger.Log.Error().Err(err).Msg(err.Error())
+		reqLog(c).Error().Err(err).Msg(err.Error())
 		newErrorResponse(c, http.StatusBadRequest, "Неверный запрос или есть незакрытая приемка")
 		return
 	}
 	if input.PVZId == nil || *input.PVZId == uuid.Nil {
-		logger.Log.Error().Msg("Некорректный UUID ПВЗ")
+		reqLog(c).Error().Msg("Некорректный UUID ПВЗ")
 		newErrorResponse(c, http.StatusBadRequest, "Некорректный UUID ПВЗ")
 		return
 	}
-	logger.Log.Debug().Msgf("Успешно прочитаны данные из запроса %s", input.PVZId)
+	reqLog(c).Debug().Msgf("Успешно прочитаны данные из запроса %s", input.PVZId)
 	now := h.Now()
 	input.DateReceived = &now
 	status := "in_progress"
 	input.Status = &status
-	result, err := h.Usecases.Pvz.CreateRecep(input)
+	warnings, ok := h.checkPvzLimits(c, *input.PVZId)
+	if !ok {
+		return
+	}
+	result, err := h.Usecases.Pvz.CreateRecep(h.auditContext(c, "reception.create", "reception"), tenantScope(c), input)
+	if pvzUnavailable(c, err) {
+		return
+	}
+	if errors.Is(err, repository.ErrReceptionInProgress) {
+		reqLog(c).Error().Err(err).Msg("")
+		newErrorResponse(c, http.StatusBadRequest, "Неверный запрос или есть незакрытая приемка")
+		return
+	}
 	if err != nil {
-		logger.Log.Error().Err(err).Msg("")
+		reqLog(c).Error().Err(err).Msg("")
 		newErrorResponse(c, http.StatusInternalServerError, "Ошибка выполнения запроса "+err.Error())
 		return
 	}
 	prometheus.NumOfCreatedRecep.Inc()
 
-	logger.Log.Info().Msg("Получен ответ на добавление информации о приемке")
-	c.JSON(http.StatusOK, map[string]any{
+	reqLog(c).Info().Msg("Получен ответ на добавление информации о приемке")
+	c.JSON(http.StatusOK, withWarnings(map[string]any{
 		"message": "Приемка создана",
 		"content": result,
-	})
+	}, warnings))
 
 }
 
 func (h *Handler) AddProducts(c *gin.Context) {
-	logger.Log.Info().Msg("Получен запрос на добавление товаров в рамках одной приёмки")
+	reqLog(c).Info().Msg("Получен запрос на добавление товаров в рамках одной приёмки")
 	if c.Request.Method != http.MethodPost {
-		logger.Log.Error().Msg("Требуется запрос POST")
+		reqLog(c).Error().Msg("Требуется запрос POST")
 		newErrorResponse(c, http.StatusBadRequest, "Требуется запрос POST")
 		return
 	}
 	userRole, err := getUserRole(c)
 	if err != nil {
-		logger.Log.Error().Err(err).Msg("")
+		reqLog(c).Error().Err(err).Msg("")
 		newErrorResponse(c, http.StatusInternalServerError, "Ошибка получения роли "+err.Error())
 		return
 	}
-	logger.Log.Debug().Msgf("Успешно получена роль %v", userRole)
+	reqLog(c).Debug().Msgf("Успешно получена роль %v", userRole)
 	if userRole != 1 {
-		logger.Log.Error().Msg("Данный запрос доступен только сотруднику ПВЗ")
+		reqLog(c).Error().Msg("Данный запрос доступен только сотруднику ПВЗ")
 		newErrorResponse(c, http.StatusBadRequest, "Доступ запрещен")
 		return
 	}
 	var input domain.Product
 	if err := c.ShouldBindJSON(&input); err != nil {
-		logger.Log.Error().Err(err).Msg(err.Error())
+		reqLog(c).Error().Err(err).Msg(err.Error())
 		newErrorResponse(c, http.StatusBadRequest, "Неверный запрос или нет активной приемки")
 		return
 	}
 	if *input.PVZId == uuid.Nil {
-		logger.Log.Error().Err(err).Msg(err.Error())
+		reqLog(c).Error().Err(err).Msg(err.Error())
 		newErrorResponse(c, http.StatusBadRequest, "Неверный запрос или нет активной приемки")
 		return
 	}
 
-	logger.Log.Debug().Msgf("Успешно прочитаны данные из запроса %s, %s", input.Type, input.PVZId)
+	reqLog(c).Debug().Msgf("Успешно прочитаны данные из запроса %s, %s", input.Type, input.PVZId)
 	now := h.Now()
 	input.DateReceived = &now
-	result, err := h.Usecases.Pvz.AddProdToRecep(input)
+	warnings, ok := h.checkPvzLimits(c, *input.PVZId)
+	if !ok {
+		return
+	}
+	result, err := h.Usecases.Pvz.AddProdToRecep(h.auditContext(c, "product.create", "product"), tenantScope(c), input)
+	if pvzUnavailable(c, err) {
+		return
+	}
 	if err != nil {
-		logger.Log.Error().Err(err).Msg("")
+		reqLog(c).Error().Err(err).Msg("")
 		newErrorResponse(c, http.StatusInternalServerError, "Ошибка выполнения запроса "+err.Error())
 		return
 	}
 
 	prometheus.NumOfAddedProducts.Inc()
-	logger.Log.Info().Msg("Получен ответ на добавление товаров")
-	c.JSON(http.StatusOK, map[string]any{
+	reqLog(c).Info().Msg("Получен ответ на добавление товаров")
+	c.JSON(http.StatusOK, withWarnings(map[string]any{
 		"message": "Товар добавлен",
 		"content": result,
-	})
+	}, warnings))
 }
 
 func getRoleName(userRole int) string {
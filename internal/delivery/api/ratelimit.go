@@ -0,0 +1,131 @@
+package api
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bllooop/pvzservice/internal/ratelimit"
+	"github.com/bllooop/pvzservice/prometheus"
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	rateLimitMessage = "Слишком много запросов, повторите позже"
+	grpcRetryAfter   = "retry-after"
+)
+
+// roleName возвращает имя роли по ее номеру из токена.
+func roleName(role int) string {
+	for name, r := range roleMap {
+		if r == role {
+			return name
+		}
+	}
+	return ""
+}
+
+// IsRole сообщает, что name - имя роли пользователя: employee, moderator или
+// admin.
+func IsRole(name string) bool {
+	_, ok := roleMap[name]
+	return ok
+}
+
+// retryAfterSeconds округляет ожидание вверх до целых секунд, как того
+// требует заголовок Retry-After.
+func retryAfterSeconds(d time.Duration) string {
+	return strconv.Itoa(max(1, int(math.Ceil(d.Seconds()))))
+}
+
+// rateLimitByIP ограничивает частоту запросов группы group с одного IP-адреса.
+// Используется для маршрутов без авторизации.
+func (h *Handler) rateLimitByIP(group string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !h.allowRequest(c, group, "", ratelimit.IPKey(c.ClientIP())) {
+			c.Abort()
+		}
+	}
+}
+
+// allowRequest проверяет лимит и при его превышении отвечает 429 с
+// заголовком Retry-After.
+func (h *Handler) allowRequest(c *gin.Context, group, role, key string) bool {
+	allowed, retryAfter := h.RateLimit.Allow(group, role, key)
+	if allowed {
+		return true
+	}
+	prometheus.RateLimitedTotal.WithLabelValues("http", group).Inc()
+	reqLog(c).Warn().Str("group", group).Dur("retry_after", retryAfter).Msg("Превышен лимит частоты запросов")
+	c.Header("Retry-After", retryAfterSeconds(retryAfter))
+	newErrorResponse(c, http.StatusTooManyRequests, rateLimitMessage)
+	return false
+}
+
+// methodGroup относит запрос авторизованного пользователя к группе read или
+// write по HTTP-методу.
+func methodGroup(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return ratelimit.GroupRead
+	default:
+		return ratelimit.GroupWrite
+	}
+}
+
+// RateLimitInterceptor - аналог ограничения частоты HTTP для gRPC.
+// Выполняется после AuthInterceptor: вызовы ограничиваются по пользователю
+// из токена, а проверки состояния без токена - по IP-адресу. При превышении
+// лимита возвращается RESOURCE_EXHAUSTED и метаданные retry-after в
+// секундах.
+func RateLimitInterceptor(guard *ratelimit.Guard) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if strings.HasPrefix(info.FullMethod, "/"+healthpb.Health_ServiceDesc.ServiceName+"/") {
+			return handler(ctx, req)
+		}
+		group := grpcMethodGroup(info.FullMethod)
+		role, key := "", ratelimit.IPKey(grpcPeerIP(ctx))
+		if claims, ok := grpcClaims(ctx); ok {
+			role, key = roleName(claims.UserRole), ratelimit.UserKey(claims.UserId)
+		}
+		allowed, retryAfter := guard.Allow(group, role, key)
+		if allowed {
+			return handler(ctx, req)
+		}
+		prometheus.RateLimitedTotal.WithLabelValues("grpc", group).Inc()
+		_ = grpc.SetHeader(ctx, metadata.Pairs(grpcRetryAfter, retryAfterSeconds(retryAfter)))
+		return nil, status.Error(codes.ResourceExhausted, rateLimitMessage)
+	}
+}
+
+// grpcMethodGroup относит методы Get* и List* к группе read, остальные - к
+// write.
+func grpcMethodGroup(fullMethod string) string {
+	name := fullMethod[strings.LastIndex(fullMethod, "/")+1:]
+	if strings.HasPrefix(name, "Get") || strings.HasPrefix(name, "List") {
+		return ratelimit.GroupRead
+	}
+	return ratelimit.GroupWrite
+}
+
+func grpcPeerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
@@ -0,0 +1,146 @@
+package api
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bllooop/pvzservice/internal/domain"
+	"github.com/bllooop/pvzservice/internal/ratelimit"
+	"github.com/bllooop/pvzservice/internal/usecase"
+	mock_usecase "github.com/bllooop/pvzservice/internal/usecase/mocks"
+	"github.com/bllooop/pvzservice/prometheus"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func testGuard() *ratelimit.Guard {
+	one := ratelimit.Limit{Rate: 1, Burst: 1}
+	return ratelimit.NewGuard(ratelimit.NewMemory(), ratelimit.Policy{
+		Enabled: true,
+		Auth:    ratelimit.Group{Limit: one},
+		Write:   ratelimit.Group{Limit: one, Roles: map[string]ratelimit.Limit{"moderator": {}}},
+		Read:    ratelimit.Group{Limit: one},
+	})
+}
+
+func TestHandler_rateLimitByIP(t *testing.T) {
+	handler := NewHandler(&usecase.Usecase{}, Config{RateLimit: testGuard()})
+	r := gin.New()
+	r.POST("/login", handler.rateLimitByIP(ratelimit.GroupAuth), func(c *gin.Context) {
+		c.Status(200)
+	})
+	throttled := testutil.ToFloat64(prometheus.RateLimitedTotal.WithLabelValues("http", ratelimit.GroupAuth))
+
+	send := func(ip string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/login", nil)
+		req.RemoteAddr = ip + ":1234"
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, 200, send("192.0.2.1").Code)
+	w := send("192.0.2.1")
+	assert.Equal(t, 429, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+	assert.JSONEq(t, `{"message":"Слишком много запросов, повторите позже"}`, w.Body.String())
+	assert.Equal(t, 200, send("192.0.2.2").Code, "у другого IP свой лимит")
+	assert.Equal(t, throttled+1, testutil.ToFloat64(prometheus.RateLimitedTotal.WithLabelValues("http", ratelimit.GroupAuth)))
+}
+
+func TestHandler_trustedProxies(t *testing.T) {
+	testTable := []struct {
+		name               string
+		trustedProxies     []string
+		expectedSecondCode int
+	}{
+		{name: "Подмененный X-Forwarded-For без доверенных прокси", expectedSecondCode: 429},
+		{name: "X-Forwarded-For от доверенного прокси", trustedProxies: []string{"192.0.2.0/24"}, expectedSecondCode: 400},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			handler := NewHandler(&usecase.Usecase{}, Config{RateLimit: testGuard(), TrustedProxies: testCase.trustedProxies})
+			r := handler.InitRoutes()
+
+			send := func(forwardedFor string) int {
+				w := httptest.NewRecorder()
+				req := httptest.NewRequest("POST", "/login", strings.NewReader("{"))
+				req.RemoteAddr = "192.0.2.1:1234"
+				req.Header.Set("X-Forwarded-For", forwardedFor)
+				r.ServeHTTP(w, req)
+				return w.Code
+			}
+
+			assert.Equal(t, 400, send("198.51.100.1"))
+			assert.Equal(t, testCase.expectedSecondCode, send("198.51.100.2"))
+		})
+	}
+}
+
+func TestHandler_authIdentityRateLimit(t *testing.T) {
+	testTable := []struct {
+		name               string
+		method             string
+		role               int
+		expectedSecondCode int
+	}{
+		{name: "Запись сотрудника", method: "POST", role: 1, expectedSecondCode: 429},
+		{name: "Чтение сотрудника", method: "GET", role: 1, expectedSecondCode: 429},
+		{name: "Запись модератора без лимита", method: "POST", role: 2, expectedSecondCode: 200},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			claims := domain.TokenClaims{UserId: "u1", UserRole: testCase.role}
+			auth := mock_usecase.NewMockAuthorization(c)
+			auth.EXPECT().ParseToken("token").Return(claims, nil).Times(2)
+			auth.EXPECT().CheckUserActive(gomock.Any(), claims).Return(nil).Times(2)
+
+			handler := NewHandler(&usecase.Usecase{Authorization: auth}, Config{RateLimit: testGuard()})
+			r := gin.New()
+			r.Handle(testCase.method, "/products", handler.authIdentity, func(c *gin.Context) {
+				c.Status(200)
+			})
+
+			codes := make([]int, 0, 2)
+			for i := 0; i < 2; i++ {
+				w := httptest.NewRecorder()
+				req := httptest.NewRequest(testCase.method, "/products", nil)
+				req.Header.Set("Authorization", "Bearer token")
+				r.ServeHTTP(w, req)
+				codes = append(codes, w.Code)
+			}
+
+			assert.Equal(t, []int{200, testCase.expectedSecondCode}, codes)
+		})
+	}
+}
+
+func TestRateLimitInterceptor(t *testing.T) {
+	interceptor := RateLimitInterceptor(testGuard())
+	info := &grpc.UnaryServerInfo{FullMethod: "/pvz.v1.PVZService/GetPVZList"}
+	ok := func(ctx context.Context, req any) (any, error) { return "ok", nil }
+	ctx := context.WithValue(context.Background(), claimsKey{}, domain.TokenClaims{UserId: "u1", UserRole: 1})
+	throttled := testutil.ToFloat64(prometheus.RateLimitedTotal.WithLabelValues("grpc", ratelimit.GroupRead))
+
+	_, err := interceptor(ctx, nil, info, ok)
+	assert.NoError(t, err)
+	_, err = interceptor(ctx, nil, info, ok)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Equal(t, throttled+1, testutil.ToFloat64(prometheus.RateLimitedTotal.WithLabelValues("grpc", ratelimit.GroupRead)))
+
+	other := context.WithValue(context.Background(), claimsKey{}, domain.TokenClaims{UserId: "u2", UserRole: 1})
+	_, err = interceptor(other, nil, info, ok)
+	assert.NoError(t, err, "у другого пользователя свой лимит")
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"context"
+	"regexp"
+
+	logger "github.com/bllooop/pvzservice/pkg/logging"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	requestIdHeader = "X-Request-Id"
+	requestIdCtx    = "requestId"
+	grpcRequestId   = "x-request-id"
+)
+
+// validRequestId ограничивает принимаемые от клиента идентификаторы, чтобы
+// через заголовок нельзя было подмешать в лог произвольный текст.
+var validRequestId = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// requestIdOrNew возвращает идентификатор запроса клиента или новый UUID,
+// если клиент его не передал или передал некорректный.
+func requestIdOrNew(id string) string {
+	if validRequestId.MatchString(id) {
+		return id
+	}
+	return uuid.NewString()
+}
+
+// requestId присваивает запросу идентификатор из X-Request-Id или новый,
+// возвращает его в ответе и кладет в контекст запроса логгер, все записи
+// которого содержат request_id.
+func (h *Handler) requestId(c *gin.Context) {
+	id := requestIdOrNew(c.GetHeader(requestIdHeader))
+	c.Set(requestIdCtx, id)
+	c.Header(requestIdHeader, id)
+	ctx := c.Request.Context()
+	l := logger.Log.With().Ctx(ctx).Str("request_id", id).Logger()
+	c.Request = c.Request.WithContext(logger.WithLogger(ctx, l))
+	c.Next()
+}
+
+// withLogFields дополняет логгер запроса полями, например автором запроса
+// после авторизации.
+func withLogFields(c *gin.Context, fields func(zerolog.Context) zerolog.Context) {
+	ctx := c.Request.Context()
+	l := fields(logger.FromContext(ctx).With()).Logger()
+	c.Request = c.Request.WithContext(logger.WithLogger(ctx, l))
+}
+
+// reqLog возвращает логгер текущего запроса.
+func reqLog(c *gin.Context) *zerolog.Logger {
+	return logger.FromContext(c.Request.Context())
+}
+
+// getRequestId возвращает идентификатор, присвоенный запросу middleware
+// requestId, или значение заголовка, если middleware не выполнялся.
+func getRequestId(c *gin.Context) string {
+	if id := c.GetString(requestIdCtx); id != "" {
+		return id
+	}
+	return c.GetHeader(requestIdHeader)
+}
+
+// RequestIdInterceptor — аналог requestId для gRPC: идентификатор берется из
+// метаданных x-request-id и возвращается в заголовках ответа.
+func RequestIdInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		var id string
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get(grpcRequestId); len(values) > 0 {
+				id = values[0]
+			}
+		}
+		id = requestIdOrNew(id)
+		_ = grpc.SetHeader(ctx, metadata.Pairs(grpcRequestId, id))
+		l := logger.Log.With().Ctx(ctx).Str("request_id", id).Str("method", info.FullMethod).Logger()
+		return handler(logger.WithLogger(ctx, l), req)
+	}
+}
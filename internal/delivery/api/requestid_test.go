@@ -0,0 +1,59 @@
+package api
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	logger "github.com/bllooop/pvzservice/pkg/logging"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler_requestId(t *testing.T) {
+	testTable := []struct {
+		name      string
+		header    string
+		keepValue bool
+	}{
+		{name: "Идентификатор клиента", header: "req-42", keepValue: true},
+		{name: "Без заголовка"},
+		{name: "Некорректный идентификатор", header: "a b\nc"},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			defer func(l zerolog.Logger) { logger.Log = l }(logger.Log)
+			logger.Log = zerolog.New(&buf)
+
+			handler := NewHandlerWithFixedTime(nil, time.Now())
+			r := gin.New()
+			var seen string
+			r.GET("/ping", handler.requestId, func(c *gin.Context) {
+				seen = getRequestId(c)
+				reqLog(c).Info().Msg("ping")
+			})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/ping", nil)
+			if testCase.header != "" {
+				req.Header.Set(requestIdHeader, testCase.header)
+			}
+			r.ServeHTTP(w, req)
+
+			got := w.Header().Get(requestIdHeader)
+			assert.Equal(t, seen, got)
+			if testCase.keepValue {
+				assert.Equal(t, testCase.header, got)
+			} else {
+				_, err := uuid.Parse(got)
+				assert.NoError(t, err)
+			}
+			assert.Contains(t, buf.String(), `"request_id":"`+got+`"`)
+		})
+	}
+}
@@ -0,0 +1,271 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bllooop/pvzservice/internal/domain"
+	"github.com/bllooop/pvzservice/internal/repository"
+	"github.com/bllooop/pvzservice/internal/usecase"
+	mock_usecase "github.com/bllooop/pvzservice/internal/usecase/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestHandler_shipProduct(t *testing.T) {
+	type mockBehavior func(s *mock_usecase.MockTransfers)
+	fixedTime := time.Date(2025, 4, 10, 15, 5, 17, 0, time.UTC)
+	fromId, toId, productId, transferId := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	inputBody := fmt.Sprintf(`{"toPvzId":"%s","reason":"ошибка сортировки"}`, toId)
+	input := domain.TransferRequest{ToPVZId: toId, Reason: "ошибка сортировки"}
+
+	testTable := []struct {
+		name                 string
+		inputBody            string
+		inputUserRole        int
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:          "Ok",
+			inputBody:     inputBody,
+			inputUserRole: 1,
+			mockBehavior: func(s *mock_usecase.MockTransfers) {
+				s.EXPECT().ShipProduct(gomock.Any(), testScope, fromId, productId, "u1", input, fixedTime).Return(domain.ProductTransfer{
+					Id: transferId, ProductId: productId, Type: "обувь", FromPVZId: fromId, ToPVZId: toId,
+					Status: domain.TransferInTransit, Reason: "ошибка сортировки", ShippedBy: "u1", ShippedAt: fixedTime,
+				}, nil)
+			},
+			expectedStatusCode: 200,
+			expectedResponseBody: fmt.Sprintf(`{"message":"Товар отправлен в другой ПВЗ","content":{"id":"%s","productId":"%s","type":"обувь","fromPvzId":"%s","toPvzId":"%s","status":"in_transit","reason":"ошибка сортировки","shippedBy":"u1","shippedAt":"2025-04-10T15:05:17Z"}}`,
+				transferId, productId, fromId, toId),
+		},
+		{
+			name:                 "Не указан ПВЗ получения",
+			inputBody:            `{"reason":"ошибка сортировки"}`,
+			inputUserRole:        1,
+			mockBehavior:         func(s *mock_usecase.MockTransfers) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"Неверный запрос"}`,
+		},
+		{
+			name:          "Товар уже перемещен",
+			inputBody:     inputBody,
+			inputUserRole: 1,
+			mockBehavior: func(s *mock_usecase.MockTransfers) {
+				s.EXPECT().ShipProduct(gomock.Any(), testScope, fromId, productId, "u1", input, fixedTime).Return(domain.ProductTransfer{}, repository.ErrProductTransferred)
+			},
+			expectedStatusCode:   400,
+			expectedResponseBody: fmt.Sprintf(`{"message":"Неверный запрос, %s"}`, repository.ErrProductTransferred),
+		},
+		{
+			name:          "ПВЗ получения не найден",
+			inputBody:     inputBody,
+			inputUserRole: 1,
+			mockBehavior: func(s *mock_usecase.MockTransfers) {
+				s.EXPECT().ShipProduct(gomock.Any(), testScope, fromId, productId, "u1", input, fixedTime).Return(domain.ProductTransfer{}, repository.ErrPvzNotFound)
+			},
+			expectedStatusCode:   404,
+			expectedResponseBody: `{"message":"ПВЗ не найден"}`,
+		},
+		{
+			name:                 "Запрещен доступ",
+			inputBody:            inputBody,
+			inputUserRole:        2,
+			mockBehavior:         func(s *mock_usecase.MockTransfers) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"Доступ запрещен"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			transfers := mock_usecase.NewMockTransfers(c)
+			testCase.mockBehavior(transfers)
+			audit := mock_usecase.NewMockAudit(c)
+			audit.EXPECT().RecordAudit(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+			handler := NewHandlerWithFixedTime(&usecase.Usecase{Transfers: transfers, Audit: audit}, fixedTime)
+			r := gin.New()
+			r.POST("/pvz/:pvzId/transfer_product/:productId", func(c *gin.Context) {
+				c.Set(userCtx, testCase.inputUserRole)
+				c.Set(userId, "u1")
+				handler.ShipProduct(c)
+			})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", fmt.Sprintf("/pvz/%s/transfer_product/%s", fromId, productId), bytes.NewBufferString(testCase.inputBody))
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.JSONEq(t, testCase.expectedResponseBody, w.Body.String())
+		})
+	}
+}
+
+func TestHandler_acceptTransfer(t *testing.T) {
+	type mockBehavior func(s *mock_usecase.MockTransfers)
+	fixedTime := time.Date(2025, 4, 10, 15, 5, 17, 0, time.UTC)
+	fromId, toId, productId, addedId, transferId := uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	receivedBy := "u1"
+
+	testTable := []struct {
+		name                 string
+		inputUserRole        int
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:          "Ok",
+			inputUserRole: 1,
+			mockBehavior: func(s *mock_usecase.MockTransfers) {
+				s.EXPECT().AcceptTransfer(gomock.Any(), testScope, transferId, "u1", fixedTime).Return(domain.ProductTransfer{
+					Id: transferId, ProductId: productId, Type: "обувь", FromPVZId: fromId, ToPVZId: toId,
+					Status: domain.TransferReceived, ShippedBy: "u2", ShippedAt: fixedTime,
+					ReceivedProductId: &addedId, ReceivedBy: &receivedBy, ReceivedAt: &fixedTime,
+				}, nil)
+			},
+			expectedStatusCode: 200,
+			expectedResponseBody: fmt.Sprintf(`{"message":"Перемещенный товар принят","content":{"id":"%s","productId":"%s","type":"обувь","fromPvzId":"%s","toPvzId":"%s","status":"received","shippedBy":"u2","shippedAt":"2025-04-10T15:05:17Z","receivedProductId":"%s","receivedBy":"u1","receivedAt":"2025-04-10T15:05:17Z"}}`,
+				transferId, productId, fromId, toId, addedId),
+		},
+		{
+			name:          "Нет открытой приемки",
+			inputUserRole: 1,
+			mockBehavior: func(s *mock_usecase.MockTransfers) {
+				s.EXPECT().AcceptTransfer(gomock.Any(), testScope, transferId, "u1", fixedTime).Return(domain.ProductTransfer{}, repository.ErrNoOpenReception)
+			},
+			expectedStatusCode:   400,
+			expectedResponseBody: fmt.Sprintf(`{"message":"Неверный запрос, %s"}`, repository.ErrNoOpenReception),
+		},
+		{
+			name:          "ПВЗ получения заполнен",
+			inputUserRole: 1,
+			mockBehavior: func(s *mock_usecase.MockTransfers) {
+				s.EXPECT().AcceptTransfer(gomock.Any(), testScope, transferId, "u1", fixedTime).Return(domain.ProductTransfer{}, repository.ErrPvzFull)
+			},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"ПВЗ заполнен"}`,
+		},
+		{
+			name:          "Перемещение не найдено",
+			inputUserRole: 1,
+			mockBehavior: func(s *mock_usecase.MockTransfers) {
+				s.EXPECT().AcceptTransfer(gomock.Any(), testScope, transferId, "u1", fixedTime).Return(domain.ProductTransfer{}, repository.ErrTransferNotFound)
+			},
+			expectedStatusCode:   404,
+			expectedResponseBody: fmt.Sprintf(`{"message":"Ошибка выполнения запроса %s"}`, repository.ErrTransferNotFound),
+		},
+		{
+			name:                 "Запрещен доступ",
+			inputUserRole:        2,
+			mockBehavior:         func(s *mock_usecase.MockTransfers) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"Доступ запрещен"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			transfers := mock_usecase.NewMockTransfers(c)
+			testCase.mockBehavior(transfers)
+			audit := mock_usecase.NewMockAudit(c)
+			audit.EXPECT().RecordAudit(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+			handler := NewHandlerWithFixedTime(&usecase.Usecase{Transfers: transfers, Audit: audit}, fixedTime)
+			r := gin.New()
+			r.POST("/transfers/:transferId/accept", func(c *gin.Context) {
+				c.Set(userCtx, testCase.inputUserRole)
+				c.Set(userId, "u1")
+				handler.AcceptTransfer(c)
+			})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", fmt.Sprintf("/transfers/%s/accept", transferId), nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.JSONEq(t, testCase.expectedResponseBody, w.Body.String())
+		})
+	}
+}
+
+func TestHandler_getTransfersInTransit(t *testing.T) {
+	type mockBehavior func(s *mock_usecase.MockTransfers)
+	pvzId := uuid.New()
+
+	testTable := []struct {
+		name                 string
+		query                string
+		inputUserRole        int
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:          "Ok",
+			query:         "?pvzId=" + pvzId.String() + "&limit=100",
+			inputUserRole: 2,
+			mockBehavior: func(s *mock_usecase.MockTransfers) {
+				s.EXPECT().GetTransfersInTransit(gomock.Any(), testScope, domain.InTransitParams{PVZId: &pvzId, Page: 1, Limit: 30}).Return(nil, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"message":"Товары в пути","content":[]}`,
+		},
+		{
+			name:                 "Некорректный ПВЗ",
+			query:                "?pvzId=abc",
+			inputUserRole:        2,
+			mockBehavior:         func(s *mock_usecase.MockTransfers) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"Некорректный UUID ПВЗ"}`,
+		},
+		{
+			name:                 "Запрещен доступ",
+			inputUserRole:        1,
+			mockBehavior:         func(s *mock_usecase.MockTransfers) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"Доступ запрещен"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			transfers := mock_usecase.NewMockTransfers(c)
+			testCase.mockBehavior(transfers)
+
+			handler := NewHandlerWithFixedTime(&usecase.Usecase{Transfers: transfers}, time.Now())
+			r := gin.New()
+			r.GET("/transfers/in_transit", func(c *gin.Context) {
+				c.Set(userCtx, testCase.inputUserRole)
+				handler.GetTransfersInTransit(c)
+			})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/transfers/in_transit"+testCase.query, nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.JSONEq(t, testCase.expectedResponseBody, w.Body.String())
+		})
+	}
+}
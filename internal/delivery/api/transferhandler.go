@@ -0,0 +1,151 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/bllooop/pvzservice/internal/domain"
+	"github.com/bllooop/pvzservice/internal/repository"
+	"github.com/bllooop/pvzservice/internal/usecase"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func (h *Handler) ShipProduct(c *gin.Context) {
+	reqLog(c).Info().Msg("Получен запрос на перемещение товара в другой ПВЗ")
+	pvzId, err := uuid.Parse(c.Param("pvzId"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "Некорректный UUID ПВЗ")
+		return
+	}
+	productId, err := uuid.Parse(c.Param("productId"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "Некорректный UUID товара")
+		return
+	}
+	userRole, err := getUserRole(c)
+	if err != nil {
+		reqLog(c).Error().Err(err).Msg("")
+		newErrorResponse(c, http.StatusInternalServerError, "Ошибка получения роли "+err.Error())
+		return
+	}
+	if userRole != 1 {
+		reqLog(c).Error().Msg("Данный запрос доступен только сотруднику ПВЗ")
+		newErrorResponse(c, http.StatusBadRequest, "Доступ запрещен")
+		return
+	}
+	var input domain.TransferRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		reqLog(c).Error().Err(err).Msg(err.Error())
+		newErrorResponse(c, http.StatusBadRequest, "Неверный запрос")
+		return
+	}
+	actorId, _ := getUserId(c)
+	result, err := h.Usecases.Transfers.ShipProduct(h.auditContext(c, "transfer.ship", "transfer"), tenantScope(c), pvzId, productId, actorId, input, h.Now())
+	if transferFailed(c, err) {
+		return
+	}
+	reqLog(c).Info().Msg("Получен ответ на перемещение товара")
+	c.JSON(http.StatusOK, map[string]any{
+		"message": "Товар отправлен в другой ПВЗ",
+		"content": result,
+	})
+}
+
+func (h *Handler) AcceptTransfer(c *gin.Context) {
+	reqLog(c).Info().Msg("Получен запрос на приемку перемещенного товара")
+	transferId, err := uuid.Parse(c.Param("transferId"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "Некорректный UUID перемещения")
+		return
+	}
+	userRole, err := getUserRole(c)
+	if err != nil {
+		reqLog(c).Error().Err(err).Msg("")
+		newErrorResponse(c, http.StatusInternalServerError, "Ошибка получения роли "+err.Error())
+		return
+	}
+	if userRole != 1 {
+		reqLog(c).Error().Msg("Данный запрос доступен только сотруднику ПВЗ")
+		newErrorResponse(c, http.StatusBadRequest, "Доступ запрещен")
+		return
+	}
+	actorId, _ := getUserId(c)
+	result, err := h.Usecases.Transfers.AcceptTransfer(h.auditContext(c, "transfer.accept", "transfer"), tenantScope(c), transferId, actorId, h.Now())
+	if transferFailed(c, err) {
+		return
+	}
+	reqLog(c).Info().Msg("Получен ответ на приемку перемещенного товара")
+	c.JSON(http.StatusOK, map[string]any{
+		"message": "Перемещенный товар принят",
+		"content": result,
+	})
+}
+
+func (h *Handler) GetTransfersInTransit(c *gin.Context) {
+	reqLog(c).Info().Msg("Получен запрос на получение товаров в пути")
+	userRole, err := getUserRole(c)
+	if err != nil {
+		reqLog(c).Error().Err(err).Msg("")
+		newErrorResponse(c, http.StatusInternalServerError, "Ошибка получения роли "+err.Error())
+		return
+	}
+	if !isReader(userRole) {
+		reqLog(c).Error().Msg("Данный запрос доступен только модератору или администратору")
+		newErrorResponse(c, http.StatusBadRequest, "Доступ запрещен")
+		return
+	}
+	params := domain.InTransitParams{}
+	if param := c.Query("pvzId"); param != "" {
+		id, err := uuid.Parse(param)
+		if err != nil {
+			newErrorResponse(c, http.StatusBadRequest, "Некорректный UUID ПВЗ")
+			return
+		}
+		params.PVZId = &id
+	}
+	pageInt, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || pageInt < 1 {
+		pageInt = 1
+	}
+	limitInt, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limitInt < 1 {
+		limitInt = 10
+	} else if limitInt > h.maxPageLimit() {
+		limitInt = h.maxPageLimit()
+	}
+	params.Page, params.Limit = pageInt, limitInt
+	result, err := h.Usecases.Transfers.GetTransfersInTransit(c.Request.Context(), tenantScope(c), params)
+	if err != nil {
+		reqLog(c).Error().Err(err).Msg("")
+		newErrorResponse(c, http.StatusInternalServerError, "Ошибка выполнения запроса "+err.Error())
+		return
+	}
+	if result == nil {
+		result = []domain.ProductTransfer{}
+	}
+	c.JSON(http.StatusOK, map[string]any{
+		"message": "Товары в пути",
+		"content": result,
+	})
+}
+
+// transferFailed отправляет ответ с ошибкой перемещения, если она есть.
+func transferFailed(c *gin.Context, err error) bool {
+	if err == nil || pvzUnavailable(c, err) {
+		return err != nil
+	}
+	reqLog(c).Error().Err(err).Msg("")
+	switch {
+	case errors.Is(err, repository.ErrProductNotFound), errors.Is(err, repository.ErrTransferNotFound):
+		newErrorResponse(c, http.StatusNotFound, "Ошибка выполнения запроса "+err.Error())
+	case errors.Is(err, usecase.ErrTransferToSamePvz), errors.Is(err, repository.ErrProductNotReceived),
+		errors.Is(err, repository.ErrProductIssued), errors.Is(err, repository.ErrProductTransferred),
+		errors.Is(err, repository.ErrTransferNotInTransit), errors.Is(err, repository.ErrNoOpenReception):
+		newErrorResponse(c, http.StatusBadRequest, "Неверный запрос, "+err.Error())
+	default:
+		newErrorResponse(c, http.StatusInternalServerError, "Ошибка выполнения запроса "+err.Error())
+	}
+	return true
+}
@@ -0,0 +1,305 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bllooop/pvzservice/internal/domain"
+	"github.com/bllooop/pvzservice/internal/repository"
+	"github.com/bllooop/pvzservice/internal/usecase"
+	mock_usecase "github.com/bllooop/pvzservice/internal/usecase/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestHandler_getUsers(t *testing.T) {
+	type mockBehavior func(s *mock_usecase.MockAuthorization)
+	userID, err := uuid.NewRandom()
+	if err != nil {
+		panic(err)
+	}
+	testTable := []struct {
+		name                 string
+		query                string
+		inputUserRole        int
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:          "OK",
+			query:         "?page=2&limit=100",
+			inputUserRole: 2,
+			mockBehavior: func(s *mock_usecase.MockAuthorization) {
+				s.EXPECT().GetUsers(gomock.Any(), testScope, domain.GettingUsersParams{Page: 2, Limit: 30}).Return([]domain.UserInfo{
+					{Id: userID, Email: "test", Role: "employee"},
+				}, nil)
+			},
+			expectedStatusCode: 200,
+			expectedResponseBody: fmt.Sprintf(`{"message":"Список пользователей",
+				"content":[{"id":"%s","email":"test","role":"employee"}]}`, userID),
+		},
+		{
+			name:          "Администратор выбирает компанию",
+			query:         "?tenant=t2",
+			inputUserRole: 3,
+			mockBehavior: func(s *mock_usecase.MockAuthorization) {
+				s.EXPECT().GetUsers(gomock.Any(), domain.TenantOf("t2"), domain.GettingUsersParams{Page: 1, Limit: 10}).Return([]domain.UserInfo{
+					{Id: userID, Email: "test", Role: "employee", TenantId: "t2"},
+				}, nil)
+			},
+			expectedStatusCode: 200,
+			expectedResponseBody: fmt.Sprintf(`{"message":"Список пользователей",
+				"content":[{"id":"%s","email":"test","role":"employee","tenant":"t2"}]}`, userID),
+		},
+		{
+			name:                 "Запрещен доступ",
+			inputUserRole:        1,
+			mockBehavior:         func(s *mock_usecase.MockAuthorization) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"Доступ запрещен"}`,
+		},
+		{
+			name:          "Ошибка выполнения запроса",
+			inputUserRole: 2,
+			mockBehavior: func(s *mock_usecase.MockAuthorization) {
+				s.EXPECT().GetUsers(gomock.Any(), testScope, domain.GettingUsersParams{Page: 1, Limit: 10}).Return(nil, errors.New("Internal Server Error"))
+			},
+			expectedStatusCode:   500,
+			expectedResponseBody: `{"message":"Ошибка выполнения запроса Internal Server Error"}`,
+		},
+	}
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			repo := mock_usecase.NewMockAuthorization(c)
+			testCase.mockBehavior(repo)
+
+			usecases := &usecase.Usecase{Authorization: repo}
+			handler := Handler{Usecases: usecases}
+			r := gin.New()
+			r.GET("/users", func(c *gin.Context) {
+				c.Set(userCtx, testCase.inputUserRole)
+				handler.GetUsers(c)
+			})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/users"+testCase.query, nil)
+
+			r.ServeHTTP(w, req)
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.JSONEq(t, testCase.expectedResponseBody, w.Body.String())
+		})
+	}
+}
+
+func TestHandler_updateUser(t *testing.T) {
+	type mockBehavior func(s *mock_usecase.MockAuthorization, userId uuid.UUID)
+	userID, err := uuid.NewRandom()
+	if err != nil {
+		panic(err)
+	}
+	role := "moderator"
+	testTable := []struct {
+		name                 string
+		userId               string
+		inputBody            string
+		inputUserRole        int
+		actorId              string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:          "OK",
+			userId:        userID.String(),
+			inputBody:     `{"role":"moderator"}`,
+			inputUserRole: 2,
+			mockBehavior: func(s *mock_usecase.MockAuthorization, userId uuid.UUID) {
+				s.EXPECT().UpdateUser(gomock.Any(), testScope, userId, domain.UpdateUserInput{Role: &role}).Return(domain.UserInfo{
+					Id: userId, Email: "test", Role: "moderator",
+				}, nil)
+			},
+			expectedStatusCode: 200,
+			expectedResponseBody: fmt.Sprintf(`{"message":"Пользователь изменен",
+				"content":{"id":"%s","email":"test","role":"moderator"}}`, userID),
+		},
+		{
+			name:          "Смена пароля",
+			userId:        userID.String(),
+			inputBody:     `{"password":"Str0ng!Passw0rd"}`,
+			inputUserRole: 2,
+			mockBehavior: func(s *mock_usecase.MockAuthorization, userId uuid.UUID) {
+				s.EXPECT().UpdateUser(gomock.Any(), testScope, userId, gomock.Any()).DoAndReturn(
+					func(ctx context.Context, _ domain.TenantScope, _ uuid.UUID, _ domain.UpdateUserInput) (domain.UserInfo, error) {
+						entry, _ := repository.AuditFromContext(ctx)
+						if entry.Action != "user.password.change" {
+							return domain.UserInfo{}, fmt.Errorf("действие аудита %q", entry.Action)
+						}
+						return domain.UserInfo{Id: userId, Email: "test", Role: "employee"}, nil
+					})
+			},
+			expectedStatusCode: 200,
+			expectedResponseBody: fmt.Sprintf(`{"message":"Пользователь изменен",
+				"content":{"id":"%s","email":"test","role":"employee"}}`, userID),
+		},
+		{
+			name:                 "Блокировка себя",
+			userId:               userID.String(),
+			inputBody:            `{"disabled":true}`,
+			inputUserRole:        2,
+			actorId:              userID.String(),
+			mockBehavior:         func(s *mock_usecase.MockAuthorization, userId uuid.UUID) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"Нельзя заблокировать собственную учетную запись"}`,
+		},
+		{
+			name:                 "Неверная роль",
+			userId:               userID.String(),
+			inputBody:            `{"role":"admin"}`,
+			inputUserRole:        2,
+			mockBehavior:         func(s *mock_usecase.MockAuthorization, userId uuid.UUID) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"Неверный запрос"}`,
+		},
+		{
+			name:          "Пользователь не найден",
+			userId:        userID.String(),
+			inputBody:     `{"role":"moderator"}`,
+			inputUserRole: 2,
+			mockBehavior: func(s *mock_usecase.MockAuthorization, userId uuid.UUID) {
+				s.EXPECT().UpdateUser(gomock.Any(), testScope, userId, domain.UpdateUserInput{Role: &role}).Return(domain.UserInfo{}, repository.ErrUserNotFound)
+			},
+			expectedStatusCode:   404,
+			expectedResponseBody: `{"message":"Ошибка выполнения запроса пользователь не найден"}`,
+		},
+		{
+			name:                 "Некорректный UUID",
+			userId:               "123",
+			inputBody:            `{"role":"moderator"}`,
+			inputUserRole:        2,
+			mockBehavior:         func(s *mock_usecase.MockAuthorization, userId uuid.UUID) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"Некорректный UUID пользователя"}`,
+		},
+		{
+			name:                 "Запрещен доступ",
+			userId:               userID.String(),
+			inputBody:            `{"role":"moderator"}`,
+			inputUserRole:        1,
+			mockBehavior:         func(s *mock_usecase.MockAuthorization, userId uuid.UUID) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"Доступ запрещен"}`,
+		},
+	}
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			repo := mock_usecase.NewMockAuthorization(c)
+			testCase.mockBehavior(repo, userID)
+			audit := mock_usecase.NewMockAudit(c)
+			audit.EXPECT().RecordAudit(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+			usecases := &usecase.Usecase{Authorization: repo, Audit: audit}
+			handler := Handler{Usecases: usecases}
+			r := gin.New()
+			r.PATCH("/users/:userId", func(c *gin.Context) {
+				c.Set(userCtx, testCase.inputUserRole)
+				c.Set(userId, testCase.actorId)
+				handler.UpdateUser(c)
+			})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("PATCH", "/users/"+testCase.userId, bytes.NewBufferString(testCase.inputBody))
+
+			r.ServeHTTP(w, req)
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			if json.Valid([]byte(testCase.expectedResponseBody)) {
+				assert.JSONEq(t, testCase.expectedResponseBody, w.Body.String())
+			} else {
+				assert.Equal(t, testCase.expectedResponseBody, strings.TrimSpace(w.Body.String()))
+			}
+		})
+	}
+}
+
+func TestHandler_deleteUser(t *testing.T) {
+	type mockBehavior func(s *mock_usecase.MockAuthorization, userId uuid.UUID)
+	userID, err := uuid.NewRandom()
+	if err != nil {
+		panic(err)
+	}
+	testTable := []struct {
+		name                 string
+		inputUserRole        int
+		actorId              string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:          "OK",
+			inputUserRole: 2,
+			mockBehavior: func(s *mock_usecase.MockAuthorization, userId uuid.UUID) {
+				s.EXPECT().DisableUser(gomock.Any(), testScope, userId).Return(domain.UserInfo{Id: userId, Email: "test", Role: "employee"}, nil)
+			},
+			expectedStatusCode: 200,
+			expectedResponseBody: fmt.Sprintf(`{"message":"Пользователь заблокирован",
+				"content":{"id":"%s","email":"test","role":"employee"}}`, userID),
+		},
+		{
+			name:                 "Блокировка себя",
+			inputUserRole:        2,
+			actorId:              userID.String(),
+			mockBehavior:         func(s *mock_usecase.MockAuthorization, userId uuid.UUID) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"Нельзя заблокировать собственную учетную запись"}`,
+		},
+		{
+			name:                 "Запрещен доступ",
+			inputUserRole:        1,
+			mockBehavior:         func(s *mock_usecase.MockAuthorization, userId uuid.UUID) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"Доступ запрещен"}`,
+		},
+	}
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			repo := mock_usecase.NewMockAuthorization(c)
+			testCase.mockBehavior(repo, userID)
+			audit := mock_usecase.NewMockAudit(c)
+			audit.EXPECT().RecordAudit(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+			usecases := &usecase.Usecase{Authorization: repo, Audit: audit}
+			handler := Handler{Usecases: usecases}
+			r := gin.New()
+			r.DELETE("/users/:userId", func(c *gin.Context) {
+				c.Set(userCtx, testCase.inputUserRole)
+				c.Set(userId, testCase.actorId)
+				handler.DeleteUser(c)
+			})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("DELETE", "/users/"+userID.String(), nil)
+
+			r.ServeHTTP(w, req)
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.JSONEq(t, testCase.expectedResponseBody, w.Body.String())
+		})
+	}
+}
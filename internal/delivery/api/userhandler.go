@@ -0,0 +1,190 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/bllooop/pvzservice/internal/domain"
+	"github.com/bllooop/pvzservice/internal/repository"
+	"github.com/bllooop/pvzservice/internal/usecase"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func (h *Handler) GetUsers(c *gin.Context) {
+	reqLog(c).Info().Msg("Получен запрос на получение списка пользователей")
+	userRole, err := getUserRole(c)
+	if err != nil {
+		reqLog(c).Error().Err(err).Msg("")
+		newErrorResponse(c, http.StatusInternalServerError, "Ошибка получения роли "+err.Error())
+		return
+	}
+	reqLog(c).Debug().Msgf("Успешно получена роль %v", getRoleName(userRole))
+	if !isReader(userRole) {
+		reqLog(c).Error().Msg("Данный запрос доступен только модератору или администратору")
+		newErrorResponse(c, http.StatusBadRequest, "Доступ запрещен")
+		return
+	}
+	pageInt, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || pageInt < 1 {
+		pageInt = 1
+	}
+	limitInt, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limitInt < 1 {
+		limitInt = 10
+	} else if limitInt > h.maxPageLimit() {
+		limitInt = h.maxPageLimit()
+	}
+	result, err := h.Usecases.Authorization.GetUsers(c.Request.Context(), tenantScope(c), domain.GettingUsersParams{Page: pageInt, Limit: limitInt})
+	if err != nil {
+		reqLog(c).Error().Err(err).Msg("")
+		newErrorResponse(c, http.StatusInternalServerError, "Ошибка выполнения запроса "+err.Error())
+		return
+	}
+	reqLog(c).Info().Msg("Получен ответ на запрос списка пользователей")
+	c.JSON(http.StatusOK, map[string]any{
+		"message": "Список пользователей",
+		"content": result,
+	})
+}
+
+func (h *Handler) UpdateUser(c *gin.Context) {
+	reqLog(c).Info().Msg("Получен запрос на изменение пользователя")
+	userRole, err := getUserRole(c)
+	if err != nil {
+		reqLog(c).Error().Err(err).Msg("")
+		newErrorResponse(c, http.StatusInternalServerError, "Ошибка получения роли "+err.Error())
+		return
+	}
+	if userRole != 2 {
+		reqLog(c).Error().Msg("Данный запрос доступен только модератору")
+		newErrorResponse(c, http.StatusBadRequest, "Доступ запрещен")
+		return
+	}
+	targetId, err := uuid.Parse(c.Param("userId"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "Некорректный UUID пользователя")
+		return
+	}
+	var input domain.UpdateUserInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		reqLog(c).Error().Err(err).Msg(err.Error())
+		newErrorResponse(c, http.StatusBadRequest, "Неверный запрос")
+		return
+	}
+	reqLog(c).Debug().Msgf("Успешно прочитаны данные из запроса %s", targetId)
+	if input.Disabled != nil && *input.Disabled && isSelf(c, targetId) {
+		reqLog(c).Error().Msg("Модератор пытается заблокировать сам себя")
+		newErrorResponse(c, http.StatusBadRequest, "Нельзя заблокировать собственную учетную запись")
+		return
+	}
+	action := "user.update"
+	if input.Password != nil {
+		action = "user.password.change"
+	}
+	result, err := h.Usecases.Authorization.UpdateUser(h.auditContext(c, action, "user"), tenantScope(c), targetId, input)
+	if err != nil {
+		reqLog(c).Error().Err(err).Msg("")
+		newErrorResponse(c, userErrorStatus(err), "Ошибка выполнения запроса "+err.Error())
+		return
+	}
+	reqLog(c).Info().Msg("Получен ответ на изменение пользователя")
+	c.JSON(http.StatusOK, map[string]any{
+		"message": "Пользователь изменен",
+		"content": result,
+	})
+}
+
+func (h *Handler) DeleteUser(c *gin.Context) {
+	reqLog(c).Info().Msg("Получен запрос на блокировку пользователя")
+	userRole, err := getUserRole(c)
+	if err != nil {
+		reqLog(c).Error().Err(err).Msg("")
+		newErrorResponse(c, http.StatusInternalServerError, "Ошибка получения роли "+err.Error())
+		return
+	}
+	if userRole != 2 {
+		reqLog(c).Error().Msg("Данный запрос доступен только модератору")
+		newErrorResponse(c, http.StatusBadRequest, "Доступ запрещен")
+		return
+	}
+	targetId, err := uuid.Parse(c.Param("userId"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "Некорректный UUID пользователя")
+		return
+	}
+	if isSelf(c, targetId) {
+		reqLog(c).Error().Msg("Модератор пытается заблокировать сам себя")
+		newErrorResponse(c, http.StatusBadRequest, "Нельзя заблокировать собственную учетную запись")
+		return
+	}
+	result, err := h.Usecases.Authorization.DisableUser(h.auditContext(c, "user.disable", "user"), tenantScope(c), targetId)
+	if err != nil {
+		reqLog(c).Error().Err(err).Msg("")
+		newErrorResponse(c, userErrorStatus(err), "Ошибка выполнения запроса "+err.Error())
+		return
+	}
+	reqLog(c).Info().Msg("Получен ответ на блокировку пользователя")
+	c.JSON(http.StatusOK, map[string]any{
+		"message": "Пользователь заблокирован",
+		"content": result,
+	})
+}
+
+func (h *Handler) UnlockLogin(c *gin.Context) {
+	reqLog(c).Info().Msg("Получен запрос на снятие блокировки входа")
+	userRole, err := getUserRole(c)
+	if err != nil {
+		reqLog(c).Error().Err(err).Msg("")
+		newErrorResponse(c, http.StatusInternalServerError, "Ошибка получения роли "+err.Error())
+		return
+	}
+	if userRole != 2 {
+		reqLog(c).Error().Msg("Данный запрос доступен только модератору")
+		newErrorResponse(c, http.StatusBadRequest, "Доступ запрещен")
+		return
+	}
+	var input domain.UnlockLoginInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		reqLog(c).Error().Err(err).Msg(err.Error())
+		newErrorResponse(c, http.StatusBadRequest, "Неверный запрос")
+		return
+	}
+	if input.Email == "" && input.IP == "" {
+		newErrorResponse(c, http.StatusBadRequest, "Необходимо указать почту или IP-адрес")
+		return
+	}
+	reqLog(c).Debug().Msgf("Успешно прочитаны данные из запроса %s, %s", input.Email, input.IP)
+	if err := h.Usecases.LoginProtection.UnlockLogin(c.Request.Context(), tenantScope(c).TenantId, input.Email, input.IP); err != nil {
+		reqLog(c).Error().Err(err).Msg("")
+		newErrorResponse(c, http.StatusInternalServerError, "Ошибка выполнения запроса "+err.Error())
+		return
+	}
+	h.recordAudit(c, "login.unlock", "login", input.Email+" "+input.IP, nil, input)
+	reqLog(c).Info().Msg("Получен ответ на снятие блокировки входа")
+	c.JSON(http.StatusOK, map[string]any{
+		"message": "Блокировка входа снята",
+	})
+}
+
+// isSelf сообщает, что запрос относится к учетной записи самого автора
+// запроса.
+func isSelf(c *gin.Context, targetId uuid.UUID) bool {
+	actorId, err := getUserId(c)
+	return err == nil && actorId == targetId.String()
+}
+
+func userErrorStatus(err error) int {
+	var policyErr *usecase.PasswordPolicyError
+	switch {
+	case errors.As(err, &policyErr):
+		return http.StatusBadRequest
+	case errors.Is(err, repository.ErrUserNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, repository.ErrNothingToUpdate):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,64 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	AmendmentPending  = "pending"
+	AmendmentApproved = "approved"
+	AmendmentRejected = "rejected"
+
+	CorrectionAdd = "add"
+
+	// ReasonAmendment проставляется у исправлений, внесенных одобренной заявкой.
+	ReasonAmendment = "amendment"
+)
+
+type AmendmentItem struct {
+	Action    string     `json:"action" binding:"required,oneof=add remove"`
+	ProductId *uuid.UUID `json:"productId,omitempty"`
+	Type      string     `json:"type,omitempty" binding:"omitempty,oneof=электроника одежда обувь"`
+}
+
+type AmendmentRequest struct {
+	Reason string          `json:"reason" binding:"required,max=500"`
+	Items  []AmendmentItem `json:"items" binding:"required,min=1,max=100,dive"`
+}
+
+type AmendmentReview struct {
+	Comment string `json:"comment" binding:"max=500"`
+}
+
+// ReceptionAmendment — заявка на изменение состава закрытой приемки.
+type ReceptionAmendment struct {
+	Id            uuid.UUID       `json:"id"`
+	ReceptionId   uuid.UUID       `json:"receptionId"`
+	PVZId         uuid.UUID       `json:"pvzId"`
+	Status        string          `json:"status"`
+	Reason        string          `json:"reason"`
+	Items         []AmendmentItem `json:"items"`
+	RequestedBy   string          `json:"requestedBy"`
+	ReviewedBy    *string         `json:"reviewedBy,omitempty"`
+	ReviewComment string          `json:"reviewComment,omitempty"`
+	CreatedAt     time.Time       `json:"createdAt"`
+	ReviewedAt    *time.Time      `json:"reviewedAt,omitempty"`
+}
+
+// ReceptionSnapshot — состояние приемки, сохраняемое в истории версий.
+type ReceptionSnapshot struct {
+	Reception ProductReception `json:"reception"`
+	Products  []Product        `json:"products"`
+}
+
+type ReceptionVersion struct {
+	ReceptionId uuid.UUID       `json:"receptionId" db:"reception_id"`
+	Version     int             `json:"version" db:"version"`
+	AmendmentId *uuid.UUID      `json:"amendmentId,omitempty" db:"amendment_id"`
+	CreatedBy   string          `json:"createdBy" db:"created_by"`
+	CreatedAt   time.Time       `json:"createdAt" db:"created_at"`
+	Snapshot    json.RawMessage `json:"snapshot" db:"snapshot"`
+}
@@ -0,0 +1,35 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type AuditEntry struct {
+	Id         int64           `json:"id" db:"id"`
+	CreatedAt  time.Time       `json:"createdAt" db:"created_at"`
+	ActorId    string          `json:"actorId" db:"actor_id"`
+	ActorRole  string          `json:"actorRole" db:"actor_role"`
+	Action     string          `json:"action" db:"action"`
+	EntityType string          `json:"entityType" db:"entity_type"`
+	EntityId   string          `json:"entityId" db:"entity_id"`
+	Before     json.RawMessage `json:"before,omitempty" db:"before_state"`
+	After      json.RawMessage `json:"after,omitempty" db:"after_state"`
+	RequestId  string          `json:"requestId,omitempty" db:"request_id"`
+	ClientIP   string          `json:"clientIp,omitempty" db:"client_ip"`
+	TenantId   string          `json:"tenant,omitempty" db:"tenant_id"`
+	PrevHash   string          `json:"prevHash" db:"prev_hash"`
+	Hash       string          `json:"hash" db:"hash"`
+}
+
+type AuditFilter struct {
+	Scope      TenantScope
+	ActorId    string
+	Action     string
+	EntityType string
+	EntityId   string
+	From       time.Time
+	To         time.Time
+	Page       int
+	Limit      int
+}
@@ -0,0 +1,16 @@
+package domain
+
+import "time"
+
+// IdempotencyRecord хранит первый ответ на запрос с ключом идемпотентности.
+// Scope отделяет ключи разных пользователей; StatusCode равен нулю, пока
+// исходный запрос еще выполняется.
+type IdempotencyRecord struct {
+	Scope       string    `db:"scope"`
+	Key         string    `db:"idem_key"`
+	RequestHash string    `db:"request_hash"`
+	StatusCode  int       `db:"status_code"`
+	Response    []byte    `db:"response"`
+	CreatedAt   time.Time `db:"created_at"`
+	ExpiresAt   time.Time `db:"expires_at"`
+}
@@ -10,6 +10,73 @@ type PVZ struct {
 	Id           *uuid.UUID `json:"id" db:"id"`
 	DateRegister *time.Time `json:"registrationDate,omitempty" db:"registrationdate"`
 	City         string     `json:"city" binding:"required,oneof=Москва Санкт-Петербург Казань"`
+	Address      string     `json:"address,omitempty" db:"address" binding:"max=500"`
+	PostalCode   string     `json:"postalCode,omitempty" db:"postal_code" binding:"omitempty,numeric,len=6"`
+	WorkingHours string     `json:"workingHours,omitempty" db:"working_hours" binding:"max=200"`
+	Latitude     *float64   `json:"latitude,omitempty" db:"latitude" binding:"required_with=Longitude,omitempty,min=-90,max=90"`
+	Longitude    *float64   `json:"longitude,omitempty" db:"longitude" binding:"required_with=Latitude,omitempty,min=-180,max=180"`
+	Capacity     *int       `json:"capacity,omitempty" db:"capacity" binding:"omitempty,min=1"`
+	Timezone     string     `json:"timezone,omitempty" db:"timezone" binding:"omitempty,timezone"`
+	TenantId     string     `json:"tenant,omitempty" db:"tenant_id"`
+	Status       string     `json:"status,omitempty" db:"status"`
+	ArchivedAt   *time.Time `json:"archivedAt,omitempty" db:"archived_at"`
+}
+
+const (
+	PvzActive            = "active"
+	PvzTemporarilyClosed = "temporarily_closed"
+	PvzDecommissioned    = "decommissioned"
+)
+
+// PvzTransitionAllowed сообщает, можно ли перевести ПВЗ из статуса from в to.
+// Выведенный из работы ПВЗ обратно не возвращается.
+func PvzTransitionAllowed(from, to string) bool {
+	switch from {
+	case PvzActive:
+		return to == PvzTemporarilyClosed || to == PvzDecommissioned
+	case PvzTemporarilyClosed:
+		return to == PvzActive || to == PvzDecommissioned
+	}
+	return false
+}
+
+type PvzUpdate struct {
+	City          *string    `json:"city" binding:"omitempty,oneof=Москва Санкт-Петербург Казань"`
+	Address       *string    `json:"address" binding:"omitempty,max=500"`
+	PostalCode    *string    `json:"postalCode" binding:"omitempty,numeric,len=6"`
+	WorkingHours  *string    `json:"workingHours" binding:"omitempty,max=200"`
+	Latitude      *float64   `json:"latitude" binding:"required_with=Longitude,omitempty,min=-90,max=90"`
+	Longitude     *float64   `json:"longitude" binding:"required_with=Latitude,omitempty,min=-180,max=180"`
+	Capacity      *int       `json:"capacity" binding:"omitempty,min=1"`
+	Timezone      *string    `json:"timezone" binding:"omitempty,timezone"`
+	Status        *string    `json:"status" binding:"omitempty,oneof=active temporarily_closed decommissioned"`
+	EffectiveFrom *time.Time `json:"effectiveFrom"`
+	EffectiveTo   *time.Time `json:"effectiveTo"`
+	Reason        string     `json:"reason" binding:"max=500"`
+	ActorId       string     `json:"-"`
+}
+
+type NearestPvzParams struct {
+	Latitude  float64
+	Longitude float64
+	RadiusKm  float64
+	Limit     int
+}
+
+type PvzDistance struct {
+	PVZ
+	DistanceKm float64 `json:"distanceKm" db:"distance_km"`
+}
+
+type PvzStatusChange struct {
+	Id            int64      `json:"id" db:"id"`
+	PVZId         uuid.UUID  `json:"pvzId" db:"pvz_id"`
+	Status        string     `json:"status" db:"status"`
+	EffectiveFrom time.Time  `json:"effectiveFrom" db:"effective_from"`
+	EffectiveTo   *time.Time `json:"effectiveTo,omitempty" db:"effective_to"`
+	Reason        string     `json:"reason,omitempty" db:"reason"`
+	ActorId       string     `json:"actorId" db:"actor_id"`
+	CreatedAt     time.Time  `json:"createdAt" db:"created_at"`
 }
 
 type ProductReception struct {
@@ -17,6 +84,7 @@ type ProductReception struct {
 	DateReceived *time.Time `json:"dateTime,omitempty" db:"date_received"`
 	PVZId        *uuid.UUID `json:"pvzId" db:"pvz_id"`
 	Status       *string    `json:"status,omitempty" db:"status_reception"`
+	FlaggedAt    *time.Time `json:"flaggedAt,omitempty" db:"flagged_at"`
 }
 
 type Product struct {
@@ -25,6 +93,7 @@ type Product struct {
 	Type         string     `json:"type" db:"type_product" binding:"required,oneof=электроника одежда обувь"`
 	ReceptionId  *uuid.UUID `json:"receptionId" db:"reception_id"`
 	PVZId        *uuid.UUID `json:"pvzId,omitempty" db:"pvz_id"`
+	IssuedAt     *time.Time `json:"issuedAt,omitempty" db:"issued_at"`
 }
 
 type PvzSummary struct {
@@ -33,12 +102,79 @@ type PvzSummary struct {
 }
 
 type Receptions struct {
-	ReceptionInfo ProductReception `json:"reception"`
-	ProductInfo   []Product        `json:"products"`
+	ReceptionInfo ProductReception    `json:"reception"`
+	ProductInfo   []Product           `json:"products"`
+	Corrections   []ProductCorrection `json:"corrections,omitempty"`
+}
+
+// ProductDeletion описывает удаление товара из открытой приемки. Если ProductId
+// не задан, удаляется последний добавленный товар.
+type ProductDeletion struct {
+	PVZId     uuid.UUID  `json:"-"`
+	ProductId *uuid.UUID `json:"-"`
+	Reason    string     `json:"reason" binding:"required,oneof=mis_scan duplicate damaged wrong_type other"`
+	Comment   string     `json:"comment" binding:"max=500"`
+	ActorId   string     `json:"-"`
+	// AmendmentId задается, если товар удаляется одобренной заявкой на изменение.
+	AmendmentId *uuid.UUID `json:"-"`
+}
+
+// ProductCorrection — запись истории исправлений состава приемки.
+type ProductCorrection struct {
+	Id          int64      `json:"id" db:"id"`
+	ReceptionId uuid.UUID  `json:"receptionId" db:"reception_id"`
+	ProductId   uuid.UUID  `json:"productId" db:"product_id"`
+	Action      string     `json:"action" db:"action"`
+	Reason      string     `json:"reason" db:"reason"`
+	Comment     string     `json:"comment,omitempty" db:"comment"`
+	ActorId     string     `json:"actorId" db:"actor_id"`
+	AmendmentId *uuid.UUID `json:"amendmentId,omitempty" db:"amendment_id"`
+	TransferId  *uuid.UUID `json:"transferId,omitempty" db:"transfer_id"`
+	CreatedAt   time.Time  `json:"createdAt" db:"created_at"`
+}
+
+const (
+	ReceptionInProgress = "in_progress"
+	ReceptionClosed     = "close"
+	ReceptionCancelled  = "cancelled"
+)
+
+// ReceptionFinished сообщает, что в приемку больше нельзя добавлять товары.
+func ReceptionFinished(status string) bool {
+	return status == ReceptionClosed || status == ReceptionCancelled
 }
+
+const (
+	CorrectionRemove = "remove"
+
+	// ReasonUndoLast проставляется при удалении последнего товара по LIFO.
+	ReasonUndoLast = "undo_last"
+)
+
+const (
+	EmptyReceptionFlag   = "flag"
+	EmptyReceptionCancel = "cancel"
+)
+
+// AutoClosePolicy задает, какие приемки считаются забытыми и что с ними делать.
+type AutoClosePolicy struct {
+	IdleAfter   time.Duration
+	EmptyAction string
+}
+
+type AutoCloseResult struct {
+	Closed    []ProductReception
+	Cancelled []ProductReception
+	Flagged   []ProductReception
+	// Skipped выставляется, если проход уже выполняет другой экземпляр сервиса.
+	Skipped bool
+}
+
 type GettingPvzParams struct {
 	Start time.Time
 	End   time.Time
 	Page  int
 	Limit int
+	// LocalDay сравнивает только даты, взятые в часовом поясе каждого ПВЗ.
+	LocalDay bool
 }
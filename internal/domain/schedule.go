@@ -0,0 +1,84 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// LimitReject и LimitWarn задают реакцию на операции с приемками вне часов
+	// работы ПВЗ или при его заполненности: отказ или предупреждение в ответе.
+	LimitReject = "reject"
+	LimitWarn   = "warn"
+)
+
+// WorkingDay — часы работы в день недели, Weekday от 1 (понедельник) до 7 (воскресенье).
+type WorkingDay struct {
+	Weekday int    `json:"weekday" db:"weekday" binding:"min=1,max=7"`
+	Opens   string `json:"opens" db:"opens_at" binding:"required,datetime=15:04"`
+	Closes  string `json:"closes" db:"closes_at" binding:"required,datetime=15:04"`
+}
+
+// Holiday — исключение из недельного расписания. Без часов ПВЗ закрыт весь день.
+type Holiday struct {
+	Date   string `json:"date" db:"day" binding:"required,datetime=2006-01-02"`
+	Opens  string `json:"opens,omitempty" db:"opens_at" binding:"required_with=Closes,omitempty,datetime=15:04"`
+	Closes string `json:"closes,omitempty" db:"closes_at" binding:"required_with=Opens,omitempty,datetime=15:04"`
+	Reason string `json:"reason,omitempty" db:"reason" binding:"max=200"`
+}
+
+// PvzSchedule задается в местном времени ПВЗ. Timezone только для чтения,
+// часовой пояс меняется вместе с остальными данными ПВЗ.
+type PvzSchedule struct {
+	Timezone string       `json:"timezone,omitempty"`
+	Week     []WorkingDay `json:"week" binding:"max=7,dive"`
+	Holidays []Holiday    `json:"holidays" binding:"max=366,dive"`
+}
+
+// OpenAt сообщает, работает ли ПВЗ в момент t по его местному времени.
+// Если недельное расписание не задано, ПВЗ считается работающим всегда.
+func (s PvzSchedule) OpenAt(t time.Time) bool {
+	t = t.In(PvzLocation(s.Timezone))
+	date, clock := t.Format("2006-01-02"), t.Format("15:04")
+	for _, holiday := range s.Holidays {
+		if holiday.Date == date {
+			return holiday.Opens != "" && clock >= holiday.Opens && clock < holiday.Closes
+		}
+	}
+	if len(s.Week) == 0 {
+		return true
+	}
+	weekday := int(t.Weekday())
+	if weekday == 0 {
+		weekday = 7
+	}
+	for _, day := range s.Week {
+		if day.Weekday == weekday {
+			return clock >= day.Opens && clock < day.Closes
+		}
+	}
+	return false
+}
+
+// PvzOccupancy — заполненность ПВЗ товарами, которые приняты и еще не выданы.
+type PvzOccupancy struct {
+	PVZId       uuid.UUID `json:"pvzId" db:"pvz_id"`
+	City        string    `json:"city" db:"city"`
+	Capacity    *int      `json:"capacity,omitempty" db:"capacity"`
+	Stored      int       `json:"stored" db:"stored"`
+	FillPercent *float64  `json:"fillPercent,omitempty" db:"fill_percent"`
+}
+
+func (o PvzOccupancy) Full() bool {
+	return o.Capacity != nil && o.Stored >= *o.Capacity
+}
+
+// PvzStock — текущие остатки ПВЗ для метрик: открытые приемки и товары,
+// которые находятся в ПВЗ.
+type PvzStock struct {
+	PVZId          uuid.UUID `db:"pvz_id"`
+	City           string    `db:"city"`
+	OpenReceptions int       `db:"open_receptions"`
+	ProductsHeld   int       `db:"products_held"`
+}
@@ -0,0 +1,35 @@
+package domain
+
+// DefaultTenant — компания-перевозчик, к которой относятся данные, заведенные
+// до появления нескольких компаний, и токены без tenant_id.
+const DefaultTenant = "default"
+
+// TenantScope ограничивает запросы к хранилищу данными одной компании.
+// All снимает ограничение и выставляется только для суперадминистратора.
+type TenantScope struct {
+	TenantId string
+	All      bool
+}
+
+// TenantOf возвращает область видимости одной компании.
+func TenantOf(tenantId string) TenantScope {
+	if tenantId == "" {
+		tenantId = DefaultTenant
+	}
+	return TenantScope{TenantId: tenantId}
+}
+
+// AllTenants возвращает область видимости суперадминистратора.
+func AllTenants() TenantScope {
+	return TenantScope{All: true}
+}
+
+// Filter возвращает параметр для условия ($n::text IS NULL OR tenant_id = $n):
+// nil снимает ограничение по компании.
+func (s TenantScope) Filter() *string {
+	if s.All {
+		return nil
+	}
+	tenantId := s.TenantId
+	return &tenantId
+}
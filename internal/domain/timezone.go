@@ -0,0 +1,79 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultPvzTimezone — часовой пояс ПВЗ, для которого он не указан явно.
+// Москва, Санкт-Петербург и Казань живут по нему.
+const DefaultPvzTimezone = "Europe/Moscow"
+
+// PvzLocation возвращает часовой пояс ПВЗ по имени из базы часовых поясов.
+// Пустое имя означает пояс по умолчанию, неизвестное — UTC.
+func PvzLocation(timezone string) *time.Location {
+	if timezone == "" {
+		timezone = DefaultPvzTimezone
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+func timeIn(t *time.Time, loc *time.Location) *time.Time {
+	if t == nil {
+		return nil
+	}
+	local := t.In(loc)
+	return &local
+}
+
+// In переводит отметки времени ПВЗ в его часовой пояс.
+func (p PVZ) In(loc *time.Location) PVZ {
+	p.DateRegister = timeIn(p.DateRegister, loc)
+	p.ArchivedAt = timeIn(p.ArchivedAt, loc)
+	return p
+}
+
+func (r ProductReception) In(loc *time.Location) ProductReception {
+	r.DateReceived = timeIn(r.DateReceived, loc)
+	r.FlaggedAt = timeIn(r.FlaggedAt, loc)
+	return r
+}
+
+func (p Product) In(loc *time.Location) Product {
+	p.DateReceived = timeIn(p.DateReceived, loc)
+	p.IssuedAt = timeIn(p.IssuedAt, loc)
+	return p
+}
+
+func (c ProductCorrection) In(loc *time.Location) ProductCorrection {
+	c.CreatedAt = c.CreatedAt.In(loc)
+	return c
+}
+
+// PvzReportParams задает период отчета по дням в формате YYYY-MM-DD, границы
+// включаются. При LocalDay сутки считаются по часовому поясу ПВЗ, иначе по UTC.
+type PvzReportParams struct {
+	PvzId    uuid.UUID
+	From     string
+	To       string
+	LocalDay bool
+}
+
+// PvzReportDay — число приемок, принятых и выданных товаров за сутки.
+type PvzReportDay struct {
+	Day        string `json:"day" db:"day"`
+	Receptions int    `json:"receptions" db:"receptions"`
+	Products   int    `json:"products" db:"products"`
+	Issued     int    `json:"issued" db:"issued"`
+}
+
+type PvzReport struct {
+	PvzId    uuid.UUID      `json:"pvzId"`
+	Timezone string         `json:"timezone"`
+	Days     []PvzReportDay `json:"days"`
+}
@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	TransferInTransit = "in_transit"
+	TransferReceived  = "received"
+
+	// CorrectionTransferOut проставляется у товара, отправленного в другой ПВЗ.
+	CorrectionTransferOut = "transfer_out"
+
+	// ReasonTransfer проставляется у исправлений, внесенных перемещением товара.
+	ReasonTransfer = "transfer"
+)
+
+type TransferRequest struct {
+	ToPVZId uuid.UUID `json:"toPvzId" binding:"required"`
+	Reason  string    `json:"reason" binding:"max=500"`
+}
+
+// ProductTransfer — перемещение товара из ПВЗ отправления в ПВЗ получения.
+// После приемки в ПВЗ получения в его открытой приемке создается новый товар,
+// связанный с исходным через ReceivedProductId.
+type ProductTransfer struct {
+	Id                uuid.UUID  `json:"id" db:"id"`
+	ProductId         uuid.UUID  `json:"productId" db:"product_id"`
+	Type              string     `json:"type" db:"type_product"`
+	FromPVZId         uuid.UUID  `json:"fromPvzId" db:"from_pvz_id"`
+	ToPVZId           uuid.UUID  `json:"toPvzId" db:"to_pvz_id"`
+	Status            string     `json:"status" db:"status"`
+	Reason            string     `json:"reason,omitempty" db:"reason"`
+	ShippedBy         string     `json:"shippedBy" db:"shipped_by"`
+	ShippedAt         time.Time  `json:"shippedAt" db:"shipped_at"`
+	ReceivedProductId *uuid.UUID `json:"receivedProductId,omitempty" db:"received_product_id"`
+	ReceivedBy        *string    `json:"receivedBy,omitempty" db:"received_by"`
+	ReceivedAt        *time.Time `json:"receivedAt,omitempty" db:"received_at"`
+}
+
+// InTransitParams ограничивает отчет о товарах в пути одним ПВЗ, если PVZId
+// задан: в отчет попадают перемещения из него и в него.
+type InTransitParams struct {
+	PVZId *uuid.UUID
+	Page  int
+	Limit int
+}
@@ -1,19 +1,93 @@
 package domain
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ServiceUserId — служебная учетная запись, от имени которой сервис вызывает
+// собственные методы gRPC. Запись создается миграцией в компании ServiceTenant
+// и не может войти по паролю.
+var ServiceUserId = uuid.MustParse("00000000-0000-0000-0000-000000000001")
+
+// ServiceTenant — компания служебных учетных записей. Модераторы компаний-
+// перевозчиков ее не видят и не могут заблокировать служебную запись.
+const ServiceTenant = "system"
 
 type User struct {
-	Id       uuid.UUID `json:"-" db:"id"`
-	Email    string    `json:"email"`
-	Password string    `json:"password,omitempty"`
-	Role     string    `json:"role" binding:"required,oneof=employee moderator"`
+	Id         uuid.UUID  `json:"-" db:"id"`
+	Email      string     `json:"email"`
+	Password   string     `json:"password,omitempty"`
+	Role       string     `json:"role" binding:"required,oneof=employee moderator"`
+	TenantId   string     `json:"tenant,omitempty" db:"tenant_id" binding:"omitempty,max=64"`
+	DisabledAt *time.Time `json:"-" db:"disabled_at"`
+}
+
+type UserInfo struct {
+	Id          uuid.UUID  `json:"id" db:"id"`
+	Email       string     `json:"email" db:"email"`
+	Role        string     `json:"role" db:"role"`
+	TenantId    string     `json:"tenant,omitempty" db:"tenant_id"`
+	CreatedAt   *time.Time `json:"createdAt,omitempty" db:"created_at"`
+	DisabledAt  *time.Time `json:"disabledAt,omitempty" db:"disabled_at"`
+	LastLoginAt *time.Time `json:"lastLoginAt,omitempty" db:"last_login_at"`
+}
+
+type UpdateUserInput struct {
+	Role     *string `json:"role" binding:"omitempty,oneof=employee moderator"`
+	Password *string `json:"password"`
+	Disabled *bool   `json:"disabled"`
+}
+
+type GettingUsersParams struct {
+	Page  int
+	Limit int
+}
+
+type UserStatus struct {
+	Disabled          bool
+	PasswordChangedAt *time.Time
+	RoleChangedAt     *time.Time
+}
+
+type TokenClaims struct {
+	UserId   string
+	UserRole int
+	TenantId string
+	IssuedAt time.Time
+	Dummy    bool
+}
+
+type PasswordResetRequest struct {
+	Email    string `json:"email" binding:"required"`
+	TenantId string `json:"tenant"`
+}
+
+type PasswordResetConfirm struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
 }
 
 type SignInInput struct {
 	Email    string `json:"email" binding:"required"`
 	Password string `json:"password" binding:"required"`
+	TenantId string `json:"tenant"`
 }
 
 type DummyLogin struct {
-	Role string `json:"role" binding:"required,oneof=employee moderator"`
+	Role     string `json:"role" binding:"required,oneof=employee moderator admin"`
+	TenantId string `json:"tenant"`
+}
+
+type LoginAttempt struct {
+	Key           string     `json:"key" db:"attempt_key"`
+	Failures      int        `json:"failures" db:"failures"`
+	LastFailureAt *time.Time `json:"lastFailureAt,omitempty" db:"last_failure_at"`
+	LockedUntil   *time.Time `json:"lockedUntil,omitempty" db:"locked_until"`
+}
+
+type UnlockLoginInput struct {
+	Email string `json:"email"`
+	IP    string `json:"ip"`
 }
@@ -0,0 +1,123 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	logger "github.com/bllooop/pvzservice/pkg/logging"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// Component — состояние одной зависимости сервиса.
+type Component struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Report — результат проверки готовности: общий статус up, только если все
+// компоненты в состоянии up.
+type Report struct {
+	Status     string               `json:"status"`
+	Components map[string]Component `json:"components"`
+}
+
+type check struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// Checker проверяет зависимости, без которых сервис не может обслуживать
+// запросы.
+type Checker struct {
+	timeout time.Duration
+	checks  []check
+}
+
+func NewChecker(timeout time.Duration) *Checker {
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	return &Checker{timeout: timeout}
+}
+
+// Register добавляет проверку компонента name. Проверка возвращает ошибку,
+// если компонент недоступен.
+func (c *Checker) Register(name string, fn func(ctx context.Context) error) {
+	c.checks = append(c.checks, check{name: name, fn: fn})
+}
+
+// Check выполняет все проверки одновременно, каждая ограничена таймаутом.
+func (c *Checker) Check(ctx context.Context) Report {
+	report := Report{Status: StatusUp, Components: make(map[string]Component, len(c.checks))}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, ch := range c.checks {
+		wg.Add(1)
+		go func(ch check) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, c.timeout)
+			defer cancel()
+			component := Component{Status: StatusUp}
+			if err := ch.fn(ctx); err != nil {
+				component = Component{Status: StatusDown, Error: err.Error()}
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			report.Components[ch.name] = component
+			if component.Status == StatusDown {
+				report.Status = StatusDown
+			}
+		}(ch)
+	}
+	wg.Wait()
+	return report
+}
+
+// Publish переносит отчет в сервис grpc.health.v1: каждый компонент под своим
+// именем, общий статус — под пустым именем и именами services.
+func Publish(srv *health.Server, report Report, services ...string) {
+	for name, component := range report.Components {
+		srv.SetServingStatus(name, servingStatus(component.Status))
+	}
+	overall := servingStatus(report.Status)
+	srv.SetServingStatus("", overall)
+	for _, service := range services {
+		srv.SetServingStatus(service, overall)
+	}
+}
+
+// Watch каждые interval проверяет компоненты и публикует результат в srv до
+// отмены ctx.
+func (c *Checker) Watch(ctx context.Context, srv *health.Server, interval time.Duration, services ...string) {
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		report := c.Check(ctx)
+		if report.Status == StatusDown {
+			logger.Log.Warn().Any("components", report.Components).Msg("Сервис не готов обслуживать запросы")
+		}
+		Publish(srv, report, services...)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func servingStatus(status string) healthpb.HealthCheckResponse_ServingStatus {
+	if status == StatusUp {
+		return healthpb.HealthCheckResponse_SERVING
+	}
+	return healthpb.HealthCheckResponse_NOT_SERVING
+}
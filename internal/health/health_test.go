@@ -0,0 +1,88 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestChecker_Check(t *testing.T) {
+	testTable := []struct {
+		name           string
+		checks         map[string]func(ctx context.Context) error
+		expectedReport Report
+	}{
+		{
+			name: "Ok",
+			checks: map[string]func(ctx context.Context) error{
+				"database": func(ctx context.Context) error { return nil },
+				"grpc":     func(ctx context.Context) error { return nil },
+			},
+			expectedReport: Report{Status: StatusUp, Components: map[string]Component{
+				"database": {Status: StatusUp},
+				"grpc":     {Status: StatusUp},
+			}},
+		},
+		{
+			name: "Компонент недоступен",
+			checks: map[string]func(ctx context.Context) error{
+				"database": func(ctx context.Context) error { return errors.New("connection refused") },
+				"grpc":     func(ctx context.Context) error { return nil },
+			},
+			expectedReport: Report{Status: StatusDown, Components: map[string]Component{
+				"database": {Status: StatusDown, Error: "connection refused"},
+				"grpc":     {Status: StatusUp},
+			}},
+		},
+		{
+			name: "Превышен таймаут",
+			checks: map[string]func(ctx context.Context) error{
+				"database": func(ctx context.Context) error {
+					<-ctx.Done()
+					return ctx.Err()
+				},
+			},
+			expectedReport: Report{Status: StatusDown, Components: map[string]Component{
+				"database": {Status: StatusDown, Error: context.DeadlineExceeded.Error()},
+			}},
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			checker := NewChecker(10 * time.Millisecond)
+			for name, fn := range testCase.checks {
+				checker.Register(name, fn)
+			}
+
+			assert.Equal(t, testCase.expectedReport, checker.Check(context.Background()))
+		})
+	}
+}
+
+func TestPublish(t *testing.T) {
+	srv := health.NewServer()
+	report := Report{Status: StatusDown, Components: map[string]Component{
+		"database": {Status: StatusDown, Error: "connection refused"},
+		"grpc":     {Status: StatusUp},
+	}}
+
+	Publish(srv, report, "pvz.v1.PVZService")
+
+	expected := map[string]healthpb.HealthCheckResponse_ServingStatus{
+		"":                  healthpb.HealthCheckResponse_NOT_SERVING,
+		"pvz.v1.PVZService": healthpb.HealthCheckResponse_NOT_SERVING,
+		"database":          healthpb.HealthCheckResponse_NOT_SERVING,
+		"grpc":              healthpb.HealthCheckResponse_SERVING,
+	}
+	for service, status := range expected {
+		resp, err := srv.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+		assert.NoError(t, err)
+		assert.Equal(t, status, resp.GetStatus(), service)
+	}
+}
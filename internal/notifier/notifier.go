@@ -0,0 +1,50 @@
+package notifier
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	logger "github.com/bllooop/pvzservice/pkg/logging"
+)
+
+// Notifier доставляет пользователю токен сброса пароля.
+type Notifier interface {
+	SendPasswordReset(email, token string, expiresAt time.Time) error
+}
+
+// LogNotifier только отмечает в логе, что токен выпущен: секреты в лог не
+// пишутся. Для получения токена при локальном запуске используется FileNotifier.
+type LogNotifier struct{}
+
+func NewLogNotifier() *LogNotifier {
+	return &LogNotifier{}
+}
+
+func (n *LogNotifier) SendPasswordReset(email, token string, expiresAt time.Time) error {
+	logger.Log.Info().Str("email", email).Time("expiresAt", expiresAt).Msg("Выпущен токен сброса пароля")
+	return nil
+}
+
+// FileNotifier дописывает токены в файл, по строке на каждое письмо.
+type FileNotifier struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewFileNotifier(path string) *FileNotifier {
+	return &FileNotifier{path: path}
+}
+
+func (n *FileNotifier) SendPasswordReset(email, token string, expiresAt time.Time) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	f, err := os.OpenFile(n.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = fmt.Fprintf(f, "%s\t%s\t%s\t%s\n", time.Now().Format(time.RFC3339), email, token, expiresAt.Format(time.RFC3339))
+	return err
+}
@@ -0,0 +1,22 @@
+package notifier
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	logger "github.com/bllooop/pvzservice/pkg/logging"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogNotifier_doesNotLogToken(t *testing.T) {
+	var buf bytes.Buffer
+	defer func(l zerolog.Logger) { logger.Log = l }(logger.Log)
+	logger.Log = zerolog.New(&buf)
+
+	token := "0123456789abcdef0123456789abcdef"
+	assert.NoError(t, NewLogNotifier().SendPasswordReset("user@example.com", token, time.Now()))
+	assert.Contains(t, buf.String(), "Выпущен токен сброса пароля")
+	assert.NotContains(t, buf.String(), token)
+}
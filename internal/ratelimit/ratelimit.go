@@ -0,0 +1,122 @@
+package ratelimit
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// Группы маршрутов с отдельными лимитами.
+const (
+	// GroupAuth - вход, регистрация и сброс пароля, ограничиваются по IP.
+	GroupAuth = "auth"
+	// GroupWrite - изменяющие запросы авторизованных пользователей.
+	GroupWrite = "write"
+	// GroupRead - читающие запросы авторизованных пользователей.
+	GroupRead = "read"
+)
+
+// Limit - скорость пополнения корзины токенов в запросах в секунду и ее
+// емкость, то есть допустимый всплеск. Rate <= 0 снимает ограничение.
+type Limit struct {
+	Rate  float64 `mapstructure:"rps"`
+	Burst int
+}
+
+// Unlimited сообщает, что лимит не ограничивает запросы.
+func (l Limit) Unlimited() bool {
+	return l.Rate <= 0
+}
+
+// burst возвращает емкость корзины: не меньше одного запроса.
+func (l Limit) burst() float64 {
+	if l.Burst > 0 {
+		return float64(l.Burst)
+	}
+	return math.Max(1, math.Ceil(l.Rate))
+}
+
+// Group - лимит группы маршрутов и его переопределения для ролей
+// (employee, moderator, admin).
+type Group struct {
+	Limit `mapstructure:",squash"`
+	Roles map[string]Limit
+}
+
+// Policy - лимиты всех групп. При Enabled = false запросы не ограничиваются.
+type Policy struct {
+	Enabled bool
+	Auth    Group
+	Write   Group
+	Read    Group
+}
+
+// LimitFor возвращает лимит группы для роли: переопределение роли, если оно
+// задано, иначе лимит группы.
+func (p Policy) LimitFor(group, role string) Limit {
+	var g Group
+	switch group {
+	case GroupAuth:
+		g = p.Auth
+	case GroupWrite:
+		g = p.Write
+	case GroupRead:
+		g = p.Read
+	default:
+		return Limit{}
+	}
+	if limit, ok := g.Roles[role]; ok {
+		return limit
+	}
+	return g.Limit
+}
+
+// Limiter - хранилище корзин токенов. Allow списывает токен из корзины key с
+// лимитом limit или, если токенов нет, возвращает время до появления
+// следующего.
+type Limiter interface {
+	Allow(key string, limit Limit) (bool, time.Duration)
+}
+
+// UserKey и IPKey - ключи корзин авторизованного пользователя и клиента без
+// токена.
+func UserKey(userId string) string {
+	return "user:" + userId
+}
+
+func IPKey(ip string) string {
+	return "ip:" + ip
+}
+
+// Guard применяет Policy поверх Limiter. Политику можно заменить во время
+// работы через SetPolicy. Нулевой *Guard не ограничивает запросы.
+type Guard struct {
+	backend Limiter
+	policy  atomic.Pointer[Policy]
+}
+
+func NewGuard(backend Limiter, policy Policy) *Guard {
+	g := &Guard{backend: backend}
+	g.SetPolicy(policy)
+	return g
+}
+
+func (g *Guard) SetPolicy(policy Policy) {
+	g.policy.Store(&policy)
+}
+
+// Allow проверяет запрос группы group от клиента key с ролью role.
+func (g *Guard) Allow(group, role, key string) (bool, time.Duration) {
+	if g == nil {
+		return true, 0
+	}
+	policy := g.policy.Load()
+	if !policy.Enabled {
+		return true, 0
+	}
+	limit := policy.LimitFor(group, role)
+	if limit.Unlimited() {
+		return true, 0
+	}
+	return g.backend.Allow(group+":"+key, limit)
+}
@@ -0,0 +1,79 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemory_Allow(t *testing.T) {
+	now := time.Date(2025, 4, 10, 15, 0, 0, 0, time.UTC)
+	m := NewMemory()
+	m.now = func() time.Time { return now }
+	limit := Limit{Rate: 2, Burst: 3}
+
+	for i := 0; i < 3; i++ {
+		allowed, _ := m.Allow("k", limit)
+		assert.True(t, allowed, "запрос %d в пределах всплеска", i)
+	}
+	allowed, retryAfter := m.Allow("k", limit)
+	assert.False(t, allowed)
+	assert.Equal(t, 500*time.Millisecond, retryAfter)
+
+	allowed, _ = m.Allow("other", limit)
+	assert.True(t, allowed, "у другого ключа своя корзина")
+
+	now = now.Add(500 * time.Millisecond)
+	allowed, _ = m.Allow("k", limit)
+	assert.True(t, allowed, "за 0.5с пополнился один токен")
+	allowed, _ = m.Allow("k", limit)
+	assert.False(t, allowed)
+}
+
+func TestMemory_sweep(t *testing.T) {
+	now := time.Date(2025, 4, 10, 15, 0, 0, 0, time.UTC)
+	m := NewMemory()
+	m.now = func() time.Time { return now }
+	limit := Limit{Rate: 1, Burst: 2}
+
+	m.Allow("a", limit)
+	now = now.Add(sweepInterval)
+	m.Allow("b", limit)
+
+	assert.NotContains(t, m.buckets, "a")
+	assert.Contains(t, m.buckets, "b")
+}
+
+func TestGuard_Allow(t *testing.T) {
+	policy := Policy{
+		Enabled: true,
+		Write: Group{
+			Limit: Limit{Rate: 1, Burst: 1},
+			Roles: map[string]Limit{"moderator": {}},
+		},
+	}
+	g := NewGuard(NewMemory(), policy)
+
+	allowed, _ := g.Allow(GroupWrite, "employee", UserKey("u1"))
+	assert.True(t, allowed)
+	allowed, retryAfter := g.Allow(GroupWrite, "employee", UserKey("u1"))
+	assert.False(t, allowed)
+	assert.Equal(t, time.Second, retryAfter.Round(time.Second))
+
+	for i := 0; i < 5; i++ {
+		allowed, _ = g.Allow(GroupWrite, "moderator", UserKey("u2"))
+		assert.True(t, allowed, "для модератора лимит снят")
+		allowed, _ = g.Allow(GroupRead, "employee", UserKey("u1"))
+		assert.True(t, allowed, "группа read не ограничена")
+	}
+
+	policy.Enabled = false
+	g.SetPolicy(policy)
+	allowed, _ = g.Allow(GroupWrite, "employee", UserKey("u1"))
+	assert.True(t, allowed)
+
+	var disabled *Guard
+	allowed, _ = disabled.Allow(GroupWrite, "employee", UserKey("u1"))
+	assert.True(t, allowed)
+}
@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/bllooop/pvzservice/internal/domain"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPvzPostgres_ApplyAmendment(t *testing.T) {
+	fixedTime := time.Date(2025, 4, 10, 15, 5, 17, 0, time.UTC)
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	sqlxDB := sqlx.NewDb(db, "postgres")
+	r := NewPvzPostgres(sqlxDB)
+
+	amendmentID := uuid.New()
+	receptionID := uuid.New()
+	pvzID := uuid.New()
+	removedID := uuid.New()
+	addedID := uuid.New()
+	reviewer := "m1"
+	columns := []string{"id", "reception_id", "pvz_id", "status", "reason", "items", "requested_by", "reviewed_by", "review_comment", "created_at", "reviewed_at"}
+	items := fmt.Sprintf(`[{"action":"add","type":"обувь"},{"action":"remove","productId":"%s"}]`, removedID)
+	receptionColumns := []string{"id", "date_received", "pvz_id", "status_reception"}
+	productColumnsList := []string{"id", "date_received", "type_product", "reception_id", "pvz_id", "issued_at"}
+
+	tests := []struct {
+		name    string
+		mock    func()
+		want    string
+		wantErr error
+	}{
+		{
+			name: "Ok",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE id = \\$1 AND (.+) FOR UPDATE", amendmentsTable)).
+					WithArgs(amendmentID, "t1").
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(amendmentID, receptionID, pvzID, "pending", "пересчет", []byte(items), "u1", nil, "", fixedTime, nil))
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE id = \\$1 AND (.+) FOR UPDATE", receptionTable)).
+					WithArgs(receptionID, "t1").
+					WillReturnRows(sqlmock.NewRows(receptionColumns).AddRow(receptionID, fixedTime, pvzID, "close"))
+				mock.ExpectQuery(fmt.Sprintf("SELECT COALESCE\\(MAX\\(version\\), 0\\) FROM %s", versionsTable)).
+					WithArgs(receptionID).WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(0))
+				// исходная версия восстанавливается по приемке, закрытой до появления версий
+				closedAt := fixedTime.Add(-time.Hour)
+				mock.ExpectQuery(fmt.Sprintf("SELECT GREATEST(.+) FROM %s r LEFT JOIN %s p", receptionTable, productTable)).
+					WithArgs(receptionID).WillReturnRows(sqlmock.NewRows([]string{"greatest"}).AddRow(closedAt))
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE id = \\$1 AND (.+) = \\$2\\)$", receptionTable)).
+					WithArgs(receptionID, "t1").
+					WillReturnRows(sqlmock.NewRows(receptionColumns).AddRow(receptionID, fixedTime, pvzID, "close"))
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE reception_id = \\$1 AND deleted_at IS NULL", productTable)).
+					WithArgs(receptionID).
+					WillReturnRows(sqlmock.NewRows(productColumnsList).AddRow(removedID, fixedTime, "одежда", receptionID, pvzID, nil))
+				mock.ExpectExec(fmt.Sprintf("INSERT INTO %s", versionsTable)).
+					WithArgs(receptionID, 1, nil, "", sqlmock.AnyArg(), closedAt).WillReturnResult(sqlmock.NewResult(0, 1))
+				// добавление товара
+				mock.ExpectQuery(fmt.Sprintf("INSERT INTO %s", productTable)).
+					WithArgs(sqlmock.AnyArg(), "обувь", receptionID, pvzID).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "date_received", "type_product", "reception_id"}).AddRow(addedID, fixedTime, "обувь", receptionID))
+				mock.ExpectExec(fmt.Sprintf("INSERT INTO %s", correctionsTable)).
+					WithArgs(receptionID, addedID, domain.CorrectionAdd, domain.ReasonAmendment, "пересчет", reviewer, &amendmentID, nil).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				// удаление товара
+				mock.ExpectQuery(fmt.Sprintf("SELECT id FROM %s WHERE id = \\$1 AND reception_id = \\$2", productTable)).
+					WithArgs(&removedID, receptionID).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(removedID))
+				mock.ExpectQuery(fmt.Sprintf("UPDATE %s SET deleted_at", productTable)).
+					WithArgs(removedID).
+					WillReturnRows(sqlmock.NewRows(productColumnsList).AddRow(removedID, fixedTime, "одежда", receptionID, pvzID, nil))
+				mock.ExpectExec(fmt.Sprintf("INSERT INTO %s", correctionsTable)).
+					WithArgs(receptionID, removedID, domain.CorrectionRemove, domain.ReasonAmendment, "пересчет", reviewer, &amendmentID, nil).
+					WillReturnResult(sqlmock.NewResult(2, 1))
+				// новая версия
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE id = \\$1 AND (.+) = \\$2\\)$", receptionTable)).
+					WithArgs(receptionID, "t1").
+					WillReturnRows(sqlmock.NewRows(receptionColumns).AddRow(receptionID, fixedTime, pvzID, "close"))
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE reception_id = \\$1 AND deleted_at IS NULL", productTable)).
+					WithArgs(receptionID).
+					WillReturnRows(sqlmock.NewRows(productColumnsList).AddRow(addedID, fixedTime, "обувь", receptionID, pvzID, nil))
+				mock.ExpectExec(fmt.Sprintf("INSERT INTO %s", versionsTable)).
+					WithArgs(receptionID, 2, &amendmentID, reviewer, sqlmock.AnyArg(), nil).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery(fmt.Sprintf("UPDATE %s SET status", amendmentsTable)).
+					WithArgs(domain.AmendmentApproved, reviewer, "ок", amendmentID).
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(amendmentID, receptionID, pvzID, "approved", "пересчет", []byte(items), "u1", reviewer, "ок", fixedTime, fixedTime))
+				mock.ExpectCommit()
+			},
+			want: domain.AmendmentApproved,
+		},
+		{
+			name: "Заявка уже рассмотрена",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE id = \\$1 AND (.+) FOR UPDATE", amendmentsTable)).
+					WithArgs(amendmentID, "t1").
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(amendmentID, receptionID, pvzID, "rejected", "пересчет", []byte(items), "u1", reviewer, "", fixedTime, fixedTime))
+				mock.ExpectRollback()
+			},
+			wantErr: ErrAmendmentNotPending,
+		},
+		{
+			name: "Товар уже удален",
+			mock: func() {
+				mock.ExpectBegin()
+				removeOnly := fmt.Sprintf(`[{"action":"remove","productId":"%s"}]`, removedID)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE id = \\$1 AND (.+) FOR UPDATE", amendmentsTable)).
+					WithArgs(amendmentID, "t1").
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(amendmentID, receptionID, pvzID, "pending", "пересчет", []byte(removeOnly), "u1", nil, "", fixedTime, nil))
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE id = \\$1 AND (.+) FOR UPDATE", receptionTable)).
+					WithArgs(receptionID, "t1").
+					WillReturnRows(sqlmock.NewRows(receptionColumns).AddRow(receptionID, fixedTime, pvzID, "close"))
+				mock.ExpectQuery(fmt.Sprintf("SELECT COALESCE\\(MAX\\(version\\), 0\\) FROM %s", versionsTable)).
+					WithArgs(receptionID).WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(2))
+				mock.ExpectQuery(fmt.Sprintf("SELECT id FROM %s WHERE id = \\$1 AND reception_id = \\$2", productTable)).
+					WithArgs(&removedID, receptionID).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+				mock.ExpectRollback()
+			},
+			wantErr: ErrProductNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			got, err := r.ApplyAmendment(context.Background(), testScope, amendmentID, reviewer, "ок")
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got.Status)
+				assert.Len(t, got.Items, 2)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,355 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bllooop/pvzservice/internal/domain"
+	logger "github.com/bllooop/pvzservice/pkg/logging"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrReceptionNotFound   = errors.New("приемка не найдена")
+	ErrReceptionNotClosed  = errors.New("изменять через заявку можно только закрытую приемку")
+	ErrAmendmentNotFound   = errors.New("заявка на изменение не найдена")
+	ErrAmendmentNotPending = errors.New("заявка на изменение уже рассмотрена")
+)
+
+const amendmentColumns = "id,reception_id,pvz_id,status,reason,items,requested_by,reviewed_by,review_comment,created_at,reviewed_at"
+
+// amendmentRow — строка reception_amendments, позиции хранятся в JSONB.
+type amendmentRow struct {
+	Id            uuid.UUID  `db:"id"`
+	ReceptionId   uuid.UUID  `db:"reception_id"`
+	PVZId         uuid.UUID  `db:"pvz_id"`
+	Status        string     `db:"status"`
+	Reason        string     `db:"reason"`
+	Items         []byte     `db:"items"`
+	RequestedBy   string     `db:"requested_by"`
+	ReviewedBy    *string    `db:"reviewed_by"`
+	ReviewComment string     `db:"review_comment"`
+	CreatedAt     time.Time  `db:"created_at"`
+	ReviewedAt    *time.Time `db:"reviewed_at"`
+}
+
+// versionRow читает снимок в []byte, который database/sql копирует при сканировании.
+type versionRow struct {
+	domain.ReceptionVersion
+	Snapshot []byte `db:"snapshot"`
+}
+
+func (row amendmentRow) amendment() (domain.ReceptionAmendment, error) {
+	amendment := domain.ReceptionAmendment{
+		Id:            row.Id,
+		ReceptionId:   row.ReceptionId,
+		PVZId:         row.PVZId,
+		Status:        row.Status,
+		Reason:        row.Reason,
+		RequestedBy:   row.RequestedBy,
+		ReviewedBy:    row.ReviewedBy,
+		ReviewComment: row.ReviewComment,
+		CreatedAt:     row.CreatedAt,
+		ReviewedAt:    row.ReviewedAt,
+	}
+	if err := json.Unmarshal(row.Items, &amendment.Items); err != nil {
+		return domain.ReceptionAmendment{}, err
+	}
+	return amendment, nil
+}
+
+func (r *PvzPostgres) CreateAmendment(ctx context.Context, scope domain.TenantScope, amendment domain.ReceptionAmendment) (domain.ReceptionAmendment, error) {
+	ctx, done := startQuery(ctx, r.timeouts.Write, "PvzPostgres.CreateAmendment")
+	defer done()
+	items, err := json.Marshal(amendment.Items)
+	if err != nil {
+		return domain.ReceptionAmendment{}, err
+	}
+	tx, err := r.beginTx(ctx)
+	if err != nil {
+		return domain.ReceptionAmendment{}, err
+	}
+	defer tx.Rollback()
+	reception, err := r.getReception(ctx, tx, scope, amendment.ReceptionId, false)
+	if err != nil {
+		return domain.ReceptionAmendment{}, err
+	}
+	if *reception.Status != "close" {
+		return domain.ReceptionAmendment{}, ErrReceptionNotClosed
+	}
+	query := fmt.Sprintf(`INSERT INTO %s (reception_id,pvz_id,reason,items,requested_by) VALUES ($1,$2,$3,$4,$5) RETURNING %s`, amendmentsTable, amendmentColumns)
+	logger.FromContext(ctx).Debug().Str("query", query).Msg("Создание заявки на изменение приемки")
+	var row amendmentRow
+	if err := tx.QueryRowxContext(ctx, query, amendment.ReceptionId, reception.PVZId, amendment.Reason, string(items), amendment.RequestedBy).StructScan(&row); err != nil {
+		return domain.ReceptionAmendment{}, err
+	}
+	created, err := row.amendment()
+	if err != nil {
+		return domain.ReceptionAmendment{}, err
+	}
+	if err := auditTx(ctx, tx, amendment.ReceptionId.String(), nil, created); err != nil {
+		return domain.ReceptionAmendment{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return domain.ReceptionAmendment{}, err
+	}
+	return created, nil
+}
+
+func (r *PvzPostgres) GetAmendments(ctx context.Context, scope domain.TenantScope, receptionId uuid.UUID) ([]domain.ReceptionAmendment, error) {
+	ctx, done := startQuery(ctx, r.timeouts.Read, "PvzPostgres.GetAmendments")
+	defer done()
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE reception_id = $1 AND %s ORDER BY created_at`, amendmentColumns, amendmentsTable, receptionInScope(2))
+	logger.FromContext(ctx).Debug().Str("query", query).Msg("Запрос заявок на изменение приемки")
+	var rows []amendmentRow
+	if err := r.db.SelectContext(ctx, &rows, query, receptionId, scope.Filter()); err != nil {
+		return nil, err
+	}
+	amendments := make([]domain.ReceptionAmendment, 0, len(rows))
+	for _, row := range rows {
+		amendment, err := row.amendment()
+		if err != nil {
+			return nil, err
+		}
+		amendments = append(amendments, amendment)
+	}
+	return amendments, nil
+}
+
+func (r *PvzPostgres) RejectAmendment(ctx context.Context, scope domain.TenantScope, id uuid.UUID, reviewer, comment string) (domain.ReceptionAmendment, error) {
+	ctx, done := startQuery(ctx, r.timeouts.Write, "PvzPostgres.RejectAmendment")
+	defer done()
+	tx, err := r.beginTx(ctx)
+	if err != nil {
+		return domain.ReceptionAmendment{}, err
+	}
+	defer tx.Rollback()
+	pending, err := r.lockPendingAmendment(ctx, tx, scope, id)
+	if err != nil {
+		return domain.ReceptionAmendment{}, err
+	}
+	amendment, err := r.finishAmendment(ctx, tx, id, domain.AmendmentRejected, reviewer, comment)
+	if err != nil {
+		return domain.ReceptionAmendment{}, err
+	}
+	if err := auditTx(ctx, tx, pending.ReceptionId.String(), pending, amendment); err != nil {
+		return domain.ReceptionAmendment{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return domain.ReceptionAmendment{}, err
+	}
+	return amendment, nil
+}
+
+// ApplyAmendment одобряет заявку и в одной транзакции вносит изменения в
+// состав приемки, записывает исправления и новую версию приемки.
+func (r *PvzPostgres) ApplyAmendment(ctx context.Context, scope domain.TenantScope, id uuid.UUID, reviewer, comment string) (domain.ReceptionAmendment, error) {
+	ctx, done := startQuery(ctx, r.timeouts.Write, "PvzPostgres.ApplyAmendment")
+	defer done()
+	tx, err := r.beginTx(ctx)
+	if err != nil {
+		return domain.ReceptionAmendment{}, err
+	}
+	defer tx.Rollback()
+	pending, err := r.lockPendingAmendment(ctx, tx, scope, id)
+	if err != nil {
+		return domain.ReceptionAmendment{}, err
+	}
+	reception, err := r.getReception(ctx, tx, scope, pending.ReceptionId, true)
+	if err != nil {
+		return domain.ReceptionAmendment{}, err
+	}
+	if *reception.Status != "close" {
+		return domain.ReceptionAmendment{}, ErrReceptionNotClosed
+	}
+	version, err := r.lastReceptionVersion(ctx, tx, pending.ReceptionId)
+	if err != nil {
+		return domain.ReceptionAmendment{}, err
+	}
+	if version == 0 {
+		// приемка закрыта до появления версий: первая версия восстанавливается
+		// по самой приемке, автор закрытия неизвестен
+		closedAt, err := r.receptionLastChange(ctx, tx, pending.ReceptionId)
+		if err != nil {
+			return domain.ReceptionAmendment{}, err
+		}
+		if err := r.insertReceptionVersion(ctx, tx, scope, pending.ReceptionId, 1, nil, "", &closedAt); err != nil {
+			return domain.ReceptionAmendment{}, err
+		}
+		version = 1
+	}
+	for _, item := range pending.Items {
+		if err := r.applyAmendmentItem(ctx, tx, pending, item, reviewer); err != nil {
+			return domain.ReceptionAmendment{}, err
+		}
+	}
+	if err := r.insertReceptionVersion(ctx, tx, scope, pending.ReceptionId, version+1, &pending.Id, reviewer, nil); err != nil {
+		return domain.ReceptionAmendment{}, err
+	}
+	amendment, err := r.finishAmendment(ctx, tx, id, domain.AmendmentApproved, reviewer, comment)
+	if err != nil {
+		return domain.ReceptionAmendment{}, err
+	}
+	if err := auditTx(ctx, tx, pending.ReceptionId.String(), pending, amendment); err != nil {
+		return domain.ReceptionAmendment{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return domain.ReceptionAmendment{}, err
+	}
+	return amendment, nil
+}
+
+func (r *PvzPostgres) GetReceptionHistory(ctx context.Context, scope domain.TenantScope, receptionId uuid.UUID) ([]domain.ReceptionVersion, error) {
+	ctx, done := startQuery(ctx, r.timeouts.Read, "PvzPostgres.GetReceptionHistory")
+	defer done()
+	query := fmt.Sprintf(`SELECT reception_id,version,amendment_id,created_by,created_at,snapshot FROM %s WHERE reception_id = $1 AND %s ORDER BY version`,
+		versionsTable, receptionInScope(2))
+	logger.FromContext(ctx).Debug().Str("query", query).Msg("Запрос истории версий приемки")
+	var rows []versionRow
+	if err := r.db.SelectContext(ctx, &rows, query, receptionId, scope.Filter()); err != nil {
+		return nil, err
+	}
+	versions := make([]domain.ReceptionVersion, 0, len(rows))
+	for _, row := range rows {
+		version := row.ReceptionVersion
+		version.Snapshot = row.Snapshot
+		versions = append(versions, version)
+	}
+	return versions, nil
+}
+
+func (r *PvzPostgres) applyAmendmentItem(ctx context.Context, tx *sqlx.Tx, amendment domain.ReceptionAmendment, item domain.AmendmentItem, reviewer string) error {
+	switch item.Action {
+	case domain.CorrectionAdd:
+		now := time.Now()
+		added, err := r.insertProduct(ctx, tx, domain.Product{DateReceived: &now, Type: item.Type}, amendment.ReceptionId, amendment.PVZId)
+		if err != nil {
+			return err
+		}
+		return r.insertCorrection(ctx, tx, domain.ProductCorrection{
+			ReceptionId: amendment.ReceptionId,
+			ProductId:   *added.Id,
+			Action:      domain.CorrectionAdd,
+			Reason:      domain.ReasonAmendment,
+			Comment:     amendment.Reason,
+			ActorId:     reviewer,
+			AmendmentId: &amendment.Id,
+		})
+	case domain.CorrectionRemove:
+		query := fmt.Sprintf(`SELECT id FROM %s WHERE id = $1 AND reception_id = $2 AND deleted_at IS NULL AND transferred_at IS NULL FOR UPDATE`, productTable)
+		var productId uuid.UUID
+		if err := tx.QueryRowxContext(ctx, query, item.ProductId, amendment.ReceptionId).Scan(&productId); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("%w: %s", ErrProductNotFound, item.ProductId)
+			}
+			return err
+		}
+		_, err := r.softDeleteProduct(ctx, tx, productId, amendment.ReceptionId, domain.ProductDeletion{
+			Reason:      domain.ReasonAmendment,
+			Comment:     amendment.Reason,
+			ActorId:     reviewer,
+			AmendmentId: &amendment.Id,
+		})
+		return err
+	}
+	return fmt.Errorf("неизвестное действие %q", item.Action)
+}
+
+// lockPendingAmendment блокирует заявку к приемке компании из scope. Заявки
+// других компаний неотличимы от несуществующих.
+func (r *PvzPostgres) lockPendingAmendment(ctx context.Context, tx *sqlx.Tx, scope domain.TenantScope, id uuid.UUID) (domain.ReceptionAmendment, error) {
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE id = $1 AND %s FOR UPDATE`, amendmentColumns, amendmentsTable, receptionInScope(2))
+	var row amendmentRow
+	if err := tx.QueryRowxContext(ctx, query, id, scope.Filter()).StructScan(&row); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.ReceptionAmendment{}, ErrAmendmentNotFound
+		}
+		return domain.ReceptionAmendment{}, err
+	}
+	if row.Status != domain.AmendmentPending {
+		return domain.ReceptionAmendment{}, ErrAmendmentNotPending
+	}
+	return row.amendment()
+}
+
+func (r *PvzPostgres) finishAmendment(ctx context.Context, tx *sqlx.Tx, id uuid.UUID, status, reviewer, comment string) (domain.ReceptionAmendment, error) {
+	query := fmt.Sprintf(`UPDATE %s SET status = $1, reviewed_by = $2, review_comment = $3, reviewed_at = now() WHERE id = $4 RETURNING %s`, amendmentsTable, amendmentColumns)
+	logger.FromContext(ctx).Debug().Str("query", query).Msg("Рассмотрение заявки на изменение приемки")
+	var row amendmentRow
+	if err := tx.QueryRowxContext(ctx, query, status, reviewer, comment, id).StructScan(&row); err != nil {
+		return domain.ReceptionAmendment{}, err
+	}
+	return row.amendment()
+}
+
+func (r *PvzPostgres) getReception(ctx context.Context, tx *sqlx.Tx, scope domain.TenantScope, receptionId uuid.UUID, forUpdate bool) (domain.ProductReception, error) {
+	query := fmt.Sprintf(`SELECT id,date_received,pvz_id,status_reception FROM %s WHERE id = $1 AND %s`, receptionTable, tenantCondition("tenant_id", 2))
+	if forUpdate {
+		query += " FOR UPDATE"
+	}
+	var reception domain.ProductReception
+	if err := tx.QueryRowxContext(ctx, query, receptionId, scope.Filter()).Scan(&reception.Id, &reception.DateReceived, &reception.PVZId, &reception.Status); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.ProductReception{}, ErrReceptionNotFound
+		}
+		return domain.ProductReception{}, err
+	}
+	return reception, nil
+}
+
+// receptionInScope ограничивает reception_id приемками компании из параметра $n.
+func receptionInScope(n int) string {
+	return fmt.Sprintf("reception_id IN (SELECT r.id FROM %s r WHERE %s)", receptionTable, tenantCondition("r.tenant_id", n))
+}
+
+func (r *PvzPostgres) lastReceptionVersion(ctx context.Context, tx *sqlx.Tx, receptionId uuid.UUID) (int, error) {
+	var version int
+	query := fmt.Sprintf(`SELECT COALESCE(MAX(version), 0) FROM %s WHERE reception_id = $1`, versionsTable)
+	err := tx.QueryRowxContext(ctx, query, receptionId).Scan(&version)
+	return version, err
+}
+
+// insertClosedVersion сохраняет состав только что закрытой приемки как первую
+// версию. Автором версии считается автор изменения из черновика аудита.
+func (r *PvzPostgres) insertClosedVersion(ctx context.Context, tx *sqlx.Tx, scope domain.TenantScope, receptionId uuid.UUID) error {
+	draft, _ := AuditFromContext(ctx)
+	return r.insertReceptionVersion(ctx, tx, scope, receptionId, 1, nil, draft.ActorId, nil)
+}
+
+// receptionLastChange возвращает время последнего изменения состава приемки:
+// ее создания, добавления или удаления товара.
+func (r *PvzPostgres) receptionLastChange(ctx context.Context, tx *sqlx.Tx, receptionId uuid.UUID) (time.Time, error) {
+	var changed time.Time
+	query := fmt.Sprintf(`SELECT GREATEST(r.date_received, MAX(p.date_received), MAX(p.deleted_at))
+  FROM %s r LEFT JOIN %s p ON p.reception_id = r.id
+  WHERE r.id = $1 GROUP BY r.id`, receptionTable, productTable)
+	err := tx.QueryRowxContext(ctx, query, receptionId).Scan(&changed)
+	return changed, err
+}
+
+// insertReceptionVersion сохраняет снимок приемки. Если createdAt не указан,
+// версия датируется текущим временем.
+func (r *PvzPostgres) insertReceptionVersion(ctx context.Context, tx *sqlx.Tx, scope domain.TenantScope, receptionId uuid.UUID, version int, amendmentId *uuid.UUID, createdBy string, createdAt *time.Time) error {
+	reception, err := r.getReception(ctx, tx, scope, receptionId, false)
+	if err != nil {
+		return err
+	}
+	snapshot := domain.ReceptionSnapshot{Reception: reception}
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE reception_id = $1 AND deleted_at IS NULL ORDER BY date_received`, productColumns, productTable)
+	if err := tx.SelectContext(ctx, &snapshot.Products, query, receptionId); err != nil {
+		return err
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	query = fmt.Sprintf(`INSERT INTO %s (reception_id,version,amendment_id,created_by,snapshot,created_at) VALUES ($1,$2,$3,$4,$5,COALESCE($6, now()))`, versionsTable)
+	logger.FromContext(ctx).Debug().Str("query", query).Msg("Сохранение версии приемки")
+	_, err = tx.ExecContext(ctx, query, receptionId, version, amendmentId, createdBy, string(data), createdAt)
+	return err
+}
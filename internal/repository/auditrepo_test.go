@@ -0,0 +1,239 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/bllooop/pvzservice/internal/domain"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditPostgres_AppendAudit(t *testing.T) {
+	fixedTime := time.Date(2025, 4, 10, 15, 5, 17, 123456789, time.UTC)
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	sqlxDB := sqlx.NewDb(db, "postgres")
+	r := NewAuditPostgres(sqlxDB)
+
+	entry := domain.AuditEntry{
+		CreatedAt:  fixedTime,
+		ActorId:    "u1",
+		ActorRole:  "moderator",
+		Action:     "pvz.create",
+		EntityType: "pvz",
+		EntityId:   "p1",
+		After:      json.RawMessage(`{"city":"Москва"}`),
+	}
+	expected := entry
+	expected.CreatedAt = fixedTime.Truncate(time.Microsecond)
+	expected.PrevHash = "prev"
+	expected.Hash = AuditHash(expected)
+	expected.Id = 2
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_xact_lock($1)")).WithArgs(auditLockKey).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(fmt.Sprintf("SELECT hash FROM %s", auditTable)).
+		WillReturnRows(sqlmock.NewRows([]string{"hash"}).AddRow("prev"))
+	mock.ExpectQuery(fmt.Sprintf("INSERT INTO %s", auditTable)).
+		WithArgs(expected.CreatedAt, "u1", "moderator", "pvz.create", "pvz", "p1", nil, `{"city":"Москва"}`, "", "", "", "prev", expected.Hash).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	mock.ExpectCommit()
+
+	got, err := r.AppendAudit(context.Background(), entry)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, got)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// jsonContains сопоставляет снимок аудита, содержащий подстроку.
+type jsonContains string
+
+func (a jsonContains) Match(v driver.Value) bool {
+	s, ok := v.(string)
+	return ok && strings.Contains(s, string(a))
+}
+
+func TestAuditTx(t *testing.T) {
+	fixedTime := time.Date(2025, 4, 10, 15, 5, 17, 0, time.UTC)
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	sqlxDB := sqlx.NewDb(db, "postgres")
+	pvz := NewPvzPostgres(sqlxDB)
+	auth := NewAuthPostgres(sqlxDB)
+	id := uuid.New()
+	role := "moderator"
+	draft := domain.AuditEntry{CreatedAt: fixedTime, ActorId: "u1", ActorRole: "employee", Action: "reception.close", EntityType: "reception", TenantId: "t1"}
+	expectChain := func() {
+		mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_xact_lock($1)")).WithArgs(auditLockKey).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(fmt.Sprintf("SELECT hash FROM %s", auditTable)).
+			WillReturnRows(sqlmock.NewRows([]string{"hash"}).AddRow("prev"))
+	}
+
+	tests := []struct {
+		name    string
+		mock    func()
+		run     func(ctx context.Context) error
+		wantErr bool
+	}{
+		{
+			name: "Снимок до закрытия приемки читается из БД",
+			mock: func() {
+				mock.ExpectBegin()
+				expectPvzActive(mock)
+				mock.ExpectQuery(fmt.Sprintf("SELECT status_reception,id FROM %s (.+)", receptionTable)).
+					WillReturnRows(sqlmock.NewRows([]string{"status_reception", "id"}).AddRow("in_progress", id))
+				mock.ExpectQuery(fmt.Sprintf(`COUNT\(\*\) FROM %s (.+)`, productTable)).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+				mock.ExpectQuery(fmt.Sprintf("SELECT id,date_received,pvz_id,status_reception FROM %s (.+) FOR UPDATE", receptionTable)).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "date_received", "pvz_id", "status_reception"}).AddRow(id, fixedTime, id, "in_progress"))
+				mock.ExpectQuery(fmt.Sprintf("UPDATE %s SET (.+)", receptionTable)).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "date_received", "pvz_id", "status_reception", "flagged_at"}).AddRow(id, fixedTime, id, "close", nil))
+				// первая версия приемки записывается от имени закрывшего ее сотрудника
+				mock.ExpectQuery(fmt.Sprintf("SELECT id,date_received,pvz_id,status_reception FROM %s WHERE id = \\$1 AND (.+) = \\$2\\)$", receptionTable)).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "date_received", "pvz_id", "status_reception"}).AddRow(id, fixedTime, id, "close"))
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE reception_id = \\$1 AND deleted_at IS NULL", productTable)).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "date_received", "type_product", "reception_id", "pvz_id", "issued_at"}))
+				mock.ExpectExec(fmt.Sprintf("INSERT INTO %s", versionsTable)).
+					WithArgs(id, 1, nil, "u1", sqlmock.AnyArg(), nil).WillReturnResult(sqlmock.NewResult(0, 1))
+				expectChain()
+				mock.ExpectQuery(fmt.Sprintf("INSERT INTO %s", auditTable)).
+					WithArgs(sqlmock.AnyArg(), "u1", "employee", "reception.close", "reception", id.String(),
+						jsonContains(`"status":"in_progress"`), jsonContains(`"status":"close"`), "", "", "t1", "prev", sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+				mock.ExpectCommit()
+			},
+			run: func(ctx context.Context) error {
+				_, err := pvz.CloseReception(ctx, testScope, id)
+				return err
+			},
+		},
+		{
+			name: "Ошибка записи в журнал отменяет изменение",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(fmt.Sprintf("UPDATE %s", userListTable)).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "email", "role", "tenant_id", "created_at", "disabled_at", "last_login_at"}).
+						AddRow(id, "test", role, "t1", fixedTime, nil, nil))
+				expectChain()
+				mock.ExpectQuery(fmt.Sprintf("INSERT INTO %s", auditTable)).WillReturnError(errors.New("ошибка бд"))
+				mock.ExpectRollback()
+			},
+			run: func(ctx context.Context) error {
+				_, err := auth.UpdateUser(ctx, testScope, id, domain.UpdateUserInput{Role: &role})
+				return err
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			err := tt.run(WithAudit(context.Background(), draft))
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestAuditPostgres_VerifyAuditChain(t *testing.T) {
+	fixedTime := time.Date(2025, 4, 10, 15, 5, 17, 0, time.UTC)
+	// Запись, сделанная до появления компаний: tenant_id пуст, хеш вычислен без него.
+	legacy := domain.AuditEntry{Id: 1, CreatedAt: fixedTime.Add(-time.Hour), ActorId: "u0", Action: "user.register", EntityType: "user", EntityId: "u0"}
+	legacySum := sha256.Sum256([]byte(strings.Join([]string{"", legacy.CreatedAt.Format(time.RFC3339Nano), "u0", "", "user.register", "user", "u0", "", "", "", ""}, "\x1f")))
+	legacy.Hash = hex.EncodeToString(legacySum[:])
+	first := domain.AuditEntry{Id: 2, CreatedAt: fixedTime, ActorId: "u1", Action: "pvz.create", EntityType: "pvz", EntityId: "p1", TenantId: "t1", PrevHash: legacy.Hash}
+	first.Hash = AuditHash(first)
+	second := domain.AuditEntry{Id: 3, CreatedAt: fixedTime.Add(time.Second), ActorId: "u1", Action: "reception.create", EntityType: "reception", EntityId: "r1", TenantId: "t1", PrevHash: first.Hash}
+	second.Hash = AuditHash(second)
+
+	addRow := func(rows *sqlmock.Rows, e domain.AuditEntry) *sqlmock.Rows {
+		return rows.AddRow(e.Id, e.CreatedAt, e.ActorId, e.ActorRole, e.Action, e.EntityType, e.EntityId, nil, nil, e.RequestId, e.ClientIP, e.TenantId, e.PrevHash, e.Hash)
+	}
+	columns := []string{"id", "created_at", "actor_id", "actor_role", "action", "entity_type", "entity_id", "before_state", "after_state", "request_id", "client_ip", "tenant_id", "prev_hash", "hash"}
+	tampered := second
+	tampered.EntityId = "r2"
+	movedTenant := second
+	movedTenant.TenantId = ""
+
+	tests := []struct {
+		name    string
+		rows    []domain.AuditEntry
+		want    int
+		wantErr bool
+	}{
+		{
+			name: "Цепочка целостна",
+			rows: []domain.AuditEntry{legacy, first, second},
+			want: 3,
+		},
+		{
+			name:    "Запись изменена",
+			rows:    []domain.AuditEntry{legacy, first, tampered},
+			want:    2,
+			wantErr: true,
+		},
+		{
+			name:    "Компания записи изменена",
+			rows:    []domain.AuditEntry{legacy, first, movedTenant},
+			want:    2,
+			wantErr: true,
+		},
+		{
+			name:    "Запись удалена",
+			rows:    []domain.AuditEntry{legacy, second},
+			want:    1,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+			r := NewAuditPostgres(sqlx.NewDb(db, "postgres"))
+
+			rows := sqlmock.NewRows(columns)
+			for _, e := range tt.rows {
+				rows = addRow(rows, e)
+			}
+			mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s ORDER BY id", auditTable)).WillReturnRows(rows)
+
+			got, err := r.VerifyAuditChain(context.Background())
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrAuditChainBroken)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,290 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bllooop/pvzservice/internal/domain"
+	logger "github.com/bllooop/pvzservice/pkg/logging"
+	"github.com/bllooop/pvzservice/prometheus"
+	"github.com/jmoiron/sqlx"
+)
+
+var ErrAuditChainBroken = errors.New("цепочка журнала аудита нарушена")
+
+// auditLockKey сериализует запись в журнал аудита между экземплярами сервиса,
+// чтобы каждая запись ссылалась на хеш действительно последней строки.
+const auditLockKey = 730_001
+
+const auditColumns = "id,created_at,actor_id,actor_role,action,entity_type,entity_id,before_state,after_state,request_id,client_ip,tenant_id,prev_hash,hash"
+
+// auditRow нужен для чтения NULL в before_state/after_state: json.RawMessage
+// не умеет сканировать NULL, а []byte умеет.
+type auditRow struct {
+	domain.AuditEntry
+	Before []byte `db:"before_state"`
+	After  []byte `db:"after_state"`
+}
+
+func (r auditRow) entry() domain.AuditEntry {
+	entry := r.AuditEntry
+	entry.Before = r.Before
+	entry.After = r.After
+	return entry
+}
+
+type AuditPostgres struct {
+	db       *sqlx.DB
+	timeouts Timeouts
+}
+
+func NewAuditPostgres(db *sqlx.DB) *AuditPostgres {
+	return &AuditPostgres{
+		db:       db,
+		timeouts: DefaultTimeouts,
+	}
+}
+
+// AuditHash вычисляет хеш записи журнала вместе с хешем предыдущей записи.
+// Компания входит в хеш, только если указана, поэтому хеши записей, сделанных
+// до появления нескольких компаний, не меняются.
+func AuditHash(entry domain.AuditEntry) string {
+	fields := []string{
+		entry.PrevHash,
+		entry.CreatedAt.UTC().Format(time.RFC3339Nano),
+		entry.ActorId,
+		entry.ActorRole,
+		entry.Action,
+		entry.EntityType,
+		entry.EntityId,
+		string(entry.Before),
+		string(entry.After),
+		entry.RequestId,
+		entry.ClientIP,
+	}
+	if entry.TenantId != "" {
+		fields = append(fields, entry.TenantId)
+	}
+	payload := strings.Join(fields, "\x1f")
+	sum := sha256.Sum256([]byte(payload))
+	return hex.EncodeToString(sum[:])
+}
+
+func (r *AuditPostgres) AppendAudit(ctx context.Context, entry domain.AuditEntry) (domain.AuditEntry, error) {
+	ctx, done := startQuery(ctx, r.timeouts.Write, "AuditPostgres.AppendAudit")
+	defer done()
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return domain.AuditEntry{}, err
+	}
+	defer tx.Rollback()
+	entry, err = appendAuditTx(ctx, tx, entry)
+	if err != nil {
+		return domain.AuditEntry{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return domain.AuditEntry{}, err
+	}
+	return entry, nil
+}
+
+type auditCtxKey struct{}
+
+// WithAudit кладет в ctx черновик записи журнала аудита: автора, действие,
+// тип сущности и данные запроса. Метод репозитория, выполняющий изменение,
+// дополняет черновик сущностью и снимками и пишет запись в той же транзакции,
+// поэтому изменение без записи в журнале не сохраняется.
+func WithAudit(ctx context.Context, draft domain.AuditEntry) context.Context {
+	return context.WithValue(ctx, auditCtxKey{}, draft)
+}
+
+// AuditFromContext возвращает черновик записи журнала аудита из ctx.
+func AuditFromContext(ctx context.Context) (domain.AuditEntry, bool) {
+	entry, ok := ctx.Value(auditCtxKey{}).(domain.AuditEntry)
+	return entry, ok
+}
+
+// auditTenant указывает компанию в черновике из ctx, если запрос выполнен без
+// токена и компания заранее не известна.
+func auditTenant(ctx context.Context, tenantId string) context.Context {
+	entry, ok := AuditFromContext(ctx)
+	if !ok || entry.TenantId != "" {
+		return ctx
+	}
+	entry.TenantId = tenantId
+	return WithAudit(ctx, entry)
+}
+
+// auditEntity указывает тип сущности в черновике из ctx. Нужна методам, которые
+// за одну транзакцию меняют сущности разных типов.
+func auditEntity(ctx context.Context, entityType string) context.Context {
+	entry, ok := AuditFromContext(ctx)
+	if !ok {
+		return ctx
+	}
+	entry.EntityType = entityType
+	return WithAudit(ctx, entry)
+}
+
+// auditTx пишет в журнал черновик из ctx в транзакции tx. Без черновика
+// ничего не делает.
+func auditTx(ctx context.Context, tx *sqlx.Tx, entityId string, before, after any) error {
+	return auditActionTx(ctx, tx, "", entityId, before, after)
+}
+
+// auditActionTx как auditTx, но задает действие сама, если action не пуст.
+// Нужна методам, которые за одну транзакцию выполняют разные действия.
+func auditActionTx(ctx context.Context, tx *sqlx.Tx, action, entityId string, before, after any) error {
+	entry, ok := AuditFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	if action != "" {
+		entry.Action = action
+	}
+	entry.EntityId = entityId
+	entry.Before = auditSnapshot(before)
+	entry.After = auditSnapshot(after)
+	if _, err := appendAuditTx(ctx, tx, entry); err != nil {
+		prometheus.AuditWriteFailures.Inc()
+		logger.FromContext(ctx).Error().Err(err).Str("action", entry.Action).Str("entity", entityId).Msg("Ошибка записи в журнал аудита")
+		return err
+	}
+	return nil
+}
+
+// appendAuditTx дописывает запись в конец цепочки журнала. Advisory-блокировка
+// держится до конца tx, поэтому записи из параллельных транзакций выстраиваются
+// в цепочку в порядке фиксации.
+func appendAuditTx(ctx context.Context, tx *sqlx.Tx, entry domain.AuditEntry) (domain.AuditEntry, error) {
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, auditLockKey); err != nil {
+		return domain.AuditEntry{}, err
+	}
+	var prevHash string
+	query := fmt.Sprintf(`SELECT hash FROM %s ORDER BY id DESC LIMIT 1`, auditTable)
+	if err := tx.QueryRowxContext(ctx, query).Scan(&prevHash); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return domain.AuditEntry{}, err
+	}
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	entry.CreatedAt = entry.CreatedAt.UTC().Truncate(time.Microsecond)
+	entry.PrevHash = prevHash
+	entry.Hash = AuditHash(entry)
+
+	query = fmt.Sprintf(`INSERT INTO %s (created_at,actor_id,actor_role,action,entity_type,entity_id,before_state,after_state,request_id,client_ip,tenant_id,prev_hash,hash)
+VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13) RETURNING id`, auditTable)
+	logger.FromContext(ctx).Debug().Str("query", query).Msg("Запись в журнал аудита")
+	err := tx.QueryRowxContext(ctx, query, entry.CreatedAt, entry.ActorId, entry.ActorRole, entry.Action, entry.EntityType, entry.EntityId,
+		nullableJSON(entry.Before), nullableJSON(entry.After), entry.RequestId, entry.ClientIP, entry.TenantId, entry.PrevHash, entry.Hash).Scan(&entry.Id)
+	if err != nil {
+		return domain.AuditEntry{}, err
+	}
+	return entry, nil
+}
+
+func auditSnapshot(v any) json.RawMessage {
+	if v == nil {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+func (r *AuditPostgres) GetAudit(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditEntry, error) {
+	ctx, done := startQuery(ctx, r.timeouts.Read, "AuditPostgres.GetAudit")
+	defer done()
+	var conditions []string
+	var args []interface{}
+	addCondition := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(cond, len(args)))
+	}
+	if tenantId := filter.Scope.Filter(); tenantId != nil {
+		addCondition("tenant_id = $%d", *tenantId)
+	}
+	if filter.ActorId != "" {
+		addCondition("actor_id = $%d", filter.ActorId)
+	}
+	if filter.Action != "" {
+		addCondition("action = $%d", filter.Action)
+	}
+	if filter.EntityType != "" {
+		addCondition("entity_type = $%d", filter.EntityType)
+	}
+	if filter.EntityId != "" {
+		addCondition("entity_id = $%d", filter.EntityId)
+	}
+	if !filter.From.IsZero() {
+		addCondition("created_at >= $%d", filter.From)
+	}
+	if !filter.To.IsZero() {
+		addCondition("created_at <= $%d", filter.To)
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s", auditColumns, auditTable)
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	args = append(args, filter.Limit, (filter.Page-1)*filter.Limit)
+	query += fmt.Sprintf(" ORDER BY id DESC LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+	logger.FromContext(ctx).Debug().Str("query", query).Msg("Запрос журнала аудита")
+	var rows []auditRow
+	if err := r.db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, err
+	}
+	entries := make([]domain.AuditEntry, 0, len(rows))
+	for _, row := range rows {
+		entries = append(entries, row.entry())
+	}
+	return entries, nil
+}
+
+// VerifyAuditChain последовательно пересчитывает хеши всех записей журнала
+// и возвращает количество проверенных записей. Проверка читает весь журнал,
+// поэтому таймаут db.timeouts к ней не применяется: длительность ограничивает
+// вызывающий через ctx.
+func (r *AuditPostgres) VerifyAuditChain(ctx context.Context) (int, error) {
+	ctx, done := startQuery(ctx, 0, "AuditPostgres.VerifyAuditChain")
+	defer done()
+	query := fmt.Sprintf("SELECT %s FROM %s ORDER BY id", auditColumns, auditTable)
+	rows, err := r.db.QueryxContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	checked := 0
+	prevHash := ""
+	for rows.Next() {
+		var row auditRow
+		if err := rows.StructScan(&row); err != nil {
+			return checked, err
+		}
+		entry := row.entry()
+		if entry.PrevHash != prevHash {
+			return checked, fmt.Errorf("%w: запись %d ссылается на неверную предыдущую запись", ErrAuditChainBroken, entry.Id)
+		}
+		if AuditHash(entry) != entry.Hash {
+			return checked, fmt.Errorf("%w: запись %d изменена", ErrAuditChainBroken, entry.Id)
+		}
+		prevHash = entry.Hash
+		checked++
+	}
+	return checked, rows.Err()
+}
+
+func nullableJSON(raw []byte) interface{} {
+	if len(raw) == 0 {
+		return nil
+	}
+	return string(raw)
+}
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"fmt"
 	"testing"
 
@@ -20,6 +21,7 @@ func TestAuthPostgres_Create(t *testing.T) {
 
 	sqlxDB := sqlx.NewDb(db, "postgres")
 	r := NewAuthPostgres(sqlxDB)
+	userID := uuid.New()
 
 	tests := []struct {
 		name    string
@@ -31,18 +33,23 @@ func TestAuthPostgres_Create(t *testing.T) {
 		{
 			name: "Ok",
 			mock: func() {
-				rows := sqlmock.NewRows([]string{"email", "password"}).AddRow("email", "employee")
+				mock.ExpectBegin()
+				rows := sqlmock.NewRows([]string{"id", "email", "role", "tenant_id"}).AddRow(userID, "email", "employee", "t1")
 				mock.ExpectQuery("INSERT INTO userlist").
-					WithArgs("email", "123", "employee").WillReturnRows(rows)
+					WithArgs("email", "123", "employee", "t1").WillReturnRows(rows)
+				mock.ExpectCommit()
 			},
 			input: domain.User{
 				Email:    "email",
 				Password: "123",
 				Role:     "employee",
+				TenantId: "t1",
 			},
 			want: domain.User{
-				Email: "email",
-				Role:  "employee",
+				Id:       userID,
+				Email:    "email",
+				Role:     "employee",
+				TenantId: "t1",
 			},
 		},
 		{
@@ -62,7 +69,7 @@ func TestAuthPostgres_Create(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.mock()
 
-			got, err := r.CreateUser(tt.input)
+			got, err := r.CreateUser(context.Background(), tt.input)
 			if tt.wantErr {
 				assert.Error(t, err)
 			} else {
@@ -102,10 +109,10 @@ func TestAuthPostgres_SignUser(t *testing.T) {
 		{
 			name: "Ok",
 			mock: func() {
-				rows := sqlmock.NewRows([]string{"id", "email", "password", "role"}).
-					AddRow(userID, "test", "password", "employee")
+				rows := sqlmock.NewRows([]string{"id", "email", "password", "role", "tenant_id", "disabled_at"}).
+					AddRow(userID, "test", "password", "employee", "t1", nil)
 				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s", userListTable)).
-					WithArgs("test").WillReturnRows(rows)
+					WithArgs("t1", "test").WillReturnRows(rows)
 			},
 			input: args{"test"},
 			want: domain.User{
@@ -113,14 +120,15 @@ func TestAuthPostgres_SignUser(t *testing.T) {
 				Email:    "test",
 				Password: "password",
 				Role:     "employee",
+				TenantId: "t1",
 			},
 		},
 		{
 			name: "Пользователь не найден",
 			mock: func() {
-				rows := sqlmock.NewRows([]string{"id", "email", "password", "role"})
+				rows := sqlmock.NewRows([]string{"id", "email", "password", "role", "tenant_id", "disabled_at"})
 				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s", userListTable)).
-					WithArgs("not").WillReturnRows(rows)
+					WithArgs("t1", "not").WillReturnRows(rows)
 			},
 			input:   args{"not"},
 			wantErr: true,
@@ -138,7 +146,7 @@ func TestAuthPostgres_SignUser(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.mock()
 
-			got, err := r.SignUser(tt.input.username)
+			got, err := r.SignUser(context.Background(), "t1", tt.input.username)
 			if tt.wantErr {
 				assert.Error(t, err)
 			} else {
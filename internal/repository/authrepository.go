@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -10,41 +11,60 @@ import (
 	"github.com/jmoiron/sqlx"
 )
 
+var ErrUserNotFound = errors.New("пользователь не найден")
+
 type AuthPostgres struct {
-	db *sqlx.DB
+	db       *sqlx.DB
+	timeouts Timeouts
 }
 
 func NewAuthPostgres(db *sqlx.DB) *AuthPostgres {
 	return &AuthPostgres{
-		db: db,
+		db:       db,
+		timeouts: DefaultTimeouts,
 	}
 }
 
-func (r *AuthPostgres) CreateUser(user domain.User) (domain.User, error) {
+func (r *AuthPostgres) CreateUser(ctx context.Context, user domain.User) (domain.User, error) {
+	ctx, done := startQuery(ctx, r.timeouts.Write, "AuthPostgres.CreateUser")
+	defer done()
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return domain.User{}, err
+	}
+	defer tx.Rollback()
 	var respUser domain.User
-	query := fmt.Sprintf(`INSERT INTO %s (email,password,role) VALUES ($1,$2,$3) RETURNING email,role`, userListTable)
-	row := r.db.QueryRowx(query, user.Email, user.Password, user.Role)
-	logger.Log.Debug().Str("query", query).Msg("Выполнение запроса регистрации")
-	if err := row.Scan(&respUser.Email, &respUser.Role); err != nil {
+	query := fmt.Sprintf(`INSERT INTO %s (email,password,role,tenant_id) VALUES ($1,$2,$3,$4) RETURNING id,email,role,tenant_id`, userListTable)
+	row := tx.QueryRowxContext(ctx, query, user.Email, user.Password, user.Role, user.TenantId)
+	logger.FromContext(ctx).Debug().Str("query", query).Msg("Выполнение запроса регистрации")
+	if err := row.Scan(&respUser.Id, &respUser.Email, &respUser.Role, &respUser.TenantId); err != nil {
+		return domain.User{}, err
+	}
+	if err := auditTx(ctx, tx, respUser.Id.String(), nil, respUser); err != nil {
+		return domain.User{}, err
+	}
+	if err := tx.Commit(); err != nil {
 		return domain.User{}, err
 	}
-	logger.Log.Debug().Any("user", respUser).Msg("Успешно зарегестрирован пользователь")
+	logger.FromContext(ctx).Debug().Any("user", respUser).Msg("Успешно зарегестрирован пользователь")
 	return respUser, nil
 }
 
-func (r *AuthPostgres) SignUser(email string) (domain.User, error) {
+func (r *AuthPostgres) SignUser(ctx context.Context, tenantId, email string) (domain.User, error) {
+	ctx, done := startQuery(ctx, r.timeouts.Read, "AuthPostgres.SignUser")
+	defer done()
 	var user domain.User
-	query := fmt.Sprintf(`SELECT id,email,password,role FROM %s WHERE email=$1`, userListTable)
-	res := r.db.QueryRowx(query, email)
-	err := res.Scan(&user.Id, &user.Email, &user.Password, &user.Role)
-	logger.Log.Debug().Str("query", query).Msg("Выполнение запроса авторизации")
+	query := fmt.Sprintf(`SELECT id,email,password,role,tenant_id,disabled_at FROM %s WHERE tenant_id=$1 AND email=$2`, userListTable)
+	res := r.db.QueryRowxContext(ctx, query, tenantId, email)
+	err := res.Scan(&user.Id, &user.Email, &user.Password, &user.Role, &user.TenantId, &user.DisabledAt)
+	logger.FromContext(ctx).Debug().Str("query", query).Msg("Выполнение запроса авторизации")
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return domain.User{}, errors.New("пользователь не найден")
+			return domain.User{}, ErrUserNotFound
 		}
 		return domain.User{}, err
 	}
-	logger.Log.Debug().Any("user", user).Msg("Успешно найден пользователь")
+	logger.FromContext(ctx).Debug().Any("user", user).Msg("Успешно найден пользователь")
 	return user, nil
 }
 
@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/bllooop/pvzservice/internal/domain"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPvzPostgres_AutoCloseReceptions(t *testing.T) {
+	now := time.Date(2025, 4, 10, 15, 5, 17, 0, time.UTC)
+	opened := now.Add(-24 * time.Hour)
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	sqlxDB := sqlx.NewDb(db, "postgres")
+	r := NewPvzPostgres(sqlxDB)
+	pvzId := uuid.New()
+	fullId, emptyId, flaggedId := uuid.New(), uuid.New(), uuid.New()
+	closed, cancelled, inProgress := domain.ReceptionClosed, domain.ReceptionCancelled, domain.ReceptionInProgress
+	columns := []string{"id", "date_received", "pvz_id", "status_reception", "flagged_at"}
+	idleColumns := append(append([]string{}, columns...), "tenant_id", "products")
+
+	tests := []struct {
+		name    string
+		policy  domain.AutoClosePolicy
+		audit   bool
+		mock    func()
+		want    domain.AutoCloseResult
+		wantErr bool
+	}{
+		{
+			name:   "Блокировка у другой реплики",
+			policy: domain.AutoClosePolicy{IdleAfter: time.Hour, EmptyAction: domain.EmptyReceptionFlag},
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT pg_try_advisory_xact_lock\(\$1\)`).WithArgs(autoCloseLockKey).
+					WillReturnRows(sqlmock.NewRows([]string{"pg_try_advisory_xact_lock"}).AddRow(false))
+				mock.ExpectRollback()
+			},
+			want: domain.AutoCloseResult{Skipped: true},
+		},
+		{
+			name:   "Закрытие и пометка",
+			policy: domain.AutoClosePolicy{IdleAfter: time.Hour, EmptyAction: domain.EmptyReceptionFlag},
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT pg_try_advisory_xact_lock\(\$1\)`).WithArgs(autoCloseLockKey).
+					WillReturnRows(sqlmock.NewRows([]string{"pg_try_advisory_xact_lock"}).AddRow(true))
+				mock.ExpectQuery(fmt.Sprintf(`(.+) FROM %s r (.+) FOR UPDATE OF r SKIP LOCKED`, receptionTable)).
+					WithArgs(now.Add(-time.Hour)).
+					WillReturnRows(sqlmock.NewRows(idleColumns).
+						AddRow(fullId, opened, pvzId, inProgress, nil, "t1", 3).
+						AddRow(emptyId, opened, pvzId, inProgress, nil, "t1", 0).
+						AddRow(flaggedId, opened, pvzId, inProgress, opened, "t1", 0))
+				mock.ExpectQuery(fmt.Sprintf(`UPDATE %s SET status_reception = \$1`, receptionTable)).
+					WithArgs(closed, fullId).
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(fullId, opened, pvzId, closed, nil))
+				mock.ExpectQuery(fmt.Sprintf(`SELECT id,date_received,pvz_id,status_reception FROM %s WHERE id = \$1`, receptionTable)).
+					WithArgs(fullId, sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "date_received", "pvz_id", "status_reception"}).AddRow(fullId, opened, pvzId, closed))
+				mock.ExpectQuery(fmt.Sprintf(`SELECT (.+) FROM %s WHERE reception_id = \$1 AND deleted_at IS NULL`, productTable)).
+					WithArgs(fullId).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "date_received", "type_product", "reception_id", "pvz_id", "issued_at"}))
+				mock.ExpectExec(fmt.Sprintf(`INSERT INTO %s`, versionsTable)).
+					WithArgs(fullId, 1, nil, "", sqlmock.AnyArg(), nil).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery(fmt.Sprintf(`UPDATE %s SET flagged_at = \$1`, receptionTable)).
+					WithArgs(now, emptyId).
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(emptyId, opened, pvzId, inProgress, now))
+				mock.ExpectCommit()
+			},
+			want: domain.AutoCloseResult{
+				Closed:  []domain.ProductReception{{Id: &fullId, DateReceived: &opened, PVZId: &pvzId, Status: &closed}},
+				Flagged: []domain.ProductReception{{Id: &emptyId, DateReceived: &opened, PVZId: &pvzId, Status: &inProgress, FlaggedAt: &now}},
+			},
+		},
+		{
+			name:   "Отмена пустой приемки",
+			policy: domain.AutoClosePolicy{IdleAfter: time.Hour, EmptyAction: domain.EmptyReceptionCancel},
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT pg_try_advisory_xact_lock\(\$1\)`).WithArgs(autoCloseLockKey).
+					WillReturnRows(sqlmock.NewRows([]string{"pg_try_advisory_xact_lock"}).AddRow(true))
+				mock.ExpectQuery(fmt.Sprintf(`(.+) FROM %s r (.+) FOR UPDATE OF r SKIP LOCKED`, receptionTable)).
+					WithArgs(now.Add(-time.Hour)).
+					WillReturnRows(sqlmock.NewRows(idleColumns).AddRow(emptyId, opened, pvzId, inProgress, nil, "t1", 0))
+				mock.ExpectQuery(fmt.Sprintf(`UPDATE %s SET status_reception = \$1`, receptionTable)).
+					WithArgs(cancelled, emptyId).
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(emptyId, opened, pvzId, cancelled, nil))
+				mock.ExpectCommit()
+			},
+			want: domain.AutoCloseResult{
+				Cancelled: []domain.ProductReception{{Id: &emptyId, DateReceived: &opened, PVZId: &pvzId, Status: &cancelled}},
+			},
+		},
+		{
+			name:   "Аудит в компании приемки",
+			policy: domain.AutoClosePolicy{IdleAfter: time.Hour, EmptyAction: domain.EmptyReceptionCancel},
+			audit:  true,
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT pg_try_advisory_xact_lock\(\$1\)`).WithArgs(autoCloseLockKey).
+					WillReturnRows(sqlmock.NewRows([]string{"pg_try_advisory_xact_lock"}).AddRow(true))
+				mock.ExpectQuery(fmt.Sprintf(`(.+) FROM %s r (.+) FOR UPDATE OF r SKIP LOCKED`, receptionTable)).
+					WithArgs(now.Add(-time.Hour)).
+					WillReturnRows(sqlmock.NewRows(idleColumns).AddRow(emptyId, opened, pvzId, inProgress, nil, "t1", 0))
+				mock.ExpectQuery(fmt.Sprintf(`UPDATE %s SET status_reception = \$1`, receptionTable)).
+					WithArgs(cancelled, emptyId).
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(emptyId, opened, pvzId, cancelled, nil))
+				mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_xact_lock($1)")).WithArgs(auditLockKey).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery(fmt.Sprintf("SELECT hash FROM %s", auditTable)).
+					WillReturnRows(sqlmock.NewRows([]string{"hash"}))
+				mock.ExpectQuery(fmt.Sprintf("INSERT INTO %s", auditTable)).
+					WithArgs(sqlmock.AnyArg(), "system", "", "reception.cancel", "reception", emptyId.String(),
+						sqlmock.AnyArg(), sqlmock.AnyArg(), "", "", "t1", "", sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+				mock.ExpectCommit()
+			},
+			want: domain.AutoCloseResult{
+				Cancelled: []domain.ProductReception{{Id: &emptyId, DateReceived: &opened, PVZId: &pvzId, Status: &cancelled}},
+			},
+		},
+		{
+			name:   "Ошибка БД",
+			policy: domain.AutoClosePolicy{IdleAfter: time.Hour, EmptyAction: domain.EmptyReceptionFlag},
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT pg_try_advisory_xact_lock\(\$1\)`).WithArgs(autoCloseLockKey).
+					WillReturnRows(sqlmock.NewRows([]string{"pg_try_advisory_xact_lock"}).AddRow(true))
+				mock.ExpectQuery(fmt.Sprintf(`(.+) FROM %s r (.+)`, receptionTable)).
+					WillReturnError(errors.New("ошибка бд"))
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			ctx := context.Background()
+			if tt.audit {
+				ctx = WithAudit(ctx, domain.AuditEntry{ActorId: "system", EntityType: "reception"})
+			}
+			got, err := r.AutoCloseReceptions(ctx, tt.policy, now)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bllooop/pvzservice/internal/domain"
+	logger "github.com/bllooop/pvzservice/pkg/logging"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// autoCloseLockKey — ключ advisory-блокировки, под которой выполняется проход
+// автозакрытия. Блокировка берется на время транзакции, поэтому одновременно
+// проход выполняет только один экземпляр сервиса.
+const autoCloseLockKey int64 = 730002
+
+type idleReceptionRow struct {
+	domain.ProductReception
+	TenantId string `db:"tenant_id"`
+	Products int    `db:"products"`
+}
+
+func (r *PvzPostgres) AutoCloseReceptions(ctx context.Context, policy domain.AutoClosePolicy, now time.Time) (domain.AutoCloseResult, error) {
+	ctx, done := startQuery(ctx, r.timeouts.Report, "PvzPostgres.AutoCloseReceptions")
+	defer done()
+	var result domain.AutoCloseResult
+	tx, err := r.beginTx(ctx)
+	if err != nil {
+		return result, err
+	}
+	defer tx.Rollback()
+
+	var locked bool
+	if err := tx.QueryRowxContext(ctx, `SELECT pg_try_advisory_xact_lock($1)`, autoCloseLockKey).Scan(&locked); err != nil {
+		return result, err
+	}
+	if !locked {
+		result.Skipped = true
+		return result, nil
+	}
+
+	idle, err := r.selectIdleReceptions(ctx, tx, now.Add(-policy.IdleAfter))
+	if err != nil {
+		return result, err
+	}
+	for _, row := range idle {
+		// Проход выполняется от имени system для всех компаний, поэтому
+		// компания записи аудита берется из приемки, чтобы модераторы
+		// компании видели эти записи.
+		rowCtx := auditTenant(ctx, row.TenantId)
+		switch {
+		case row.Products > 0:
+			recep, err := r.setReceptionStatus(ctx, tx, *row.Id, domain.ReceptionClosed)
+			if err != nil {
+				return domain.AutoCloseResult{}, err
+			}
+			if err := r.insertClosedVersion(rowCtx, tx, domain.AllTenants(), *row.Id); err != nil {
+				return domain.AutoCloseResult{}, err
+			}
+			if err := auditActionTx(rowCtx, tx, "reception.autoclose", row.Id.String(), row.ProductReception, recep); err != nil {
+				return domain.AutoCloseResult{}, err
+			}
+			result.Closed = append(result.Closed, recep)
+		case policy.EmptyAction == domain.EmptyReceptionCancel:
+			recep, err := r.setReceptionStatus(ctx, tx, *row.Id, domain.ReceptionCancelled)
+			if err != nil {
+				return domain.AutoCloseResult{}, err
+			}
+			if err := auditActionTx(rowCtx, tx, "reception.cancel", row.Id.String(), row.ProductReception, recep); err != nil {
+				return domain.AutoCloseResult{}, err
+			}
+			result.Cancelled = append(result.Cancelled, recep)
+		case row.FlaggedAt == nil:
+			recep, err := r.flagReception(ctx, tx, *row.Id, now)
+			if err != nil {
+				return domain.AutoCloseResult{}, err
+			}
+			if err := auditActionTx(rowCtx, tx, "reception.flag", row.Id.String(), row.ProductReception, recep); err != nil {
+				return domain.AutoCloseResult{}, err
+			}
+			result.Flagged = append(result.Flagged, recep)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return domain.AutoCloseResult{}, err
+	}
+	for _, row := range idle {
+		if row.Products > 0 {
+			observeReceptionClosed(row.ProductReception, row.Products, now)
+		}
+	}
+	return result, nil
+}
+
+// selectIdleReceptions выбирает незакрытые приемки без активности с момента cutoff.
+// Активностью считается создание приемки и добавление в нее товаров.
+func (r *PvzPostgres) selectIdleReceptions(ctx context.Context, tx *sqlx.Tx, cutoff time.Time) ([]idleReceptionRow, error) {
+	query := fmt.Sprintf(`SELECT r.id, r.date_received, r.pvz_id, r.status_reception, r.flagged_at, r.tenant_id,
+  (SELECT COUNT(*) FROM %[2]s p WHERE p.reception_id = r.id AND p.deleted_at IS NULL) AS products
+  FROM %[1]s r
+  WHERE r.status_reception = 'in_progress'
+  AND GREATEST(r.date_received, COALESCE((SELECT MAX(p.date_received) FROM %[2]s p WHERE p.reception_id = r.id), r.date_received)) < $1
+  ORDER BY r.date_received
+  FOR UPDATE OF r SKIP LOCKED`, receptionTable, productTable)
+	logger.FromContext(ctx).Debug().Str("query", query).Msg("Поиск забытых приемок")
+	var rows []idleReceptionRow
+	if err := tx.SelectContext(ctx, &rows, query, cutoff); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+func (r *PvzPostgres) setReceptionStatus(ctx context.Context, tx *sqlx.Tx, recepId uuid.UUID, status string) (domain.ProductReception, error) {
+	query := fmt.Sprintf(`UPDATE %s SET status_reception = $1 WHERE id = $2 RETURNING id, date_received, pvz_id, status_reception, flagged_at`, receptionTable)
+	logger.FromContext(ctx).Debug().Str("query", query).Msg("Смена статуса приемки")
+	var res domain.ProductReception
+	err := tx.QueryRowxContext(ctx, query, status, recepId).Scan(&res.Id, &res.DateReceived, &res.PVZId, &res.Status, &res.FlaggedAt)
+	return res, err
+}
+
+func (r *PvzPostgres) flagReception(ctx context.Context, tx *sqlx.Tx, recepId uuid.UUID, now time.Time) (domain.ProductReception, error) {
+	query := fmt.Sprintf(`UPDATE %s SET flagged_at = $1 WHERE id = $2 RETURNING id, date_received, pvz_id, status_reception, flagged_at`, receptionTable)
+	logger.FromContext(ctx).Debug().Str("query", query).Msg("Пометка пустой приемки")
+	var res domain.ProductReception
+	err := tx.QueryRowxContext(ctx, query, now, recepId).Scan(&res.Id, &res.DateReceived, &res.PVZId, &res.Status, &res.FlaggedAt)
+	return res, err
+}
@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/bllooop/pvzservice/internal/domain"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdempotencyPostgres_ReserveIdempotencyKey(t *testing.T) {
+	fixedTime := time.Date(2025, 4, 10, 15, 5, 17, 0, time.UTC)
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	sqlxDB := sqlx.NewDb(db, "postgres")
+	r := NewIdempotencyPostgres(sqlxDB)
+	record := domain.IdempotencyRecord{
+		Scope:       "u1",
+		Key:         "k1",
+		RequestHash: "hash",
+		CreatedAt:   fixedTime,
+		ExpiresAt:   fixedTime.Add(24 * time.Hour),
+	}
+
+	tests := []struct {
+		name         string
+		mock         func()
+		want         domain.IdempotencyRecord
+		wantReserved bool
+	}{
+		{
+			name: "Ключ свободен",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectExec(fmt.Sprintf("DELETE FROM %s", idempotencyTable)).
+					WithArgs("u1", "k1", fixedTime).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(fmt.Sprintf("INSERT INTO %s (.+) ON CONFLICT", idempotencyTable)).
+					WithArgs("u1", "k1", "hash", fixedTime, record.ExpiresAt).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			want:         record,
+			wantReserved: true,
+		},
+		{
+			name: "Сохраненный ответ",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectExec(fmt.Sprintf("DELETE FROM %s", idempotencyTable)).
+					WithArgs("u1", "k1", fixedTime).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(fmt.Sprintf("INSERT INTO %s (.+) ON CONFLICT", idempotencyTable)).
+					WithArgs("u1", "k1", "hash", fixedTime, record.ExpiresAt).WillReturnResult(sqlmock.NewResult(0, 0))
+				rows := sqlmock.NewRows([]string{"request_hash", "status_code", "response", "created_at", "expires_at"}).
+					AddRow("hash", 200, []byte(`{"message":"ok"}`), fixedTime, record.ExpiresAt)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s", idempotencyTable)).
+					WithArgs("u1", "k1").WillReturnRows(rows)
+				mock.ExpectCommit()
+			},
+			want: domain.IdempotencyRecord{
+				Scope:       "u1",
+				Key:         "k1",
+				RequestHash: "hash",
+				StatusCode:  200,
+				Response:    []byte(`{"message":"ok"}`),
+				CreatedAt:   fixedTime,
+				ExpiresAt:   record.ExpiresAt,
+			},
+		},
+		{
+			name: "Запрос еще выполняется",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectExec(fmt.Sprintf("DELETE FROM %s", idempotencyTable)).
+					WithArgs("u1", "k1", fixedTime).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(fmt.Sprintf("INSERT INTO %s (.+) ON CONFLICT", idempotencyTable)).
+					WithArgs("u1", "k1", "hash", fixedTime, record.ExpiresAt).WillReturnResult(sqlmock.NewResult(0, 0))
+				rows := sqlmock.NewRows([]string{"request_hash", "status_code", "response", "created_at", "expires_at"}).
+					AddRow("hash", nil, nil, fixedTime, record.ExpiresAt)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s", idempotencyTable)).
+					WithArgs("u1", "k1").WillReturnRows(rows)
+				mock.ExpectCommit()
+			},
+			want: domain.IdempotencyRecord{
+				Scope:       "u1",
+				Key:         "k1",
+				RequestHash: "hash",
+				CreatedAt:   fixedTime,
+				ExpiresAt:   record.ExpiresAt,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			got, reserved, err := r.ReserveIdempotencyKey(context.Background(), record)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantReserved, reserved)
+			assert.Equal(t, tt.want, got)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bllooop/pvzservice/internal/domain"
+	logger "github.com/bllooop/pvzservice/pkg/logging"
+	"github.com/jmoiron/sqlx"
+)
+
+type IdempotencyPostgres struct {
+	db       *sqlx.DB
+	timeouts Timeouts
+}
+
+func NewIdempotencyPostgres(db *sqlx.DB) *IdempotencyPostgres {
+	return &IdempotencyPostgres{
+		db:       db,
+		timeouts: DefaultTimeouts,
+	}
+}
+
+// ReserveIdempotencyKey занимает ключ за текущим запросом. Если ключ уже занят
+// и не истек, возвращается существующая запись и false.
+func (r *IdempotencyPostgres) ReserveIdempotencyKey(ctx context.Context, record domain.IdempotencyRecord) (domain.IdempotencyRecord, bool, error) {
+	ctx, done := startQuery(ctx, r.timeouts.Write, "IdempotencyPostgres.ReserveIdempotencyKey")
+	defer done()
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return domain.IdempotencyRecord{}, false, err
+	}
+	defer tx.Rollback()
+
+	query := fmt.Sprintf(`DELETE FROM %s WHERE scope=$1 AND idem_key=$2 AND expires_at <= $3`, idempotencyTable)
+	if _, err := tx.ExecContext(ctx, query, record.Scope, record.Key, record.CreatedAt); err != nil {
+		return domain.IdempotencyRecord{}, false, err
+	}
+	query = fmt.Sprintf(`INSERT INTO %s (scope,idem_key,request_hash,created_at,expires_at) VALUES ($1,$2,$3,$4,$5)
+ON CONFLICT (scope, idem_key) DO NOTHING`, idempotencyTable)
+	logger.FromContext(ctx).Debug().Str("query", query).Msg("Резервирование ключа идемпотентности")
+	res, err := tx.ExecContext(ctx, query, record.Scope, record.Key, record.RequestHash, record.CreatedAt, record.ExpiresAt)
+	if err != nil {
+		return domain.IdempotencyRecord{}, false, err
+	}
+	inserted, err := res.RowsAffected()
+	if err != nil {
+		return domain.IdempotencyRecord{}, false, err
+	}
+	if inserted == 1 {
+		if err := tx.Commit(); err != nil {
+			return domain.IdempotencyRecord{}, false, err
+		}
+		return record, true, nil
+	}
+
+	var existing domain.IdempotencyRecord
+	var status sql.NullInt64
+	query = fmt.Sprintf(`SELECT request_hash,status_code,response,created_at,expires_at FROM %s WHERE scope=$1 AND idem_key=$2`, idempotencyTable)
+	err = tx.QueryRowxContext(ctx, query, record.Scope, record.Key).
+		Scan(&existing.RequestHash, &status, &existing.Response, &existing.CreatedAt, &existing.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// запись удалили между вставкой и чтением, клиент может повторить запрос
+			return domain.IdempotencyRecord{}, false, errors.New("ключ идемпотентности освобожден во время проверки")
+		}
+		return domain.IdempotencyRecord{}, false, err
+	}
+	existing.Scope = record.Scope
+	existing.Key = record.Key
+	existing.StatusCode = int(status.Int64)
+	return existing, false, tx.Commit()
+}
+
+func (r *IdempotencyPostgres) CompleteIdempotencyKey(ctx context.Context, scope, key string, statusCode int, response []byte) error {
+	ctx, done := startQuery(ctx, r.timeouts.Write, "IdempotencyPostgres.CompleteIdempotencyKey")
+	defer done()
+	query := fmt.Sprintf(`UPDATE %s SET status_code=$1, response=$2 WHERE scope=$3 AND idem_key=$4`, idempotencyTable)
+	logger.FromContext(ctx).Debug().Str("query", query).Msg("Сохранение ответа по ключу идемпотентности")
+	_, err := r.db.ExecContext(ctx, query, statusCode, response, scope, key)
+	return err
+}
+
+func (r *IdempotencyPostgres) ReleaseIdempotencyKey(ctx context.Context, scope, key string) error {
+	ctx, done := startQuery(ctx, r.timeouts.Write, "IdempotencyPostgres.ReleaseIdempotencyKey")
+	defer done()
+	query := fmt.Sprintf(`DELETE FROM %s WHERE scope=$1 AND idem_key=$2 AND status_code IS NULL`, idempotencyTable)
+	logger.FromContext(ctx).Debug().Str("query", query).Msg("Освобождение ключа идемпотентности")
+	_, err := r.db.ExecContext(ctx, query, scope, key)
+	return err
+}
+
+func (r *IdempotencyPostgres) DeleteExpiredIdempotencyKeys(ctx context.Context, before time.Time) (int64, error) {
+	ctx, done := startQuery(ctx, r.timeouts.Write, "IdempotencyPostgres.DeleteExpiredIdempotencyKeys")
+	defer done()
+	query := fmt.Sprintf(`DELETE FROM %s WHERE expires_at <= $1`, idempotencyTable)
+	res, err := r.db.ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/bllooop/pvzservice/internal/repository"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewPostgresDB(t *testing.T) {
@@ -20,8 +21,8 @@ func TestNewPostgresDB(t *testing.T) {
 	tempCfg := cfg
 	tempCfg.DBname = "postgres"
 	db, err := repository.NewPostgresDB(tempCfg)
-	assert.NoError(t, err)
-	assert.NotNil(t, db)
+	require.NoError(t, err)
+	require.NotNil(t, db)
 
 	var exists bool
 	err = db.QueryRow(`SELECT EXISTS (
@@ -36,8 +37,8 @@ func TestNewPostgresDB(t *testing.T) {
 
 	tempCfg.DBname = "test-db"
 	db, err = repository.NewPostgresDB(tempCfg)
-	assert.NoError(t, err)
-	assert.NotNil(t, db)
+	require.NoError(t, err)
+	require.NotNil(t, db)
 
 	err = db.Ping()
 	assert.NoError(t, err)
@@ -94,7 +94,7 @@ func (suite *PvzRepoTestSuite) TestPvzWork() {
 		City:         "Москва",
 	}
 
-	createdPvz, err := suite.repository.CreatePvz(input)
+	createdPvz, err := suite.repository.CreatePvz(context.Background(), domain.TenantOf(domain.DefaultTenant), input)
 	if err != nil {
 		t.Fatalf("Failed to createPvz: %s", err)
 	}
@@ -110,7 +110,7 @@ func (suite *PvzRepoTestSuite) TestPvzWork() {
 		Status:       &stat,
 		PVZId:        &pvzIDTest,
 	}
-	createdRecep, err := suite.repository.CreateRecep(inputRecep)
+	createdRecep, err := suite.repository.CreateRecep(context.Background(), domain.TenantOf(domain.DefaultTenant), inputRecep)
 	if err != nil {
 		t.Fatalf("Failed to createRecep: %s", err)
 	}
@@ -128,7 +128,7 @@ func (suite *PvzRepoTestSuite) TestPvzWork() {
 			Type:         typeProd,
 		}
 
-		addedProd, err := suite.repository.AddProdToRecep(inputProd)
+		addedProd, err := suite.repository.AddProdToRecep(context.Background(), domain.TenantOf(domain.DefaultTenant), inputProd)
 		if err != nil {
 			t.Fatalf("Failed to add Product: %s", err)
 		}
@@ -138,7 +138,7 @@ func (suite *PvzRepoTestSuite) TestPvzWork() {
 		assert.Equal(t, receptionID, *addedProd.ReceptionId)
 		assert.Equal(t, typeProd, addedProd.Type)
 	}
-	closedRecep, err := suite.repository.CloseReception(*inputRecep.PVZId)
+	closedRecep, err := suite.repository.CloseReception(context.Background(), domain.TenantOf(domain.DefaultTenant), *inputRecep.PVZId)
 	if err != nil {
 		t.Fatalf("Failed to close Reception: %s", err)
 	}
@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/bllooop/pvzservice/internal/domain"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoginAttemptsPostgres_UpdateLoginAttempt(t *testing.T) {
+	fixedTime := time.Date(2025, 4, 10, 15, 5, 17, 0, time.UTC)
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	sqlxDB := sqlx.NewDb(db, "postgres")
+	r := NewLoginAttemptsPostgres(sqlxDB)
+	errBlocked := errors.New("заблокирован")
+
+	tests := []struct {
+		name    string
+		mock    func()
+		update  func(domain.LoginAttempt) (domain.LoginAttempt, error)
+		want    domain.LoginAttempt
+		wantErr error
+	}{
+		{
+			name: "Ok",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectExec(fmt.Sprintf("INSERT INTO %s (.+) ON CONFLICT", loginAttemptsTable)).
+					WithArgs("email:test").WillReturnResult(sqlmock.NewResult(0, 0))
+				rows := sqlmock.NewRows([]string{"failures", "last_failure_at", "locked_until"}).AddRow(2, fixedTime, nil)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE attempt_key=\\$1 FOR UPDATE", loginAttemptsTable)).
+					WithArgs("email:test").WillReturnRows(rows)
+				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET failures", loginAttemptsTable)).
+					WithArgs("email:test", 3, &fixedTime, nil).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			update: func(a domain.LoginAttempt) (domain.LoginAttempt, error) {
+				a.Failures++
+				return a, nil
+			},
+			want: domain.LoginAttempt{Key: "email:test", Failures: 3, LastFailureAt: &fixedTime},
+		},
+		{
+			name: "Попытка отклонена",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectExec(fmt.Sprintf("INSERT INTO %s", loginAttemptsTable)).
+					WithArgs("email:test").WillReturnResult(sqlmock.NewResult(0, 0))
+				rows := sqlmock.NewRows([]string{"failures", "last_failure_at", "locked_until"}).AddRow(5, fixedTime, fixedTime)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s", loginAttemptsTable)).
+					WithArgs("email:test").WillReturnRows(rows)
+				mock.ExpectRollback()
+			},
+			update: func(a domain.LoginAttempt) (domain.LoginAttempt, error) {
+				return a, errBlocked
+			},
+			wantErr: errBlocked,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			got, err := r.UpdateLoginAttempt(context.Background(), "email:test", tt.update)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestLoginAttemptsMemory(t *testing.T) {
+	start := time.Date(2025, 4, 10, 15, 0, 0, 0, time.UTC)
+	r := NewLoginAttemptsMemory(15 * time.Minute)
+	r.now = func() time.Time { return start }
+	increment := func(a domain.LoginAttempt) (domain.LoginAttempt, error) {
+		a.Failures++
+		a.LastFailureAt = &start
+		return a, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := r.UpdateLoginAttempt(context.Background(), "ip:1", increment)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	got, err := r.UpdateLoginAttempt(context.Background(), "ip:1", func(a domain.LoginAttempt) (domain.LoginAttempt, error) {
+		return a, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 50, got.Failures, "параллельные попытки учитываются без потерь")
+
+	errBlocked := errors.New("заблокирован")
+	_, err = r.UpdateLoginAttempt(context.Background(), "ip:1", func(a domain.LoginAttempt) (domain.LoginAttempt, error) {
+		a.Failures = 0
+		return a, errBlocked
+	})
+	assert.ErrorIs(t, err, errBlocked)
+	assert.Equal(t, 50, r.attempts["ip:1"].Failures, "отклоненная попытка не меняет счетчик")
+
+	assert.NoError(t, r.ResetLoginAttempts(context.Background(), "ip:1"))
+	assert.NotContains(t, r.attempts, "ip:1")
+}
+
+func TestLoginAttemptsMemory_sweep(t *testing.T) {
+	start := time.Date(2025, 4, 10, 15, 0, 0, 0, time.UTC)
+	r := NewLoginAttemptsMemory(15 * time.Minute)
+	now := start
+	r.now = func() time.Time { return now }
+	lockedUntil := start.Add(time.Hour)
+	r.attempts["ip:old"] = domain.LoginAttempt{Key: "ip:old", Failures: 1, LastFailureAt: &start}
+	r.attempts["ip:locked"] = domain.LoginAttempt{Key: "ip:locked", Failures: 20, LastFailureAt: &start, LockedUntil: &lockedUntil}
+
+	now = start.Add(30 * time.Minute)
+	_, err := r.UpdateLoginAttempt(context.Background(), "ip:new", func(a domain.LoginAttempt) (domain.LoginAttempt, error) {
+		a.Failures++
+		a.LastFailureAt = &now
+		return a, nil
+	})
+	assert.NoError(t, err)
+	assert.NotContains(t, r.attempts, "ip:old", "устаревший счетчик удален")
+	assert.Contains(t, r.attempts, "ip:locked", "действующая блокировка сохраняется")
+	assert.Contains(t, r.attempts, "ip:new")
+}
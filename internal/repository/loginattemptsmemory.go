@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/bllooop/pvzservice/internal/domain"
+)
+
+// loginAttemptsSweepInterval — как часто LoginAttemptsMemory удаляет
+// устаревшие счетчики.
+const loginAttemptsSweepInterval = time.Minute
+
+// LoginAttemptsMemory хранит счетчики попыток входа в памяти процесса.
+// Подходит для одного экземпляра сервиса и для тестов. Счетчики без ошибок и
+// блокировок за последние retention удаляются, чтобы перебор случайных
+// адресов и почт не занимал память бесконечно.
+type LoginAttemptsMemory struct {
+	mu        sync.Mutex
+	attempts  map[string]domain.LoginAttempt
+	retention time.Duration
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewLoginAttemptsMemory(retention time.Duration) *LoginAttemptsMemory {
+	return &LoginAttemptsMemory{
+		attempts:  make(map[string]domain.LoginAttempt),
+		retention: retention,
+		now:       time.Now,
+	}
+}
+
+func (r *LoginAttemptsMemory) UpdateLoginAttempt(ctx context.Context, key string, update func(domain.LoginAttempt) (domain.LoginAttempt, error)) (domain.LoginAttempt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sweep()
+	attempt, ok := r.attempts[key]
+	if !ok {
+		attempt = domain.LoginAttempt{Key: key}
+	}
+	updated, err := update(attempt)
+	if err != nil {
+		return attempt, err
+	}
+	updated.Key = key
+	r.attempts[key] = updated
+	return updated, nil
+}
+
+func (r *LoginAttemptsMemory) ResetLoginAttempts(ctx context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.attempts, key)
+	return nil
+}
+
+// sweep удаляет устаревшие счетчики не чаще loginAttemptsSweepInterval.
+// Вызывается под r.mu.
+func (r *LoginAttemptsMemory) sweep() {
+	now := r.now()
+	if r.retention <= 0 || now.Sub(r.lastSweep) < loginAttemptsSweepInterval {
+		return
+	}
+	r.lastSweep = now
+	expired := now.Add(-r.retention)
+	for key, attempt := range r.attempts {
+		if attempt.LockedUntil != nil && attempt.LockedUntil.After(now) {
+			continue
+		}
+		if attempt.LastFailureAt == nil || attempt.LastFailureAt.Before(expired) {
+			delete(r.attempts, key)
+		}
+	}
+}
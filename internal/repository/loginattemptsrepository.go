@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bllooop/pvzservice/internal/domain"
+	logger "github.com/bllooop/pvzservice/pkg/logging"
+	"github.com/jmoiron/sqlx"
+)
+
+type LoginAttemptsPostgres struct {
+	db       *sqlx.DB
+	timeouts Timeouts
+}
+
+func NewLoginAttemptsPostgres(db *sqlx.DB) *LoginAttemptsPostgres {
+	return &LoginAttemptsPostgres{
+		db:       db,
+		timeouts: DefaultTimeouts,
+	}
+}
+
+// UpdateLoginAttempt блокирует строку счетчика до конца транзакции, поэтому
+// параллельные попытки входа с тем же ключом выполняются по очереди.
+func (r *LoginAttemptsPostgres) UpdateLoginAttempt(ctx context.Context, key string, update func(domain.LoginAttempt) (domain.LoginAttempt, error)) (domain.LoginAttempt, error) {
+	ctx, done := startQuery(ctx, r.timeouts.Write, "LoginAttemptsPostgres.UpdateLoginAttempt")
+	defer done()
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return domain.LoginAttempt{}, err
+	}
+	defer tx.Rollback()
+	query := fmt.Sprintf(`INSERT INTO %s (attempt_key) VALUES ($1) ON CONFLICT (attempt_key) DO NOTHING`, loginAttemptsTable)
+	if _, err := tx.ExecContext(ctx, query, key); err != nil {
+		return domain.LoginAttempt{}, err
+	}
+	attempt := domain.LoginAttempt{Key: key}
+	query = fmt.Sprintf(`SELECT failures,last_failure_at,locked_until FROM %s WHERE attempt_key=$1 FOR UPDATE`, loginAttemptsTable)
+	logger.FromContext(ctx).Debug().Str("query", query).Msg("Блокировка счетчика попыток входа")
+	if err := tx.QueryRowxContext(ctx, query, key).Scan(&attempt.Failures, &attempt.LastFailureAt, &attempt.LockedUntil); err != nil {
+		return domain.LoginAttempt{}, err
+	}
+	updated, err := update(attempt)
+	if err != nil {
+		return attempt, err
+	}
+	query = fmt.Sprintf(`UPDATE %s SET failures=$2,last_failure_at=$3,locked_until=$4 WHERE attempt_key=$1`, loginAttemptsTable)
+	logger.FromContext(ctx).Debug().Str("query", query).Msg("Обновление счетчика попыток входа")
+	if _, err := tx.ExecContext(ctx, query, key, updated.Failures, updated.LastFailureAt, updated.LockedUntil); err != nil {
+		return domain.LoginAttempt{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return domain.LoginAttempt{}, err
+	}
+	updated.Key = key
+	return updated, nil
+}
+
+func (r *LoginAttemptsPostgres) ResetLoginAttempts(ctx context.Context, key string) error {
+	ctx, done := startQuery(ctx, r.timeouts.Write, "LoginAttemptsPostgres.ResetLoginAttempts")
+	defer done()
+	query := fmt.Sprintf(`DELETE FROM %s WHERE attempt_key=$1`, loginAttemptsTable)
+	logger.FromContext(ctx).Debug().Str("query", query).Msg("Сброс попыток входа")
+	_, err := r.db.ExecContext(ctx, query, key)
+	return err
+}
@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/bllooop/pvzservice/internal/domain"
+	"github.com/bllooop/pvzservice/prometheus"
+)
+
+// startQuery ограничивает ctx таймаутом timeout и засекает время выполнения
+// метода репозитория. Если к завершению метода ctx отменен, запрос
+// учитывается как отмененный клиентом или прерванный по таймауту.
+// Вызывается как
+//
+//	ctx, done := startQuery(ctx, r.timeouts.Read, "Тип.Метод")
+//	defer done()
+func startQuery(ctx context.Context, timeout time.Duration, method string) (context.Context, func()) {
+	start := time.Now()
+	cancel := context.CancelFunc(func() {})
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	}
+	return ctx, func() {
+		prometheus.DBQueryDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+		switch err := ctx.Err(); {
+		case errors.Is(err, context.DeadlineExceeded):
+			prometheus.DBQueriesCancelledTotal.WithLabelValues(method, "timeout").Inc()
+		case errors.Is(err, context.Canceled):
+			prometheus.DBQueriesCancelledTotal.WithLabelValues(method, "canceled").Inc()
+		}
+		cancel()
+	}
+}
+
+// observeReceptionClosed учитывает длительность и размер закрытой приемки.
+func observeReceptionClosed(recep domain.ProductReception, products int, closedAt time.Time) {
+	if recep.DateReceived != nil {
+		prometheus.ReceptionDuration.Observe(closedAt.Sub(*recep.DateReceived).Seconds())
+	}
+	prometheus.ProductsPerReception.Observe(float64(products))
+}
@@ -1,18 +1,21 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"math"
 
 	logger "github.com/bllooop/pvzservice/pkg/logging"
 	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/jmoiron/sqlx"
 	"github.com/pressly/goose"
 )
 
 func RunMigrate(cfg Config, migratePath string) error {
 	connStr := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=%s",
 		cfg.Username, cfg.Password, cfg.Host, cfg.Port, cfg.DBname, cfg.SSLMode)
-	logger.Log.Debug().Str("conn", connStr).Msg("Обработка подключения к БД")
+	logger.Log.Debug().Str("host", cfg.Host).Str("dbname", cfg.DBname).Str("path", migratePath).Msg("Обработка подключения к БД")
 
 	db, err := sql.Open("pgx", connStr)
 	if err != nil {
@@ -31,3 +34,30 @@ func RunMigrate(cfg Config, migratePath string) error {
 	logger.Log.Info().Msg("Миграция прошла успешно!")
 	return nil
 }
+
+// LatestMigration возвращает версию последней миграции в migratePath — ту,
+// до которой RunMigrate должен довести базу.
+func LatestMigration(migratePath string) (int64, error) {
+	migrations, err := goose.CollectMigrations(migratePath, 0, math.MaxInt64)
+	if err != nil {
+		return 0, err
+	}
+	last, err := migrations.Last()
+	if err != nil {
+		return 0, err
+	}
+	return last.Version, nil
+}
+
+// MigrationVersion возвращает версию последней примененной миграции. Откаченные
+// миграции не учитываются: по каждой версии смотрится последняя запись goose.
+func MigrationVersion(ctx context.Context, db *sqlx.DB) (int64, error) {
+	query := `SELECT COALESCE(MAX(version_id), 0) FROM (
+  SELECT DISTINCT ON (version_id) version_id, is_applied FROM goose_db_version ORDER BY version_id, id DESC
+  ) v WHERE v.is_applied`
+	var version int64
+	if err := db.QueryRowxContext(ctx, query).Scan(&version); err != nil {
+		return 0, err
+	}
+	return version, nil
+}
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	logger "github.com/bllooop/pvzservice/pkg/logging"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthPostgres_ConsumeResetToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	userID, err := uuid.NewRandom()
+	if err != nil {
+		panic(err)
+	}
+	sqlxDB := sqlx.NewDb(db, "postgres")
+	r := NewAuthPostgres(sqlxDB)
+
+	tests := []struct {
+		name    string
+		mock    func()
+		want    uuid.UUID
+		wantErr error
+	}{
+		{
+			name: "Ok",
+			mock: func() {
+				mock.ExpectBegin()
+				rows := sqlmock.NewRows([]string{"user_id"}).AddRow(userID)
+				mock.ExpectQuery(fmt.Sprintf("SELECT user_id FROM %s (.+) FOR UPDATE", resetTokensTable)).
+					WithArgs("hash").WillReturnRows(rows)
+				mock.ExpectQuery(fmt.Sprintf("UPDATE %s SET password", userListTable)).
+					WithArgs("newhash", userID).WillReturnRows(sqlmock.NewRows([]string{"tenant_id"}).AddRow("t1"))
+				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET used_at", resetTokensTable)).
+					WithArgs(userID).WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectCommit()
+			},
+			want: userID,
+		},
+		{
+			name: "Токен недействителен",
+			mock: func() {
+				mock.ExpectBegin()
+				rows := sqlmock.NewRows([]string{"user_id"})
+				mock.ExpectQuery(fmt.Sprintf("SELECT user_id FROM %s (.+) FOR UPDATE", resetTokensTable)).
+					WithArgs("hash").WillReturnRows(rows)
+				mock.ExpectRollback()
+			},
+			wantErr: ErrResetTokenInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			got, err := r.ConsumeResetToken(context.Background(), "hash", "newhash")
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestAuthPostgres_CreateResetTokenRequestLogger(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	r := NewAuthPostgres(sqlx.NewDb(db, "postgres"))
+
+	var buf bytes.Buffer
+	ctx := logger.WithLogger(context.Background(), zerolog.New(&buf).With().Str("request_id", "req-42").Logger())
+	userID := uuid.New()
+	expiresAt := time.Now().Add(time.Hour)
+	mock.ExpectBegin()
+	mock.ExpectQuery(fmt.Sprintf("INSERT INTO %s", resetTokensTable)).
+		WithArgs(userID, "hash", expiresAt).WillReturnRows(sqlmock.NewRows([]string{"tenant_id"}).AddRow("t1"))
+	mock.ExpectCommit()
+
+	assert.NoError(t, r.CreateResetToken(ctx, userID, "hash", expiresAt))
+	assert.Contains(t, buf.String(), `"request_id":"req-42"`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	logger "github.com/bllooop/pvzservice/pkg/logging"
+	"github.com/google/uuid"
+)
+
+var ErrResetTokenInvalid = errors.New("токен сброса пароля недействителен или истек")
+
+func (r *AuthPostgres) CreateResetToken(ctx context.Context, userId uuid.UUID, tokenHash string, expiresAt time.Time) error {
+	ctx, done := startQuery(ctx, r.timeouts.Write, "AuthPostgres.CreateResetToken")
+	defer done()
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	var tenantId string
+	query := fmt.Sprintf(`INSERT INTO %s (user_id,token_hash,expires_at) VALUES ($1,$2,$3)
+  RETURNING (SELECT tenant_id FROM %s WHERE id = $1)`, resetTokensTable, userListTable)
+	logger.FromContext(ctx).Debug().Str("query", query).Msg("Создание токена сброса пароля")
+	if err := tx.QueryRowxContext(ctx, query, userId, tokenHash, expiresAt).Scan(&tenantId); err != nil {
+		return err
+	}
+	if err := auditTx(auditTenant(ctx, tenantId), tx, userId.String(), nil, map[string]time.Time{"expiresAt": expiresAt}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *AuthPostgres) ConsumeResetToken(ctx context.Context, tokenHash string, passwordHash string) (uuid.UUID, error) {
+	ctx, done := startQuery(ctx, r.timeouts.Write, "AuthPostgres.ConsumeResetToken")
+	defer done()
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	defer tx.Rollback()
+
+	var userId uuid.UUID
+	query := fmt.Sprintf(`SELECT user_id FROM %s WHERE token_hash=$1 AND used_at IS NULL AND expires_at > now() FOR UPDATE`, resetTokensTable)
+	logger.FromContext(ctx).Debug().Str("query", query).Msg("Проверка токена сброса пароля")
+	if err := tx.QueryRowxContext(ctx, query, tokenHash).Scan(&userId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return uuid.Nil, ErrResetTokenInvalid
+		}
+		return uuid.Nil, err
+	}
+	var tenantId string
+	query = fmt.Sprintf(`UPDATE %s SET password=$1, password_changed_at=now() WHERE id=$2 RETURNING tenant_id`, userListTable)
+	logger.FromContext(ctx).Debug().Str("query", query).Msg("Смена пароля по токену")
+	if err := tx.QueryRowxContext(ctx, query, passwordHash, userId).Scan(&tenantId); err != nil {
+		return uuid.Nil, err
+	}
+	query = fmt.Sprintf(`UPDATE %s SET used_at=now() WHERE user_id=$1 AND used_at IS NULL`, resetTokensTable)
+	if _, err := tx.ExecContext(ctx, query, userId); err != nil {
+		return uuid.Nil, err
+	}
+	if err := auditTx(auditTenant(ctx, tenantId), tx, userId.String(), nil, nil); err != nil {
+		return uuid.Nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return uuid.Nil, err
+	}
+	return userId, nil
+}
+
+func (r *AuthPostgres) InvalidateResetTokens(ctx context.Context, userId uuid.UUID) error {
+	ctx, done := startQuery(ctx, r.timeouts.Write, "AuthPostgres.InvalidateResetTokens")
+	defer done()
+	query := fmt.Sprintf(`UPDATE %s SET used_at=now() WHERE user_id=$1 AND used_at IS NULL`, resetTokensTable)
+	logger.FromContext(ctx).Debug().Str("query", query).Msg("Аннулирование токенов сброса пароля")
+	_, err := r.db.ExecContext(ctx, query, userId)
+	return err
+}
@@ -1,11 +1,15 @@
 package repository
 
 import (
+	"context"
 	"fmt"
+	"time"
 
+	"github.com/XSAM/otelsql"
 	logger "github.com/bllooop/pvzservice/pkg/logging"
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/jmoiron/sqlx"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
 )
 
 type Config struct {
@@ -17,22 +21,71 @@ type Config struct {
 	SSLMode  string
 }
 
+// Timeouts - ограничения времени запросов к базе по видам операций. Запрос,
+// не уложившийся в таймаут, отменяется, транзакция откатывается. Нулевое
+// значение снимает ограничение.
+type Timeouts struct {
+	Read   time.Duration
+	Write  time.Duration
+	Report time.Duration
+}
+
+// DefaultTimeouts - таймауты запросов, если они не заданы.
+var DefaultTimeouts = Timeouts{Read: 5 * time.Second, Write: 5 * time.Second, Report: 30 * time.Second}
+
 const (
 	userListTable  = "userlist"
 	pvzTable       = "pvz"
 	receptionTable = "product_reception"
 	productTable   = "product"
+
+	pvzStatusTable = "pvz_status_history"
+
+	correctionsTable = "product_corrections"
+	amendmentsTable  = "reception_amendments"
+	versionsTable    = "reception_versions"
+	transfersTable   = "product_transfers"
+
+	loginAttemptsTable = "login_attempts"
+	resetTokensTable   = "password_reset_tokens"
+	auditTable         = "audit_log"
+	idempotencyTable   = "idempotency_keys"
 )
 
 func NewPostgresDB(cfg Config) (*sqlx.DB, error) {
 	logger.Log.Info().Msg("Подключение к базе данных")
 	constring := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=%s", cfg.Username, cfg.Password, cfg.Host, cfg.Port, cfg.DBname, cfg.SSLMode)
-	db, err := sqlx.Open("pgx", constring)
-	connStr := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=%s",
-		cfg.Username, cfg.Password, cfg.Host, cfg.Port, cfg.DBname, cfg.SSLMode)
-	logger.Log.Debug().Str("conn", connStr).Msg("Обработка подключения к БД")
+	db, err := openPostgres(constring)
+	logger.Log.Debug().Str("host", cfg.Host).Str("port", cfg.Port).Str("dbname", cfg.DBname).
+		Str("user", cfg.Username).Msg("Обработка подключения к БД")
 	if err != nil {
 		return nil, err
 	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("база данных недоступна: %w", err)
+	}
 	return db, nil
 }
+
+// NewReplicaDB открывает пул соединений с репликой по cfg.DSN. В отличие от
+// NewPostgresDB доступность не проверяется: сервис запускается и без
+// реплики, а ее состояние отслеживает ReplicaRouter.
+func NewReplicaDB(cfg ReplicaConfig) (*sqlx.DB, error) {
+	logger.Log.Info().Msg("Подключение к реплике базы данных")
+	return openPostgres(cfg.DSN)
+}
+
+func openPostgres(dsn string) (*sqlx.DB, error) {
+	// Драйвер обернут otelsql: каждый запрос получает спан, дочерний к спану
+	// из переданного в запрос контекста.
+	sqlDB, err := otelsql.Open("pgx", dsn,
+		otelsql.WithAttributes(semconv.DBSystemPostgreSQL),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{OmitConnResetSession: true, OmitRows: true}))
+	if err != nil {
+		return nil, err
+	}
+	return sqlx.NewDb(sqlDB, "pgx"), nil
+}
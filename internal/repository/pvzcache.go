@@ -0,0 +1,234 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/bllooop/pvzservice/internal/domain"
+	"github.com/bllooop/pvzservice/prometheus"
+	"github.com/google/uuid"
+	"golang.org/x/sync/singleflight"
+)
+
+// DefaultCacheTTL - время жизни записей кэша ПВЗ, если оно не задано.
+const DefaultCacheTTL = 30 * time.Second
+
+// cacheGenerations - версии данных, входящие в ключи кэша. Запись на этом
+// экземпляре увеличивает версию компании, и следующие чтения идут мимо
+// старых записей, даже если их TTL еще не истек. Чтения всех компаний
+// суперадминистратором зависят от записей в любой компании.
+type cacheGenerations struct {
+	mu      sync.Mutex
+	epoch   uint64
+	all     uint64
+	tenants map[string]uint64
+}
+
+func newCacheGenerations() *cacheGenerations {
+	return &cacheGenerations{tenants: make(map[string]uint64)}
+}
+
+func (g *cacheGenerations) version(scope domain.TenantScope) string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if scope.All {
+		return fmt.Sprintf("*:%d.%d", g.epoch, g.all)
+	}
+	return fmt.Sprintf("%s:%d.%d", scope.TenantId, g.epoch, g.tenants[scope.TenantId])
+}
+
+// invalidate отмечает запись в данных scope.
+func (g *cacheGenerations) invalidate(scope domain.TenantScope) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if scope.All {
+		g.epoch++
+		return
+	}
+	g.tenants[scope.TenantId]++
+	g.all++
+}
+
+// invalidateAll отмечает запись, затронувшую неизвестные компании.
+func (g *cacheGenerations) invalidateAll() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.epoch++
+}
+
+// PvzCache кэширует списки ПВЗ и сводки по ним поверх Pvz. Одновременные
+// промахи по одному ключу выполняют один запрос к базе. Записи через
+// PvzCache и через обертки WithPvzCache сразу делают кэш неактуальным на
+// этом экземпляре, записи других экземпляров видны не позже чем через ttl.
+type PvzCache struct {
+	Pvz
+	cache Cache
+	ttl   time.Duration
+	gens  *cacheGenerations
+	group singleflight.Group
+}
+
+// WithPvzCache подключает кэш к repo: заменяет Pvz на PvzCache, а
+// Amendments, Transfers, ReceptionAutoClose и PvzCapacity - на обертки,
+// которые сбрасывают кэш после записей, меняющих приемки и товары.
+func WithPvzCache(repo *Repository, cache Cache, ttl time.Duration) *Repository {
+	if ttl <= 0 {
+		ttl = DefaultCacheTTL
+	}
+	gens := newCacheGenerations()
+	repo.Pvz = &PvzCache{Pvz: repo.Pvz, cache: cache, ttl: ttl, gens: gens}
+	repo.Amendments = amendmentsInvalidator{Amendments: repo.Amendments, gens: gens}
+	repo.Transfers = transfersInvalidator{Transfers: repo.Transfers, gens: gens}
+	repo.ReceptionAutoClose = autoCloseInvalidator{ReceptionAutoClose: repo.ReceptionAutoClose, gens: gens}
+	repo.PvzCapacity = capacityInvalidator{PvzCapacity: repo.PvzCapacity, gens: gens}
+	return repo
+}
+
+func (r *PvzCache) GetPvz(ctx context.Context, scope domain.TenantScope, input domain.GettingPvzParams) ([]domain.PvzSummary, error) {
+	v, err := r.load(ctx, "PvzCache.GetPvz", scope, input, func(ctx context.Context) (any, error) {
+		return r.Pvz.GetPvz(ctx, scope, input)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return slices.Clone(v.([]domain.PvzSummary)), nil
+}
+
+func (r *PvzCache) GetListOFpvz(ctx context.Context, scope domain.TenantScope) ([]domain.PVZ, error) {
+	v, err := r.load(ctx, "PvzCache.GetListOFpvz", scope, nil, func(ctx context.Context) (any, error) {
+		return r.Pvz.GetListOFpvz(ctx, scope)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return slices.Clone(v.([]domain.PVZ)), nil
+}
+
+// load возвращает значение из кэша или загружает его через fetch. Загрузка
+// не отменяется вместе с контекстом первого вызвавшего, потому что ее
+// результат ждут и другие запросы, но каждый из них перестает ждать при
+// отмене своего контекста.
+func (r *PvzCache) load(ctx context.Context, method string, scope domain.TenantScope, params any, fetch func(ctx context.Context) (any, error)) (any, error) {
+	encoded, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	key := method + "|" + r.gens.version(scope) + "|" + string(encoded)
+	if v, ok := r.cache.Get(key); ok {
+		prometheus.CacheRequestsTotal.WithLabelValues(method, "hit").Inc()
+		return v, nil
+	}
+	prometheus.CacheRequestsTotal.WithLabelValues(method, "miss").Inc()
+	loadCtx := context.WithoutCancel(ctx)
+	ch := r.group.DoChan(key, func() (any, error) {
+		v, err := fetch(loadCtx)
+		if err == nil {
+			r.cache.Set(key, v, r.ttl)
+		}
+		return v, err
+	})
+	select {
+	case res := <-ch:
+		return res.Val, res.Err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (r *PvzCache) CreatePvz(ctx context.Context, scope domain.TenantScope, pvz domain.PVZ) (domain.PVZ, error) {
+	defer r.gens.invalidate(scope)
+	return r.Pvz.CreatePvz(ctx, scope, pvz)
+}
+
+func (r *PvzCache) UpdatePvz(ctx context.Context, scope domain.TenantScope, pvzId uuid.UUID, input domain.PvzUpdate) (domain.PVZ, error) {
+	defer r.gens.invalidate(scope)
+	return r.Pvz.UpdatePvz(ctx, scope, pvzId, input)
+}
+
+func (r *PvzCache) ArchiveDecommissionedPvz(ctx context.Context, now time.Time) ([]domain.PVZ, error) {
+	defer r.gens.invalidateAll()
+	return r.Pvz.ArchiveDecommissionedPvz(ctx, now)
+}
+
+func (r *PvzCache) CreateRecep(ctx context.Context, scope domain.TenantScope, recep domain.ProductReception) (domain.ProductReception, error) {
+	defer r.gens.invalidate(scope)
+	return r.Pvz.CreateRecep(ctx, scope, recep)
+}
+
+func (r *PvzCache) AddProdToRecep(ctx context.Context, scope domain.TenantScope, product domain.Product) (domain.Product, error) {
+	defer r.gens.invalidate(scope)
+	return r.Pvz.AddProdToRecep(ctx, scope, product)
+}
+
+func (r *PvzCache) DeleteLastProduct(ctx context.Context, scope domain.TenantScope, input domain.ProductDeletion) (domain.Product, error) {
+	defer r.gens.invalidate(scope)
+	return r.Pvz.DeleteLastProduct(ctx, scope, input)
+}
+
+func (r *PvzCache) DeleteProduct(ctx context.Context, scope domain.TenantScope, input domain.ProductDeletion) (domain.Product, error) {
+	defer r.gens.invalidate(scope)
+	return r.Pvz.DeleteProduct(ctx, scope, input)
+}
+
+func (r *PvzCache) CloseReception(ctx context.Context, scope domain.TenantScope, closeRec uuid.UUID) (domain.ProductReception, error) {
+	defer r.gens.invalidate(scope)
+	return r.Pvz.CloseReception(ctx, scope, closeRec)
+}
+
+// Обертки ниже сбрасывают кэш после записей, которые меняют приемки и
+// товары в обход Pvz. Кэш сбрасывается и при ошибке: запись могла быть
+// зафиксирована до нее.
+
+type amendmentsInvalidator struct {
+	Amendments
+	gens *cacheGenerations
+}
+
+func (r amendmentsInvalidator) ApplyAmendment(ctx context.Context, scope domain.TenantScope, id uuid.UUID, reviewer, comment string) (domain.ReceptionAmendment, error) {
+	defer r.gens.invalidate(scope)
+	return r.Amendments.ApplyAmendment(ctx, scope, id, reviewer, comment)
+}
+
+type transfersInvalidator struct {
+	Transfers
+	gens *cacheGenerations
+}
+
+func (r transfersInvalidator) ShipTransfer(ctx context.Context, scope domain.TenantScope, transfer domain.ProductTransfer) (domain.ProductTransfer, error) {
+	defer r.gens.invalidate(scope)
+	return r.Transfers.ShipTransfer(ctx, scope, transfer)
+}
+
+func (r transfersInvalidator) AcceptTransfer(ctx context.Context, scope domain.TenantScope, id uuid.UUID, actorId string, at time.Time) (domain.ProductTransfer, error) {
+	defer r.gens.invalidate(scope)
+	return r.Transfers.AcceptTransfer(ctx, scope, id, actorId, at)
+}
+
+type autoCloseInvalidator struct {
+	ReceptionAutoClose
+	gens *cacheGenerations
+}
+
+func (r autoCloseInvalidator) AutoCloseReceptions(ctx context.Context, policy domain.AutoClosePolicy, now time.Time) (domain.AutoCloseResult, error) {
+	defer r.gens.invalidateAll()
+	return r.ReceptionAutoClose.AutoCloseReceptions(ctx, policy, now)
+}
+
+type capacityInvalidator struct {
+	PvzCapacity
+	gens *cacheGenerations
+}
+
+func (r capacityInvalidator) SetPvzSchedule(ctx context.Context, scope domain.TenantScope, pvzId uuid.UUID, schedule domain.PvzSchedule) (domain.PvzSchedule, error) {
+	defer r.gens.invalidate(scope)
+	return r.PvzCapacity.SetPvzSchedule(ctx, scope, pvzId, schedule)
+}
+
+func (r capacityInvalidator) IssueProduct(ctx context.Context, scope domain.TenantScope, pvzId, productId uuid.UUID, at time.Time) (domain.Product, error) {
+	defer r.gens.invalidate(scope)
+	return r.PvzCapacity.IssueProduct(ctx, scope, pvzId, productId, at)
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bllooop/pvzservice/internal/domain"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type countingPvz struct {
+	Pvz
+	calls   atomic.Int32
+	release chan struct{}
+	city    string
+}
+
+func (r *countingPvz) GetListOFpvz(ctx context.Context, scope domain.TenantScope) ([]domain.PVZ, error) {
+	r.calls.Add(1)
+	if r.release != nil {
+		<-r.release
+	}
+	return []domain.PVZ{{City: r.city + scope.TenantId}}, nil
+}
+
+func (r *countingPvz) CloseReception(ctx context.Context, scope domain.TenantScope, closeRec uuid.UUID) (domain.ProductReception, error) {
+	r.city = "закрыта:"
+	return domain.ProductReception{}, nil
+}
+
+func newCachedRepo(inner *countingPvz) *Repository {
+	return WithPvzCache(&Repository{Pvz: inner}, NewLRUCache(16), time.Minute)
+}
+
+func TestPvzCache_HitAndInvalidate(t *testing.T) {
+	inner := &countingPvz{}
+	repo := newCachedRepo(inner)
+	tenantA, tenantB := domain.TenantOf("a"), domain.TenantOf("b")
+
+	first, err := repo.GetListOFpvz(context.Background(), tenantA)
+	require.NoError(t, err)
+	first[0].City = "изменено вызывающим"
+	second, err := repo.GetListOFpvz(context.Background(), tenantA)
+	require.NoError(t, err)
+	assert.Equal(t, "a", second[0].City)
+	assert.EqualValues(t, 1, inner.calls.Load())
+
+	_, err = repo.GetListOFpvz(context.Background(), tenantB)
+	require.NoError(t, err)
+	_, err = repo.GetListOFpvz(context.Background(), domain.AllTenants())
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, inner.calls.Load())
+
+	_, err = repo.CloseReception(context.Background(), tenantA, uuid.New())
+	require.NoError(t, err)
+	got, err := repo.GetListOFpvz(context.Background(), tenantA)
+	require.NoError(t, err)
+	assert.Equal(t, "закрыта:a", got[0].City)
+	_, err = repo.GetListOFpvz(context.Background(), domain.AllTenants())
+	require.NoError(t, err)
+	assert.EqualValues(t, 5, inner.calls.Load())
+
+	// Запись в компании a не затрагивает кэш компании b.
+	_, err = repo.GetListOFpvz(context.Background(), tenantB)
+	require.NoError(t, err)
+	assert.EqualValues(t, 5, inner.calls.Load())
+}
+
+func TestPvzCache_Singleflight(t *testing.T) {
+	inner := &countingPvz{release: make(chan struct{})}
+	repo := newCachedRepo(inner)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got, err := repo.GetListOFpvz(context.Background(), domain.TenantOf("a"))
+			assert.NoError(t, err)
+			assert.Len(t, got, 1)
+		}()
+	}
+	require.Eventually(t, func() bool { return inner.calls.Load() == 1 }, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	close(inner.release)
+	wg.Wait()
+	assert.EqualValues(t, 1, inner.calls.Load())
+}
+
+func TestPvzCache_CallerCancel(t *testing.T) {
+	inner := &countingPvz{release: make(chan struct{})}
+	repo := newCachedRepo(inner)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := repo.GetListOFpvz(ctx, domain.TenantOf("a"))
+	assert.ErrorIs(t, err, context.Canceled)
+	close(inner.release)
+}
+
+func TestLRUCache(t *testing.T) {
+	now := time.Date(2025, 4, 10, 15, 0, 0, 0, time.UTC)
+	c := NewLRUCache(2)
+	c.now = func() time.Time { return now }
+
+	c.Set("a", 1, time.Minute)
+	c.Set("b", 2, time.Minute)
+	_, ok := c.Get("a")
+	assert.True(t, ok)
+	c.Set("c", 3, time.Minute)
+	_, ok = c.Get("b")
+	assert.False(t, ok, "давно не использованная запись вытесняется")
+	assert.Equal(t, 2, c.Len())
+
+	now = now.Add(time.Minute)
+	_, ok = c.Get("a")
+	assert.False(t, ok, "просроченная запись не возвращается")
+	assert.Equal(t, 1, c.Len())
+}
@@ -0,0 +1,337 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/bllooop/pvzservice/internal/domain"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPvzPostgres_GetPvzSchedule(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	r := NewPvzPostgres(sqlx.NewDb(db, "postgres"))
+	pvzId := uuid.New()
+	selectTimezone := fmt.Sprintf(`SELECT timezone FROM %s WHERE id = \$1 AND (.+)tenant_id = \$2`, pvzTable)
+
+	tests := []struct {
+		name    string
+		mock    func()
+		want    domain.PvzSchedule
+		wantErr error
+	}{
+		{
+			name: "Ok",
+			mock: func() {
+				mock.ExpectQuery(selectTimezone).WithArgs(pvzId, "t1").
+					WillReturnRows(sqlmock.NewRows([]string{"timezone"}).AddRow("Asia/Novosibirsk"))
+				mock.ExpectQuery(fmt.Sprintf(`SELECT weekday, (.+) FROM %s WHERE pvz_id = \$1`, workingHoursTable)).WithArgs(pvzId).
+					WillReturnRows(sqlmock.NewRows([]string{"weekday", "opens_at", "closes_at"}).AddRow(1, "09:00", "21:00"))
+				mock.ExpectQuery(fmt.Sprintf(`SELECT (.+) FROM %s WHERE pvz_id = \$1`, holidaysTable)).WithArgs(pvzId).
+					WillReturnRows(sqlmock.NewRows([]string{"day", "opens_at", "closes_at", "reason"}).AddRow("2025-05-01", "", "", "праздник"))
+			},
+			want: domain.PvzSchedule{
+				Timezone: "Asia/Novosibirsk",
+				Week:     []domain.WorkingDay{{Weekday: 1, Opens: "09:00", Closes: "21:00"}},
+				Holidays: []domain.Holiday{{Date: "2025-05-01", Reason: "праздник"}},
+			},
+		},
+		{
+			name: "ПВЗ не найден",
+			mock: func() {
+				mock.ExpectQuery(selectTimezone).WithArgs(pvzId, "t1").WillReturnRows(sqlmock.NewRows([]string{"timezone"}))
+			},
+			wantErr: ErrPvzNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			got, err := r.GetPvzSchedule(context.Background(), testScope, pvzId)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPvzPostgres_SetPvzSchedule(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	r := NewPvzPostgres(sqlx.NewDb(db, "postgres"))
+	pvzId := uuid.New()
+	schedule := domain.PvzSchedule{
+		Week:     []domain.WorkingDay{{Weekday: 1, Opens: "09:00", Closes: "21:00"}},
+		Holidays: []domain.Holiday{{Date: "2025-05-09", Opens: "10:00", Closes: "16:00"}},
+	}
+	selectPvz := fmt.Sprintf(`SELECT (.+) FROM %s p WHERE p.id = \$1 AND (.+) FOR UPDATE OF p`, pvzTable)
+
+	tests := []struct {
+		name    string
+		mock    func()
+		wantErr error
+	}{
+		{
+			name: "Ok",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectPvz).WithArgs(pvzId, "t1").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "city", "status"}).AddRow(pvzId, "Москва", domain.PvzActive))
+				mock.ExpectExec(fmt.Sprintf(`DELETE FROM %s`, workingHoursTable)).WithArgs(pvzId).WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectExec(fmt.Sprintf(`INSERT INTO %s`, workingHoursTable)).WithArgs(pvzId, 1, "09:00", "21:00").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(fmt.Sprintf(`DELETE FROM %s`, holidaysTable)).WithArgs(pvzId).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(fmt.Sprintf(`INSERT INTO %s`, holidaysTable)).WithArgs(pvzId, "2025-05-09", "10:00", "16:00", "").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "ПВЗ не найден",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectPvz).WithArgs(pvzId, "t1").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+				mock.ExpectRollback()
+			},
+			wantErr: ErrPvzNotFound,
+		},
+		{
+			name: "Ошибка БД",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectPvz).WithArgs(pvzId, "t1").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "city", "status"}).AddRow(pvzId, "Москва", domain.PvzActive))
+				mock.ExpectExec(fmt.Sprintf(`DELETE FROM %s`, workingHoursTable)).WillReturnError(errors.New("ошибка бд"))
+				mock.ExpectRollback()
+			},
+			wantErr: errors.New("ошибка бд"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			got, err := r.SetPvzSchedule(context.Background(), testScope, pvzId, schedule)
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, tt.wantErr.Error())
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, schedule, got)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPvzPostgres_GetPvzOccupancy(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	r := NewPvzPostgres(sqlx.NewDb(db, "postgres"))
+	pvzId := uuid.New()
+	capacity, fill := 200, 75.5
+
+	mock.ExpectQuery(fmt.Sprintf(`SELECT (.+) FROM %s p LEFT JOIN %s pr`, pvzTable, productTable)).WithArgs(&pvzId, "t1").
+		WillReturnRows(sqlmock.NewRows([]string{"pvz_id", "city", "capacity", "stored", "fill_percent"}).
+			AddRow(pvzId, "Казань", capacity, 151, fill))
+
+	got, err := r.GetPvzOccupancy(context.Background(), testScope, &pvzId)
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.PvzOccupancy{{PVZId: pvzId, City: "Казань", Capacity: &capacity, Stored: 151, FillPercent: &fill}}, got)
+	assert.False(t, got[0].Full())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPvzPostgres_GetPvzStock(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	r := NewPvzPostgres(sqlx.NewDb(db, "postgres"))
+	pvzId := uuid.New()
+
+	mock.ExpectQuery(fmt.Sprintf(`SELECT p.id AS pvz_id, p.city, (.+) FROM %s p WHERE p.archived_at IS NULL`, pvzTable)).
+		WillReturnRows(sqlmock.NewRows([]string{"pvz_id", "city", "open_receptions", "products_held"}).
+			AddRow(pvzId, "Казань", 1, 151))
+
+	got, err := r.GetPvzStock(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.PvzStock{{PVZId: pvzId, City: "Казань", OpenReceptions: 1, ProductsHeld: 151}}, got)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPvzPostgres_IssueProduct(t *testing.T) {
+	fixedTime := time.Date(2025, 4, 10, 15, 5, 17, 0, time.UTC)
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	r := NewPvzPostgres(sqlx.NewDb(db, "postgres"))
+	pvzId, productId, recepId := uuid.New(), uuid.New(), uuid.New()
+	selectProduct := fmt.Sprintf(`SELECT r.status_reception, p.issued_at FROM %s p JOIN %s r`, productTable, receptionTable)
+
+	tests := []struct {
+		name    string
+		mock    func()
+		want    domain.Product
+		wantErr error
+	}{
+		{
+			name: "Ok",
+			mock: func() {
+				mock.ExpectBegin()
+				expectPvzActive(mock)
+				mock.ExpectQuery(selectProduct).WithArgs(productId, pvzId).
+					WillReturnRows(sqlmock.NewRows([]string{"status_reception", "issued_at"}).AddRow(domain.ReceptionClosed, nil))
+				mock.ExpectQuery(fmt.Sprintf(`UPDATE %s SET issued_at = \$2`, productTable)).WithArgs(productId, fixedTime).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "date_received", "type_product", "reception_id", "pvz_id", "issued_at"}).
+						AddRow(productId, fixedTime, "обувь", recepId, pvzId, fixedTime))
+				mock.ExpectCommit()
+			},
+			want: domain.Product{Id: &productId, DateReceived: &fixedTime, Type: "обувь", ReceptionId: &recepId, PVZId: &pvzId, IssuedAt: &fixedTime},
+		},
+		{
+			name: "Приемка не закрыта",
+			mock: func() {
+				mock.ExpectBegin()
+				expectPvzActive(mock)
+				mock.ExpectQuery(selectProduct).WithArgs(productId, pvzId).
+					WillReturnRows(sqlmock.NewRows([]string{"status_reception", "issued_at"}).AddRow(domain.ReceptionInProgress, nil))
+				mock.ExpectRollback()
+			},
+			wantErr: ErrProductNotReceived,
+		},
+		{
+			name: "Товар уже выдан",
+			mock: func() {
+				mock.ExpectBegin()
+				expectPvzActive(mock)
+				mock.ExpectQuery(selectProduct).WithArgs(productId, pvzId).
+					WillReturnRows(sqlmock.NewRows([]string{"status_reception", "issued_at"}).AddRow(domain.ReceptionClosed, fixedTime))
+				mock.ExpectRollback()
+			},
+			wantErr: ErrProductIssued,
+		},
+		{
+			name: "Товар не найден",
+			mock: func() {
+				mock.ExpectBegin()
+				expectPvzActive(mock)
+				mock.ExpectQuery(selectProduct).WithArgs(productId, pvzId).
+					WillReturnRows(sqlmock.NewRows([]string{"status_reception", "issued_at"}))
+				mock.ExpectRollback()
+			},
+			wantErr: ErrProductNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			got, err := r.IssueProduct(context.Background(), testScope, pvzId, productId, fixedTime)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPvzPostgres_CapacityLimit(t *testing.T) {
+	fixedTime := time.Date(2025, 4, 10, 15, 5, 17, 0, time.UTC)
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	r := NewPvzPostgres(sqlx.NewDb(db, "postgres"))
+	pvzId, recepId := uuid.New(), uuid.New()
+	stat := domain.ReceptionInProgress
+	columns := []string{"id", "registrationdate", "city", "capacity", "status", "timezone"}
+	selectPvz := fmt.Sprintf(`SELECT (.+) FROM %s p WHERE p.id = \$1 AND (.+) FOR UPDATE OF p`, pvzTable)
+	countStored := fmt.Sprintf(`SELECT COUNT\(\*\) FROM %s WHERE pvz_id = \$1 AND deleted_at IS NULL AND issued_at IS NULL AND transferred_at IS NULL`, productTable)
+
+	tests := []struct {
+		name    string
+		mock    func()
+		wantErr error
+	}{
+		{
+			name: "Есть место",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectPvz).WithArgs(pvzId, "t1").
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(pvzId, fixedTime, "Казань", 2, domain.PvzActive, "UTC"))
+				mock.ExpectQuery(countStored).WithArgs(pvzId).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+				mock.ExpectQuery(fmt.Sprintf("SELECT status_reception,id FROM %s (.+)", receptionTable)).
+					WithArgs(&pvzId).WillReturnRows(sqlmock.NewRows([]string{"status_reception", "id"}))
+				mock.ExpectQuery(fmt.Sprintf("INSERT INTO %s", receptionTable)).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "date_received", "pvz_id", "status_reception"}).AddRow(recepId, fixedTime, pvzId, stat))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "ПВЗ заполнен",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectPvz).WithArgs(pvzId, "t1").
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(pvzId, fixedTime, "Казань", 2, domain.PvzActive, "UTC"))
+				mock.ExpectQuery(countStored).WithArgs(pvzId).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+				mock.ExpectRollback()
+			},
+			wantErr: ErrPvzFull,
+		},
+		{
+			name: "ПВЗ не работает",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectPvz).WithArgs(pvzId, "t1").
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(pvzId, fixedTime, "Казань", 2, domain.PvzTemporarilyClosed, "UTC"))
+				mock.ExpectRollback()
+			},
+			wantErr: ErrPvzNotActive,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			_, err := r.CreateRecep(WithCapacityLimit(context.Background()), testScope, domain.ProductReception{DateReceived: &fixedTime, PVZId: &pvzId, Status: &stat})
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
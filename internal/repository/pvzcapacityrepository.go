@@ -0,0 +1,245 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bllooop/pvzservice/internal/domain"
+	logger "github.com/bllooop/pvzservice/pkg/logging"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrProductNotReceived = errors.New("выдать можно только товар из закрытой приемки")
+	ErrProductIssued      = errors.New("товар уже выдан")
+	ErrPvzFull            = errors.New("ПВЗ заполнен")
+	ErrPvzClosedNow       = errors.New("ПВЗ сейчас не работает по расписанию")
+)
+
+const (
+	workingHoursTable = "pvz_working_hours"
+	holidaysTable     = "pvz_holidays"
+)
+
+type capacityLimitKey struct{}
+
+// WithCapacityLimit требует от CreateRecep, AddProdToRecep и AcceptTransfer
+// отклонять операцию ошибкой ErrPvzFull, если ПВЗ заполнен. Заполненность
+// считается в транзакции операции под блокировкой ПВЗ, поэтому параллельные
+// запросы не превышают capacity. AcceptTransfer, кроме того, отклоняет прием
+// вне часов работы ПВЗ ошибкой ErrPvzClosedNow.
+func WithCapacityLimit(ctx context.Context) context.Context {
+	return context.WithValue(ctx, capacityLimitKey{}, true)
+}
+
+func capacityLimited(ctx context.Context) bool {
+	limited, _ := ctx.Value(capacityLimitKey{}).(bool)
+	return limited
+}
+
+// checkPvzAccepts проверяет перед приемкой товаров, что ПВЗ работает, как
+// checkPvzActive. С WithCapacityLimit ПВЗ блокируется на запись, чтобы
+// параллельные приемки считали заполненность по очереди, и заполненный ПВЗ
+// отклоняется.
+func (r *PvzPostgres) checkPvzAccepts(ctx context.Context, tx *sqlx.Tx, scope domain.TenantScope, pvzId uuid.UUID) (*time.Location, error) {
+	if !capacityLimited(ctx) {
+		return r.checkPvzActive(ctx, tx, scope, pvzId)
+	}
+	pvz, err := r.getPvzForUpdate(ctx, tx, scope, pvzId)
+	if err != nil {
+		return nil, err
+	}
+	if pvz.Status != domain.PvzActive {
+		return nil, ErrPvzNotActive
+	}
+	occupancy := domain.PvzOccupancy{PVZId: pvzId, Capacity: pvz.Capacity}
+	query := fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE pvz_id = $1 AND deleted_at IS NULL AND issued_at IS NULL AND transferred_at IS NULL`, productTable)
+	logger.FromContext(ctx).Debug().Str("query", query).Msg("Проверка заполненности ПВЗ")
+	if err := tx.GetContext(ctx, &occupancy.Stored, query, pvzId); err != nil {
+		return nil, err
+	}
+	if occupancy.Full() {
+		return nil, ErrPvzFull
+	}
+	return domain.PvzLocation(pvz.Timezone), nil
+}
+
+// checkPvzOpen проверяет с WithCapacityLimit, что ПВЗ работает в момент at.
+func (r *PvzPostgres) checkPvzOpen(ctx context.Context, tx *sqlx.Tx, scope domain.TenantScope, pvzId uuid.UUID, at time.Time) error {
+	if !capacityLimited(ctx) {
+		return nil
+	}
+	schedule, err := r.loadPvzSchedule(ctx, tx, scope, pvzId)
+	if err != nil {
+		return err
+	}
+	if !schedule.OpenAt(at) {
+		return ErrPvzClosedNow
+	}
+	return nil
+}
+
+func (r *PvzPostgres) GetPvzSchedule(ctx context.Context, scope domain.TenantScope, pvzId uuid.UUID) (domain.PvzSchedule, error) {
+	ctx, done := startQuery(ctx, r.timeouts.Read, "PvzPostgres.GetPvzSchedule")
+	defer done()
+	return r.loadPvzSchedule(ctx, r.db, scope, pvzId)
+}
+
+// loadPvzSchedule читает расписание ПВЗ через q: базу или транзакцию операции.
+func (r *PvzPostgres) loadPvzSchedule(ctx context.Context, q sqlx.QueryerContext, scope domain.TenantScope, pvzId uuid.UUID) (domain.PvzSchedule, error) {
+	schedule := domain.PvzSchedule{Week: []domain.WorkingDay{}, Holidays: []domain.Holiday{}}
+	query := fmt.Sprintf(`SELECT timezone FROM %s WHERE id = $1 AND %s`, pvzTable, tenantCondition("tenant_id", 2))
+	if err := sqlx.GetContext(ctx, q, &schedule.Timezone, query, pvzId, scope.Filter()); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.PvzSchedule{}, ErrPvzNotFound
+		}
+		return domain.PvzSchedule{}, err
+	}
+	query = fmt.Sprintf(`SELECT weekday, to_char(opens_at, 'HH24:MI') AS opens_at, to_char(closes_at, 'HH24:MI') AS closes_at
+  FROM %s WHERE pvz_id = $1 ORDER BY weekday`, workingHoursTable)
+	logger.FromContext(ctx).Debug().Str("query", query).Msg("Получение расписания ПВЗ")
+	if err := sqlx.SelectContext(ctx, q, &schedule.Week, query, pvzId); err != nil {
+		return domain.PvzSchedule{}, err
+	}
+	query = fmt.Sprintf(`SELECT to_char(day, 'YYYY-MM-DD') AS day, COALESCE(to_char(opens_at, 'HH24:MI'), '') AS opens_at,
+  COALESCE(to_char(closes_at, 'HH24:MI'), '') AS closes_at, reason
+  FROM %s WHERE pvz_id = $1 ORDER BY day`, holidaysTable)
+	logger.FromContext(ctx).Debug().Str("query", query).Msg("Получение праздничных дней ПВЗ")
+	if err := sqlx.SelectContext(ctx, q, &schedule.Holidays, query, pvzId); err != nil {
+		return domain.PvzSchedule{}, err
+	}
+	return schedule, nil
+}
+
+// SetPvzSchedule полностью заменяет недельное расписание и исключения ПВЗ.
+func (r *PvzPostgres) SetPvzSchedule(ctx context.Context, scope domain.TenantScope, pvzId uuid.UUID, schedule domain.PvzSchedule) (domain.PvzSchedule, error) {
+	ctx, done := startQuery(ctx, r.timeouts.Write, "PvzPostgres.SetPvzSchedule")
+	defer done()
+	tx, err := r.beginTx(ctx)
+	if err != nil {
+		return domain.PvzSchedule{}, err
+	}
+	defer tx.Rollback()
+	pvz, err := r.getPvzForUpdate(ctx, tx, scope, pvzId)
+	if err != nil {
+		return domain.PvzSchedule{}, err
+	}
+	schedule.Timezone = pvz.Timezone
+
+	query := fmt.Sprintf(`DELETE FROM %s WHERE pvz_id = $1`, workingHoursTable)
+	logger.FromContext(ctx).Debug().Str("query", query).Msg("Очистка расписания ПВЗ")
+	if _, err := tx.ExecContext(ctx, query, pvzId); err != nil {
+		return domain.PvzSchedule{}, err
+	}
+	query = fmt.Sprintf(`INSERT INTO %s (pvz_id, weekday, opens_at, closes_at) VALUES ($1, $2, $3, $4)`, workingHoursTable)
+	for _, day := range schedule.Week {
+		if _, err := tx.ExecContext(ctx, query, pvzId, day.Weekday, day.Opens, day.Closes); err != nil {
+			return domain.PvzSchedule{}, err
+		}
+	}
+
+	query = fmt.Sprintf(`DELETE FROM %s WHERE pvz_id = $1`, holidaysTable)
+	logger.FromContext(ctx).Debug().Str("query", query).Msg("Очистка праздничных дней ПВЗ")
+	if _, err := tx.ExecContext(ctx, query, pvzId); err != nil {
+		return domain.PvzSchedule{}, err
+	}
+	query = fmt.Sprintf(`INSERT INTO %s (pvz_id, day, opens_at, closes_at, reason) VALUES ($1, $2, NULLIF($3, '')::time, NULLIF($4, '')::time, $5)`, holidaysTable)
+	for _, holiday := range schedule.Holidays {
+		if _, err := tx.ExecContext(ctx, query, pvzId, holiday.Date, holiday.Opens, holiday.Closes, holiday.Reason); err != nil {
+			return domain.PvzSchedule{}, err
+		}
+	}
+	if err := auditTx(ctx, tx, pvzId.String(), nil, schedule); err != nil {
+		return domain.PvzSchedule{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return domain.PvzSchedule{}, err
+	}
+	return schedule, nil
+}
+
+// GetPvzOccupancy считает товары, принятые и еще не выданные, по каждому
+// неархивному ПВЗ или только по pvzId, если он задан.
+func (r *PvzPostgres) GetPvzOccupancy(ctx context.Context, scope domain.TenantScope, pvzId *uuid.UUID) ([]domain.PvzOccupancy, error) {
+	ctx, done := startQuery(ctx, r.timeouts.Read, "PvzPostgres.GetPvzOccupancy")
+	defer done()
+	query := fmt.Sprintf(`SELECT p.id AS pvz_id, p.city, p.capacity, COUNT(pr.id) AS stored,
+  ROUND(COUNT(pr.id) * 100.0 / p.capacity, 1)::float8 AS fill_percent
+  FROM %s p LEFT JOIN %s pr ON pr.pvz_id = p.id AND pr.deleted_at IS NULL AND pr.issued_at IS NULL AND pr.transferred_at IS NULL
+  WHERE p.archived_at IS NULL AND ($1::uuid IS NULL OR p.id = $1) AND %s
+  GROUP BY p.id ORDER BY fill_percent DESC NULLS LAST, p.id`, pvzTable, productTable, tenantCondition("p.tenant_id", 2))
+	logger.FromContext(ctx).Debug().Str("query", query).Msg("Получение заполненности ПВЗ")
+	var result []domain.PvzOccupancy
+	if err := r.db.SelectContext(ctx, &result, query, pvzId, scope.Filter()); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetPvzStock считает открытые приемки и товары в каждом неархивном ПВЗ всех
+// компаний.
+func (r *PvzPostgres) GetPvzStock(ctx context.Context) ([]domain.PvzStock, error) {
+	ctx, done := startQuery(ctx, r.timeouts.Read, "PvzPostgres.GetPvzStock")
+	defer done()
+	query := fmt.Sprintf(`SELECT p.id AS pvz_id, p.city,
+  (SELECT COUNT(*) FROM %[2]s r WHERE r.pvz_id = p.id AND r.status_reception = 'in_progress') AS open_receptions,
+  (SELECT COUNT(*) FROM %[3]s pr WHERE pr.pvz_id = p.id AND pr.deleted_at IS NULL AND pr.issued_at IS NULL AND pr.transferred_at IS NULL) AS products_held
+  FROM %[1]s p WHERE p.archived_at IS NULL`, pvzTable, receptionTable, productTable)
+	logger.FromContext(ctx).Debug().Str("query", query).Msg("Получение остатков ПВЗ")
+	var result []domain.PvzStock
+	if err := r.db.SelectContext(ctx, &result, query); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// IssueProduct отмечает выдачу товара из закрытой приемки, после чего он
+// перестает занимать место в ПВЗ.
+func (r *PvzPostgres) IssueProduct(ctx context.Context, scope domain.TenantScope, pvzId, productId uuid.UUID, at time.Time) (domain.Product, error) {
+	ctx, done := startQuery(ctx, r.timeouts.Write, "PvzPostgres.IssueProduct")
+	defer done()
+	tx, err := r.beginTx(ctx)
+	if err != nil {
+		return domain.Product{}, err
+	}
+	defer tx.Rollback()
+	loc, err := r.checkPvzActive(ctx, tx, scope, pvzId)
+	if err != nil {
+		return domain.Product{}, err
+	}
+	var status string
+	var issuedAt *time.Time
+	query := fmt.Sprintf(`SELECT r.status_reception, p.issued_at FROM %s p JOIN %s r ON r.id = p.reception_id
+  WHERE p.id = $1 AND p.pvz_id = $2 AND p.deleted_at IS NULL AND p.transferred_at IS NULL FOR UPDATE OF p`, productTable, receptionTable)
+	logger.FromContext(ctx).Debug().Str("query", query).Msg("Проверка товара перед выдачей")
+	if err := tx.QueryRowxContext(ctx, query, productId, pvzId).Scan(&status, &issuedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.Product{}, ErrProductNotFound
+		}
+		return domain.Product{}, err
+	}
+	if status != domain.ReceptionClosed {
+		return domain.Product{}, ErrProductNotReceived
+	}
+	if issuedAt != nil {
+		return domain.Product{}, ErrProductIssued
+	}
+	query = fmt.Sprintf(`UPDATE %s SET issued_at = $2 WHERE id = $1 RETURNING %s`, productTable, productColumns)
+	logger.FromContext(ctx).Debug().Str("query", query).Msg("Выдача товара")
+	var res domain.Product
+	if err := tx.QueryRowxContext(ctx, query, productId, at).Scan(&res.Id, &res.DateReceived, &res.Type, &res.ReceptionId, &res.PVZId, &res.IssuedAt); err != nil {
+		return domain.Product{}, err
+	}
+	res = res.In(loc)
+	if err := auditTx(ctx, tx, productId.String(), nil, res); err != nil {
+		return domain.Product{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return domain.Product{}, err
+	}
+	return res, nil
+}
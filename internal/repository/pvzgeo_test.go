@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/bllooop/pvzservice/internal/domain"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBoundingBox(t *testing.T) {
+	minLat, maxLat, minLon, maxLon := boundingBox(55.75, 37.62, 10)
+	assert.InDelta(t, 55.66, minLat, 0.01)
+	assert.InDelta(t, 55.84, maxLat, 0.01)
+	assert.InDelta(t, 37.46, minLon, 0.01)
+	assert.InDelta(t, 37.78, maxLon, 0.01)
+
+	_, _, minLon, maxLon = boundingBox(89.99, 0, 10)
+	assert.Equal(t, -180.0, minLon)
+	assert.Equal(t, 180.0, maxLon)
+
+	_, _, minLon, maxLon = boundingBox(0, 179.99, 10)
+	assert.Equal(t, -180.0, minLon)
+	assert.Equal(t, 180.0, maxLon)
+}
+
+func TestPvzPostgres_GetNearestPvz(t *testing.T) {
+	fixedTime := time.Date(2025, 4, 10, 15, 5, 17, 0, time.UTC)
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	r := NewPvzPostgres(sqlx.NewDb(db, "postgres"))
+	pvzId := uuid.New()
+	lat, lon := 55.7558, 37.6173
+	params := domain.NearestPvzParams{Latitude: 55.75, Longitude: 37.62, RadiusKm: 5, Limit: 10}
+	minLat, maxLat, minLon, maxLon := boundingBox(params.Latitude, params.Longitude, params.RadiusKm)
+	query := fmt.Sprintf(`SELECT \* FROM \(\s+SELECT (.+) AS distance_km\s+FROM %s p`, pvzTable)
+
+	tests := []struct {
+		name    string
+		mock    func()
+		want    []domain.PvzDistance
+		wantErr bool
+	}{
+		{
+			name: "Ok",
+			mock: func() {
+				rows := sqlmock.NewRows([]string{"id", "registrationdate", "city", "address", "postal_code", "working_hours",
+					"latitude", "longitude", "timezone", "archived_at", "status", "distance_km"}).
+					AddRow(pvzId, fixedTime, "Москва", "ул. Ленина, 1", "101000", "", lat, lon, "UTC", nil, domain.PvzActive, 0.63)
+				mock.ExpectQuery(query).
+					WithArgs(params.Latitude, params.Longitude, minLat, maxLat, minLon, maxLon, params.RadiusKm, params.Limit, "t1").
+					WillReturnRows(rows)
+			},
+			want: []domain.PvzDistance{{
+				PVZ: domain.PVZ{Id: &pvzId, DateRegister: &fixedTime, City: "Москва", Address: "ул. Ленина, 1",
+					PostalCode: "101000", Latitude: &lat, Longitude: &lon, Timezone: "UTC", Status: domain.PvzActive},
+				DistanceKm: 0.63,
+			}},
+		},
+		{
+			name: "Ошибка БД",
+			mock: func() {
+				mock.ExpectQuery(query).WillReturnError(errors.New("ошибка бд"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			got, err := r.GetNearestPvz(context.Background(), testScope, params)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/bllooop/pvzservice/internal/domain"
+	logger "github.com/bllooop/pvzservice/pkg/logging"
+)
+
+const (
+	earthRadiusKm = 6371.0
+	// kmPerDegree — длина одного градуса широты, по ней строится
+	// ограничивающий прямоугольник перед точным расчетом расстояния.
+	kmPerDegree = 111.045
+)
+
+// GetNearestPvz возвращает неархивные ПВЗ в радиусе params.RadiusKm от точки,
+// отсортированные по расстоянию. Индекс по координатам сужает выборку до
+// прямоугольника, точное расстояние считается по формуле гаверсинуса.
+func (r *PvzPostgres) GetNearestPvz(ctx context.Context, scope domain.TenantScope, params domain.NearestPvzParams) ([]domain.PvzDistance, error) {
+	ctx, done := startQuery(ctx, r.timeouts.Read, "PvzPostgres.GetNearestPvz")
+	defer done()
+	minLat, maxLat, minLon, maxLon := boundingBox(params.Latitude, params.Longitude, params.RadiusKm)
+	query := fmt.Sprintf(`SELECT * FROM (
+  SELECT %s, %.1f * 2 * ASIN(SQRT(
+    POWER(SIN(RADIANS(p.latitude - $1) / 2), 2) +
+    COS(RADIANS($1)) * COS(RADIANS(p.latitude)) * POWER(SIN(RADIANS(p.longitude - $2) / 2), 2)
+  )) AS distance_km
+  FROM %s p
+  WHERE p.archived_at IS NULL AND p.latitude BETWEEN $3 AND $4 AND p.longitude BETWEEN $5 AND $6 AND %s
+) d WHERE d.distance_km <= $7 ORDER BY d.distance_km LIMIT $8`, pvzColumns, earthRadiusKm, pvzTable, tenantCondition("p.tenant_id", 9))
+	logger.FromContext(ctx).Debug().Str("query", query).Msg("Поиск ближайших ПВЗ")
+	var result []domain.PvzDistance
+	err := r.db.SelectContext(ctx, &result, query, params.Latitude, params.Longitude, minLat, maxLat, minLon, maxLon,
+		params.RadiusKm, params.Limit, scope.Filter())
+	if err != nil {
+		return nil, err
+	}
+	for i, pvz := range result {
+		result[i].PVZ = pvz.PVZ.In(domain.PvzLocation(pvz.Timezone))
+	}
+	return result, nil
+}
+
+// boundingBox возвращает прямоугольник, гарантированно содержащий круг
+// радиуса radiusKm. Вблизи полюсов и линии перемены дат долгота не ограничивается.
+func boundingBox(lat, lon, radiusKm float64) (minLat, maxLat, minLon, maxLon float64) {
+	dLat := radiusKm / kmPerDegree
+	minLat, maxLat = math.Max(lat-dLat, -90), math.Min(lat+dLat, 90)
+	cosLat := math.Cos(lat * math.Pi / 180)
+	if cosLat < 0.01 {
+		return minLat, maxLat, -180, 180
+	}
+	dLon := radiusKm / (kmPerDegree * cosLat)
+	minLon, maxLon = lon-dLon, lon+dLon
+	if minLon < -180 || maxLon > 180 {
+		return minLat, maxLat, -180, 180
+	}
+	return minLat, maxLat, minLon, maxLon
+}
@@ -0,0 +1,312 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/bllooop/pvzservice/internal/domain"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func expectPvzActive(mock sqlmock.Sqlmock) {
+	expectPvzStatus(mock, domain.PvzActive)
+}
+
+func expectPvzStatus(mock sqlmock.Sqlmock, status string) {
+	mock.ExpectQuery(fmt.Sprintf(`SELECT (.+) FROM %s p WHERE p.id = \$1 AND (.+) FOR SHARE OF p`, pvzTable)).
+		WillReturnRows(sqlmock.NewRows([]string{"status", "timezone"}).AddRow(status, "UTC"))
+}
+
+func TestPvzPostgres_ReceptionOnInactivePvz(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	r := NewPvzPostgres(sqlx.NewDb(db, "postgres"))
+	pvzId := uuid.New()
+	now := time.Now()
+	status := domain.ReceptionInProgress
+
+	mock.ExpectBegin()
+	expectPvzStatus(mock, domain.PvzTemporarilyClosed)
+	mock.ExpectRollback()
+	_, err = r.CreateRecep(context.Background(), testScope, domain.ProductReception{PVZId: &pvzId, DateReceived: &now, Status: &status})
+	assert.ErrorIs(t, err, ErrPvzNotActive)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(fmt.Sprintf(`SELECT (.+) FROM %s p WHERE p.id = \$1 AND (.+) FOR SHARE OF p`, pvzTable)).
+		WillReturnRows(sqlmock.NewRows([]string{"status"}))
+	mock.ExpectRollback()
+	_, err = r.CloseReception(context.Background(), testScope, pvzId)
+	assert.ErrorIs(t, err, ErrPvzNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPvzPostgres_UpdatePvz(t *testing.T) {
+	fixedTime := time.Date(2025, 4, 10, 15, 5, 17, 0, time.UTC)
+	until := fixedTime.Add(48 * time.Hour)
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	r := NewPvzPostgres(sqlx.NewDb(db, "postgres"))
+	pvzId := uuid.New()
+	address := "ул. Ленина, 1"
+	closed := domain.PvzTemporarilyClosed
+	decommissioned := domain.PvzDecommissioned
+	active := domain.PvzActive
+	columns := []string{"id", "registrationdate", "city", "address", "archived_at", "status", "timezone"}
+	yekaterinburg := "Asia/Yekaterinburg"
+	localTime := fixedTime.In(domain.PvzLocation(yekaterinburg))
+	selectPvz := fmt.Sprintf(`SELECT (.+) FROM %s p WHERE p.id = \$1 AND (.+) FOR UPDATE OF p`, pvzTable)
+	future := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	expectStatusAt := func(at time.Time, status string, scheduled bool) {
+		mock.ExpectQuery(fmt.Sprintf(`SELECT (.+), EXISTS(.+) FROM %s p WHERE p.id = \$1$`, pvzTable)).
+			WithArgs(pvzId, at).WillReturnRows(sqlmock.NewRows([]string{"status", "exists"}).AddRow(status, scheduled))
+	}
+
+	tests := []struct {
+		name    string
+		mock    func()
+		input   domain.PvzUpdate
+		want    domain.PVZ
+		wantErr error
+	}{
+		{
+			name: "Временное закрытие и смена адреса",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectPvz).WithArgs(pvzId, "t1").
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(pvzId, fixedTime, "Москва", "", nil, active, "UTC"))
+				mock.ExpectExec(fmt.Sprintf(`UPDATE %s SET city = COALESCE`, pvzTable)).
+					WithArgs(pvzId, nil, &address, nil, nil, nil, nil, nil, &yekaterinburg).WillReturnResult(sqlmock.NewResult(0, 1))
+				expectStatusAt(fixedTime, active, false)
+				mock.ExpectExec(fmt.Sprintf(`INSERT INTO %s`, pvzStatusTable)).
+					WithArgs(pvzId, closed, &fixedTime, &until, "ремонт", "u1").WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectQuery(selectPvz).WithArgs(pvzId, "t1").
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(pvzId, fixedTime, "Москва", address, nil, closed, yekaterinburg))
+				mock.ExpectCommit()
+			},
+			input: domain.PvzUpdate{Address: &address, Timezone: &yekaterinburg, Status: &closed, EffectiveFrom: &fixedTime, EffectiveTo: &until, Reason: "ремонт", ActorId: "u1"},
+			want:  domain.PVZ{Id: &pvzId, DateRegister: &localTime, City: "Москва", Address: address, Timezone: yekaterinburg, Status: closed},
+		},
+		{
+			name: "Вывод из работы архивирует ПВЗ",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectPvz).WithArgs(pvzId, "t1").
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(pvzId, fixedTime, "Москва", address, nil, active, "UTC"))
+				expectStatusAt(fixedTime, active, false)
+				mock.ExpectQuery(fmt.Sprintf("SELECT status_reception,id FROM %s (.+)", receptionTable)).
+					WithArgs(pvzId).WillReturnRows(sqlmock.NewRows([]string{"status_reception", "id"}).AddRow("close", uuid.New()))
+				mock.ExpectExec(fmt.Sprintf(`UPDATE %s SET archived_at`, pvzTable)).
+					WithArgs(pvzId, fixedTime).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(fmt.Sprintf(`INSERT INTO %s`, pvzStatusTable)).
+					WithArgs(pvzId, decommissioned, &fixedTime, nil, "", "u1").WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectQuery(selectPvz).WithArgs(pvzId, "t1").
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(pvzId, fixedTime, "Москва", address, fixedTime, decommissioned, "UTC"))
+				mock.ExpectCommit()
+			},
+			input: domain.PvzUpdate{Status: &decommissioned, EffectiveFrom: &fixedTime, ActorId: "u1"},
+			want:  domain.PVZ{Id: &pvzId, DateRegister: &fixedTime, City: "Москва", Address: address, Timezone: "UTC", Status: decommissioned, ArchivedAt: &fixedTime},
+		},
+		{
+			name: "Незакрытая приемка мешает выводу из работы",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectPvz).WithArgs(pvzId, "t1").
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(pvzId, fixedTime, "Москва", address, nil, active, "UTC"))
+				expectStatusAt(fixedTime, active, false)
+				mock.ExpectQuery(fmt.Sprintf("SELECT status_reception,id FROM %s (.+)", receptionTable)).
+					WithArgs(pvzId).WillReturnRows(sqlmock.NewRows([]string{"status_reception", "id"}).AddRow("in_progress", uuid.New()))
+				mock.ExpectRollback()
+			},
+			input:   domain.PvzUpdate{Status: &decommissioned, EffectiveFrom: &fixedTime},
+			wantErr: ErrReceptionInProgress,
+		},
+		{
+			name: "Вывод из работы с будущей даты только планируется",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectPvz).WithArgs(pvzId, "t1").
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(pvzId, fixedTime, "Москва", address, nil, active, "UTC"))
+				expectStatusAt(future, active, false)
+				mock.ExpectExec(fmt.Sprintf(`INSERT INTO %s`, pvzStatusTable)).
+					WithArgs(pvzId, decommissioned, future, nil, "", "u1").WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectQuery(selectPvz).WithArgs(pvzId, "t1").
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(pvzId, fixedTime, "Москва", address, nil, active, "UTC"))
+				mock.ExpectCommit()
+			},
+			input: domain.PvzUpdate{Status: &decommissioned, EffectiveFrom: &future, ActorId: "u1"},
+			want:  domain.PVZ{Id: &pvzId, DateRegister: &fixedTime, City: "Москва", Address: address, Timezone: "UTC", Status: active},
+		},
+		{
+			name: "Переход проверяется от статуса на дату",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectPvz).WithArgs(pvzId, "t1").
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(pvzId, fixedTime, "Москва", address, nil, active, "UTC"))
+				expectStatusAt(future, decommissioned, false)
+				mock.ExpectRollback()
+			},
+			input:   domain.PvzUpdate{Status: &closed, EffectiveFrom: &future},
+			wantErr: ErrPvzTransitionNotAllowed,
+		},
+		{
+			name: "Смена статуса раньше запланированной",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectPvz).WithArgs(pvzId, "t1").
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(pvzId, fixedTime, "Москва", address, nil, active, "UTC"))
+				expectStatusAt(fixedTime, active, true)
+				mock.ExpectRollback()
+			},
+			input:   domain.PvzUpdate{Status: &closed, EffectiveFrom: &fixedTime},
+			wantErr: ErrPvzTransitionNotAllowed,
+		},
+		{
+			name: "Архивный ПВЗ не меняется",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectPvz).WithArgs(pvzId, "t1").
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(pvzId, fixedTime, "Москва", address, fixedTime, decommissioned, "UTC"))
+				mock.ExpectRollback()
+			},
+			input:   domain.PvzUpdate{Status: &active, EffectiveFrom: &fixedTime},
+			wantErr: ErrPvzTransitionNotAllowed,
+		},
+		{
+			name: "ПВЗ не найден",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectPvz).WithArgs(pvzId, "t1").WillReturnRows(sqlmock.NewRows(columns))
+				mock.ExpectRollback()
+			},
+			input:   domain.PvzUpdate{Address: &address},
+			wantErr: ErrPvzNotFound,
+		},
+		{
+			name: "Ошибка БД",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectPvz).WithArgs(pvzId, "t1").WillReturnError(errors.New("ошибка бд"))
+				mock.ExpectRollback()
+			},
+			input:   domain.PvzUpdate{Address: &address},
+			wantErr: errors.New("ошибка бд"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			got, err := r.UpdatePvz(context.Background(), testScope, pvzId, tt.input)
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, tt.wantErr.Error())
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPvzPostgres_ArchiveDecommissionedPvz(t *testing.T) {
+	now := time.Date(2025, 4, 10, 15, 5, 17, 0, time.UTC)
+	effective := now.Add(-time.Hour)
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	r := NewPvzPostgres(sqlx.NewDb(db, "postgres"))
+	pvzId, recepId := uuid.New(), uuid.New()
+	decommissioned := domain.PvzDecommissioned
+	columns := []string{"id", "registrationdate", "city", "address", "archived_at", "status", "timezone"}
+	selectPvz := fmt.Sprintf(`SELECT (.+) FROM %s p WHERE p.id = \$1 AND (.+) FOR UPDATE OF p`, pvzTable)
+	receptionColumns := []string{"id", "date_received", "pvz_id", "status_reception"}
+
+	tests := []struct {
+		name    string
+		mock    func()
+		want    []domain.PVZ
+		wantErr bool
+	}{
+		{
+			name: "Открытая приемка закрывается перед архивированием",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(fmt.Sprintf(`SELECT p.id, (.+) FROM %s p WHERE p.archived_at IS NULL (.+) SKIP LOCKED`, pvzTable)).
+					WithArgs(now).WillReturnRows(sqlmock.NewRows([]string{"id", "effective_from"}).AddRow(pvzId, effective))
+				mock.ExpectQuery(selectPvz).WithArgs(pvzId, nil).
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(pvzId, now, "Москва", "", nil, decommissioned, "UTC"))
+				mock.ExpectQuery(fmt.Sprintf("SELECT status_reception,id FROM %s (.+)", receptionTable)).
+					WithArgs(pvzId).WillReturnRows(sqlmock.NewRows([]string{"status_reception", "id"}).AddRow("in_progress", recepId))
+				mock.ExpectQuery(fmt.Sprintf("SELECT id,date_received,pvz_id,status_reception FROM %s (.+) FOR UPDATE", receptionTable)).
+					WithArgs(recepId, nil).WillReturnRows(sqlmock.NewRows(receptionColumns).AddRow(recepId, effective, pvzId, "in_progress"))
+				mock.ExpectQuery(fmt.Sprintf(`COUNT\(\*\) FROM %s (.+)`, productTable)).
+					WithArgs(pvzId, recepId).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+				mock.ExpectQuery(fmt.Sprintf(`UPDATE %s SET status_reception = \$1`, receptionTable)).
+					WithArgs(domain.ReceptionClosed, recepId).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "date_received", "pvz_id", "status_reception", "flagged_at"}).AddRow(recepId, effective, pvzId, "close", nil))
+				mock.ExpectQuery(fmt.Sprintf(`SELECT id,date_received,pvz_id,status_reception FROM %s WHERE id = \$1`, receptionTable)).
+					WithArgs(recepId, nil).WillReturnRows(sqlmock.NewRows(receptionColumns).AddRow(recepId, effective, pvzId, "close"))
+				mock.ExpectQuery(fmt.Sprintf(`SELECT (.+) FROM %s WHERE reception_id = \$1 AND deleted_at IS NULL`, productTable)).
+					WithArgs(recepId).WillReturnRows(sqlmock.NewRows([]string{"id", "date_received", "type_product", "reception_id", "pvz_id", "issued_at"}))
+				mock.ExpectExec(fmt.Sprintf(`INSERT INTO %s`, versionsTable)).
+					WithArgs(recepId, 1, nil, "", sqlmock.AnyArg(), nil).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(fmt.Sprintf(`UPDATE %s SET archived_at`, pvzTable)).
+					WithArgs(pvzId, effective).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery(selectPvz).WithArgs(pvzId, nil).
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(pvzId, now, "Москва", "", effective, decommissioned, "UTC"))
+				mock.ExpectCommit()
+			},
+			want: []domain.PVZ{{Id: &pvzId, DateRegister: &now, City: "Москва", Timezone: "UTC", Status: decommissioned, ArchivedAt: &effective}},
+		},
+		{
+			name: "Нет ПВЗ для архивирования",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(fmt.Sprintf(`SELECT p.id, (.+) FROM %s p WHERE p.archived_at IS NULL (.+) SKIP LOCKED`, pvzTable)).
+					WithArgs(now).WillReturnRows(sqlmock.NewRows([]string{"id", "effective_from"}))
+				mock.ExpectCommit()
+			},
+			want: []domain.PVZ{},
+		},
+		{
+			name: "Ошибка БД",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(fmt.Sprintf(`SELECT p.id, (.+) FROM %s p WHERE p.archived_at IS NULL (.+) SKIP LOCKED`, pvzTable)).
+					WithArgs(now).WillReturnError(errors.New("ошибка бд"))
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			got, err := r.ArchiveDecommissionedPvz(context.Background(), now)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
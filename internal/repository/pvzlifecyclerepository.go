@@ -0,0 +1,258 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bllooop/pvzservice/internal/domain"
+	logger "github.com/bllooop/pvzservice/pkg/logging"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrPvzNotFound             = errors.New("ПВЗ не найден")
+	ErrPvzNotActive            = errors.New("ПВЗ не работает")
+	ErrPvzTransitionNotAllowed = errors.New("недопустимая смена статуса ПВЗ")
+)
+
+// pvzStatusAt вычисляет статус ПВЗ p на момент at: последнюю по дате начала
+// запись истории, срок действия которой к этому моменту не истек. Без записей
+// ПВЗ активен.
+func pvzStatusAt(at string) string {
+	return fmt.Sprintf(`COALESCE((SELECT s.status::text FROM %[1]s s
+  WHERE s.pvz_id = p.id AND s.effective_from <= %[2]s AND (s.effective_to IS NULL OR s.effective_to > %[2]s)
+  ORDER BY s.effective_from DESC, s.id DESC LIMIT 1), '%[3]s')`, pvzStatusTable, at, domain.PvzActive)
+}
+
+// pvzStatusExpr вычисляет действующий статус ПВЗ p.
+var pvzStatusExpr = pvzStatusAt("now()")
+
+var pvzColumns = "p.id, p.registrationdate, p.city, p.address, p.postal_code, p.working_hours, p.latitude, p.longitude, p.capacity, p.timezone, p.tenant_id, p.archived_at, " +
+	pvzStatusExpr + " AS status"
+
+// UpdatePvz меняет город и адрес ПВЗ и записывает смену статуса с датами действия.
+// При выводе из работы ПВЗ архивируется, его приемки и товары сохраняются.
+// Вывод из работы с будущей даты только записывается в историю, ПВЗ
+// архивирует ArchiveDecommissionedPvz после наступления этой даты.
+func (r *PvzPostgres) UpdatePvz(ctx context.Context, scope domain.TenantScope, pvzId uuid.UUID, input domain.PvzUpdate) (domain.PVZ, error) {
+	ctx, done := startQuery(ctx, r.timeouts.Write, "PvzPostgres.UpdatePvz")
+	defer done()
+	tx, err := r.beginTx(ctx)
+	if err != nil {
+		return domain.PVZ{}, err
+	}
+	defer tx.Rollback()
+
+	current, err := r.getPvzForUpdate(ctx, tx, scope, pvzId)
+	if err != nil {
+		return domain.PVZ{}, err
+	}
+	if current.ArchivedAt != nil {
+		return domain.PVZ{}, ErrPvzTransitionNotAllowed
+	}
+	if input.City != nil || input.Address != nil || input.PostalCode != nil || input.WorkingHours != nil || input.Latitude != nil || input.Capacity != nil || input.Timezone != nil {
+		query := fmt.Sprintf(`UPDATE %s SET city = COALESCE($2, city), address = COALESCE($3, address),
+  postal_code = COALESCE($4, postal_code), working_hours = COALESCE($5, working_hours),
+  latitude = COALESCE($6, latitude), longitude = COALESCE($7, longitude), capacity = COALESCE($8, capacity),
+  timezone = COALESCE($9, timezone) WHERE id = $1`, pvzTable)
+		logger.FromContext(ctx).Debug().Str("query", query).Msg("Изменение данных ПВЗ")
+		if _, err := tx.ExecContext(ctx, query, pvzId, input.City, input.Address, input.PostalCode, input.WorkingHours, input.Latitude, input.Longitude, input.Capacity, input.Timezone); err != nil {
+			return domain.PVZ{}, err
+		}
+	}
+	if input.Status != nil {
+		if err := r.changePvzStatus(ctx, tx, pvzId, input); err != nil {
+			return domain.PVZ{}, err
+		}
+	}
+	updated, err := r.getPvzForUpdate(ctx, tx, scope, pvzId)
+	if err != nil {
+		return domain.PVZ{}, err
+	}
+	updated = updated.In(domain.PvzLocation(updated.Timezone))
+	if err := auditTx(ctx, tx, pvzId.String(), current.In(domain.PvzLocation(current.Timezone)), updated); err != nil {
+		return domain.PVZ{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return domain.PVZ{}, err
+	}
+	return updated, nil
+}
+
+// getPvzForUpdate блокирует ПВЗ компании из scope. ПВЗ другой компании
+// неотличим от несуществующего.
+func (r *PvzPostgres) getPvzForUpdate(ctx context.Context, tx *sqlx.Tx, scope domain.TenantScope, pvzId uuid.UUID) (domain.PVZ, error) {
+	query := fmt.Sprintf(`SELECT %s FROM %s p WHERE p.id = $1 AND %s FOR UPDATE OF p`, pvzColumns, pvzTable, tenantCondition("p.tenant_id", 2))
+	logger.FromContext(ctx).Debug().Str("query", query).Msg("Получение ПВЗ")
+	var pvz domain.PVZ
+	if err := tx.GetContext(ctx, &pvz, query, pvzId, scope.Filter()); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.PVZ{}, ErrPvzNotFound
+		}
+		return domain.PVZ{}, err
+	}
+	return pvz, nil
+}
+
+// changePvzStatus записывает смену статуса с input.EffectiveFrom. Переход
+// проверяется от статуса, действующего на эту дату, а не на текущий момент.
+// Смена статуса раньше уже запланированной не допускается.
+func (r *PvzPostgres) changePvzStatus(ctx context.Context, tx *sqlx.Tx, pvzId uuid.UUID, input domain.PvzUpdate) error {
+	at := *input.EffectiveFrom
+	query := fmt.Sprintf(`SELECT %s, EXISTS(SELECT 1 FROM %s s WHERE s.pvz_id = p.id AND s.effective_from > $2::timestamptz)
+  FROM %s p WHERE p.id = $1`, pvzStatusAt("$2::timestamptz"), pvzStatusTable, pvzTable)
+	logger.FromContext(ctx).Debug().Str("query", query).Msg("Получение статуса ПВЗ на дату")
+	var status string
+	var scheduled bool
+	if err := tx.QueryRowxContext(ctx, query, pvzId, at).Scan(&status, &scheduled); err != nil {
+		return err
+	}
+	if *input.Status == status {
+		return nil
+	}
+	if scheduled || !domain.PvzTransitionAllowed(status, *input.Status) {
+		return ErrPvzTransitionNotAllowed
+	}
+	if *input.Status == domain.PvzDecommissioned && !at.After(time.Now()) {
+		if err := r.archivePvz(ctx, tx, pvzId, at); err != nil {
+			return err
+		}
+	}
+	query = fmt.Sprintf(`INSERT INTO %s (pvz_id,status,effective_from,effective_to,reason,actor_id) VALUES ($1,$2,$3,$4,$5,$6)`, pvzStatusTable)
+	logger.FromContext(ctx).Debug().Str("query", query).Msg("Смена статуса ПВЗ")
+	_, err := tx.ExecContext(ctx, query, pvzId, *input.Status, at, input.EffectiveTo, input.Reason, input.ActorId)
+	return err
+}
+
+// archivePvz помечает ПВЗ архивным. Вывести из работы ПВЗ с незакрытой
+// приемкой нельзя: ее нужно сначала закрыть.
+func (r *PvzPostgres) archivePvz(ctx context.Context, tx *sqlx.Tx, pvzId uuid.UUID, at time.Time) error {
+	lastStatus, _, err := r.getLastReceptionStatus(ctx, tx, pvzId)
+	if err != nil {
+		return err
+	}
+	if lastStatus == domain.ReceptionInProgress {
+		return ErrReceptionInProgress
+	}
+	return r.setPvzArchived(ctx, tx, pvzId, at)
+}
+
+func (r *PvzPostgres) setPvzArchived(ctx context.Context, tx *sqlx.Tx, pvzId uuid.UUID, at time.Time) error {
+	query := fmt.Sprintf(`UPDATE %s SET archived_at = $2 WHERE id = $1`, pvzTable)
+	logger.FromContext(ctx).Debug().Str("query", query).Msg("Архивирование ПВЗ")
+	_, err := tx.ExecContext(ctx, query, pvzId, at)
+	return err
+}
+
+type decommissionedPvzRow struct {
+	Id            uuid.UUID `db:"id"`
+	EffectiveFrom time.Time `db:"effective_from"`
+}
+
+// ArchiveDecommissionedPvz архивирует ПВЗ, вывод из работы которых вступил в
+// силу к now. Незакрытая приемка такого ПВЗ закрывается, а если в ней нет
+// товаров - отменяется: после вывода из работы закрыть ее вручную уже нельзя.
+// ПВЗ, заблокированные другой транзакцией, пропускаются до следующего прохода.
+func (r *PvzPostgres) ArchiveDecommissionedPvz(ctx context.Context, now time.Time) ([]domain.PVZ, error) {
+	ctx, done := startQuery(ctx, r.timeouts.Report, "PvzPostgres.ArchiveDecommissionedPvz")
+	defer done()
+	tx, err := r.beginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := fmt.Sprintf(`SELECT p.id, (SELECT MAX(s.effective_from) FROM %s s WHERE s.pvz_id = p.id AND s.status = '%s') AS effective_from
+  FROM %s p WHERE p.archived_at IS NULL AND %s = '%s'
+  ORDER BY p.id FOR UPDATE OF p SKIP LOCKED`, pvzStatusTable, domain.PvzDecommissioned, pvzTable, pvzStatusAt("$1::timestamptz"), domain.PvzDecommissioned)
+	logger.FromContext(ctx).Debug().Str("query", query).Msg("Поиск выведенных из работы ПВЗ")
+	var rows []decommissionedPvzRow
+	if err := tx.SelectContext(ctx, &rows, query, now); err != nil {
+		return nil, err
+	}
+	archived := make([]domain.PVZ, 0, len(rows))
+	for _, row := range rows {
+		before, err := r.getPvzForUpdate(ctx, tx, domain.AllTenants(), row.Id)
+		if err != nil {
+			return nil, err
+		}
+		pvzCtx := auditTenant(ctx, before.TenantId)
+		if err := r.finishOpenReception(pvzCtx, tx, row.Id); err != nil {
+			return nil, err
+		}
+		if err := r.setPvzArchived(ctx, tx, row.Id, row.EffectiveFrom); err != nil {
+			return nil, err
+		}
+		after, err := r.getPvzForUpdate(ctx, tx, domain.AllTenants(), row.Id)
+		if err != nil {
+			return nil, err
+		}
+		loc := domain.PvzLocation(after.Timezone)
+		if err := auditActionTx(auditEntity(pvzCtx, "pvz"), tx, "pvz.archive", row.Id.String(), before.In(loc), after.In(loc)); err != nil {
+			return nil, err
+		}
+		archived = append(archived, after.In(loc))
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return archived, nil
+}
+
+// finishOpenReception закрывает незакрытую приемку ПВЗ или отменяет ее, если
+// в ней нет товаров.
+func (r *PvzPostgres) finishOpenReception(ctx context.Context, tx *sqlx.Tx, pvzId uuid.UUID) error {
+	lastStatus, recepId, err := r.getLastReceptionStatus(ctx, tx, pvzId)
+	if err != nil || lastStatus != domain.ReceptionInProgress {
+		return err
+	}
+	before, err := r.getReception(ctx, tx, domain.AllTenants(), recepId, true)
+	if err != nil {
+		return err
+	}
+	products, err := r.countReceptionProducts(ctx, tx, pvzId, recepId)
+	if err != nil {
+		return err
+	}
+	ctx = auditEntity(ctx, "reception")
+	if products == 0 {
+		recep, err := r.setReceptionStatus(ctx, tx, recepId, domain.ReceptionCancelled)
+		if err != nil {
+			return err
+		}
+		return auditActionTx(ctx, tx, "reception.cancel", recepId.String(), before, recep)
+	}
+	recep, err := r.setReceptionStatus(ctx, tx, recepId, domain.ReceptionClosed)
+	if err != nil {
+		return err
+	}
+	if err := r.insertClosedVersion(ctx, tx, domain.AllTenants(), recepId); err != nil {
+		return err
+	}
+	return auditActionTx(ctx, tx, "reception.autoclose", recepId.String(), before, recep)
+}
+
+// checkPvzActive блокирует ПВЗ от смены статуса до конца транзакции и
+// проверяет, что он относится к компании из scope и сейчас принимает операции
+// с приемками. Приемки и товары всегда принадлежат компании своего ПВЗ, поэтому
+// дальнейшие запросы по pvz_id не выходят за ее пределы. Возвращает часовой
+// пояс ПВЗ, в котором отдаются отметки времени его приемок и товаров.
+func (r *PvzPostgres) checkPvzActive(ctx context.Context, tx *sqlx.Tx, scope domain.TenantScope, pvzId uuid.UUID) (*time.Location, error) {
+	query := fmt.Sprintf(`SELECT %s, p.timezone FROM %s p WHERE p.id = $1 AND %s FOR SHARE OF p`, pvzStatusExpr, pvzTable, tenantCondition("p.tenant_id", 2))
+	logger.FromContext(ctx).Debug().Str("query", query).Msg("Проверка статуса ПВЗ")
+	var status, timezone string
+	if err := tx.QueryRowxContext(ctx, query, pvzId, scope.Filter()).Scan(&status, &timezone); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrPvzNotFound
+		}
+		return nil, err
+	}
+	if status != domain.PvzActive {
+		return nil, ErrPvzNotActive
+	}
+	return domain.PvzLocation(timezone), nil
+}
@@ -1,19 +1,28 @@
 package repository
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"testing"
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/bllooop/pvzservice/internal/domain"
+	"github.com/bllooop/pvzservice/prometheus"
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 )
 
+// testScope — компания, которой ограничены запросы в тестах хранилища.
+var testScope = domain.TenantOf("t1")
+
 func TestPvzPostgres_CreatePvz(t *testing.T) {
 	fixedTime := time.Date(2025, 4, 10, 15, 5, 17, 329922000, time.UTC)
+	lat, lon := 55.7558, 37.6173
+	capacity := 500
 	db, mock, err := sqlmock.New()
 	if err != nil {
 		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
@@ -36,26 +45,47 @@ func TestPvzPostgres_CreatePvz(t *testing.T) {
 		{
 			name: "Ok",
 			mock: func() {
-				rows := sqlmock.NewRows([]string{"id", "registrationdate", "city"}).AddRow(&userID, fixedTime, "Москва")
+				rows := sqlmock.NewRows([]string{"id", "registrationdate", "city", "address", "postal_code", "working_hours", "latitude", "longitude", "capacity", "timezone", "tenant_id"}).
+					AddRow(&userID, fixedTime, "Москва", "ул. Ленина, 1", "101000", "Пн-Вс 9:00-21:00", lat, lon, capacity, "UTC", "t1")
+				mock.ExpectBegin()
 				mock.ExpectQuery("INSERT INTO pvz").
-					WithArgs(&fixedTime, "Москва").WillReturnRows(rows)
+					WithArgs(&fixedTime, "Москва", "ул. Ленина, 1", "101000", "Пн-Вс 9:00-21:00", &lat, &lon, &capacity, "UTC", "t1").WillReturnRows(rows)
+				mock.ExpectCommit()
 			},
 			input: domain.PVZ{
 				DateRegister: &fixedTime,
 				City:         "Москва",
+				Address:      "ул. Ленина, 1",
+				PostalCode:   "101000",
+				WorkingHours: "Пн-Вс 9:00-21:00",
+				Latitude:     &lat,
+				Longitude:    &lon,
+				Capacity:     &capacity,
+				Timezone:     "UTC",
 			},
 			want: domain.PVZ{
 				Id:           &userID,
 				DateRegister: &fixedTime,
 				City:         "Москва",
+				Address:      "ул. Ленина, 1",
+				PostalCode:   "101000",
+				WorkingHours: "Пн-Вс 9:00-21:00",
+				Latitude:     &lat,
+				Longitude:    &lon,
+				Capacity:     &capacity,
+				Timezone:     "UTC",
+				Status:       domain.PvzActive,
+				TenantId:     "t1",
 			},
 		},
 		{
 			name: "Ошибка БД",
 			mock: func() {
+				mock.ExpectBegin()
 				mock.ExpectQuery("INSERT INTO pvz").
-					WithArgs(&fixedTime, "Москва").
+					WithArgs(&fixedTime, "Москва", "", "", "", nil, nil, nil, "", "t1").
 					WillReturnError(errors.New("ошибка бд"))
+				mock.ExpectRollback()
 			},
 			input: domain.PVZ{
 				DateRegister: &fixedTime,
@@ -68,9 +98,11 @@ func TestPvzPostgres_CreatePvz(t *testing.T) {
 			name: "Ошибка Scan",
 			mock: func() {
 				rows := sqlmock.NewRows([]string{"id", "registrationdate"}).AddRow(uuid.New(), time.Now())
+				mock.ExpectBegin()
 				mock.ExpectQuery("INSERT INTO pvz").
-					WithArgs(&fixedTime, "Москва").
+					WithArgs(&fixedTime, "Москва", "", "", "", nil, nil, nil, "", "t1").
 					WillReturnRows(rows)
+				mock.ExpectRollback()
 			},
 			input: domain.PVZ{
 				DateRegister: &fixedTime,
@@ -85,7 +117,7 @@ func TestPvzPostgres_CreatePvz(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.mock()
 
-			got, err := r.CreatePvz(tt.input)
+			got, err := r.CreatePvz(context.Background(), testScope, tt.input)
 			if tt.wantErr {
 				assert.Error(t, err)
 			} else {
@@ -97,6 +129,77 @@ func TestPvzPostgres_CreatePvz(t *testing.T) {
 	}
 }
 
+func TestPvzPostgres_GetListOFpvz(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	r := NewPvzPostgres(sqlx.NewDb(db, "postgres"))
+	pvzId := uuid.New()
+	query := `SELECT (.+) FROM pvz p WHERE \(\$1::text IS NULL OR p.tenant_id = \$1\)`
+
+	tests := []struct {
+		name  string
+		scope domain.TenantScope
+		arg   any
+	}{
+		{
+			name:  "ПВЗ одной компании",
+			scope: testScope,
+			arg:   "t1",
+		},
+		{
+			name:  "Все компании для администратора",
+			scope: domain.AllTenants(),
+			arg:   nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock.ExpectQuery(query).WithArgs(tt.arg).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "city", "timezone", "tenant_id"}).AddRow(pvzId, "Москва", "UTC", "t1"))
+
+			got, err := r.GetListOFpvz(context.Background(), tt.scope)
+			assert.NoError(t, err)
+			assert.Len(t, got, 1)
+			assert.Equal(t, "t1", got[0].TenantId)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPvzPostgres_GetListOFpvzCancelled(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	r := NewPvzPostgres(sqlx.NewDb(db, "postgres"))
+	r.timeouts.Read = 20 * time.Millisecond
+	query := `SELECT (.+) FROM pvz p`
+	method := "PvzPostgres.GetListOFpvz"
+
+	t.Run("Таймаут операции", func(t *testing.T) {
+		before := testutil.ToFloat64(prometheus.DBQueriesCancelledTotal.WithLabelValues(method, "timeout"))
+		mock.ExpectQuery(query).WillDelayFor(time.Second).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		_, err := r.GetListOFpvz(context.Background(), testScope)
+		assert.Error(t, err)
+		assert.Equal(t, before+1, testutil.ToFloat64(prometheus.DBQueriesCancelledTotal.WithLabelValues(method, "timeout")))
+	})
+	t.Run("Отмена клиентом", func(t *testing.T) {
+		before := testutil.ToFloat64(prometheus.DBQueriesCancelledTotal.WithLabelValues(method, "canceled"))
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := r.GetListOFpvz(ctx, testScope)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, before+1, testutil.ToFloat64(prometheus.DBQueriesCancelledTotal.WithLabelValues(method, "canceled")))
+	})
+}
+
 func TestPvzPostgres_getPvz(t *testing.T) {
 	fixedTime := time.Date(2025, 4, 10, 15, 5, 17, 329922000, time.UTC)
 	db, mock, err := sqlmock.New()
@@ -110,8 +213,10 @@ func TestPvzPostgres_getPvz(t *testing.T) {
 	}
 	sqlxDB := sqlx.NewDb(db, "postgres")
 	r := NewPvzPostgres(sqlxDB)
+	productID := uuid.New()
 	typ := "электроника"
 	stat := "in_progress"
+	localTime := fixedTime.In(domain.PvzLocation("Asia/Yekaterinburg"))
 	tests := []struct {
 		name    string
 		mock    func()
@@ -123,12 +228,16 @@ func TestPvzPostgres_getPvz(t *testing.T) {
 			name: "Ok",
 			mock: func() {
 				mock.ExpectBegin()
-				pvzRows := sqlmock.NewRows([]string{"id", "registrationdate", "city"}).AddRow(userID, fixedTime, "Москва")
-				mock.ExpectQuery("SELECT \\* FROM pvz").WillReturnRows(pvzRows)
+				pvzRows := sqlmock.NewRows([]string{"id", "registrationdate", "city", "timezone"}).AddRow(userID, fixedTime, "Москва", "Asia/Yekaterinburg")
+				mock.ExpectQuery("SELECT (.+) FROM pvz p").WillReturnRows(pvzRows)
 				recepRows := sqlmock.NewRows([]string{"id", "date_received", "pvz_id", "status_reception"}).AddRow(userID, fixedTime, userID, stat)
-				mock.ExpectQuery("SELECT \\* FROM product_reception").WillReturnRows(recepRows)
+				mock.ExpectQuery("SELECT (.+) FROM product_reception").WillReturnRows(recepRows)
 				prodRows := sqlmock.NewRows([]string{"id", "date_received", "type_product", "reception_id", "pvz_id"}).AddRow(userID, fixedTime, typ, userID, userID)
-				mock.ExpectQuery("SELECT \\* FROM product").WillReturnRows(prodRows)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE deleted_at IS NULL", productTable)).WillReturnRows(prodRows)
+				corrRows := sqlmock.NewRows([]string{"id", "reception_id", "product_id", "action", "reason", "comment", "actor_id", "created_at"}).
+					AddRow(1, userID, productID, "remove", "mis_scan", "", "u1", fixedTime)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE reception_id IN \\(\\$1\\)", correctionsTable)).
+					WithArgs(userID).WillReturnRows(corrRows)
 				mock.ExpectCommit()
 			},
 			input: domain.GettingPvzParams{
@@ -140,15 +249,16 @@ func TestPvzPostgres_getPvz(t *testing.T) {
 				{
 					PvzInfo: domain.PVZ{
 						Id:           &userID,
-						DateRegister: &fixedTime,
+						DateRegister: &localTime,
 						City:         "Москва",
+						Timezone:     "Asia/Yekaterinburg",
 					},
 					ReceptionsInfo: []domain.Receptions{
 						{
 							ReceptionInfo: domain.ProductReception{
 								Id:           &userID,
 								PVZId:        &userID,
-								DateReceived: &fixedTime,
+								DateReceived: &localTime,
 								Status:       &stat,
 							},
 							ProductInfo: []domain.Product{
@@ -156,21 +266,52 @@ func TestPvzPostgres_getPvz(t *testing.T) {
 									Id:           &userID,
 									ReceptionId:  &userID,
 									Type:         typ,
-									DateReceived: &fixedTime,
+									DateReceived: &localTime,
 									PVZId:        &userID,
 								},
 							},
+							Corrections: []domain.ProductCorrection{
+								{
+									Id:          1,
+									ReceptionId: userID,
+									ProductId:   productID,
+									Action:      "remove",
+									Reason:      "mis_scan",
+									ActorId:     "u1",
+									CreatedAt:   localTime,
+								},
+							},
 						},
 					},
 				},
 			},
 			wantErr: false,
 		},
+		{
+			name: "Фильтр по местным суткам ПВЗ",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT (.+) FROM pvz p WHERE \(registrationDate AT TIME ZONE timezone\)::date >= \$1::date AND (.+) <= \$2::date AND \(\$3::text IS NULL OR p.tenant_id = \$3\) LIMIT \$4 OFFSET \$5`).
+					WithArgs("2025-04-10", "2025-04-11", "t1", 10, 0).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+				mock.ExpectQuery(`SELECT (.+) FROM product_reception WHERE \(date_received AT TIME ZONE \(SELECT z.timezone FROM pvz z WHERE z.id = product_reception.pvz_id\)\)::date >= \$1::date`).
+					WithArgs("2025-04-10", "2025-04-11", "t1", 10, 0).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+				mock.ExpectQuery(`SELECT (.+) FROM product WHERE deleted_at IS NULL AND \(date_received AT TIME ZONE \(SELECT z.timezone FROM pvz z WHERE z.id = product.pvz_id\)\)::date >= \$1::date`).
+					WithArgs("2025-04-10", "2025-04-11", "t1", 10, 0).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+				mock.ExpectCommit()
+			},
+			input: domain.GettingPvzParams{
+				Start:    time.Date(2025, 4, 10, 0, 0, 0, 0, time.UTC),
+				End:      time.Date(2025, 4, 11, 0, 0, 0, 0, time.UTC),
+				Page:     1,
+				Limit:    10,
+				LocalDay: true,
+			},
+		},
 		{
 			name: "Ошибка при запросе PVZ",
 			mock: func() {
 				mock.ExpectBegin()
-				mock.ExpectQuery("SELECT \\* FROM pvz").
+				mock.ExpectQuery("SELECT (.+) FROM pvz p").
 					WillReturnError(errors.New("db error"))
 				mock.ExpectRollback()
 			},
@@ -187,10 +328,10 @@ func TestPvzPostgres_getPvz(t *testing.T) {
 			mock: func() {
 				mock.ExpectBegin()
 				pvzRows := sqlmock.NewRows([]string{"id", "registrationdate", "city"}).AddRow(userID, fixedTime, "Москва")
-				mock.ExpectQuery("SELECT \\* FROM pvz").
+				mock.ExpectQuery("SELECT (.+) FROM pvz p").
 					WillReturnRows(pvzRows)
 
-				mock.ExpectQuery("SELECT \\* FROM product_reception").
+				mock.ExpectQuery("SELECT (.+) FROM product_reception").
 					WillReturnError(errors.New("reception error"))
 				mock.ExpectRollback()
 
@@ -209,14 +350,13 @@ func TestPvzPostgres_getPvz(t *testing.T) {
 				mock.ExpectBegin()
 				pvzRows := sqlmock.NewRows([]string{"id", "registrationdate", "city"}).
 					AddRow(userID, fixedTime, "Москва")
-				mock.ExpectQuery("SELECT \\* FROM pvz").
+				mock.ExpectQuery("SELECT (.+) FROM pvz p").
 					WillReturnRows(pvzRows)
 
 				recepRows := sqlmock.NewRows([]string{"id", "date_received", "pvz_id", "status_reception"}).AddRow(userID, fixedTime, userID, stat)
-				mock.ExpectQuery("SELECT \\* FROM product_reception").
+				mock.ExpectQuery("SELECT (.+) FROM product_reception").
 					WillReturnRows(recepRows)
-
-				mock.ExpectQuery("SELECT \\* FROM product").
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE deleted_at IS NULL", productTable)).
 					WillReturnError(errors.New("product error"))
 				mock.ExpectRollback()
 			},
@@ -233,7 +373,7 @@ func TestPvzPostgres_getPvz(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.mock()
 
-			got, err := r.GetPvz(tt.input)
+			got, err := r.GetPvz(context.Background(), testScope, tt.input)
 			if tt.wantErr {
 				assert.Error(t, err)
 			} else {
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/bllooop/pvzservice/internal/domain"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPvzPostgres_GetPvzReport(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	r := NewPvzPostgres(sqlx.NewDb(db, "postgres"))
+	pvzId := uuid.New()
+	selectTimezone := fmt.Sprintf(`SELECT timezone FROM %s WHERE id = \$1 AND (.+)tenant_id = \$2`, pvzTable)
+	selectReport := `WITH bounds AS (.+) SELECT to_char\(day, 'YYYY-MM-DD'\) AS day`
+	reportColumns := []string{"day", "receptions", "products", "issued"}
+
+	tests := []struct {
+		name    string
+		mock    func()
+		input   domain.PvzReportParams
+		want    domain.PvzReport
+		wantErr error
+	}{
+		{
+			name: "Местные сутки ПВЗ",
+			mock: func() {
+				mock.ExpectQuery(selectTimezone).WithArgs(pvzId, "t1").
+					WillReturnRows(sqlmock.NewRows([]string{"timezone"}).AddRow("Asia/Yekaterinburg"))
+				mock.ExpectQuery(selectReport).WithArgs(pvzId, "Asia/Yekaterinburg", "2025-04-10", "2025-04-11").
+					WillReturnRows(sqlmock.NewRows(reportColumns).AddRow("2025-04-10", 1, 12, 0).AddRow("2025-04-11", 0, 0, 5))
+			},
+			input: domain.PvzReportParams{PvzId: pvzId, From: "2025-04-10", To: "2025-04-11", LocalDay: true},
+			want: domain.PvzReport{PvzId: pvzId, Timezone: "Asia/Yekaterinburg", Days: []domain.PvzReportDay{
+				{Day: "2025-04-10", Receptions: 1, Products: 12},
+				{Day: "2025-04-11", Issued: 5},
+			}},
+		},
+		{
+			name: "Сутки по UTC",
+			mock: func() {
+				mock.ExpectQuery(selectTimezone).WithArgs(pvzId, "t1").
+					WillReturnRows(sqlmock.NewRows([]string{"timezone"}).AddRow("Asia/Yekaterinburg"))
+				mock.ExpectQuery(selectReport).WithArgs(pvzId, "UTC", "2025-04-10", "2025-04-10").
+					WillReturnRows(sqlmock.NewRows(reportColumns))
+			},
+			input: domain.PvzReportParams{PvzId: pvzId, From: "2025-04-10", To: "2025-04-10"},
+			want:  domain.PvzReport{PvzId: pvzId, Timezone: "UTC", Days: []domain.PvzReportDay{}},
+		},
+		{
+			name: "ПВЗ не найден",
+			mock: func() {
+				mock.ExpectQuery(selectTimezone).WithArgs(pvzId, "t1").WillReturnRows(sqlmock.NewRows([]string{"timezone"}))
+			},
+			input:   domain.PvzReportParams{PvzId: pvzId, From: "2025-04-10", To: "2025-04-10"},
+			wantErr: ErrPvzNotFound,
+		},
+		{
+			name: "Ошибка БД",
+			mock: func() {
+				mock.ExpectQuery(selectTimezone).WithArgs(pvzId, "t1").
+					WillReturnRows(sqlmock.NewRows([]string{"timezone"}).AddRow("Europe/Moscow"))
+				mock.ExpectQuery(selectReport).WillReturnError(errors.New("ошибка бд"))
+			},
+			input:   domain.PvzReportParams{PvzId: pvzId, From: "2025-04-10", To: "2025-04-10"},
+			wantErr: errors.New("ошибка бд"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			got, err := r.GetPvzReport(context.Background(), testScope, tt.input)
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, tt.wantErr.Error())
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/bllooop/pvzservice/internal/domain"
+	logger "github.com/bllooop/pvzservice/pkg/logging"
+)
+
+// GetPvzReport считает приемки, принятые и выданные товары ПВЗ по суткам.
+// Границы суток берутся в часовом поясе ПВЗ при params.LocalDay, иначе в UTC.
+// Дни без событий в отчет не попадают.
+func (r *PvzPostgres) GetPvzReport(ctx context.Context, scope domain.TenantScope, params domain.PvzReportParams) (domain.PvzReport, error) {
+	ctx, done := startQuery(ctx, r.timeouts.Report, "PvzPostgres.GetPvzReport")
+	defer done()
+	report := domain.PvzReport{PvzId: params.PvzId, Timezone: "UTC", Days: []domain.PvzReportDay{}}
+	var timezone string
+	query := fmt.Sprintf(`SELECT timezone FROM %s WHERE id = $1 AND %s`, pvzTable, tenantCondition("tenant_id", 2))
+	if err := r.db.GetContext(ctx, &timezone, query, params.PvzId, scope.Filter()); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.PvzReport{}, ErrPvzNotFound
+		}
+		return domain.PvzReport{}, err
+	}
+	if params.LocalDay {
+		report.Timezone = timezone
+	}
+	query = fmt.Sprintf(`WITH bounds AS (
+    SELECT $3::date::timestamp AT TIME ZONE $2 AS since, ($4::date + 1)::timestamp AT TIME ZONE $2 AS until
+  )
+  SELECT to_char(day, 'YYYY-MM-DD') AS day, SUM(receptions) AS receptions, SUM(products) AS products, SUM(issued) AS issued
+  FROM (
+    SELECT (r.date_received AT TIME ZONE $2)::date AS day, 1 AS receptions, 0 AS products, 0 AS issued
+    FROM %[1]s r, bounds b
+    WHERE r.pvz_id = $1 AND r.status_reception <> '%[3]s' AND r.date_received >= b.since AND r.date_received < b.until
+    UNION ALL
+    SELECT (p.date_received AT TIME ZONE $2)::date, 0, 1, 0
+    FROM %[2]s p, bounds b
+    WHERE p.pvz_id = $1 AND p.deleted_at IS NULL AND p.date_received >= b.since AND p.date_received < b.until
+    UNION ALL
+    SELECT (p.issued_at AT TIME ZONE $2)::date, 0, 0, 1
+    FROM %[2]s p, bounds b
+    WHERE p.pvz_id = $1 AND p.deleted_at IS NULL AND p.issued_at >= b.since AND p.issued_at < b.until
+  ) events GROUP BY day ORDER BY day`, receptionTable, productTable, domain.ReceptionCancelled)
+	logger.FromContext(ctx).Debug().Str("query", query).Msg("Получение отчета ПВЗ по дням")
+	if err := r.db.SelectContext(ctx, &report.Days, query, params.PvzId, report.Timezone, params.From, params.To); err != nil {
+		return domain.PvzReport{}, err
+	}
+	return report, nil
+}
@@ -4,52 +4,82 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/bllooop/pvzservice/internal/domain"
 	logger "github.com/bllooop/pvzservice/pkg/logging"
+	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 )
 
 type PvzPostgres struct {
-	db *sqlx.DB
+	db       *sqlx.DB
+	timeouts Timeouts
 }
 
 func NewPvzPostgres(db *sqlx.DB) *PvzPostgres {
 	return &PvzPostgres{
-		db: db,
+		db:       db,
+		timeouts: DefaultTimeouts,
 	}
 }
-func (r *PvzPostgres) beginTx() (*sqlx.Tx, error) {
-	tx, err := r.db.Beginx()
+
+// beginTx начинает транзакцию, которая откатывается при отмене ctx.
+func (r *PvzPostgres) beginTx(ctx context.Context) (*sqlx.Tx, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
 	return tx, nil
 }
-func (r *PvzPostgres) GetListOFpvz(ctx context.Context) ([]domain.PVZ, error) {
+func (r *PvzPostgres) GetListOFpvz(ctx context.Context, scope domain.TenantScope) ([]domain.PVZ, error) {
+	ctx, done := startQuery(ctx, r.timeouts.Read, "PvzPostgres.GetListOFpvz")
+	defer done()
 	var pvzList []domain.PVZ
-	query := fmt.Sprintf("SELECT * FROM %s", pvzTable)
-	err := r.db.SelectContext(ctx, &pvzList, query)
+	query := fmt.Sprintf("SELECT %s FROM %s p WHERE %s", pvzColumns, pvzTable, tenantCondition("p.tenant_id", 1))
+	err := r.db.SelectContext(ctx, &pvzList, query, scope.Filter())
 	if err != nil {
-		logger.Log.Error().Err(err).Msg("Ошибка при выполнении запроса для получения списка ПВЗ")
+		logger.FromContext(ctx).Error().Err(err).Msg("Ошибка при выполнении запроса для получения списка ПВЗ")
 		return nil, err
 	}
-	logger.Log.Debug().Any("query", query).Msg("Запрос данных о ПВЗ")
+	logger.FromContext(ctx).Debug().Any("query", query).Msg("Запрос данных о ПВЗ")
+	for i, pvz := range pvzList {
+		pvzList[i] = pvz.In(domain.PvzLocation(pvz.Timezone))
+	}
 	return pvzList, nil
 }
-func (r *PvzPostgres) CreatePvz(pvz domain.PVZ) (domain.PVZ, error) {
+func (r *PvzPostgres) CreatePvz(ctx context.Context, scope domain.TenantScope, pvz domain.PVZ) (domain.PVZ, error) {
+	ctx, done := startQuery(ctx, r.timeouts.Write, "PvzPostgres.CreatePvz")
+	defer done()
+	tx, err := r.beginTx(ctx)
+	if err != nil {
+		return domain.PVZ{}, err
+	}
+	defer tx.Rollback()
 	var pvzResponse domain.PVZ
-	query := fmt.Sprintf(`INSERT INTO %s (registrationdate,city) VALUES ($1,$2) RETURNING id,registrationdate,city`, pvzTable)
-	row := r.db.QueryRowx(query, pvz.DateRegister, pvz.City)
-	logger.Log.Debug().Str("query", query).Msg("Выполнение запроса заведния ПВЗ")
-	if err := row.Scan(&pvzResponse.Id, &pvzResponse.DateRegister, &pvzResponse.City); err != nil {
+	query := fmt.Sprintf(`INSERT INTO %s (registrationdate,city,address,postal_code,working_hours,latitude,longitude,capacity,timezone,tenant_id) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)
+  RETURNING id,registrationdate,city,address,postal_code,working_hours,latitude,longitude,capacity,timezone,tenant_id`, pvzTable)
+	row := tx.QueryRowxContext(ctx, query, pvz.DateRegister, pvz.City, pvz.Address, pvz.PostalCode, pvz.WorkingHours, pvz.Latitude, pvz.Longitude, pvz.Capacity, pvz.Timezone, scope.TenantId)
+	logger.FromContext(ctx).Debug().Str("query", query).Msg("Выполнение запроса заведния ПВЗ")
+	if err := row.Scan(&pvzResponse.Id, &pvzResponse.DateRegister, &pvzResponse.City, &pvzResponse.Address,
+		&pvzResponse.PostalCode, &pvzResponse.WorkingHours, &pvzResponse.Latitude, &pvzResponse.Longitude, &pvzResponse.Capacity, &pvzResponse.Timezone, &pvzResponse.TenantId); err != nil {
 		return domain.PVZ{}, err
 	}
-	logger.Log.Debug().Any("pvz response", pvzResponse).Msg("Успешно заведно ПВЗ")
+	pvzResponse.Status = domain.PvzActive
+	pvzResponse = pvzResponse.In(domain.PvzLocation(pvzResponse.Timezone))
+	if err := auditTx(ctx, tx, pvzResponse.Id.String(), nil, pvzResponse); err != nil {
+		return domain.PVZ{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return domain.PVZ{}, err
+	}
+	logger.FromContext(ctx).Debug().Any("pvz response", pvzResponse).Msg("Успешно заведно ПВЗ")
 	return pvzResponse, nil
 }
-func (r *PvzPostgres) GetPvz(input domain.GettingPvzParams) ([]domain.PvzSummary, error) {
-	tx, err := r.beginTx()
+func (r *PvzPostgres) GetPvz(ctx context.Context, scope domain.TenantScope, input domain.GettingPvzParams) ([]domain.PvzSummary, error) {
+	ctx, done := startQuery(ctx, r.timeouts.Read, "PvzPostgres.GetPvz")
+	defer done()
+	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -57,29 +87,49 @@ func (r *PvzPostgres) GetPvz(input domain.GettingPvzParams) ([]domain.PvzSummary
 	var result []domain.PvzSummary
 
 	conditions, args := buildConditions(input)
-	conditionsOther := buildConditionsOther(input)
+	receptionConditions := buildConditionsOther(input, receptionTable)
+	productConditions := buildConditionsOther(input, productTable)
+	// Приемки и товары фильтруются по компании отдельно от ПВЗ, так как
+	// выбираются независимыми запросами.
+	args = append(args, scope.Filter())
+	conditions = append(conditions, tenantCondition("p.tenant_id", len(args)))
+	receptionConditions = append(receptionConditions, tenantCondition("tenant_id", len(args)))
+	productConditions = append(productConditions, tenantCondition("tenant_id", len(args)))
 	offset := (input.Page - 1) * input.Limit
 	args = append(args, input.Limit, offset)
-	pvzs, err := r.queryPvzData(conditions, args)
+	pvzs, err := r.queryPvzData(ctx, conditions, args)
 	if err != nil {
 		return nil, err
 	}
-	receptions, err := r.queryReceptionData(conditionsOther, args)
+	receptions, err := r.queryReceptionData(ctx, receptionConditions, args)
 	if err != nil {
 		return nil, err
 	}
 
-	products, err := r.queryProductData(conditionsOther, args)
+	products, err := r.queryProductData(ctx, productConditions, args)
 	if err != nil {
 		return nil, err
 	}
-	logger.Log.Debug().Any("receptions", receptions).Msg("Получены данные о приемках")
-	logger.Log.Debug().Any("products", products).Msg("Получены данные о товарах")
+	receptionIds := make([]uuid.UUID, 0, len(receptions))
+	for _, reception := range receptions {
+		receptionIds = append(receptionIds, *reception.Id)
+	}
+	corrections, err := r.queryCorrectionData(ctx, receptionIds)
+	if err != nil {
+		return nil, err
+	}
+	logger.FromContext(ctx).Debug().Any("receptions", receptions).Msg("Получены данные о приемках")
+	logger.FromContext(ctx).Debug().Any("products", products).Msg("Получены данные о товарах")
 	receptionMap := make(map[string][]domain.ProductReception)
 	for _, reception := range receptions {
 		receptionMap[reception.PVZId.String()] = append(receptionMap[reception.PVZId.String()], reception)
 	}
 
+	correctionMap := make(map[string][]domain.ProductCorrection)
+	for _, correction := range corrections {
+		correctionMap[correction.ReceptionId.String()] = append(correctionMap[correction.ReceptionId.String()], correction)
+	}
+
 	productMap := make(map[string][]domain.Product)
 	for _, product := range products {
 		productMap[product.ReceptionId.String()] = append(productMap[product.ReceptionId.String()], product)
@@ -87,16 +137,26 @@ func (r *PvzPostgres) GetPvz(input domain.GettingPvzParams) ([]domain.PvzSummary
 
 	for _, pvz := range pvzs {
 		var receptionsWithProducts []domain.Receptions
+		loc := domain.PvzLocation(pvz.Timezone)
 
 		for _, reception := range receptionMap[pvz.Id.String()] {
+			products := productMap[reception.Id.String()]
+			for i, product := range products {
+				products[i] = product.In(loc)
+			}
+			corrections := correctionMap[reception.Id.String()]
+			for i, correction := range corrections {
+				corrections[i] = correction.In(loc)
+			}
 			receptionsWithProducts = append(receptionsWithProducts, domain.Receptions{
-				ReceptionInfo: reception,
-				ProductInfo:   productMap[reception.Id.String()],
+				ReceptionInfo: reception.In(loc),
+				ProductInfo:   products,
+				Corrections:   corrections,
 			})
 		}
 
 		result = append(result, domain.PvzSummary{
-			PvzInfo:        pvz,
+			PvzInfo:        pvz.In(loc),
 			ReceptionsInfo: receptionsWithProducts,
 		})
 	}
@@ -104,73 +164,114 @@ func (r *PvzPostgres) GetPvz(input domain.GettingPvzParams) ([]domain.PvzSummary
 	if err != nil {
 		return nil, err
 	}
-	logger.Log.Debug().Any("response", result).Msg("Успешно получены данные о ПВЗ")
+	logger.FromContext(ctx).Debug().Any("response", result).Msg("Успешно получены данные о ПВЗ")
 	return result, nil
 }
 
 func buildConditions(input domain.GettingPvzParams) ([]string, []interface{}) {
-	var conditions []string
+	column := "registrationDate"
+	if input.LocalDay {
+		column = "(registrationDate AT TIME ZONE timezone)::date"
+	}
+	conditions := dateConditions(input, column)
 	var args []interface{}
-
-	if !input.Start.IsZero() {
-		conditions = append(conditions, "registrationDate >= $1")
-		args = append(args, input.Start)
+	for _, bound := range []time.Time{input.Start, input.End} {
+		if bound.IsZero() {
+			continue
+		}
+		if input.LocalDay {
+			args = append(args, bound.Format("2006-01-02"))
+		} else {
+			args = append(args, bound)
+		}
 	}
+	return conditions, args
+}
 
-	if !input.End.IsZero() {
-		conditions = append(conditions, "registrationDate <= $2")
-		args = append(args, input.End)
+// buildConditionsOther строит фильтр по дате приемок или товаров из table.
+// В режиме LocalDay дата берется в часовом поясе ПВЗ, к которому они относятся.
+func buildConditionsOther(input domain.GettingPvzParams, table string) []string {
+	column := "date_received"
+	if input.LocalDay {
+		column = fmt.Sprintf("(date_received AT TIME ZONE (SELECT z.timezone FROM %s z WHERE z.id = %s.pvz_id))::date", pvzTable, table)
 	}
+	return dateConditions(input, column)
+}
 
-	return conditions, args
+// tenantCondition ограничивает column компанией из параметра $n, значение
+// которого получено из TenantScope.Filter.
+func tenantCondition(column string, n int) string {
+	return fmt.Sprintf("($%d::text IS NULL OR %s = $%d)", n, column, n)
 }
-func buildConditionsOther(input domain.GettingPvzParams) []string {
-	var conditionsOther []string
+
+func dateConditions(input domain.GettingPvzParams, column string) []string {
+	var conditions []string
+	cast := ""
+	if input.LocalDay {
+		cast = "::date"
+	}
+	n := 1
 	if !input.Start.IsZero() {
-		conditionsOther = append(conditionsOther, "date_received >= $1")
+		conditions = append(conditions, fmt.Sprintf("%s >= $%d%s", column, n, cast))
+		n++
 	}
 	if !input.End.IsZero() {
-		conditionsOther = append(conditionsOther, "date_received <= $2")
+		conditions = append(conditions, fmt.Sprintf("%s <= $%d%s", column, n, cast))
 	}
-	return conditionsOther
+	return conditions
 }
 
-func (r *PvzPostgres) queryPvzData(conditions []string, args []interface{}) ([]domain.PVZ, error) {
-	query := fmt.Sprintf("SELECT * FROM %s", pvzTable)
+func (r *PvzPostgres) queryPvzData(ctx context.Context, conditions []string, args []interface{}) ([]domain.PVZ, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s p", pvzColumns, pvzTable)
 	if len(conditions) > 0 {
 		query += " WHERE " + strings.Join(conditions, " AND ")
 	}
 	query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", len(args)-1, len(args))
 	var pvz []domain.PVZ
-	err := r.db.Select(&pvz, query, args...)
-	logger.Log.Debug().Any("query", query).Msg("Запрос данных о ПВЗ")
+	err := r.db.SelectContext(ctx, &pvz, query, args...)
+	logger.FromContext(ctx).Debug().Any("query", query).Msg("Запрос данных о ПВЗ")
 	return pvz, err
 }
 
-func (r *PvzPostgres) queryReceptionData(conditionsOther []string, args []interface{}) ([]domain.ProductReception, error) {
-	query := fmt.Sprintf("SELECT * FROM %s", receptionTable)
+const receptionColumns = "id,date_received,pvz_id,status_reception,flagged_at"
+
+func (r *PvzPostgres) queryReceptionData(ctx context.Context, conditionsOther []string, args []interface{}) ([]domain.ProductReception, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s", receptionColumns, receptionTable)
 	if len(conditionsOther) > 0 {
 		query += " WHERE " + strings.Join(conditionsOther, " AND ")
 	}
 	query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", len(args)-1, len(args))
 	var receptions []domain.ProductReception
-	err := r.db.Select(&receptions, query, args...)
-	logger.Log.Debug().Any("query", query).Msg("Запрос данных о приемках")
+	err := r.db.SelectContext(ctx, &receptions, query, args...)
+	logger.FromContext(ctx).Debug().Any("query", query).Msg("Запрос данных о приемках")
 	return receptions, err
 }
 
-func (r *PvzPostgres) queryProductData(conditionsOther []string, args []interface{}) ([]domain.Product, error) {
-	query := fmt.Sprintf("SELECT * FROM %s", productTable)
-	if len(conditionsOther) > 0 {
-		query += " WHERE " + strings.Join(conditionsOther, " AND ")
-	}
+func (r *PvzPostgres) queryProductData(ctx context.Context, conditionsOther []string, args []interface{}) ([]domain.Product, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s", productColumns, productTable)
+	query += " WHERE " + strings.Join(append([]string{"deleted_at IS NULL"}, conditionsOther...), " AND ")
 	query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", len(args)-1, len(args))
 	var products []domain.Product
-	err := r.db.Select(&products, query, args...)
-	logger.Log.Debug().Any("query", query).Msg("Запрос данных о товарах")
+	err := r.db.SelectContext(ctx, &products, query, args...)
+	logger.FromContext(ctx).Debug().Any("query", query).Msg("Запрос данных о товарах")
 	return products, err
 }
 
+func (r *PvzPostgres) queryCorrectionData(ctx context.Context, receptionIds []uuid.UUID) ([]domain.ProductCorrection, error) {
+	if len(receptionIds) == 0 {
+		return nil, nil
+	}
+	query, args, err := sqlx.In(fmt.Sprintf("SELECT id,reception_id,product_id,action,reason,comment,actor_id,amendment_id,transfer_id,created_at FROM %s WHERE reception_id IN (?) ORDER BY created_at", correctionsTable), receptionIds)
+	if err != nil {
+		return nil, err
+	}
+	query = r.db.Rebind(query)
+	var corrections []domain.ProductCorrection
+	err = r.db.SelectContext(ctx, &corrections, query, args...)
+	logger.FromContext(ctx).Debug().Any("query", query).Msg("Запрос истории исправлений приемок")
+	return corrections, err
+}
+
 func (r *PvzPostgres) DB() *sqlx.DB {
 	return r.db
 }
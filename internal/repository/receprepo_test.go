@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -28,6 +29,7 @@ func TestPvzPostgres_CreateRecep(t *testing.T) {
 	sqlxDB := sqlx.NewDb(db, "postgres")
 	r := NewPvzPostgres(sqlxDB)
 	stat := "in_progress"
+	localTime := fixedTime.In(domain.PvzLocation("Asia/Vladivostok"))
 
 	tests := []struct {
 		name    string
@@ -40,6 +42,7 @@ func TestPvzPostgres_CreateRecep(t *testing.T) {
 			name: "Ok",
 			mock: func() {
 				mock.ExpectBegin()
+				expectPvzActive(mock)
 				mock.ExpectQuery(fmt.Sprintf("SELECT status_reception,id FROM %s (.+)", receptionTable)).
 					WithArgs(&userID).WillReturnError(sql.ErrNoRows)
 				rows := sqlmock.NewRows([]string{"id", "date_received", "pvz_id", "status_reception"}).AddRow(userID, fixedTime, userID, stat)
@@ -59,10 +62,36 @@ func TestPvzPostgres_CreateRecep(t *testing.T) {
 				Status:       &stat,
 			},
 		},
+		{
+			name: "Время в часовом поясе ПВЗ",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(fmt.Sprintf(`SELECT (.+) FROM %s p WHERE p.id = \$1 AND (.+) FOR SHARE OF p`, pvzTable)).
+					WillReturnRows(sqlmock.NewRows([]string{"status", "timezone"}).AddRow(domain.PvzActive, "Asia/Vladivostok"))
+				mock.ExpectQuery(fmt.Sprintf("SELECT status_reception,id FROM %s (.+)", receptionTable)).
+					WithArgs(&userID).WillReturnError(sql.ErrNoRows)
+				rows := sqlmock.NewRows([]string{"id", "date_received", "pvz_id", "status_reception"}).AddRow(userID, fixedTime, userID, stat)
+				mock.ExpectQuery(fmt.Sprintf("INSERT INTO %s", receptionTable)).
+					WithArgs(&fixedTime, &userID, &stat).WillReturnRows(rows)
+				mock.ExpectCommit()
+			},
+			input: domain.ProductReception{
+				DateReceived: &fixedTime,
+				PVZId:        &userID,
+				Status:       &stat,
+			},
+			want: domain.ProductReception{
+				Id:           &userID,
+				DateReceived: &localTime,
+				PVZId:        &userID,
+				Status:       &stat,
+			},
+		},
 		{
 			name: "Ошибка БД",
 			mock: func() {
 				mock.ExpectBegin()
+				expectPvzActive(mock)
 				mock.ExpectQuery(fmt.Sprintf("SELECT status_reception,id FROM %s (.+)", receptionTable)).
 					WithArgs(&userID).WillReturnError(sql.ErrNoRows)
 				mock.ExpectQuery(fmt.Sprintf("INSERT INTO %s", receptionTable)).
@@ -81,6 +110,7 @@ func TestPvzPostgres_CreateRecep(t *testing.T) {
 			name: "Ошибка Scan",
 			mock: func() {
 				mock.ExpectBegin()
+				expectPvzActive(mock)
 				mock.ExpectQuery(fmt.Sprintf("SELECT status_reception,id FROM %s (.+)", receptionTable)).
 					WithArgs(&userID).WillReturnError(sql.ErrNoRows)
 				rows := sqlmock.NewRows([]string{"id", "date_received"}).AddRow(uuid.New(), time.Now())
@@ -101,6 +131,7 @@ func TestPvzPostgres_CreateRecep(t *testing.T) {
 			name: "Есть незакрытая приемка",
 			mock: func() {
 				mock.ExpectBegin()
+				expectPvzActive(mock)
 				rows := sqlmock.NewRows([]string{"status_reception", "id"}).AddRow("in_progress", userID)
 				mock.ExpectQuery(fmt.Sprintf("SELECT status_reception,id FROM %s (.+)", receptionTable)).
 					WithArgs(&userID).WillReturnRows(rows)
@@ -120,7 +151,7 @@ func TestPvzPostgres_CreateRecep(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.mock()
 
-			got, err := r.CreateRecep(tt.input)
+			got, err := r.CreateRecep(context.Background(), testScope, tt.input)
 			if tt.wantErr {
 				assert.Error(t, err)
 			} else {
@@ -158,6 +189,7 @@ func TestPvzPostgres_AddProdToRecep(t *testing.T) {
 			name: "Ok",
 			mock: func() {
 				mock.ExpectBegin()
+				expectPvzActive(mock)
 				rows2 := sqlmock.NewRows([]string{"status_reception", "id"}).AddRow("in_progress", userID)
 				mock.ExpectQuery(fmt.Sprintf("SELECT status_reception,id FROM %s (.+)", receptionTable)).
 					WithArgs(&userID).WillReturnRows(rows2)
@@ -182,6 +214,7 @@ func TestPvzPostgres_AddProdToRecep(t *testing.T) {
 			name: "Ошибка БД",
 			mock: func() {
 				mock.ExpectBegin()
+				expectPvzActive(mock)
 				rows2 := sqlmock.NewRows([]string{"status_reception", "id"}).AddRow("in_progress", userID)
 				mock.ExpectQuery(fmt.Sprintf("SELECT status_reception,id FROM %s (.+)", receptionTable)).
 					WithArgs(&userID).WillReturnRows(rows2)
@@ -201,6 +234,7 @@ func TestPvzPostgres_AddProdToRecep(t *testing.T) {
 			name: "Ошибка Scan",
 			mock: func() {
 				mock.ExpectBegin()
+				expectPvzActive(mock)
 				rows2 := sqlmock.NewRows([]string{"status_reception", "id"}).AddRow("in_progress", userID)
 				mock.ExpectQuery(fmt.Sprintf("SELECT status_reception,id FROM %s (.+)", receptionTable)).
 					WithArgs(&userID).WillReturnRows(rows2)
@@ -222,6 +256,7 @@ func TestPvzPostgres_AddProdToRecep(t *testing.T) {
 			name: "Нет активной приемки",
 			mock: func() {
 				mock.ExpectBegin()
+				expectPvzActive(mock)
 				mock.ExpectQuery(fmt.Sprintf("SELECT status_reception,id FROM %s (.+)", receptionTable)).
 					WithArgs(&userID).WillReturnError(sql.ErrNoRows)
 				mock.ExpectRollback()
@@ -240,7 +275,7 @@ func TestPvzPostgres_AddProdToRecep(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.mock()
 
-			got, err := r.AddProdToRecep(tt.input)
+			got, err := r.AddProdToRecep(context.Background(), testScope, tt.input)
 			if tt.wantErr {
 				assert.Error(t, err)
 			} else {
@@ -262,92 +297,81 @@ func TestPvzPostgres_deleteLast(t *testing.T) {
 	if err != nil {
 		panic(err)
 	}
+	productID := uuid.New()
 	sqlxDB := sqlx.NewDb(db, "postgres")
 	r := NewPvzPostgres(sqlxDB)
+	input := domain.ProductDeletion{PVZId: userID, ActorId: "u1"}
 
 	tests := []struct {
 		name    string
 		mock    func()
-		input   uuid.UUID
-		want    error
+		input   domain.ProductDeletion
 		wantErr bool
 	}{
 		{
 			name: "Ok",
 			mock: func() {
 				mock.ExpectBegin()
+				expectPvzActive(mock)
 				rows2 := sqlmock.NewRows([]string{"status_reception", "id"}).AddRow("in_progress", userID)
 				mock.ExpectQuery(fmt.Sprintf("SELECT status_reception,id FROM %s (.+)", receptionTable)).
-					WithArgs(&userID).WillReturnRows(rows2)
-				//rows := sqlmock.NewRows([]string{"pvz_id", "reception_id"}).AddRow(userID, userID)
-				mock.ExpectExec(fmt.Sprintf("DELETE FROM %s ", productTable)).
-					WithArgs(&userID, &userID).
-					WillReturnResult(sqlmock.NewResult(0, 1))
+					WithArgs(userID).WillReturnRows(rows2)
+				mock.ExpectQuery(fmt.Sprintf("SELECT id FROM %s (.+) FOR UPDATE", productTable)).
+					WithArgs(userID, userID).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(productID))
+				rows := sqlmock.NewRows([]string{"id", "date_received", "type_product", "reception_id", "pvz_id", "issued_at"}).
+					AddRow(productID, time.Now(), "обувь", userID, userID, nil)
+				mock.ExpectQuery(fmt.Sprintf("UPDATE %s SET deleted_at", productTable)).
+					WithArgs(productID).WillReturnRows(rows)
+				mock.ExpectExec(fmt.Sprintf("INSERT INTO %s", correctionsTable)).
+					WithArgs(userID, productID, domain.CorrectionRemove, domain.ReasonUndoLast, "", "u1", nil, nil).
+					WillReturnResult(sqlmock.NewResult(1, 1))
 				mock.ExpectCommit()
 			},
-			input: userID,
-			want:  nil,
+			input: input,
 		},
 		{
 			name: "Ошибка БД",
 			mock: func() {
 				mock.ExpectBegin()
+				expectPvzActive(mock)
 				rows2 := sqlmock.NewRows([]string{"status_reception", "id"}).AddRow("in_progress", userID)
 				mock.ExpectQuery(fmt.Sprintf("SELECT status_reception,id FROM %s (.+)", receptionTable)).
-					WithArgs(&userID).WillReturnRows(rows2)
-				mock.ExpectExec(fmt.Sprintf("DELETE FROM %s ", productTable)).
-					WithArgs(&userID, &userID).WillReturnError(errors.New("ошибка бд"))
+					WithArgs(userID).WillReturnRows(rows2)
+				mock.ExpectQuery(fmt.Sprintf("SELECT id FROM %s ", productTable)).
+					WithArgs(userID, userID).WillReturnError(errors.New("ошибка бд"))
 				mock.ExpectRollback()
 			},
-			input:   userID,
-			want:    err,
+			input:   input,
 			wantErr: true,
 		},
-		/*{
-			name: "Ошибка Scan",
-			mock: func() {
-				mock.ExpectBegin()
-				rows2 := sqlmock.NewRows([]string{"status_reception", "id"}).AddRow("in_progress", userID)
-				mock.ExpectQuery(fmt.Sprintf("SELECT status_reception,id FROM %s (.+)", receptionTable)).
-					WithArgs(&userID).WillReturnRows(rows2)
-				//rows := sqlmock.NewRows([]string{"id", "date_received"}).AddRow(uuid.New(), time.Now())
-				mock.ExpectExec(fmt.Sprintf("DELETE FROM %s ", productTable)).
-					WithArgs(&userID, &userID).
-					WillReturnResult(sqlmock.NewResult(0, 1))
-				mock.ExpectRollback()
-			},
-			input:   userID,
-			want:    err,
-			wantErr: true,
-		},*/
 		{
 			name: "Нет активной приемки",
 			mock: func() {
 				mock.ExpectBegin()
+				expectPvzActive(mock)
 				mock.ExpectQuery(fmt.Sprintf("SELECT status_reception,id FROM %s (.+)", receptionTable)).
-					WithArgs(&userID).WillReturnError(sql.ErrNoRows)
+					WithArgs(userID).WillReturnError(sql.ErrNoRows)
 				mock.ExpectRollback()
 			},
-			input:   userID,
-			want:    err,
+			input:   input,
 			wantErr: true,
 		},
 		{
 			name: "Нет товаров для удаления",
 			mock: func() {
 				mock.ExpectBegin()
+				expectPvzActive(mock)
 				mock.ExpectQuery(fmt.Sprintf("SELECT status_reception,id FROM %s (.+)", receptionTable)).
-					WithArgs(&userID).
+					WithArgs(userID).
 					WillReturnRows(sqlmock.NewRows([]string{"status_reception", "id"}).AddRow("open", userID))
 
-				mock.ExpectExec(fmt.Sprintf("DELETE FROM %s (.+)", productTable)).
-					WithArgs(&userID, &userID).
-					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery(fmt.Sprintf("SELECT id FROM %s (.+)", productTable)).
+					WithArgs(userID, userID).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}))
 
 				mock.ExpectRollback()
 			},
-			input:   userID,
-			want:    fmt.Errorf("Неверный запрос, нет активной приемки или нет товаров для удаления"),
+			input:   input,
 			wantErr: true,
 		},
 	}
@@ -356,12 +380,93 @@ func TestPvzPostgres_deleteLast(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.mock()
 
-			err := r.DeleteLastProduct(tt.input)
+			_, err := r.DeleteLastProduct(context.Background(), testScope, tt.input)
 			if tt.wantErr {
 				assert.Error(t, err)
 			} else {
 				assert.NoError(t, err)
-				assert.Equal(t, tt.want, err)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPvzPostgres_DeleteProduct(t *testing.T) {
+	fixedTime := time.Date(2025, 4, 10, 15, 5, 17, 0, time.UTC)
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	pvzID := uuid.New()
+	recepID := uuid.New()
+	productID := uuid.New()
+	sqlxDB := sqlx.NewDb(db, "postgres")
+	r := NewPvzPostgres(sqlxDB)
+	input := domain.ProductDeletion{PVZId: pvzID, ProductId: &productID, Reason: "duplicate", Comment: "дважды отсканирован", ActorId: "u1"}
+	typ := "обувь"
+
+	tests := []struct {
+		name    string
+		mock    func()
+		want    domain.Product
+		wantErr error
+	}{
+		{
+			name: "Ok",
+			mock: func() {
+				mock.ExpectBegin()
+				expectPvzActive(mock)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s p JOIN %s r (.+) FOR UPDATE", productTable, receptionTable)).
+					WithArgs(&productID, pvzID).
+					WillReturnRows(sqlmock.NewRows([]string{"status_reception", "id"}).AddRow("in_progress", recepID))
+				rows := sqlmock.NewRows([]string{"id", "date_received", "type_product", "reception_id", "pvz_id", "issued_at"}).
+					AddRow(productID, fixedTime, typ, recepID, pvzID, nil)
+				mock.ExpectQuery(fmt.Sprintf("UPDATE %s SET deleted_at", productTable)).
+					WithArgs(productID).WillReturnRows(rows)
+				mock.ExpectExec(fmt.Sprintf("INSERT INTO %s", correctionsTable)).
+					WithArgs(recepID, productID, domain.CorrectionRemove, "duplicate", "дважды отсканирован", "u1", nil, nil).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectCommit()
+			},
+			want: domain.Product{Id: &productID, DateReceived: &fixedTime, Type: typ, ReceptionId: &recepID, PVZId: &pvzID},
+		},
+		{
+			name: "Товар не найден",
+			mock: func() {
+				mock.ExpectBegin()
+				expectPvzActive(mock)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s p", productTable)).
+					WithArgs(&productID, pvzID).
+					WillReturnRows(sqlmock.NewRows([]string{"status_reception", "id"}))
+				mock.ExpectRollback()
+			},
+			wantErr: ErrProductNotFound,
+		},
+		{
+			name: "Приемка закрыта",
+			mock: func() {
+				mock.ExpectBegin()
+				expectPvzActive(mock)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s p", productTable)).
+					WithArgs(&productID, pvzID).
+					WillReturnRows(sqlmock.NewRows([]string{"status_reception", "id"}).AddRow("close", recepID))
+				mock.ExpectRollback()
+			},
+			wantErr: ErrReceptionClosed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			got, err := r.DeleteProduct(context.Background(), testScope, input)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
 			}
 			assert.NoError(t, mock.ExpectationsWereMet())
 		})
@@ -394,14 +499,26 @@ func TestPvzPostgres_closeLast(t *testing.T) {
 			name: "Ok",
 			mock: func() {
 				mock.ExpectBegin()
+				expectPvzActive(mock)
 				rows2 := sqlmock.NewRows([]string{"status_reception", "id"}).AddRow("in_progress", userID)
 				mock.ExpectQuery(fmt.Sprintf("SELECT status_reception,id FROM %s (.+)", receptionTable)).
 					WithArgs(&userID).WillReturnRows(rows2)
 				mock.ExpectQuery(fmt.Sprintf(`COUNT\(\*\) FROM %s (.+)`, productTable)).
 					WithArgs(&userID, &userID).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
-				rows := sqlmock.NewRows([]string{"id", "date_received", "pvz_id", "status_reception"}).AddRow(userID, fixedTime, userID, stat)
+				mock.ExpectQuery(fmt.Sprintf("SELECT id,date_received,pvz_id,status_reception FROM %s (.+) FOR UPDATE", receptionTable)).
+					WithArgs(userID, sqlmock.AnyArg()).WillReturnRows(sqlmock.NewRows([]string{"id", "date_received", "pvz_id", "status_reception"}).
+					AddRow(userID, fixedTime, userID, "in_progress"))
+				rows := sqlmock.NewRows([]string{"id", "date_received", "pvz_id", "status_reception", "flagged_at"}).AddRow(userID, fixedTime, userID, stat, nil)
 				mock.ExpectQuery(fmt.Sprintf("UPDATE %s SET (.+)", receptionTable)).
 					WithArgs(&userID, &userID).WillReturnRows(rows)
+				// первая версия приемки
+				mock.ExpectQuery(fmt.Sprintf("SELECT id,date_received,pvz_id,status_reception FROM %s WHERE id = \\$1 AND (.+) = \\$2\\)$", receptionTable)).
+					WithArgs(userID, sqlmock.AnyArg()).WillReturnRows(sqlmock.NewRows([]string{"id", "date_received", "pvz_id", "status_reception"}).
+					AddRow(userID, fixedTime, userID, stat))
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE reception_id = \\$1 AND deleted_at IS NULL", productTable)).
+					WithArgs(userID).WillReturnRows(sqlmock.NewRows([]string{"id", "date_received", "type_product", "reception_id", "pvz_id", "issued_at"}))
+				mock.ExpectExec(fmt.Sprintf("INSERT INTO %s", versionsTable)).
+					WithArgs(userID, 1, nil, "", sqlmock.AnyArg(), nil).WillReturnResult(sqlmock.NewResult(0, 1))
 				mock.ExpectCommit()
 			},
 			input: userID,
@@ -416,11 +533,15 @@ func TestPvzPostgres_closeLast(t *testing.T) {
 			name: "Ошибка БД",
 			mock: func() {
 				mock.ExpectBegin()
+				expectPvzActive(mock)
 				rows2 := sqlmock.NewRows([]string{"status_reception", "id"}).AddRow("in_progress", userID)
 				mock.ExpectQuery(fmt.Sprintf("SELECT status_reception,id FROM %s (.+)", receptionTable)).
 					WithArgs(&userID).WillReturnRows(rows2)
 				mock.ExpectQuery(fmt.Sprintf(`COUNT\(\*\) FROM %s (.+)`, productTable)).
 					WithArgs(&userID, &userID).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+				mock.ExpectQuery(fmt.Sprintf("SELECT id,date_received,pvz_id,status_reception FROM %s (.+) FOR UPDATE", receptionTable)).
+					WithArgs(userID, sqlmock.AnyArg()).WillReturnRows(sqlmock.NewRows([]string{"id", "date_received", "pvz_id", "status_reception"}).
+					AddRow(userID, fixedTime, userID, "in_progress"))
 				mock.ExpectQuery(fmt.Sprintf("UPDATE %s SET (.+)", receptionTable)).
 					WithArgs(&userID, &userID).WillReturnError(errors.New("ошибка бд"))
 				mock.ExpectRollback()
@@ -433,6 +554,7 @@ func TestPvzPostgres_closeLast(t *testing.T) {
 			name: "Приемка уже закрыта",
 			mock: func() {
 				mock.ExpectBegin()
+				expectPvzActive(mock)
 
 				rows2 := sqlmock.NewRows([]string{"status_reception", "id"}).AddRow("close", userID)
 				mock.ExpectQuery(fmt.Sprintf("SELECT status_reception,id FROM %s (.+)", receptionTable)).
@@ -448,6 +570,7 @@ func TestPvzPostgres_closeLast(t *testing.T) {
 			name: "Ошибка проверки наличия товаров",
 			mock: func() {
 				mock.ExpectBegin()
+				expectPvzActive(mock)
 
 				rows2 := sqlmock.NewRows([]string{"status_reception", "id"}).AddRow("in_progress", userID)
 				mock.ExpectQuery(fmt.Sprintf("SELECT status_reception,id FROM %s (.+)", receptionTable)).
@@ -470,7 +593,7 @@ func TestPvzPostgres_closeLast(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.mock()
 
-			got, err := r.CloseReception(tt.input)
+			got, err := r.CloseReception(context.Background(), testScope, tt.input)
 			if tt.wantErr {
 				assert.Error(t, err)
 			} else {
@@ -1,9 +1,11 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/bllooop/pvzservice/internal/domain"
 	logger "github.com/bllooop/pvzservice/pkg/logging"
@@ -11,137 +13,237 @@ import (
 	"github.com/jmoiron/sqlx"
 )
 
-var ErrNoProductsToDelete = errors.New("нет товаров для удаления")
+var (
+	ErrNoProductsToDelete  = errors.New("нет товаров для удаления")
+	ErrReceptionInProgress = errors.New("в ПВЗ уже есть незакрытая приемка")
+	ErrProductNotFound     = errors.New("товар не найден")
+	ErrReceptionClosed     = errors.New("приемка уже закрыта")
+)
+
+const productColumns = "id,date_received,type_product,reception_id,pvz_id,issued_at"
 
-func (r *PvzPostgres) CreateRecep(recep domain.ProductReception) (domain.ProductReception, error) {
-	tx, err := r.beginTx()
+func (r *PvzPostgres) CreateRecep(ctx context.Context, scope domain.TenantScope, recep domain.ProductReception) (domain.ProductReception, error) {
+	ctx, done := startQuery(ctx, r.timeouts.Write, "PvzPostgres.CreateRecep")
+	defer done()
+	tx, err := r.beginTx(ctx)
 	if err != nil {
 		return domain.ProductReception{}, err
 	}
 	defer tx.Rollback()
+	loc, err := r.checkPvzAccepts(ctx, tx, scope, *recep.PVZId)
+	if err != nil {
+		return domain.ProductReception{}, err
+	}
 
-	lastStatus, _, err := r.getLastReceptionStatus(tx, *recep.PVZId)
+	lastStatus, _, err := r.getLastReceptionStatus(ctx, tx, *recep.PVZId)
 	if err != nil {
 		return domain.ProductReception{}, err
 	}
-	if lastStatus == "in_progress" {
-		return domain.ProductReception{}, fmt.Errorf("Неверный запрос или есть незакрытая приемка")
+	if lastStatus == domain.ReceptionInProgress {
+		return domain.ProductReception{}, ErrReceptionInProgress
 	}
-	createdRecep, err := r.insertReception(tx, recep)
+	createdRecep, err := r.insertReception(ctx, tx, recep)
 	if err != nil {
 		return domain.ProductReception{}, err
 	}
+	createdRecep = createdRecep.In(loc)
+	if err := auditTx(ctx, tx, createdRecep.Id.String(), nil, createdRecep); err != nil {
+		return domain.ProductReception{}, err
+	}
 	if err := tx.Commit(); err != nil {
 		return domain.ProductReception{}, err
 	}
-	logger.Log.Debug().Any("pvz response", createdRecep).Msg("Успешно создана приемка")
+	logger.FromContext(ctx).Debug().Any("pvz response", createdRecep).Msg("Успешно создана приемка")
 	return createdRecep, nil
 }
 
-func (r *PvzPostgres) AddProdToRecep(product domain.Product) (domain.Product, error) {
-	tx, err := r.beginTx()
+func (r *PvzPostgres) AddProdToRecep(ctx context.Context, scope domain.TenantScope, product domain.Product) (domain.Product, error) {
+	ctx, done := startQuery(ctx, r.timeouts.Write, "PvzPostgres.AddProdToRecep")
+	defer done()
+	tx, err := r.beginTx(ctx)
 	if err != nil {
 		return domain.Product{}, err
 	}
 	defer tx.Rollback()
+	loc, err := r.checkPvzAccepts(ctx, tx, scope, *product.PVZId)
+	if err != nil {
+		return domain.Product{}, err
+	}
 
-	lastStatus, recepId, err := r.getLastReceptionStatus(tx, *product.PVZId)
-	if lastStatus == "close" {
+	lastStatus, recepId, err := r.getLastReceptionStatus(ctx, tx, *product.PVZId)
+	if domain.ReceptionFinished(lastStatus) {
 		return domain.Product{}, fmt.Errorf("Неверный запрос или нет активной приемки")
 	}
-	logger.Log.Debug().Any("reception id", recepId).Msg("id приемки")
-	addedProduct, err := r.insertProduct(tx, product, recepId, *product.PVZId)
+	logger.FromContext(ctx).Debug().Any("reception id", recepId).Msg("id приемки")
+	addedProduct, err := r.insertProduct(ctx, tx, product, recepId, *product.PVZId)
 	if err != nil {
 		return domain.Product{}, err
 	}
+	addedProduct = addedProduct.In(loc)
+	if err := auditTx(ctx, tx, addedProduct.Id.String(), nil, addedProduct); err != nil {
+		return domain.Product{}, err
+	}
 	if err := tx.Commit(); err != nil {
 		return domain.Product{}, err
 	}
-	logger.Log.Debug().Any("pvz response", addedProduct).Msg("Успешно добавлен товар")
+	logger.FromContext(ctx).Debug().Any("pvz response", addedProduct).Msg("Успешно добавлен товар")
 	return addedProduct, nil
 }
 
-func (r *PvzPostgres) DeleteLastProduct(delProd uuid.UUID) error {
-	tx, err := r.beginTx()
+func (r *PvzPostgres) DeleteLastProduct(ctx context.Context, scope domain.TenantScope, input domain.ProductDeletion) (domain.Product, error) {
+	ctx, done := startQuery(ctx, r.timeouts.Write, "PvzPostgres.DeleteLastProduct")
+	defer done()
+	tx, err := r.beginTx(ctx)
 	if err != nil {
-		return err
+		return domain.Product{}, err
 	}
 	defer tx.Rollback()
-	lastStatus, recepId, err := r.getLastReceptionStatus(tx, delProd)
+	loc, err := r.checkPvzActive(ctx, tx, scope, input.PVZId)
 	if err != nil {
-		return err
+		return domain.Product{}, err
 	}
-	if lastStatus == "close" {
-		return fmt.Errorf("Неверный запрос, нет активной приемки или нет товаров для удаления")
+	lastStatus, recepId, err := r.getLastReceptionStatus(ctx, tx, input.PVZId)
+	if err != nil {
+		return domain.Product{}, err
 	}
-	logger.Log.Debug().Any("reception id", recepId).Msg("id приемки")
-	if err = r.delLastProduct(tx, delProd, recepId); err != nil {
+	if domain.ReceptionFinished(lastStatus) {
+		return domain.Product{}, fmt.Errorf("Неверный запрос, нет активной приемки или нет товаров для удаления")
+	}
+	logger.FromContext(ctx).Debug().Any("reception id", recepId).Msg("id приемки")
+	deleted, err := r.delLastProduct(ctx, tx, input, recepId)
+	if err != nil {
 		if errors.Is(err, ErrNoProductsToDelete) {
-			return fmt.Errorf("Неверный запрос, нет активной приемки или нет товаров для удаления")
+			return domain.Product{}, fmt.Errorf("Неверный запрос, нет активной приемки или нет товаров для удаления")
+		}
+		return domain.Product{}, err
+	}
+	deleted = deleted.In(loc)
+	if err := auditTx(ctx, tx, deleted.Id.String(), deleted, nil); err != nil {
+		return domain.Product{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return domain.Product{}, err
+	}
+	return deleted, nil
+}
+
+// DeleteProduct помечает удаленным конкретный товар открытой приемки и
+// записывает исправление в историю приемки.
+func (r *PvzPostgres) DeleteProduct(ctx context.Context, scope domain.TenantScope, input domain.ProductDeletion) (domain.Product, error) {
+	ctx, done := startQuery(ctx, r.timeouts.Write, "PvzPostgres.DeleteProduct")
+	defer done()
+	tx, err := r.beginTx(ctx)
+	if err != nil {
+		return domain.Product{}, err
+	}
+	defer tx.Rollback()
+	loc, err := r.checkPvzActive(ctx, tx, scope, input.PVZId)
+	if err != nil {
+		return domain.Product{}, err
+	}
+	var status string
+	var recepId uuid.UUID
+	query := fmt.Sprintf(`SELECT r.status_reception,r.id FROM %s p JOIN %s r ON r.id = p.reception_id
+WHERE p.id = $1 AND p.pvz_id = $2 AND p.deleted_at IS NULL FOR UPDATE`, productTable, receptionTable)
+	logger.FromContext(ctx).Debug().Str("query", query).Msg("Проверка товара перед удалением")
+	if err := tx.QueryRowxContext(ctx, query, input.ProductId, input.PVZId).Scan(&status, &recepId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.Product{}, ErrProductNotFound
 		}
-		return err
+		return domain.Product{}, err
+	}
+	if status != domain.ReceptionInProgress {
+		return domain.Product{}, ErrReceptionClosed
+	}
+	deleted, err := r.softDeleteProduct(ctx, tx, *input.ProductId, recepId, input)
+	if err != nil {
+		return domain.Product{}, err
+	}
+	deleted = deleted.In(loc)
+	after := map[string]string{"reason": input.Reason, "comment": input.Comment}
+	if err := auditTx(ctx, tx, deleted.Id.String(), deleted, after); err != nil {
+		return domain.Product{}, err
 	}
 	if err := tx.Commit(); err != nil {
-		return err
+		return domain.Product{}, err
 	}
-	return nil
+	return deleted, nil
 }
 
-func (r *PvzPostgres) CloseReception(closeProd uuid.UUID) (domain.ProductReception, error) {
-	tx, err := r.beginTx()
+func (r *PvzPostgres) CloseReception(ctx context.Context, scope domain.TenantScope, closeProd uuid.UUID) (domain.ProductReception, error) {
+	ctx, done := startQuery(ctx, r.timeouts.Write, "PvzPostgres.CloseReception")
+	defer done()
+	tx, err := r.beginTx(ctx)
 	if err != nil {
 		return domain.ProductReception{}, err
 	}
 	defer tx.Rollback()
-	lastStatus, recepId, err := r.getLastReceptionStatus(tx, closeProd)
-	if lastStatus == "close" {
+	loc, err := r.checkPvzActive(ctx, tx, scope, closeProd)
+	if err != nil {
+		return domain.ProductReception{}, err
+	}
+	lastStatus, recepId, err := r.getLastReceptionStatus(ctx, tx, closeProd)
+	if domain.ReceptionFinished(lastStatus) {
 		return domain.ProductReception{}, fmt.Errorf("Неверный запрос или приемка уже закрыта")
 	}
-	if ok, err := r.checkIfAddedProducts(tx, closeProd, recepId); err != nil || !ok {
-		logger.Log.Error().Err(err).Msg("Ошибка проверки добавления товаров")
+	products, err := r.countReceptionProducts(ctx, tx, closeProd, recepId)
+	if err != nil || products == 0 {
+		logger.FromContext(ctx).Error().Err(err).Msg("Ошибка проверки добавления товаров")
 		return domain.ProductReception{}, fmt.Errorf("Неверный запрос или приемка уже закрыта")
 	}
-	logger.Log.Debug().Any("reception id", recepId).Msg("id приемки")
-	res, err := r.statusChange(tx, closeProd, recepId)
+	logger.FromContext(ctx).Debug().Any("reception id", recepId).Msg("id приемки")
+	before, err := r.getReception(ctx, tx, scope, recepId, true)
 	if err != nil {
 		return domain.ProductReception{}, err
 	}
+	res, err := r.statusChange(ctx, tx, closeProd, recepId)
+	if err != nil {
+		return domain.ProductReception{}, err
+	}
+	if err := r.insertClosedVersion(ctx, tx, scope, recepId); err != nil {
+		return domain.ProductReception{}, err
+	}
+	if err := auditTx(ctx, tx, recepId.String(), before.In(loc), res.In(loc)); err != nil {
+		return domain.ProductReception{}, err
+	}
 	if err := tx.Commit(); err != nil {
 		return domain.ProductReception{}, err
 	}
-	return res, nil
+	observeReceptionClosed(res, products, time.Now())
+	return res.In(loc), nil
 }
-func (r *PvzPostgres) statusChange(tx *sqlx.Tx, pvzId uuid.UUID, recepId uuid.UUID) (domain.ProductReception, error) {
+func (r *PvzPostgres) statusChange(ctx context.Context, tx *sqlx.Tx, pvzId uuid.UUID, recepId uuid.UUID) (domain.ProductReception, error) {
 	var respRecep domain.ProductReception
-	query := fmt.Sprintf(`UPDATE %s SET status_reception = 'close' WHERE pvz_id = $1 AND id = $2 RETURNING *`, receptionTable)
-	logger.Log.Debug().Str("query", query).Msg("Удаление последнего товара")
-	err := tx.QueryRowx(query, pvzId, recepId).Scan(&respRecep.Id, &respRecep.DateReceived, &respRecep.PVZId, &respRecep.Status)
+	query := fmt.Sprintf(`UPDATE %s SET status_reception = 'close' WHERE pvz_id = $1 AND id = $2 RETURNING id, date_received, pvz_id, status_reception, flagged_at`, receptionTable)
+	logger.FromContext(ctx).Debug().Str("query", query).Msg("Закрытие приемки")
+	err := tx.QueryRowxContext(ctx, query, pvzId, recepId).Scan(&respRecep.Id, &respRecep.DateReceived, &respRecep.PVZId, &respRecep.Status, &respRecep.FlaggedAt)
 	if err != nil {
 		return domain.ProductReception{}, err
 	}
 	return respRecep, nil
 }
 
-func (r *PvzPostgres) checkIfAddedProducts(tx *sqlx.Tx, pvzId uuid.UUID, recepId uuid.UUID) (bool, error) {
+// countReceptionProducts считает неудаленные товары приемки.
+func (r *PvzPostgres) countReceptionProducts(ctx context.Context, tx *sqlx.Tx, pvzId uuid.UUID, recepId uuid.UUID) (int, error) {
 	var amount int
-	query := fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE pvz_id = $1 AND reception_id = $2`, productTable)
-	logger.Log.Debug().Str("query", query).Msg("Проверка добавления продуктов в приемку")
-	err := tx.QueryRowx(query, pvzId, recepId).Scan(&amount)
+	query := fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE pvz_id = $1 AND reception_id = $2 AND deleted_at IS NULL`, productTable)
+	logger.FromContext(ctx).Debug().Str("query", query).Msg("Проверка добавления продуктов в приемку")
+	err := tx.QueryRowxContext(ctx, query, pvzId, recepId).Scan(&amount)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return false, nil
+			return 0, nil
 		}
-		return false, err
+		return 0, err
 	}
-	return amount > 0, nil
+	return amount, nil
 }
 
-func (r *PvzPostgres) getLastReceptionStatus(tx *sqlx.Tx, pvzId uuid.UUID) (string, uuid.UUID, error) {
+func (r *PvzPostgres) getLastReceptionStatus(ctx context.Context, tx *sqlx.Tx, pvzId uuid.UUID) (string, uuid.UUID, error) {
 	var status string
 	var recepId uuid.UUID
 	query := fmt.Sprintf(`SELECT status_reception,id FROM %s WHERE pvz_id = $1 ORDER BY date_received DESC LIMIT 1`, receptionTable)
-	logger.Log.Debug().Str("query", query).Msg("Получение последнего статуса приёмки")
-	err := tx.QueryRowx(query, pvzId).Scan(&status, &recepId)
+	logger.FromContext(ctx).Debug().Str("query", query).Msg("Получение последнего статуса приёмки")
+	err := tx.QueryRowxContext(ctx, query, pvzId).Scan(&status, &recepId)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return "", uuid.Nil, nil
@@ -151,42 +253,75 @@ func (r *PvzPostgres) getLastReceptionStatus(tx *sqlx.Tx, pvzId uuid.UUID) (stri
 	return status, recepId, nil
 }
 
-func (r *PvzPostgres) delLastProduct(tx *sqlx.Tx, pvzId uuid.UUID, recepId uuid.UUID) error {
-	query := fmt.Sprintf(`DELETE FROM %s WHERE id = (
-SELECT id FROM product
-  WHERE pvz_id = $1 AND reception_id = $2
+func (r *PvzPostgres) delLastProduct(ctx context.Context, tx *sqlx.Tx, input domain.ProductDeletion, recepId uuid.UUID) (domain.Product, error) {
+	var productId uuid.UUID
+	query := fmt.Sprintf(`SELECT id FROM %s
+  WHERE pvz_id = $1 AND reception_id = $2 AND deleted_at IS NULL
   ORDER BY date_received DESC
-  LIMIT 1
-)`, productTable)
-	logger.Log.Debug().Str("query", query).Msg("Удаление последнего товара")
-	res, err := tx.Exec(query, pvzId, recepId)
-	if err != nil {
-		return err
+  LIMIT 1 FOR UPDATE`, productTable)
+	logger.FromContext(ctx).Debug().Str("query", query).Msg("Поиск последнего товара")
+	if err := tx.QueryRowxContext(ctx, query, input.PVZId, recepId).Scan(&productId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.Product{}, ErrNoProductsToDelete
+		}
+		return domain.Product{}, err
+	}
+	if input.Reason == "" {
+		input.Reason = domain.ReasonUndoLast
 	}
-	rowsAffected, err := res.RowsAffected()
+	return r.softDeleteProduct(ctx, tx, productId, recepId, input)
+}
+
+func (r *PvzPostgres) softDeleteProduct(ctx context.Context, tx *sqlx.Tx, productId uuid.UUID, recepId uuid.UUID, input domain.ProductDeletion) (domain.Product, error) {
+	query := fmt.Sprintf(`UPDATE %s SET deleted_at = now() WHERE id = $1 AND deleted_at IS NULL RETURNING %s`, productTable, productColumns)
+	logger.FromContext(ctx).Debug().Str("query", query).Msg("Удаление товара")
+	var res domain.Product
+	err := tx.QueryRowxContext(ctx, query, productId).Scan(&res.Id, &res.DateReceived, &res.Type, &res.ReceptionId, &res.PVZId, &res.IssuedAt)
 	if err != nil {
-		return err
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.Product{}, ErrProductNotFound
+		}
+		return domain.Product{}, err
 	}
-	if rowsAffected == 0 {
-		return ErrNoProductsToDelete
+	err = r.insertCorrection(ctx, tx, domain.ProductCorrection{
+		ReceptionId: recepId,
+		ProductId:   productId,
+		Action:      domain.CorrectionRemove,
+		Reason:      input.Reason,
+		Comment:     input.Comment,
+		ActorId:     input.ActorId,
+		AmendmentId: input.AmendmentId,
+	})
+	if err != nil {
+		return domain.Product{}, err
 	}
-	return nil
+	return res, nil
+}
+
+func (r *PvzPostgres) insertCorrection(ctx context.Context, tx *sqlx.Tx, correction domain.ProductCorrection) error {
+	query := fmt.Sprintf(`INSERT INTO %s (reception_id,product_id,action,reason,comment,actor_id,amendment_id,transfer_id) VALUES ($1,$2,$3,$4,$5,$6,$7,$8)`, correctionsTable)
+	logger.FromContext(ctx).Debug().Str("query", query).Msg("Запись исправления приемки")
+	_, err := tx.ExecContext(ctx, query, correction.ReceptionId, correction.ProductId, correction.Action, correction.Reason,
+		correction.Comment, correction.ActorId, correction.AmendmentId, correction.TransferId)
+	return err
 }
 
-func (r *PvzPostgres) insertReception(tx *sqlx.Tx, recep domain.ProductReception) (domain.ProductReception, error) {
-	query := fmt.Sprintf(`INSERT INTO %s (date_received, pvz_id, status_reception) VALUES ($1, $2, $3) RETURNING id, date_received, pvz_id, status_reception`, receptionTable)
-	logger.Log.Debug().Str("query", query).Msg("Вставка новой приёмки")
+func (r *PvzPostgres) insertReception(ctx context.Context, tx *sqlx.Tx, recep domain.ProductReception) (domain.ProductReception, error) {
+	query := fmt.Sprintf(`INSERT INTO %s (date_received, pvz_id, status_reception, tenant_id) VALUES ($1, $2, $3, (SELECT tenant_id FROM %s WHERE id = $2))
+  RETURNING id, date_received, pvz_id, status_reception`, receptionTable, pvzTable)
+	logger.FromContext(ctx).Debug().Str("query", query).Msg("Вставка новой приёмки")
 	var res domain.ProductReception
-	err := tx.QueryRowx(query, recep.DateReceived, recep.PVZId, recep.Status).
+	err := tx.QueryRowxContext(ctx, query, recep.DateReceived, recep.PVZId, recep.Status).
 		Scan(&res.Id, &res.DateReceived, &res.PVZId, &res.Status)
 	return res, err
 }
 
-func (r *PvzPostgres) insertProduct(tx *sqlx.Tx, product domain.Product, recepId uuid.UUID, pvzId uuid.UUID) (domain.Product, error) {
-	query := fmt.Sprintf(`INSERT INTO %s (date_received, type_product, reception_id,pvz_id) VALUES ($1, $2, $3,$4) RETURNING id, date_received, type_product, reception_id`, productTable)
-	logger.Log.Debug().Str("query", query).Msg("Добавление нового товара")
+func (r *PvzPostgres) insertProduct(ctx context.Context, tx *sqlx.Tx, product domain.Product, recepId uuid.UUID, pvzId uuid.UUID) (domain.Product, error) {
+	query := fmt.Sprintf(`INSERT INTO %s (date_received, type_product, reception_id,pvz_id,tenant_id) VALUES ($1, $2, $3,$4,(SELECT tenant_id FROM %s WHERE id = $4))
+  RETURNING id, date_received, type_product, reception_id`, productTable, pvzTable)
+	logger.FromContext(ctx).Debug().Str("query", query).Msg("Добавление нового товара")
 	var res domain.Product
-	err := tx.QueryRowx(query, product.DateReceived, product.Type, recepId, pvzId).
+	err := tx.QueryRowxContext(ctx, query, product.DateReceived, product.Type, recepId, pvzId).
 		Scan(&res.Id, &res.DateReceived, &res.Type, &res.ReceptionId)
 	return res, err
 }
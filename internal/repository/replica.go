@@ -0,0 +1,386 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bllooop/pvzservice/internal/domain"
+	logger "github.com/bllooop/pvzservice/pkg/logging"
+	"github.com/bllooop/pvzservice/prometheus"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	// DefaultReplicaMaxLag - отставание реплики, после которого чтения
+	// переводятся на основную базу.
+	DefaultReplicaMaxLag = 5 * time.Second
+	// DefaultReplicaCheckInterval - период проверки состояния реплики.
+	DefaultReplicaCheckInterval = 5 * time.Second
+	// DefaultReadYourWritesWindow - сколько после записи чтения той же
+	// компании идут в основную базу.
+	DefaultReadYourWritesWindow = 10 * time.Second
+)
+
+// ReplicaConfig - реплика для чтения. Пустой DSN отключает реплику.
+type ReplicaConfig struct {
+	DSN           string
+	MaxLag        time.Duration `mapstructure:"maxLag"`
+	CheckInterval time.Duration `mapstructure:"checkInterval"`
+	// ReadYourWritesWindow - после записи на этом экземпляре чтения данных
+	// той же компании в течение окна идут в основную базу.
+	ReadYourWritesWindow time.Duration `mapstructure:"readYourWritesWindow"`
+}
+
+// primaryLSNQuery возвращает текущую позицию WAL основной базы.
+const primaryLSNQuery = `SELECT pg_current_wal_lsn()::text`
+
+// replicaLagQuery возвращает отставание реплики в секундах. Реплика, которая
+// применила WAL основной базы до позиции $1, не отстает, даже если на
+// основной базе давно не было записей. Иначе отставание считается от
+// последней примененной транзакции, поэтому отключенная от основной базы
+// реплика отстает все больше, а реплика без примененных транзакций считается
+// бесконечно отстающей. Если $1 неизвестна, учитывается только время. Базу
+// не в режиме восстановления (DSN основной базы) считаем неотстающей.
+const replicaLagQuery = `SELECT CASE
+	WHEN NOT pg_is_in_recovery() THEN 0::float8
+	WHEN pg_last_wal_replay_lsn() >= $1::pg_lsn THEN 0::float8
+	ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp())::float8, 'Infinity'::float8)
+END`
+
+type primaryKey struct{}
+
+// WithPrimary помечает ctx: чтения с ним всегда идут в основную базу.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// ReplicaRouter решает, можно ли направить чтение на реплику: реплика
+// отвечает, отстает не больше MaxLag и компания не выполняла записей на этом
+// экземпляре в течение ReadYourWritesWindow. До первой проверки чтения идут в
+// основную базу. Если чтение с реплики завершилось ошибкой, оно повторяется в
+// основной базе, а реплика отключается до следующей успешной проверки.
+type ReplicaRouter struct {
+	primary *sqlx.DB
+	replica *sqlx.DB
+	cfg     ReplicaConfig
+	healthy atomic.Bool
+	now     func() time.Time
+
+	mu        sync.Mutex
+	lastWrite map[string]time.Time
+	// anyWrite - последняя запись в любой компании, allWrite - последняя
+	// запись, затронувшая все компании.
+	anyWrite time.Time
+	allWrite time.Time
+}
+
+func NewReplicaRouter(primary, replica *sqlx.DB, cfg ReplicaConfig) *ReplicaRouter {
+	if cfg.MaxLag <= 0 {
+		cfg.MaxLag = DefaultReplicaMaxLag
+	}
+	if cfg.CheckInterval <= 0 {
+		cfg.CheckInterval = DefaultReplicaCheckInterval
+	}
+	if cfg.ReadYourWritesWindow < 0 {
+		cfg.ReadYourWritesWindow = 0
+	}
+	return &ReplicaRouter{primary: primary, replica: replica, cfg: cfg, now: time.Now, lastWrite: make(map[string]time.Time)}
+}
+
+// Watch проверяет реплику каждые CheckInterval до отмены ctx.
+func (r *ReplicaRouter) Watch(ctx context.Context) {
+	ticker := time.NewTicker(r.cfg.CheckInterval)
+	defer ticker.Stop()
+	for {
+		r.Check(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check измеряет отставание реплики и включает или отключает чтения с нее.
+func (r *ReplicaRouter) Check(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.CheckInterval)
+	defer cancel()
+	// Позиция основной базы читается раньше реплики: если реплика уже
+	// применила ее, к моменту проверки она не отстает.
+	var primaryLSN sql.NullString
+	if err := r.primary.GetContext(ctx, &primaryLSN, primaryLSNQuery); err != nil {
+		logger.FromContext(ctx).Warn().Err(err).Msg("Не удалось получить позицию WAL основной базы, отставание реплики оценивается по времени")
+	}
+	var lag float64
+	err := r.replica.GetContext(ctx, &lag, replicaLagQuery, primaryLSN)
+	healthy := err == nil && lag <= r.cfg.MaxLag.Seconds()
+	if err == nil {
+		prometheus.ReplicaLagSeconds.Set(lag)
+	}
+	if healthy {
+		prometheus.ReplicaUp.Set(1)
+	} else {
+		prometheus.ReplicaUp.Set(0)
+	}
+	if r.healthy.Swap(healthy) == healthy {
+		return
+	}
+	switch {
+	case healthy:
+		logger.FromContext(ctx).Info().Float64("lag", lag).Msg("Чтения направляются на реплику")
+	case err != nil:
+		logger.FromContext(ctx).Warn().Err(err).Msg("Реплика недоступна, чтения направляются в основную базу")
+	default:
+		logger.FromContext(ctx).Warn().Float64("lag", lag).Dur("max_lag", r.cfg.MaxLag).Msg("Реплика отстает, чтения направляются в основную базу")
+	}
+}
+
+// useReplica сообщает, можно ли выполнить чтение данных scope на реплике.
+func (r *ReplicaRouter) useReplica(ctx context.Context, method string, scope domain.TenantScope) bool {
+	replica := r.healthy.Load() && !r.recentlyWritten(scope)
+	if ctx.Value(primaryKey{}) != nil {
+		replica = false
+	}
+	target := "primary"
+	if replica {
+		target = "replica"
+	}
+	prometheus.DBReadsTotal.WithLabelValues(method, target).Inc()
+	return replica
+}
+
+// replicaFailed отключает чтения с реплики после ошибки запроса к ней.
+// Следующая успешная проверка Check включает их снова.
+func (r *ReplicaRouter) replicaFailed(ctx context.Context, method string, err error) {
+	prometheus.DBReadsTotal.WithLabelValues(method, "primary").Inc()
+	if !r.healthy.Swap(false) {
+		return
+	}
+	prometheus.ReplicaUp.Set(0)
+	logger.FromContext(ctx).Warn().Err(err).Str("method", method).Msg("Ошибка чтения с реплики, чтения направляются в основную базу")
+}
+
+// routeRead выполняет чтение на реплике, если router это разрешает, иначе в
+// основной базе. Чтение, завершившееся на реплике ошибкой, повторяется в
+// основной базе. Реплика отключается, только если основная база ответила без
+// ошибки, чтобы ошибки самого запроса (например, не найденная приемка) не
+// отключали ее.
+func routeRead[T any](ctx context.Context, router *ReplicaRouter, method string, scope domain.TenantScope, replica, primary func() (T, error)) (T, error) {
+	if !router.useReplica(ctx, method, scope) {
+		return primary()
+	}
+	result, err := replica()
+	if err == nil || ctx.Err() != nil {
+		return result, err
+	}
+	result, primaryErr := primary()
+	if primaryErr == nil {
+		router.replicaFailed(ctx, method, err)
+	}
+	return result, primaryErr
+}
+
+func (r *ReplicaRouter) recentlyWritten(scope domain.TenantScope) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	last := r.anyWrite
+	if !scope.All {
+		last = r.allWrite
+		if at := r.lastWrite[scope.TenantId]; at.After(last) {
+			last = at
+		}
+	}
+	return !last.IsZero() && r.now().Sub(last) < r.cfg.ReadYourWritesWindow
+}
+
+// noteWrite запоминает запись в данных scope.
+func (r *ReplicaRouter) noteWrite(scope domain.TenantScope) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	r.anyWrite = now
+	if scope.All {
+		r.allWrite = now
+		return
+	}
+	r.lastWrite[scope.TenantId] = now
+	// Устаревшие отметки удаляются, чтобы карта не росла с числом компаний.
+	for tenant, at := range r.lastWrite {
+		if now.Sub(at) >= r.cfg.ReadYourWritesWindow {
+			delete(r.lastWrite, tenant)
+		}
+	}
+}
+
+// WithReplica направляет чтения списков ПВЗ, отчетов, истории приемок,
+// перемещений в пути и остатков на replica, если router это разрешает.
+// Остальные методы, в том числе чтения перед записью (расписание,
+// загруженность, правки), выполняются на repo. Записи через repo отмечаются
+// в router для чтения своих записей.
+func WithReplica(repo, replica *Repository, router *ReplicaRouter) *Repository {
+	repo.Pvz = pvzRouter{Pvz: repo.Pvz, replica: replica.Pvz, router: router}
+	repo.Amendments = amendmentsRouter{Amendments: repo.Amendments, replica: replica.Amendments, router: router}
+	repo.Transfers = transfersRouter{Transfers: repo.Transfers, replica: replica.Transfers, router: router}
+	repo.ReceptionAutoClose = autoCloseRouter{ReceptionAutoClose: repo.ReceptionAutoClose, router: router}
+	repo.PvzCapacity = capacityRouter{PvzCapacity: repo.PvzCapacity, replica: replica.PvzCapacity, router: router}
+	return repo
+}
+
+type pvzRouter struct {
+	Pvz
+	replica Pvz
+	router  *ReplicaRouter
+}
+
+func (r pvzRouter) GetPvz(ctx context.Context, scope domain.TenantScope, input domain.GettingPvzParams) ([]domain.PvzSummary, error) {
+	return routeRead(ctx, r.router, "GetPvz", scope,
+		func() ([]domain.PvzSummary, error) { return r.replica.GetPvz(ctx, scope, input) },
+		func() ([]domain.PvzSummary, error) { return r.Pvz.GetPvz(ctx, scope, input) })
+}
+
+func (r pvzRouter) GetListOFpvz(ctx context.Context, scope domain.TenantScope) ([]domain.PVZ, error) {
+	return routeRead(ctx, r.router, "GetListOFpvz", scope,
+		func() ([]domain.PVZ, error) { return r.replica.GetListOFpvz(ctx, scope) },
+		func() ([]domain.PVZ, error) { return r.Pvz.GetListOFpvz(ctx, scope) })
+}
+
+func (r pvzRouter) GetNearestPvz(ctx context.Context, scope domain.TenantScope, params domain.NearestPvzParams) ([]domain.PvzDistance, error) {
+	return routeRead(ctx, r.router, "GetNearestPvz", scope,
+		func() ([]domain.PvzDistance, error) { return r.replica.GetNearestPvz(ctx, scope, params) },
+		func() ([]domain.PvzDistance, error) { return r.Pvz.GetNearestPvz(ctx, scope, params) })
+}
+
+func (r pvzRouter) GetPvzReport(ctx context.Context, scope domain.TenantScope, params domain.PvzReportParams) (domain.PvzReport, error) {
+	return routeRead(ctx, r.router, "GetPvzReport", scope,
+		func() (domain.PvzReport, error) { return r.replica.GetPvzReport(ctx, scope, params) },
+		func() (domain.PvzReport, error) { return r.Pvz.GetPvzReport(ctx, scope, params) })
+}
+
+func (r pvzRouter) CreatePvz(ctx context.Context, scope domain.TenantScope, pvz domain.PVZ) (domain.PVZ, error) {
+	defer r.router.noteWrite(scope)
+	return r.Pvz.CreatePvz(ctx, scope, pvz)
+}
+
+func (r pvzRouter) UpdatePvz(ctx context.Context, scope domain.TenantScope, pvzId uuid.UUID, input domain.PvzUpdate) (domain.PVZ, error) {
+	defer r.router.noteWrite(scope)
+	return r.Pvz.UpdatePvz(ctx, scope, pvzId, input)
+}
+
+func (r pvzRouter) ArchiveDecommissionedPvz(ctx context.Context, now time.Time) ([]domain.PVZ, error) {
+	defer r.router.noteWrite(domain.AllTenants())
+	return r.Pvz.ArchiveDecommissionedPvz(ctx, now)
+}
+
+func (r pvzRouter) CreateRecep(ctx context.Context, scope domain.TenantScope, recep domain.ProductReception) (domain.ProductReception, error) {
+	defer r.router.noteWrite(scope)
+	return r.Pvz.CreateRecep(ctx, scope, recep)
+}
+
+func (r pvzRouter) AddProdToRecep(ctx context.Context, scope domain.TenantScope, product domain.Product) (domain.Product, error) {
+	defer r.router.noteWrite(scope)
+	return r.Pvz.AddProdToRecep(ctx, scope, product)
+}
+
+func (r pvzRouter) DeleteLastProduct(ctx context.Context, scope domain.TenantScope, input domain.ProductDeletion) (domain.Product, error) {
+	defer r.router.noteWrite(scope)
+	return r.Pvz.DeleteLastProduct(ctx, scope, input)
+}
+
+func (r pvzRouter) DeleteProduct(ctx context.Context, scope domain.TenantScope, input domain.ProductDeletion) (domain.Product, error) {
+	defer r.router.noteWrite(scope)
+	return r.Pvz.DeleteProduct(ctx, scope, input)
+}
+
+func (r pvzRouter) CloseReception(ctx context.Context, scope domain.TenantScope, closeRec uuid.UUID) (domain.ProductReception, error) {
+	defer r.router.noteWrite(scope)
+	return r.Pvz.CloseReception(ctx, scope, closeRec)
+}
+
+type amendmentsRouter struct {
+	Amendments
+	replica Amendments
+	router  *ReplicaRouter
+}
+
+func (r amendmentsRouter) GetReceptionHistory(ctx context.Context, scope domain.TenantScope, receptionId uuid.UUID) ([]domain.ReceptionVersion, error) {
+	return routeRead(ctx, r.router, "GetReceptionHistory", scope,
+		func() ([]domain.ReceptionVersion, error) {
+			return r.replica.GetReceptionHistory(ctx, scope, receptionId)
+		},
+		func() ([]domain.ReceptionVersion, error) {
+			return r.Amendments.GetReceptionHistory(ctx, scope, receptionId)
+		})
+}
+
+func (r amendmentsRouter) CreateAmendment(ctx context.Context, scope domain.TenantScope, amendment domain.ReceptionAmendment) (domain.ReceptionAmendment, error) {
+	defer r.router.noteWrite(scope)
+	return r.Amendments.CreateAmendment(ctx, scope, amendment)
+}
+
+func (r amendmentsRouter) ApplyAmendment(ctx context.Context, scope domain.TenantScope, id uuid.UUID, reviewer, comment string) (domain.ReceptionAmendment, error) {
+	defer r.router.noteWrite(scope)
+	return r.Amendments.ApplyAmendment(ctx, scope, id, reviewer, comment)
+}
+
+func (r amendmentsRouter) RejectAmendment(ctx context.Context, scope domain.TenantScope, id uuid.UUID, reviewer, comment string) (domain.ReceptionAmendment, error) {
+	defer r.router.noteWrite(scope)
+	return r.Amendments.RejectAmendment(ctx, scope, id, reviewer, comment)
+}
+
+type transfersRouter struct {
+	Transfers
+	replica Transfers
+	router  *ReplicaRouter
+}
+
+func (r transfersRouter) GetTransfersInTransit(ctx context.Context, scope domain.TenantScope, params domain.InTransitParams) ([]domain.ProductTransfer, error) {
+	return routeRead(ctx, r.router, "GetTransfersInTransit", scope,
+		func() ([]domain.ProductTransfer, error) { return r.replica.GetTransfersInTransit(ctx, scope, params) },
+		func() ([]domain.ProductTransfer, error) { return r.Transfers.GetTransfersInTransit(ctx, scope, params) })
+}
+
+func (r transfersRouter) ShipTransfer(ctx context.Context, scope domain.TenantScope, transfer domain.ProductTransfer) (domain.ProductTransfer, error) {
+	defer r.router.noteWrite(scope)
+	return r.Transfers.ShipTransfer(ctx, scope, transfer)
+}
+
+func (r transfersRouter) AcceptTransfer(ctx context.Context, scope domain.TenantScope, id uuid.UUID, actorId string, at time.Time) (domain.ProductTransfer, error) {
+	defer r.router.noteWrite(scope)
+	return r.Transfers.AcceptTransfer(ctx, scope, id, actorId, at)
+}
+
+type autoCloseRouter struct {
+	ReceptionAutoClose
+	router *ReplicaRouter
+}
+
+func (r autoCloseRouter) AutoCloseReceptions(ctx context.Context, policy domain.AutoClosePolicy, now time.Time) (domain.AutoCloseResult, error) {
+	defer r.router.noteWrite(domain.AllTenants())
+	return r.ReceptionAutoClose.AutoCloseReceptions(ctx, policy, now)
+}
+
+type capacityRouter struct {
+	PvzCapacity
+	replica PvzCapacity
+	router  *ReplicaRouter
+}
+
+func (r capacityRouter) GetPvzStock(ctx context.Context) ([]domain.PvzStock, error) {
+	return routeRead(ctx, r.router, "GetPvzStock", domain.AllTenants(),
+		func() ([]domain.PvzStock, error) { return r.replica.GetPvzStock(ctx) },
+		func() ([]domain.PvzStock, error) { return r.PvzCapacity.GetPvzStock(ctx) })
+}
+
+func (r capacityRouter) SetPvzSchedule(ctx context.Context, scope domain.TenantScope, pvzId uuid.UUID, schedule domain.PvzSchedule) (domain.PvzSchedule, error) {
+	defer r.router.noteWrite(scope)
+	return r.PvzCapacity.SetPvzSchedule(ctx, scope, pvzId, schedule)
+}
+
+func (r capacityRouter) IssueProduct(ctx context.Context, scope domain.TenantScope, pvzId, productId uuid.UUID, at time.Time) (domain.Product, error) {
+	defer r.router.noteWrite(scope)
+	return r.PvzCapacity.IssueProduct(ctx, scope, pvzId, productId, at)
+}
@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/bllooop/pvzservice/internal/domain"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type namedPvz struct {
+	Pvz
+	name string
+	err  error
+}
+
+func (r namedPvz) GetListOFpvz(ctx context.Context, scope domain.TenantScope) ([]domain.PVZ, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	return []domain.PVZ{{City: r.name}}, nil
+}
+
+func (r namedPvz) CloseReception(ctx context.Context, scope domain.TenantScope, closeRec uuid.UUID) (domain.ProductReception, error) {
+	return domain.ProductReception{}, nil
+}
+
+func TestReplicaRouter_Check(t *testing.T) {
+	tests := []struct {
+		name       string
+		primaryErr error
+		lag        float64
+		err        error
+		healthy    bool
+	}{
+		{name: "OK", lag: 1, healthy: true},
+		{name: "Lagging", lag: 10, healthy: false},
+		{name: "Disconnected", lag: math.Inf(1), healthy: false},
+		{name: "Unavailable", err: errors.New("connection refused"), healthy: false},
+		{name: "Primary unavailable", primaryErr: errors.New("connection refused"), lag: 1, healthy: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primaryDB, primaryMock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer primaryDB.Close()
+			replicaDB, replicaMock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer replicaDB.Close()
+			router := NewReplicaRouter(sqlx.NewDb(primaryDB, "postgres"), sqlx.NewDb(replicaDB, "postgres"), ReplicaConfig{MaxLag: 5 * time.Second})
+
+			lsn := primaryMock.ExpectQuery("SELECT pg_current_wal_lsn")
+			var lsnArg driver.Value = "0/3000060"
+			if tt.primaryErr != nil {
+				lsn.WillReturnError(tt.primaryErr)
+				lsnArg = nil
+			} else {
+				lsn.WillReturnRows(sqlmock.NewRows([]string{"pg_current_wal_lsn"}).AddRow("0/3000060"))
+			}
+			query := replicaMock.ExpectQuery("pg_last_wal_replay_lsn(.+)pg_last_xact_replay_timestamp").WithArgs(lsnArg)
+			if tt.err != nil {
+				query.WillReturnError(tt.err)
+			} else {
+				query.WillReturnRows(sqlmock.NewRows([]string{"lag"}).AddRow(tt.lag))
+			}
+			router.Check(context.Background())
+			assert.Equal(t, tt.healthy, router.healthy.Load())
+			assert.NoError(t, primaryMock.ExpectationsWereMet())
+			assert.NoError(t, replicaMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestWithReplica_Routing(t *testing.T) {
+	now := time.Date(2025, 4, 10, 15, 0, 0, 0, time.UTC)
+	router := NewReplicaRouter(nil, nil, ReplicaConfig{ReadYourWritesWindow: 10 * time.Second})
+	router.now = func() time.Time { return now }
+	repo := WithReplica(&Repository{Pvz: namedPvz{name: "primary"}}, &Repository{Pvz: namedPvz{name: "replica"}}, router)
+	tenantA, tenantB := domain.TenantOf("a"), domain.TenantOf("b")
+	read := func(ctx context.Context, scope domain.TenantScope) string {
+		got, err := repo.GetListOFpvz(ctx, scope)
+		require.NoError(t, err)
+		return got[0].City
+	}
+
+	assert.Equal(t, "primary", read(context.Background(), tenantA), "до первой проверки реплика не используется")
+	router.healthy.Store(true)
+	assert.Equal(t, "replica", read(context.Background(), tenantA))
+	assert.Equal(t, "primary", read(WithPrimary(context.Background()), tenantA))
+
+	_, err := repo.CloseReception(context.Background(), tenantA, uuid.New())
+	require.NoError(t, err)
+	assert.Equal(t, "primary", read(context.Background(), tenantA), "чтение своей записи")
+	assert.Equal(t, "primary", read(context.Background(), domain.AllTenants()))
+	assert.Equal(t, "replica", read(context.Background(), tenantB))
+
+	now = now.Add(10 * time.Second)
+	assert.Equal(t, "replica", read(context.Background(), tenantA))
+	assert.Equal(t, "replica", read(context.Background(), domain.AllTenants()))
+}
+
+func TestWithReplica_Fallback(t *testing.T) {
+	tests := []struct {
+		name         string
+		primaryErr   error
+		expected     string
+		expectedErr  error
+		stillHealthy bool
+	}{
+		{name: "Ошибка реплики", expected: "primary"},
+		{name: "Ошибка запроса", primaryErr: ErrPvzNotFound, expectedErr: ErrPvzNotFound, stillHealthy: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := NewReplicaRouter(nil, nil, ReplicaConfig{})
+			router.healthy.Store(true)
+			primaryErr := tt.primaryErr
+			replicaErr := tt.primaryErr
+			if replicaErr == nil {
+				replicaErr = errors.New("connection reset by peer")
+			}
+			repo := WithReplica(&Repository{Pvz: namedPvz{name: "primary", err: primaryErr}}, &Repository{Pvz: namedPvz{name: "replica", err: replicaErr}}, router)
+
+			got, err := repo.GetListOFpvz(context.Background(), domain.TenantOf("a"))
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.expected, got[0].City)
+			}
+			assert.Equal(t, tt.stillHealthy, router.healthy.Load())
+		})
+	}
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/bllooop/pvzservice/internal/domain"
 	"github.com/google/uuid"
@@ -9,27 +10,109 @@ import (
 )
 
 type Authorization interface {
-	CreateUser(user domain.User) (domain.User, error)
-	SignUser(email string) (domain.User, error)
+	CreateUser(ctx context.Context, user domain.User) (domain.User, error)
+	SignUser(ctx context.Context, tenantId, email string) (domain.User, error)
+	GetUsers(ctx context.Context, scope domain.TenantScope, input domain.GettingUsersParams) ([]domain.UserInfo, error)
+	UpdateUser(ctx context.Context, scope domain.TenantScope, userId uuid.UUID, input domain.UpdateUserInput) (domain.UserInfo, error)
+	DisableUser(ctx context.Context, scope domain.TenantScope, userId uuid.UUID) (domain.UserInfo, error)
+	GetUserStatus(ctx context.Context, userId uuid.UUID) (domain.UserStatus, error)
+	UpdateLastLogin(ctx context.Context, userId uuid.UUID) error
+}
+type PasswordReset interface {
+	CreateResetToken(ctx context.Context, userId uuid.UUID, tokenHash string, expiresAt time.Time) error
+	ConsumeResetToken(ctx context.Context, tokenHash string, passwordHash string) (uuid.UUID, error)
+	InvalidateResetTokens(ctx context.Context, userId uuid.UUID) error
+}
+type LoginAttempts interface {
+	// UpdateLoginAttempt атомарно читает счетчик key и сохраняет результат
+	// update. Если update возвращает ошибку, счетчик не меняется.
+	UpdateLoginAttempt(ctx context.Context, key string, update func(domain.LoginAttempt) (domain.LoginAttempt, error)) (domain.LoginAttempt, error)
+	ResetLoginAttempts(ctx context.Context, key string) error
+}
+type Audit interface {
+	AppendAudit(ctx context.Context, entry domain.AuditEntry) (domain.AuditEntry, error)
+	GetAudit(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditEntry, error)
+	VerifyAuditChain(ctx context.Context) (int, error)
+}
+type Idempotency interface {
+	ReserveIdempotencyKey(ctx context.Context, record domain.IdempotencyRecord) (domain.IdempotencyRecord, bool, error)
+	CompleteIdempotencyKey(ctx context.Context, scope, key string, statusCode int, response []byte) error
+	ReleaseIdempotencyKey(ctx context.Context, scope, key string) error
+	DeleteExpiredIdempotencyKeys(ctx context.Context, before time.Time) (int64, error)
+}
+type Amendments interface {
+	CreateAmendment(ctx context.Context, scope domain.TenantScope, amendment domain.ReceptionAmendment) (domain.ReceptionAmendment, error)
+	GetAmendments(ctx context.Context, scope domain.TenantScope, receptionId uuid.UUID) ([]domain.ReceptionAmendment, error)
+	ApplyAmendment(ctx context.Context, scope domain.TenantScope, id uuid.UUID, reviewer, comment string) (domain.ReceptionAmendment, error)
+	RejectAmendment(ctx context.Context, scope domain.TenantScope, id uuid.UUID, reviewer, comment string) (domain.ReceptionAmendment, error)
+	GetReceptionHistory(ctx context.Context, scope domain.TenantScope, receptionId uuid.UUID) ([]domain.ReceptionVersion, error)
+}
+type Transfers interface {
+	ShipTransfer(ctx context.Context, scope domain.TenantScope, transfer domain.ProductTransfer) (domain.ProductTransfer, error)
+	AcceptTransfer(ctx context.Context, scope domain.TenantScope, id uuid.UUID, actorId string, at time.Time) (domain.ProductTransfer, error)
+	GetTransfersInTransit(ctx context.Context, scope domain.TenantScope, params domain.InTransitParams) ([]domain.ProductTransfer, error)
+}
+type ReceptionAutoClose interface {
+	AutoCloseReceptions(ctx context.Context, policy domain.AutoClosePolicy, now time.Time) (domain.AutoCloseResult, error)
+}
+type PvzCapacity interface {
+	GetPvzSchedule(ctx context.Context, scope domain.TenantScope, pvzId uuid.UUID) (domain.PvzSchedule, error)
+	SetPvzSchedule(ctx context.Context, scope domain.TenantScope, pvzId uuid.UUID, schedule domain.PvzSchedule) (domain.PvzSchedule, error)
+	GetPvzOccupancy(ctx context.Context, scope domain.TenantScope, pvzId *uuid.UUID) ([]domain.PvzOccupancy, error)
+	GetPvzStock(ctx context.Context) ([]domain.PvzStock, error)
+	IssueProduct(ctx context.Context, scope domain.TenantScope, pvzId, productId uuid.UUID, at time.Time) (domain.Product, error)
 }
 type Pvz interface {
-	CreatePvz(pvz domain.PVZ) (domain.PVZ, error)
-	GetPvz(input domain.GettingPvzParams) ([]domain.PvzSummary, error)
-	CreateRecep(recep domain.ProductReception) (domain.ProductReception, error)
-	AddProdToRecep(product domain.Product) (domain.Product, error)
-	DeleteLastProduct(delProd uuid.UUID) error
-	CloseReception(closeRec uuid.UUID) (domain.ProductReception, error)
-	GetListOFpvz(ctx context.Context) ([]domain.PVZ, error)
+	CreatePvz(ctx context.Context, scope domain.TenantScope, pvz domain.PVZ) (domain.PVZ, error)
+	UpdatePvz(ctx context.Context, scope domain.TenantScope, pvzId uuid.UUID, input domain.PvzUpdate) (domain.PVZ, error)
+	ArchiveDecommissionedPvz(ctx context.Context, now time.Time) ([]domain.PVZ, error)
+	GetNearestPvz(ctx context.Context, scope domain.TenantScope, params domain.NearestPvzParams) ([]domain.PvzDistance, error)
+	GetPvz(ctx context.Context, scope domain.TenantScope, input domain.GettingPvzParams) ([]domain.PvzSummary, error)
+	GetPvzReport(ctx context.Context, scope domain.TenantScope, params domain.PvzReportParams) (domain.PvzReport, error)
+	CreateRecep(ctx context.Context, scope domain.TenantScope, recep domain.ProductReception) (domain.ProductReception, error)
+	AddProdToRecep(ctx context.Context, scope domain.TenantScope, product domain.Product) (domain.Product, error)
+	DeleteLastProduct(ctx context.Context, scope domain.TenantScope, input domain.ProductDeletion) (domain.Product, error)
+	DeleteProduct(ctx context.Context, scope domain.TenantScope, input domain.ProductDeletion) (domain.Product, error)
+	CloseReception(ctx context.Context, scope domain.TenantScope, closeRec uuid.UUID) (domain.ProductReception, error)
+	GetListOFpvz(ctx context.Context, scope domain.TenantScope) ([]domain.PVZ, error)
 }
 
 type Repository struct {
 	Authorization
+	PasswordReset
+	LoginAttempts
+	Audit
+	Idempotency
+	Amendments
+	Transfers
+	ReceptionAutoClose
+	PvzCapacity
 	Pvz
 }
 
-func NewRepository(db *sqlx.DB) *Repository {
+// NewRepository создает репозиторий поверх db. Запросы ограничены
+// таймаутами timeouts.
+func NewRepository(db *sqlx.DB, timeouts Timeouts) *Repository {
+	auth := NewAuthPostgres(db)
+	auth.timeouts = timeouts
+	pvz := NewPvzPostgres(db)
+	pvz.timeouts = timeouts
+	attempts := NewLoginAttemptsPostgres(db)
+	attempts.timeouts = timeouts
+	audit := NewAuditPostgres(db)
+	audit.timeouts = timeouts
+	idempotency := NewIdempotencyPostgres(db)
+	idempotency.timeouts = timeouts
 	return &Repository{
-		Authorization: NewAuthPostgres(db),
-		Pvz:           NewPvzPostgres(db),
+		Authorization:      auth,
+		PasswordReset:      auth,
+		LoginAttempts:      attempts,
+		Audit:              audit,
+		Idempotency:        idempotency,
+		Amendments:         pvz,
+		Transfers:          pvz,
+		ReceptionAutoClose: pvz,
+		PvzCapacity:        pvz,
+		Pvz:                pvz,
 	}
 }
@@ -0,0 +1,284 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/bllooop/pvzservice/internal/domain"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+var transferRowColumns = []string{"id", "product_id", "type_product", "from_pvz_id", "to_pvz_id", "status", "reason", "shipped_by", "shipped_at", "received_product_id", "received_by", "received_at"}
+
+func TestPvzPostgres_ShipTransfer(t *testing.T) {
+	fixedTime := time.Date(2025, 4, 10, 15, 5, 17, 0, time.UTC)
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	r := NewPvzPostgres(sqlx.NewDb(db, "postgres"))
+	fromId, toId, productId, recepId, transferId := uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	selectReceiving := fmt.Sprintf(`SELECT (.+) FROM %s p WHERE p.id = \$1 AND (.+) FOR SHARE OF p`, pvzTable)
+	selectProduct := fmt.Sprintf(`SELECT r.status_reception, r.id, p.type_product, p.issued_at, p.transferred_at FROM %s p JOIN %s r`, productTable, receptionTable)
+	productColumns := []string{"status_reception", "id", "type_product", "issued_at", "transferred_at"}
+	input := domain.ProductTransfer{ProductId: productId, FromPVZId: fromId, ToPVZId: toId, Reason: "ошибка сортировки", ShippedBy: "u1", ShippedAt: fixedTime}
+	want := domain.ProductTransfer{Id: transferId, ProductId: productId, Type: "обувь", FromPVZId: fromId, ToPVZId: toId,
+		Status: domain.TransferInTransit, Reason: "ошибка сортировки", ShippedBy: "u1", ShippedAt: fixedTime}
+
+	tests := []struct {
+		name    string
+		mock    func()
+		want    domain.ProductTransfer
+		wantErr error
+	}{
+		{
+			name: "Ok",
+			mock: func() {
+				mock.ExpectBegin()
+				expectPvzActive(mock)
+				mock.ExpectQuery(selectReceiving).WithArgs(toId, "t1").
+					WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(domain.PvzTemporarilyClosed))
+				mock.ExpectQuery(selectProduct).WithArgs(productId, fromId).
+					WillReturnRows(sqlmock.NewRows(productColumns).AddRow(domain.ReceptionClosed, recepId, "обувь", nil, nil))
+				mock.ExpectExec(fmt.Sprintf(`UPDATE %s SET transferred_at = \$2`, productTable)).WithArgs(productId, fixedTime).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery(fmt.Sprintf("INSERT INTO %s", transfersTable)).
+					WithArgs(productId, "обувь", fromId, toId, domain.TransferInTransit, "ошибка сортировки", "u1", fixedTime).
+					WillReturnRows(sqlmock.NewRows(transferRowColumns).
+						AddRow(transferId, productId, "обувь", fromId, toId, domain.TransferInTransit, "ошибка сортировки", "u1", fixedTime, nil, nil, nil))
+				mock.ExpectExec(fmt.Sprintf("INSERT INTO %s", correctionsTable)).
+					WithArgs(recepId, productId, domain.CorrectionTransferOut, domain.ReasonTransfer, "ошибка сортировки", "u1", nil, &transferId).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectCommit()
+			},
+			want: want,
+		},
+		{
+			name: "ПВЗ получения другой компании",
+			mock: func() {
+				mock.ExpectBegin()
+				expectPvzActive(mock)
+				mock.ExpectQuery(selectReceiving).WithArgs(toId, "t1").WillReturnRows(sqlmock.NewRows([]string{"status"}))
+				mock.ExpectRollback()
+			},
+			wantErr: ErrPvzNotFound,
+		},
+		{
+			name: "ПВЗ получения выведен из работы",
+			mock: func() {
+				mock.ExpectBegin()
+				expectPvzActive(mock)
+				mock.ExpectQuery(selectReceiving).WithArgs(toId, "t1").
+					WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(domain.PvzDecommissioned))
+				mock.ExpectRollback()
+			},
+			wantErr: ErrPvzNotActive,
+		},
+		{
+			name: "Приемка не закрыта",
+			mock: func() {
+				mock.ExpectBegin()
+				expectPvzActive(mock)
+				mock.ExpectQuery(selectReceiving).WithArgs(toId, "t1").
+					WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(domain.PvzActive))
+				mock.ExpectQuery(selectProduct).WithArgs(productId, fromId).
+					WillReturnRows(sqlmock.NewRows(productColumns).AddRow(domain.ReceptionInProgress, recepId, "обувь", nil, nil))
+				mock.ExpectRollback()
+			},
+			wantErr: ErrProductNotReceived,
+		},
+		{
+			name: "Товар уже перемещен",
+			mock: func() {
+				mock.ExpectBegin()
+				expectPvzActive(mock)
+				mock.ExpectQuery(selectReceiving).WithArgs(toId, "t1").
+					WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(domain.PvzActive))
+				mock.ExpectQuery(selectProduct).WithArgs(productId, fromId).
+					WillReturnRows(sqlmock.NewRows(productColumns).AddRow(domain.ReceptionClosed, recepId, "обувь", nil, fixedTime))
+				mock.ExpectRollback()
+			},
+			wantErr: ErrProductTransferred,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			got, err := r.ShipTransfer(context.Background(), testScope, input)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPvzPostgres_AcceptTransfer(t *testing.T) {
+	fixedTime := time.Date(2025, 4, 10, 15, 5, 17, 0, time.UTC)
+	receivedAt := fixedTime.Add(24 * time.Hour)
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	r := NewPvzPostgres(sqlx.NewDb(db, "postgres"))
+	fromId, toId, productId, addedId, recepId, transferId := uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	selectTransfer := fmt.Sprintf(`SELECT (.+) FROM %s WHERE id = \$1 AND (.+) FOR UPDATE`, transfersTable)
+	selectReception := fmt.Sprintf(`SELECT status_reception,id FROM %s WHERE pvz_id = \$1`, receptionTable)
+	inTransit := func() *sqlmock.Rows {
+		return sqlmock.NewRows(transferRowColumns).
+			AddRow(transferId, productId, "обувь", fromId, toId, domain.TransferInTransit, "", "u1", fixedTime, nil, nil, nil)
+	}
+	receivedBy := "u2"
+	pvzColumns := []string{"id", "registrationdate", "city", "capacity", "status", "timezone"}
+	selectPvz := fmt.Sprintf(`SELECT (.+) FROM %s p WHERE p.id = \$1 AND (.+) FOR UPDATE OF p`, pvzTable)
+	countStored := fmt.Sprintf(`SELECT COUNT\(\*\) FROM %s WHERE pvz_id = \$1 AND deleted_at IS NULL AND issued_at IS NULL AND transferred_at IS NULL`, productTable)
+
+	tests := []struct {
+		name    string
+		limited bool
+		mock    func()
+		want    domain.ProductTransfer
+		wantErr error
+	}{
+		{
+			name: "Ok",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectTransfer).WithArgs(transferId, "t1").WillReturnRows(inTransit())
+				expectPvzActive(mock)
+				mock.ExpectQuery(selectReception).WithArgs(toId).
+					WillReturnRows(sqlmock.NewRows([]string{"status_reception", "id"}).AddRow(domain.ReceptionInProgress, recepId))
+				mock.ExpectQuery(fmt.Sprintf("INSERT INTO %s", productTable)).
+					WithArgs(&receivedAt, "обувь", recepId, toId).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "date_received", "type_product", "reception_id"}).AddRow(addedId, receivedAt, "обувь", recepId))
+				mock.ExpectExec(fmt.Sprintf("INSERT INTO %s", correctionsTable)).
+					WithArgs(recepId, addedId, domain.CorrectionAdd, domain.ReasonTransfer, "", receivedBy, nil, &transferId).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectQuery(fmt.Sprintf(`UPDATE %s SET status = \$2`, transfersTable)).
+					WithArgs(transferId, domain.TransferReceived, &addedId, receivedBy, receivedAt).
+					WillReturnRows(sqlmock.NewRows(transferRowColumns).
+						AddRow(transferId, productId, "обувь", fromId, toId, domain.TransferReceived, "", "u1", fixedTime, addedId, receivedBy, receivedAt))
+				mock.ExpectCommit()
+			},
+			want: domain.ProductTransfer{Id: transferId, ProductId: productId, Type: "обувь", FromPVZId: fromId, ToPVZId: toId,
+				Status: domain.TransferReceived, ShippedBy: "u1", ShippedAt: fixedTime, ReceivedProductId: &addedId, ReceivedBy: &receivedBy, ReceivedAt: &receivedAt},
+		},
+		{
+			name:    "ПВЗ получения заполнен",
+			limited: true,
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectTransfer).WithArgs(transferId, "t1").WillReturnRows(inTransit())
+				mock.ExpectQuery(selectPvz).WithArgs(toId, "t1").
+					WillReturnRows(sqlmock.NewRows(pvzColumns).AddRow(toId, fixedTime, "Казань", 2, domain.PvzActive, "UTC"))
+				mock.ExpectQuery(countStored).WithArgs(toId).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+				mock.ExpectRollback()
+			},
+			wantErr: ErrPvzFull,
+		},
+		{
+			name:    "ПВЗ получения закрыт по расписанию",
+			limited: true,
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectTransfer).WithArgs(transferId, "t1").WillReturnRows(inTransit())
+				mock.ExpectQuery(selectPvz).WithArgs(toId, "t1").
+					WillReturnRows(sqlmock.NewRows(pvzColumns).AddRow(toId, fixedTime, "Казань", 2, domain.PvzActive, "UTC"))
+				mock.ExpectQuery(countStored).WithArgs(toId).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+				mock.ExpectQuery(fmt.Sprintf(`SELECT timezone FROM %s WHERE id = \$1`, pvzTable)).WithArgs(toId, "t1").
+					WillReturnRows(sqlmock.NewRows([]string{"timezone"}).AddRow("UTC"))
+				mock.ExpectQuery(fmt.Sprintf(`SELECT weekday, (.+) FROM %s WHERE pvz_id = \$1`, workingHoursTable)).WithArgs(toId).
+					WillReturnRows(sqlmock.NewRows([]string{"weekday", "opens_at", "closes_at"}).AddRow(1, "09:00", "21:00"))
+				mock.ExpectQuery(fmt.Sprintf(`SELECT (.+) FROM %s WHERE pvz_id = \$1`, holidaysTable)).WithArgs(toId).
+					WillReturnRows(sqlmock.NewRows([]string{"day", "opens_at", "closes_at", "reason"}))
+				mock.ExpectRollback()
+			},
+			wantErr: ErrPvzClosedNow,
+		},
+		{
+			name: "Нет открытой приемки",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectTransfer).WithArgs(transferId, "t1").WillReturnRows(inTransit())
+				expectPvzActive(mock)
+				mock.ExpectQuery(selectReception).WithArgs(toId).
+					WillReturnRows(sqlmock.NewRows([]string{"status_reception", "id"}).AddRow(domain.ReceptionClosed, recepId))
+				mock.ExpectRollback()
+			},
+			wantErr: ErrNoOpenReception,
+		},
+		{
+			name: "Перемещение уже принято",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectTransfer).WithArgs(transferId, "t1").
+					WillReturnRows(sqlmock.NewRows(transferRowColumns).
+						AddRow(transferId, productId, "обувь", fromId, toId, domain.TransferReceived, "", "u1", fixedTime, addedId, receivedBy, receivedAt))
+				mock.ExpectRollback()
+			},
+			wantErr: ErrTransferNotInTransit,
+		},
+		{
+			name: "Перемещение другой компании",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectTransfer).WithArgs(transferId, "t1").WillReturnRows(sqlmock.NewRows(transferRowColumns))
+				mock.ExpectRollback()
+			},
+			wantErr: ErrTransferNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			ctx := context.Background()
+			if tt.limited {
+				ctx = WithCapacityLimit(ctx)
+			}
+			got, err := r.AcceptTransfer(ctx, testScope, transferId, receivedBy, receivedAt)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPvzPostgres_GetTransfersInTransit(t *testing.T) {
+	fixedTime := time.Date(2025, 4, 10, 15, 5, 17, 0, time.UTC)
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	r := NewPvzPostgres(sqlx.NewDb(db, "postgres"))
+	fromId, toId, productId, transferId := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+
+	mock.ExpectQuery(fmt.Sprintf(`SELECT (.+) FROM %s WHERE status = \$1 AND (.+) ORDER BY shipped_at, id LIMIT \$4 OFFSET \$5`, transfersTable)).
+		WithArgs(domain.TransferInTransit, &toId, "t1", 10, 10).
+		WillReturnRows(sqlmock.NewRows(transferRowColumns).
+			AddRow(transferId, productId, "одежда", fromId, toId, domain.TransferInTransit, "", "u1", fixedTime, nil, nil, nil))
+
+	got, err := r.GetTransfersInTransit(context.Background(), testScope, domain.InTransitParams{PVZId: &toId, Page: 2, Limit: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.ProductTransfer{{Id: transferId, ProductId: productId, Type: "одежда", FromPVZId: fromId, ToPVZId: toId,
+		Status: domain.TransferInTransit, ShippedBy: "u1", ShippedAt: fixedTime}}, got)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
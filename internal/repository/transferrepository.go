@@ -0,0 +1,195 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bllooop/pvzservice/internal/domain"
+	logger "github.com/bllooop/pvzservice/pkg/logging"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrTransferNotFound     = errors.New("перемещение не найдено")
+	ErrTransferNotInTransit = errors.New("перемещение уже принято")
+	ErrProductTransferred   = errors.New("товар уже перемещен в другой ПВЗ")
+	ErrNoOpenReception      = errors.New("в ПВЗ получения нет открытой приемки")
+)
+
+const transferColumns = "id,product_id,type_product,from_pvz_id,to_pvz_id,status,reason,shipped_by,shipped_at,received_product_id,received_by,received_at"
+
+// ShipTransfer отправляет товар закрытой приемки в другой ПВЗ той же компании:
+// товар перестает числиться на складе ПВЗ отправления, а перемещение остается
+// в пути до приемки в ПВЗ получения.
+func (r *PvzPostgres) ShipTransfer(ctx context.Context, scope domain.TenantScope, transfer domain.ProductTransfer) (domain.ProductTransfer, error) {
+	ctx, done := startQuery(ctx, r.timeouts.Write, "PvzPostgres.ShipTransfer")
+	defer done()
+	tx, err := r.beginTx(ctx)
+	if err != nil {
+		return domain.ProductTransfer{}, err
+	}
+	defer tx.Rollback()
+	if _, err := r.checkPvzActive(ctx, tx, scope, transfer.FromPVZId); err != nil {
+		return domain.ProductTransfer{}, err
+	}
+	if err := r.checkPvzReceiving(ctx, tx, scope, transfer.ToPVZId); err != nil {
+		return domain.ProductTransfer{}, err
+	}
+	var status string
+	var receptionId uuid.UUID
+	var issuedAt, transferredAt *time.Time
+	query := fmt.Sprintf(`SELECT r.status_reception, r.id, p.type_product, p.issued_at, p.transferred_at FROM %s p JOIN %s r ON r.id = p.reception_id
+  WHERE p.id = $1 AND p.pvz_id = $2 AND p.deleted_at IS NULL FOR UPDATE OF p`, productTable, receptionTable)
+	logger.FromContext(ctx).Debug().Str("query", query).Msg("Проверка товара перед перемещением")
+	if err := tx.QueryRowxContext(ctx, query, transfer.ProductId, transfer.FromPVZId).Scan(&status, &receptionId, &transfer.Type, &issuedAt, &transferredAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.ProductTransfer{}, ErrProductNotFound
+		}
+		return domain.ProductTransfer{}, err
+	}
+	switch {
+	case status != domain.ReceptionClosed:
+		return domain.ProductTransfer{}, ErrProductNotReceived
+	case issuedAt != nil:
+		return domain.ProductTransfer{}, ErrProductIssued
+	case transferredAt != nil:
+		return domain.ProductTransfer{}, ErrProductTransferred
+	}
+	query = fmt.Sprintf(`UPDATE %s SET transferred_at = $2 WHERE id = $1`, productTable)
+	logger.FromContext(ctx).Debug().Str("query", query).Msg("Списание товара со склада ПВЗ отправления")
+	if _, err := tx.ExecContext(ctx, query, transfer.ProductId, transfer.ShippedAt); err != nil {
+		return domain.ProductTransfer{}, err
+	}
+	query = fmt.Sprintf(`INSERT INTO %s (product_id,type_product,from_pvz_id,to_pvz_id,tenant_id,status,reason,shipped_by,shipped_at)
+  VALUES ($1,$2,$3,$4,(SELECT tenant_id FROM %s WHERE id = $3),$5,$6,$7,$8) RETURNING %s`, transfersTable, pvzTable, transferColumns)
+	logger.FromContext(ctx).Debug().Str("query", query).Msg("Создание перемещения товара")
+	var res domain.ProductTransfer
+	if err := tx.QueryRowxContext(ctx, query, transfer.ProductId, transfer.Type, transfer.FromPVZId, transfer.ToPVZId,
+		domain.TransferInTransit, transfer.Reason, transfer.ShippedBy, transfer.ShippedAt).StructScan(&res); err != nil {
+		return domain.ProductTransfer{}, err
+	}
+	err = r.insertCorrection(ctx, tx, domain.ProductCorrection{
+		ReceptionId: receptionId,
+		ProductId:   res.ProductId,
+		Action:      domain.CorrectionTransferOut,
+		Reason:      domain.ReasonTransfer,
+		Comment:     res.Reason,
+		ActorId:     res.ShippedBy,
+		TransferId:  &res.Id,
+	})
+	if err != nil {
+		return domain.ProductTransfer{}, err
+	}
+	if err := auditTx(ctx, tx, res.Id.String(), nil, res); err != nil {
+		return domain.ProductTransfer{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return domain.ProductTransfer{}, err
+	}
+	return res, nil
+}
+
+// AcceptTransfer принимает перемещение в ПВЗ получения: в его открытой
+// приемке создается товар того же типа, связанный с перемещением. С
+// WithCapacityLimit заполненный или закрытый по расписанию ПВЗ получения
+// отклоняется, как при приемке товаров.
+func (r *PvzPostgres) AcceptTransfer(ctx context.Context, scope domain.TenantScope, id uuid.UUID, actorId string, at time.Time) (domain.ProductTransfer, error) {
+	ctx, done := startQuery(ctx, r.timeouts.Write, "PvzPostgres.AcceptTransfer")
+	defer done()
+	tx, err := r.beginTx(ctx)
+	if err != nil {
+		return domain.ProductTransfer{}, err
+	}
+	defer tx.Rollback()
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE id = $1 AND %s FOR UPDATE`, transferColumns, transfersTable, tenantCondition("tenant_id", 2))
+	var transfer domain.ProductTransfer
+	if err := tx.QueryRowxContext(ctx, query, id, scope.Filter()).StructScan(&transfer); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.ProductTransfer{}, ErrTransferNotFound
+		}
+		return domain.ProductTransfer{}, err
+	}
+	if transfer.Status != domain.TransferInTransit {
+		return domain.ProductTransfer{}, ErrTransferNotInTransit
+	}
+	if _, err := r.checkPvzAccepts(ctx, tx, scope, transfer.ToPVZId); err != nil {
+		return domain.ProductTransfer{}, err
+	}
+	if err := r.checkPvzOpen(ctx, tx, scope, transfer.ToPVZId, at); err != nil {
+		return domain.ProductTransfer{}, err
+	}
+	lastStatus, receptionId, err := r.getLastReceptionStatus(ctx, tx, transfer.ToPVZId)
+	if err != nil {
+		return domain.ProductTransfer{}, err
+	}
+	if lastStatus != domain.ReceptionInProgress {
+		return domain.ProductTransfer{}, ErrNoOpenReception
+	}
+	added, err := r.insertProduct(ctx, tx, domain.Product{DateReceived: &at, Type: transfer.Type}, receptionId, transfer.ToPVZId)
+	if err != nil {
+		return domain.ProductTransfer{}, err
+	}
+	err = r.insertCorrection(ctx, tx, domain.ProductCorrection{
+		ReceptionId: receptionId,
+		ProductId:   *added.Id,
+		Action:      domain.CorrectionAdd,
+		Reason:      domain.ReasonTransfer,
+		Comment:     transfer.Reason,
+		ActorId:     actorId,
+		TransferId:  &transfer.Id,
+	})
+	if err != nil {
+		return domain.ProductTransfer{}, err
+	}
+	query = fmt.Sprintf(`UPDATE %s SET status = $2, received_product_id = $3, received_by = $4, received_at = $5 WHERE id = $1 RETURNING %s`,
+		transfersTable, transferColumns)
+	logger.FromContext(ctx).Debug().Str("query", query).Msg("Приемка перемещения товара")
+	var res domain.ProductTransfer
+	if err := tx.QueryRowxContext(ctx, query, id, domain.TransferReceived, added.Id, actorId, at).StructScan(&res); err != nil {
+		return domain.ProductTransfer{}, err
+	}
+	if err := auditTx(ctx, tx, res.Id.String(), transfer, res); err != nil {
+		return domain.ProductTransfer{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return domain.ProductTransfer{}, err
+	}
+	return res, nil
+}
+
+// GetTransfersInTransit возвращает отправленные, но еще не принятые
+// перемещения, начиная с самых давних.
+func (r *PvzPostgres) GetTransfersInTransit(ctx context.Context, scope domain.TenantScope, params domain.InTransitParams) ([]domain.ProductTransfer, error) {
+	ctx, done := startQuery(ctx, r.timeouts.Read, "PvzPostgres.GetTransfersInTransit")
+	defer done()
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE status = $1 AND ($2::uuid IS NULL OR from_pvz_id = $2 OR to_pvz_id = $2) AND %s
+  ORDER BY shipped_at, id LIMIT $4 OFFSET $5`, transferColumns, transfersTable, tenantCondition("tenant_id", 3))
+	logger.FromContext(ctx).Debug().Str("query", query).Msg("Запрос товаров в пути")
+	var result []domain.ProductTransfer
+	offset := (params.Page - 1) * params.Limit
+	if err := r.db.SelectContext(ctx, &result, query, domain.TransferInTransit, params.PVZId, scope.Filter(), params.Limit, offset); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// checkPvzReceiving проверяет, что ПВЗ получения принадлежит компании из scope
+// и не выведен из работы. Временно закрытому ПВЗ товар отправить можно.
+func (r *PvzPostgres) checkPvzReceiving(ctx context.Context, tx *sqlx.Tx, scope domain.TenantScope, pvzId uuid.UUID) error {
+	query := fmt.Sprintf(`SELECT %s FROM %s p WHERE p.id = $1 AND %s FOR SHARE OF p`, pvzStatusExpr, pvzTable, tenantCondition("p.tenant_id", 2))
+	var status string
+	if err := tx.QueryRowxContext(ctx, query, pvzId, scope.Filter()).Scan(&status); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrPvzNotFound
+		}
+		return err
+	}
+	if status == domain.PvzDecommissioned {
+		return ErrPvzNotActive
+	}
+	return nil
+}
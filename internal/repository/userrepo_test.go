@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/bllooop/pvzservice/internal/domain"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthPostgres_UpdateUser(t *testing.T) {
+	fixedTime := time.Date(2025, 4, 10, 15, 5, 17, 0, time.UTC)
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	userID, err := uuid.NewRandom()
+	if err != nil {
+		panic(err)
+	}
+	sqlxDB := sqlx.NewDb(db, "postgres")
+	r := NewAuthPostgres(sqlxDB)
+	role := "moderator"
+	disabled := true
+
+	tests := []struct {
+		name    string
+		mock    func()
+		input   domain.UpdateUserInput
+		want    domain.UserInfo
+		wantErr error
+	}{
+		{
+			name: "Ok",
+			mock: func() {
+				rows := sqlmock.NewRows([]string{"id", "email", "role", "tenant_id", "created_at", "disabled_at", "last_login_at"}).
+					AddRow(userID, "test", "moderator", "t1", fixedTime, fixedTime, nil)
+				mock.ExpectBegin()
+				mock.ExpectQuery(fmt.Sprintf(`UPDATE %s SET role=\$1, role_changed_at=CASE WHEN role IS DISTINCT FROM \$1 THEN now\(\) ELSE role_changed_at END, disabled_at=COALESCE\(disabled_at, now\(\)\) WHERE id=\$2`, userListTable)).
+					WithArgs(role, userID, "t1").WillReturnRows(rows)
+				mock.ExpectCommit()
+			},
+			input: domain.UpdateUserInput{Role: &role, Disabled: &disabled},
+			want: domain.UserInfo{
+				Id:         userID,
+				Email:      "test",
+				Role:       "moderator",
+				TenantId:   "t1",
+				CreatedAt:  &fixedTime,
+				DisabledAt: &fixedTime,
+			},
+		},
+		{
+			name: "Пользователь не найден",
+			mock: func() {
+				rows := sqlmock.NewRows([]string{"id", "email", "role", "tenant_id", "created_at", "disabled_at", "last_login_at"})
+				mock.ExpectBegin()
+				mock.ExpectQuery(fmt.Sprintf("UPDATE %s", userListTable)).
+					WithArgs(role, userID, "t1").WillReturnRows(rows)
+				mock.ExpectRollback()
+			},
+			input:   domain.UpdateUserInput{Role: &role},
+			wantErr: ErrUserNotFound,
+		},
+		{
+			name:    "Нет полей для обновления",
+			mock:    func() {},
+			input:   domain.UpdateUserInput{},
+			wantErr: ErrNothingToUpdate,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			got, err := r.UpdateUser(context.Background(), testScope, userID, tt.input)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestAuthPostgres_GetUserStatus(t *testing.T) {
+	fixedTime := time.Date(2025, 4, 10, 15, 5, 17, 0, time.UTC)
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	userID, err := uuid.NewRandom()
+	if err != nil {
+		panic(err)
+	}
+	sqlxDB := sqlx.NewDb(db, "postgres")
+	r := NewAuthPostgres(sqlxDB)
+
+	tests := []struct {
+		name    string
+		mock    func()
+		want    domain.UserStatus
+		wantErr error
+	}{
+		{
+			name: "Заблокирован",
+			mock: func() {
+				rows := sqlmock.NewRows([]string{"disabled", "password_changed_at", "role_changed_at"}).AddRow(true, fixedTime, nil)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s", userListTable)).
+					WithArgs(userID).WillReturnRows(rows)
+			},
+			want: domain.UserStatus{Disabled: true, PasswordChangedAt: &fixedTime},
+		},
+		{
+			name: "Роль изменена",
+			mock: func() {
+				rows := sqlmock.NewRows([]string{"disabled", "password_changed_at", "role_changed_at"}).AddRow(false, nil, fixedTime)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s", userListTable)).
+					WithArgs(userID).WillReturnRows(rows)
+			},
+			want: domain.UserStatus{RoleChangedAt: &fixedTime},
+		},
+		{
+			name: "Пользователь не найден",
+			mock: func() {
+				rows := sqlmock.NewRows([]string{"disabled", "password_changed_at", "role_changed_at"})
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s", userListTable)).
+					WithArgs(userID).WillReturnRows(rows)
+			},
+			wantErr: ErrUserNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			got, err := r.GetUserStatus(context.Background(), userID)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bllooop/pvzservice/internal/domain"
+	logger "github.com/bllooop/pvzservice/pkg/logging"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+var ErrNothingToUpdate = errors.New("нет полей для обновления")
+
+const userInfoColumns = "id,email,role,tenant_id,created_at,disabled_at,last_login_at"
+
+func (r *AuthPostgres) GetUsers(ctx context.Context, scope domain.TenantScope, input domain.GettingUsersParams) ([]domain.UserInfo, error) {
+	ctx, done := startQuery(ctx, r.timeouts.Read, "AuthPostgres.GetUsers")
+	defer done()
+	var users []domain.UserInfo
+	offset := (input.Page - 1) * input.Limit
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE ($1::text IS NULL OR tenant_id = $1) ORDER BY created_at LIMIT $2 OFFSET $3`, userInfoColumns, userListTable)
+	logger.FromContext(ctx).Debug().Str("query", query).Msg("Запрос списка пользователей")
+	if err := r.db.SelectContext(ctx, &users, query, scope.Filter(), input.Limit, offset); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (r *AuthPostgres) UpdateUser(ctx context.Context, scope domain.TenantScope, userId uuid.UUID, input domain.UpdateUserInput) (domain.UserInfo, error) {
+	ctx, done := startQuery(ctx, r.timeouts.Write, "AuthPostgres.UpdateUser")
+	defer done()
+	var setValues []string
+	var args []interface{}
+	argId := 1
+	if input.Role != nil {
+		// Смена роли отзывает выданные токены, как и смена пароля.
+		setValues = append(setValues, fmt.Sprintf("role=$%d", argId),
+			fmt.Sprintf("role_changed_at=CASE WHEN role IS DISTINCT FROM $%d THEN now() ELSE role_changed_at END", argId))
+		args = append(args, *input.Role)
+		argId++
+	}
+	if input.Password != nil {
+		setValues = append(setValues, fmt.Sprintf("password=$%d", argId), "password_changed_at=now()")
+		args = append(args, *input.Password)
+		argId++
+	}
+	if input.Disabled != nil {
+		if *input.Disabled {
+			setValues = append(setValues, "disabled_at=COALESCE(disabled_at, now())")
+		} else {
+			setValues = append(setValues, "disabled_at=NULL")
+		}
+	}
+	if len(setValues) == 0 {
+		return domain.UserInfo{}, ErrNothingToUpdate
+	}
+	args = append(args, userId, scope.Filter())
+	query := fmt.Sprintf(`UPDATE %s SET %s WHERE id=$%d AND ($%d::text IS NULL OR tenant_id = $%d) RETURNING %s`,
+		userListTable, strings.Join(setValues, ", "), argId, argId+1, argId+1, userInfoColumns)
+	logger.FromContext(ctx).Debug().Str("query", query).Msg("Обновление пользователя")
+	return r.writeUserInfo(ctx, query, args...)
+}
+
+func (r *AuthPostgres) DisableUser(ctx context.Context, scope domain.TenantScope, userId uuid.UUID) (domain.UserInfo, error) {
+	ctx, done := startQuery(ctx, r.timeouts.Write, "AuthPostgres.DisableUser")
+	defer done()
+	query := fmt.Sprintf(`UPDATE %s SET disabled_at=COALESCE(disabled_at, now()) WHERE id=$1 AND ($2::text IS NULL OR tenant_id = $2) RETURNING %s`, userListTable, userInfoColumns)
+	logger.FromContext(ctx).Debug().Str("query", query).Msg("Блокировка пользователя")
+	return r.writeUserInfo(ctx, query, userId, scope.Filter())
+}
+
+func (r *AuthPostgres) GetUserStatus(ctx context.Context, userId uuid.UUID) (domain.UserStatus, error) {
+	ctx, done := startQuery(ctx, r.timeouts.Read, "AuthPostgres.GetUserStatus")
+	defer done()
+	var status domain.UserStatus
+	query := fmt.Sprintf(`SELECT disabled_at IS NOT NULL,password_changed_at,role_changed_at FROM %s WHERE id=$1`, userListTable)
+	err := r.db.QueryRowxContext(ctx, query, userId).Scan(&status.Disabled, &status.PasswordChangedAt, &status.RoleChangedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.UserStatus{}, ErrUserNotFound
+		}
+		return domain.UserStatus{}, err
+	}
+	return status, nil
+}
+
+func (r *AuthPostgres) UpdateLastLogin(ctx context.Context, userId uuid.UUID) error {
+	ctx, done := startQuery(ctx, r.timeouts.Write, "AuthPostgres.UpdateLastLogin")
+	defer done()
+	query := fmt.Sprintf(`UPDATE %s SET last_login_at=now() WHERE id=$1`, userListTable)
+	logger.FromContext(ctx).Debug().Str("query", query).Msg("Обновление времени последнего входа")
+	_, err := r.db.ExecContext(ctx, query, userId)
+	return err
+}
+
+// writeUserInfo выполняет изменение пользователя query и записывает его в
+// журнал аудита в той же транзакции.
+func (r *AuthPostgres) writeUserInfo(ctx context.Context, query string, args ...interface{}) (domain.UserInfo, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return domain.UserInfo{}, err
+	}
+	defer tx.Rollback()
+	user, err := scanUserInfo(ctx, tx, query, args...)
+	if err != nil {
+		return domain.UserInfo{}, err
+	}
+	if err := auditTx(ctx, tx, user.Id.String(), nil, user); err != nil {
+		return domain.UserInfo{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return domain.UserInfo{}, err
+	}
+	return user, nil
+}
+
+func scanUserInfo(ctx context.Context, tx *sqlx.Tx, query string, args ...interface{}) (domain.UserInfo, error) {
+	var user domain.UserInfo
+	err := tx.QueryRowxContext(ctx, query, args...).Scan(&user.Id, &user.Email, &user.Role, &user.TenantId, &user.CreatedAt, &user.DisabledAt, &user.LastLoginAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.UserInfo{}, ErrUserNotFound
+		}
+		return domain.UserInfo{}, err
+	}
+	return user, nil
+}
@@ -0,0 +1,53 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"github.com/bllooop/pvzservice/internal/config"
+	"github.com/bllooop/pvzservice/internal/repository"
+	logger "github.com/bllooop/pvzservice/pkg/logging"
+)
+
+// VerifyAudit проверяет целостность цепочки хешей журнала аудита. Процесс
+// завершается с кодом 1, если цепочка нарушена, и с кодом 2, если проверку
+// не удалось довести до конца (таймаут audit.verifyTimeout или ошибка базы):
+// в этом случае целостность не установлена, но и нарушение не найдено.
+func VerifyAudit() {
+	cfg, err := config.Load("")
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("")
+		logger.Log.Fatal().Msg("Возникла ошибка загрузки конфига")
+	}
+	db, err := repository.NewPostgresDB(cfg.DB.Config)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Не удалось установить соединение с базой данных")
+		logger.Log.Fatal().Msg("Произошла ошибка с базой данных")
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	if cfg.Audit.VerifyTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.Audit.VerifyTimeout)
+		defer cancel()
+	}
+	checked, err := repository.NewAuditPostgres(db).VerifyAuditChain(ctx)
+	switch {
+	case errors.Is(err, repository.ErrAuditChainBroken):
+		logger.Log.Error().Err(err).Int("checked", checked).Msg("Проверка журнала аудита не пройдена")
+		db.Close()
+		os.Exit(1)
+	case err != nil && (errors.Is(err, context.DeadlineExceeded) || ctx.Err() != nil):
+		logger.Log.Error().Err(err).Int("checked", checked).Dur("timeout", cfg.Audit.VerifyTimeout).
+			Msg("Проверка журнала аудита не завершилась за audit.verifyTimeout, нарушений среди проверенных записей нет")
+		db.Close()
+		os.Exit(2)
+	case err != nil:
+		logger.Log.Error().Err(err).Int("checked", checked).Msg("Проверка журнала аудита прервана ошибкой")
+		db.Close()
+		os.Exit(2)
+	}
+	logger.Log.Info().Int("checked", checked).Msg("Цепочка журнала аудита целостна")
+}
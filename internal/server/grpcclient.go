@@ -2,37 +2,31 @@ package server
 
 import (
 	"context"
-	"time"
 
-	pb "github.com/bllooop/pvzservice/grpcpvz"
-	logger "github.com/bllooop/pvzservice/pkg/logging"
-	"github.com/spf13/viper"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
+	"github.com/bllooop/pvzservice/internal/delivery/api"
+	"github.com/bllooop/pvzservice/internal/usecase"
+	"github.com/bllooop/pvzservice/pkg/pvzclient"
 )
 
-func CallGRPCClient() error {
-	conn, err := grpc.NewClient("pvzservice"+viper.GetString("portGrpc"), grpc.WithTransportCredentials(insecure.NewCredentials()))
+// newGRPCProbe создает клиента собственного gRPC сервера для проверки
+// готовности: вызов GetPVZList с токеном суперадминистратора проходит через
+// авторизацию, обработчик и базу данных. Ошибка вызова переводит компонент в
+// down, но не останавливает сервер.
+func newGRPCProbe(address string, auth usecase.Authorization) (*pvzclient.Client, func(ctx context.Context) error, error) {
+	client, err := pvzclient.New(pvzclient.Config{
+		Address: address,
+		Token: func(context.Context) (string, error) {
+			return api.ServiceToken(auth)
+		},
+		MaxAttempts: 1,
+		Insecure:    true,
+	})
 	if err != nil {
-		logger.Log.Error().Err(err).Msg("Ошибка подключения")
-		return err
+		return nil, nil, err
 	}
-	defer conn.Close()
-	logger.Log.Info().Msg("gRPC клиент успешно подключен")
-	client := pb.NewPVZServiceClient(conn)
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-	response, err := client.GetPVZList(ctx, &pb.GetPVZListRequest{})
-	if err != nil {
-		logger.Log.Error().Err(err).Msg("Ошибка вызова GetPvzList")
+	probe := func(ctx context.Context) error {
+		_, err := client.GetPVZList(ctx)
 		return err
 	}
-	logger.Log.Info().Msgf("Получен список PVZ в количестве %d", len(response.Pvzs))
-	for _, pvz := range response.Pvzs {
-		logger.Log.Info().Msgf("ID ПВЗ: %s, Город: %s", pvz.Id, pvz.City)
-		if pvz.RegistrationDate != nil {
-			logger.Log.Info().Msgf("Дата регистрации ПВЗ: %v", pvz.RegistrationDate.AsTime())
-		}
-	}
-	return nil
+	return client, probe, nil
 }
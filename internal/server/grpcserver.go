@@ -1,29 +1,68 @@
 package server
 
 import (
+	"context"
+	"errors"
 	"net"
+	"sync"
+	"sync/atomic"
 
 	pb "github.com/bllooop/pvzservice/grpcpvz"
 	"github.com/bllooop/pvzservice/internal/delivery/api"
+	"github.com/bllooop/pvzservice/internal/ratelimit"
 	"github.com/bllooop/pvzservice/internal/usecase"
 	logger "github.com/bllooop/pvzservice/pkg/logging"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
-func StartGRPC(port string, usecase *usecase.Usecase) *grpc.Server {
+// grpcServing отмечает, что gRPC сервер принимает соединения.
+var grpcServing atomic.Bool
+
+// servingListener отмечает grpcServing при первом вызове Accept, то есть
+// когда Serve действительно начал принимать соединения.
+type servingListener struct {
+	net.Listener
+	once sync.Once
+}
+
+func (l *servingListener) Accept() (net.Conn, error) {
+	l.once.Do(func() { grpcServing.Store(true) })
+	return l.Listener.Accept()
+}
+
+func StartGRPC(port string, usecase *usecase.Usecase, env string, healthSrv *health.Server, limiter *ratelimit.Guard) *grpc.Server {
 	lis, err := net.Listen("tcp", port)
 	if err != nil {
 		logger.Log.Error().Err(err).Msg("")
 		logger.Log.Fatal().Msg("При запуске gRPC сервера произошла ошибка")
 	}
-	grpcServer := grpc.NewServer()
+	grpcServer := grpc.NewServer(grpc.StatsHandler(otelgrpc.NewServerHandler()), grpc.ChainUnaryInterceptor(
+		api.RequestIdInterceptor(),
+		api.MetricsInterceptor(),
+		api.AuthInterceptor(usecase.Authorization, env),
+		api.RateLimitInterceptor(limiter),
+		api.IdempotencyInterceptor(usecase.Idempotency),
+	))
 	pbzSrv := api.NewPVZServiceServer(usecase)
 	pb.RegisterPVZServiceServer(grpcServer, pbzSrv)
+	healthpb.RegisterHealthServer(grpcServer, healthSrv)
 	logger.Log.Info().Msgf("Сервер работает на порту %v", lis.Addr())
 	go func() {
-		if err := grpcServer.Serve(lis); err != nil && err != grpc.ErrServerStopped {
+		defer grpcServing.Store(false)
+		if err := grpcServer.Serve(&servingListener{Listener: lis}); err != nil && err != grpc.ErrServerStopped {
 			logger.Log.Error().Err(err).Msg("Ошибка при работе gRPC сервера")
 		}
 	}()
 	return grpcServer
 }
+
+// checkGRPCServing — проверка готовности gRPC сервера для health.Checker.
+func checkGRPCServing(context.Context) error {
+	if !grpcServing.Load() {
+		return errors.New("gRPC сервер не принимает соединения")
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bllooop/pvzservice/internal/health"
+	"github.com/bllooop/pvzservice/internal/repository"
+	"github.com/jmoiron/sqlx"
+)
+
+// Имена компонентов в /readyz и в сервисе grpc.health.v1.
+const (
+	componentDatabase   = "database"
+	componentMigrations = "migrations"
+	componentGRPC       = "grpc"
+	componentGRPCClient = "grpc_client"
+)
+
+// newReadiness собирает проверки готовности: база отвечает, схема доведена до
+// последней миграции и gRPC сервер принимает соединения.
+func newReadiness(db *sqlx.DB, expectedMigration int64, timeout time.Duration) *health.Checker {
+	checker := health.NewChecker(timeout)
+	checker.Register(componentDatabase, db.PingContext)
+	checker.Register(componentMigrations, func(ctx context.Context) error {
+		version, err := repository.MigrationVersion(ctx, db)
+		if err != nil {
+			return err
+		}
+		if version != expectedMigration {
+			return fmt.Errorf("версия схемы %d, ожидается %d", version, expectedMigration)
+		}
+		return nil
+	})
+	checker.Register(componentGRPC, checkGRPCServing)
+	return checker
+}
@@ -8,68 +8,145 @@ import (
 	"syscall"
 	"time"
 
+	pb "github.com/bllooop/pvzservice/grpcpvz"
+	"github.com/bllooop/pvzservice/internal/config"
 	handlers "github.com/bllooop/pvzservice/internal/delivery/api"
+	"github.com/bllooop/pvzservice/internal/notifier"
+	"github.com/bllooop/pvzservice/internal/ratelimit"
 	"github.com/bllooop/pvzservice/internal/repository"
 	"github.com/bllooop/pvzservice/internal/usecase"
 	logger "github.com/bllooop/pvzservice/pkg/logging"
-	"github.com/joho/godotenv"
-	"github.com/spf13/viper"
+	"github.com/bllooop/pvzservice/pkg/tracing"
+	"github.com/bllooop/pvzservice/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	grpchealth "google.golang.org/grpc/health"
 )
 
 func Run() {
 	logger.Log.Debug().Msg("Инициализация сервера...")
 
-	if err := initConfig(); err != nil {
+	cfg, err := config.Load("")
+	if err != nil {
 		logger.Log.Error().Err(err).Msg("")
 		logger.Log.Fatal().Msg("Возникла ошибка загрузки конфига")
 	}
-	if err := godotenv.Load(); err != nil {
+	if err := logger.Configure(cfg.Logging); err != nil {
 		logger.Log.Error().Err(err).Msg("")
-		logger.Log.Fatal().Msg("Возникла ошибка с env")
-	}
-	logger.Log.Debug().Msg("Переменные окружения успешно загружены")
-	dbpool, err := repository.NewPostgresDB(repository.Config{
-		Host:     viper.GetString("db.host"),
-		Port:     viper.GetString("db.port"),
-		Username: viper.GetString("db.username"),
-		Password: os.Getenv("DB_PASSWORD"),
-		DBname:   viper.GetString("db.dbname"),
-		SSLMode:  viper.GetString("db.sslmode"),
-	})
+		logger.Log.Fatal().Msg("Возникла ошибка настройки логирования")
+	}
+	logger.Log.Debug().Msg("Конфигурация успешно загружена")
+	shutdownTracing, err := tracing.Init(context.Background(), cfg.Tracing)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("")
+		logger.Log.Fatal().Msg("Возникла ошибка настройки трассировки")
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			logger.Log.Error().Err(err).Msg("Ошибка выгрузки спанов трассировки")
+		}
+	}()
+	dbpool, err := repository.NewPostgresDB(cfg.DB.Config)
 	if err != nil {
 		logger.Log.Error().Err(err).Msg("Не удалось установить соединение с базой данных")
 		logger.Log.Fatal().Msg("Произошла ошибка с базой данных")
 	}
 	logger.Log.Debug().Msg("База данных успешно подключена")
 
-	migratePath := "./migrations"
+	migratePath := cfg.DB.MigrationsPath
 	logger.Log.Debug().Msgf("Running database migrations from path: %s", migratePath)
-	if err = repository.RunMigrate(repository.Config{
-		Host:     viper.GetString("db.host"),
-		Port:     viper.GetString("db.port"),
-		Username: viper.GetString("db.username"),
-		Password: os.Getenv("DB_PASSWORD"),
-		DBname:   viper.GetString("db.dbname"),
-		SSLMode:  viper.GetString("db.sslmode"),
-	}, migratePath); err != nil {
+	if err = repository.RunMigrate(cfg.DB.Config, migratePath); err != nil {
 		logger.Log.Error().Err(err).Msg("")
 		logger.Log.Fatal().Msg("Возникла ошибка при переносе")
 	}
+	expectedMigration, err := repository.LatestMigration(migratePath)
 	if err != nil {
 		logger.Log.Error().Err(err).Msg("")
-		logger.Log.Fatal().Msg("Произошла ошибка с базой данных")
+		logger.Log.Fatal().Msg("Не удалось определить версию последней миграции")
 	}
+	schedCtx, stopScheduler := context.WithCancel(context.Background())
+	defer stopScheduler()
 	logger.Log.Debug().Msg("Инициализация слоя репозитория")
-	repos := repository.NewRepository(dbpool)
+	repos := repository.NewRepository(dbpool, cfg.DB.Timeouts)
+	if cfg.Auth.AttemptsStore == "memory" {
+		// Счетчик без ошибок дольше окна уже не влияет на задержку; в том же
+		// хранилище считаются и запросы сброса пароля.
+		retention := max(cfg.Auth.LoginPolicy.FailureWindow, cfg.PasswordReset.ResetThrottle.Window)
+		repos.LoginAttempts = repository.NewLoginAttemptsMemory(retention)
+	}
+	if cfg.DB.Replica.DSN != "" {
+		replicaDB, err := repository.NewReplicaDB(cfg.DB.Replica)
+		if err != nil {
+			logger.Log.Error().Err(err).Msg("Реплика отключена, все чтения идут в основную базу")
+		} else {
+			defer replicaDB.Close()
+			router := repository.NewReplicaRouter(dbpool, replicaDB, cfg.DB.Replica)
+			go router.Watch(schedCtx)
+			repos = repository.WithReplica(repos, repository.NewRepository(replicaDB, cfg.DB.Timeouts), router)
+			prometheus.Registry.MustRegister(collectors.NewDBStatsCollector(replicaDB.DB, cfg.DB.DBname+"_replica"))
+		}
+	}
+	if cfg.Cache.Enabled {
+		repos = repository.WithPvzCache(repos, repository.NewLRUCache(cfg.Cache.Size), cfg.Cache.TTL)
+	}
 	logger.Log.Debug().Msg("Инициализация usecase слоя")
-	usecases := usecase.NewUsecase(repos)
+	usecases := usecase.NewUsecase(repos, usecaseConfig(cfg))
+	prometheus.Registry.MustRegister(
+		collectors.NewDBStatsCollector(dbpool.DB, cfg.DB.DBname),
+		prometheus.NewStockCollector(usecases.GetPvzStock, cfg.Metrics.StockTimeout),
+	)
+	go purgeIdempotencyKeys(schedCtx, usecases, cfg.Idempotency.CleanupInterval)
+	if cfg.ReceptionAutoClose.Enabled {
+		go runAutoClose(schedCtx, usecases, cfg.ReceptionAutoClose.Interval)
+	}
+	go runPvzArchive(schedCtx, usecases, cfg.PvzLifecycle.ArchiveInterval)
+	// Обработчик изменений вызывается из одной горутины viper, поэтому
+	// current не требует синхронизации.
+	current := cfg
+	limiter := ratelimit.NewGuard(ratelimit.NewMemory(), cfg.RateLimit)
+	if err := config.Watch("", func(next *config.Config) {
+		current = reloadConfig(current, next, usecases, limiter)
+	}); err != nil {
+		logger.Log.Error().Err(err).Msg("Изменения файла конфигурации не отслеживаются")
+	}
 	logger.Log.Debug().Msg("Инициализация обработчиков API")
-	handler := handlers.NewHandler(usecases)
+	env := cfg.Env
+	logger.Log.Info().Msgf("Профиль окружения: %s", env)
+	handler := handlers.NewHandler(usecases, handlers.Config{
+		Env:            env,
+		CORSOrigins:    cfg.HTTP.CORSOrigins,
+		TrustedProxies: cfg.HTTP.TrustedProxies,
+		MaxPageLimit:   cfg.Pagination.MaxLimit,
+		RateLimit:      limiter,
+	})
+	readiness := newReadiness(dbpool, expectedMigration, cfg.Health.Timeout)
+	handler.Probes = handlers.Probes{
+		Readiness: readiness,
+		MigrationVersion: func(ctx context.Context) (int64, error) {
+			return repository.MigrationVersion(ctx, dbpool)
+		},
+		ExpectedMigration: expectedMigration,
+	}
+	if cfg.GRPCProbe.Enabled {
+		address := cfg.GRPCProbe.Address
+		if address == "" {
+			address = "localhost" + cfg.PortGrpc
+		}
+		probeClient, probe, err := newGRPCProbe(address, usecases.Authorization)
+		if err != nil {
+			logger.Log.Error().Err(err).Msg("Проверка вызова gRPC отключена")
+		} else {
+			defer probeClient.Close()
+			readiness.Register(componentGRPCClient, probe)
+		}
+	}
+	healthSrv := grpchealth.NewServer()
 	srv := new(Server)
 	//http serv
 	go func() {
 		logger.Log.Info().Msg("Запуск сервера...")
-		if err := srv.StartHTTP(viper.GetString("port"), handler.InitRoutes()); err != nil && err != http.ErrServerClosed {
+		if err := srv.StartHTTP(cfg.Port, cfg.HTTP, handler.InitRoutes()); err != nil && err != http.ErrServerClosed {
 			logger.Log.Error().Err(err).Msg("")
 			logger.Log.Fatal().Msg("При запуске HTTP сервера произошла ошибка")
 		} else {
@@ -78,38 +155,19 @@ func Run() {
 	}()
 	//grpc serv
 	logger.Log.Info().Msg("Запуск сервера gRPC...")
-	grpcServer := StartGRPC(viper.GetString("portGrpc"), usecases)
+	grpcServer := StartGRPC(cfg.PortGrpc, usecases, env, healthSrv, limiter)
+	go readiness.Watch(schedCtx, healthSrv, cfg.Health.Interval, pb.PVZService_ServiceDesc.ServiceName)
 	logger.Log.Info().Msg("Сервер HTTP и gRPC работает")
-	go func() {
-		logger.Log.Info().Msg("Попытка подключения к клиенту GRPC")
-		err := CallGRPCClient()
-		if err != nil {
-			logger.Log.Error().Err(err).Msg("Ошибка подключения к клиенту gRPC")
-		}
-
-		ticker := time.NewTicker(1 * time.Minute)
-		defer ticker.Stop()
-
-		for {
-			select {
-			case <-ticker.C:
-				logger.Log.Info().Msg("Вызов клиента gRPC")
-				err := CallGRPCClient()
-				if err != nil {
-					logger.Log.Error().Err(err).Msg("Вызов gRPC закончился с ошибкой")
-					logger.Log.Fatal().Msg("Ошибка подключения")
-				} else {
-					logger.Log.Info().Msg("Успешно вызван клиент gRPC")
-				}
-			}
-		}
-	}()
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGTERM, syscall.SIGINT)
 	logger.Log.Debug().Msg("Прослушивание сигналов завершения работы ОС")
 	<-quit
 	logger.Log.Info().Msg("Сервер отключается")
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	// Пробы переводятся в NOT_SERVING до остановки, чтобы балансировщик
+	// перестал направлять запросы.
+	healthSrv.Shutdown()
+	stopScheduler()
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.HTTP.ShutdownTimeout)
 	defer cancel()
 	defer dbpool.Close()
 	logger.Log.Debug().Msg("Закрытие соединения с базой данных ")
@@ -121,8 +179,124 @@ func Run() {
 	logger.Log.Info().Msg("gRPC сервер отключен")
 }
 
-func initConfig() error {
-	viper.AddConfigPath("./config")
-	viper.SetConfigName("config")
-	return viper.ReadInConfig()
+// reloadConfig применяет к работающему сервису поля next, которые меняются
+// без перезапуска, и возвращает действующую конфигурацию.
+func reloadConfig(current, next *config.Config, usecases *usecase.Usecase, limiter *ratelimit.Guard) *config.Config {
+	updated, restartRequired := current.Reload(next)
+	if err := logger.SetLevel(updated.Logging.Level); err != nil {
+		logger.Log.Error().Err(err).Msg("Не удалось изменить уровень логирования")
+	}
+	usecases.Reload(usecase.Config{Login: updated.Auth.LoginPolicy})
+	limiter.SetPolicy(updated.RateLimit)
+	logger.Log.Info().Str("level", updated.Logging.Level).Msg("Конфигурация обновлена")
+	if restartRequired {
+		logger.Log.Warn().Msg("Часть изменений конфигурации вступит в силу только после перезапуска")
+	}
+	return updated
+}
+
+// purgeIdempotencyKeys периодически удаляет истекшие ключи идемпотентности до
+// отмены ctx.
+func purgeIdempotencyKeys(ctx context.Context, usecases *usecase.Usecase, interval time.Duration) {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := usecases.PurgeExpiredIdempotency(ctx)
+			if err != nil {
+				logger.Log.Error().Err(err).Msg("Ошибка удаления истекших ключей идемпотентности")
+				continue
+			}
+			logger.Log.Debug().Int64("deleted", deleted).Msg("Удалены истекшие ключи идемпотентности")
+		}
+	}
+}
+
+// runAutoClose периодически закрывает забытые приемки до отмены ctx.
+// Одновременный запуск на нескольких репликах безопасен: проход выполняет
+// только та, что получила advisory-блокировку в базе.
+func runAutoClose(ctx context.Context, usecases *usecase.Usecase, interval time.Duration) {
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Log.Debug().Msg("Планировщик автозакрытия приемок остановлен")
+			return
+		case <-ticker.C:
+			result, err := usecases.AutoCloseReceptions(ctx)
+			if err != nil {
+				logger.Log.Error().Err(err).Msg("Ошибка автозакрытия приемок")
+				continue
+			}
+			if result.Skipped {
+				logger.Log.Debug().Msg("Автозакрытие приемок выполняет другой экземпляр")
+				continue
+			}
+			prometheus.ReceptionAutoCloseTotal.WithLabelValues("close").Add(float64(len(result.Closed)))
+			prometheus.ReceptionAutoCloseTotal.WithLabelValues("cancel").Add(float64(len(result.Cancelled)))
+			prometheus.ReceptionAutoCloseTotal.WithLabelValues("flag").Add(float64(len(result.Flagged)))
+			logger.Log.Info().Int("closed", len(result.Closed)).Int("cancelled", len(result.Cancelled)).
+				Int("flagged", len(result.Flagged)).Msg("Выполнено автозакрытие приемок")
+		}
+	}
+}
+
+// runPvzArchive периодически архивирует ПВЗ, вывод из работы которых вступил
+// в силу, до отмены ctx. Несколько реплик не архивируют один ПВЗ дважды:
+// проход пропускает ПВЗ, заблокированные другой транзакцией.
+func runPvzArchive(ctx context.Context, usecases *usecase.Usecase, interval time.Duration) {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Log.Debug().Msg("Планировщик архивирования ПВЗ остановлен")
+			return
+		case <-ticker.C:
+			archived, err := usecases.ArchiveDecommissionedPvz(ctx)
+			if err != nil {
+				logger.Log.Error().Err(err).Msg("Ошибка архивирования выведенных из работы ПВЗ")
+				continue
+			}
+			if len(archived) > 0 {
+				logger.Log.Info().Int("archived", len(archived)).Msg("Архивированы выведенные из работы ПВЗ")
+			}
+		}
+	}
+}
+
+func usecaseConfig(cfg *config.Config) usecase.Config {
+	return usecase.Config{
+		Login:          cfg.Auth.LoginPolicy,
+		Password:       cfg.Password,
+		TokenTTL:       cfg.Auth.TokenTTL,
+		ResetTokenTTL:  cfg.PasswordReset.TokenTTL,
+		ResetThrottle:  cfg.PasswordReset.ResetThrottle,
+		Notifier:       notifierFromConfig(cfg.PasswordReset),
+		IdempotencyTTL: cfg.Idempotency.TTL,
+		AutoClose:      cfg.ReceptionAutoClose.AutoClosePolicy,
+		PvzLimitMode:   cfg.PvzLimits.Mode,
+	}
+}
+
+func notifierFromConfig(cfg config.PasswordReset) notifier.Notifier {
+	switch cfg.Notifier {
+	case "file":
+		return notifier.NewFileNotifier(cfg.File)
+	default:
+		return notifier.NewLogNotifier()
+	}
 }
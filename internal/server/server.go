@@ -3,20 +3,21 @@ package server
 import (
 	"context"
 	"net/http"
-	"time"
+
+	"github.com/bllooop/pvzservice/internal/config"
 )
 
 type Server struct {
 	httpServer *http.Server
 }
 
-func (s *Server) StartHTTP(port string, handler http.Handler) error {
+func (s *Server) StartHTTP(port string, cfg config.HTTP, handler http.Handler) error {
 	s.httpServer = &http.Server{
 		Addr:         ":" + port,
 		Handler:      handler,
-		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 10 * time.Second,
-		IdleTimeout:  60 * time.Second,
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+		IdleTimeout:  cfg.IdleTimeout,
 	}
 	return s.httpServer.ListenAndServe()
 }
@@ -0,0 +1,75 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bllooop/pvzservice/internal/domain"
+	"github.com/bllooop/pvzservice/internal/repository"
+	"github.com/google/uuid"
+)
+
+var ErrInvalidAmendment = errors.New("некорректная заявка на изменение")
+
+type AmendmentUsecase struct {
+	repo repository.Amendments
+}
+
+func NewAmendmentUsecase(repo *repository.Repository) *AmendmentUsecase {
+	return &AmendmentUsecase{
+		repo: repo,
+	}
+}
+
+func (s *AmendmentUsecase) RequestAmendment(ctx context.Context, scope domain.TenantScope, receptionId uuid.UUID, actorId string, input domain.AmendmentRequest) (domain.ReceptionAmendment, error) {
+	if err := validateAmendmentItems(input.Items); err != nil {
+		return domain.ReceptionAmendment{}, err
+	}
+	return s.repo.CreateAmendment(ctx, scope, domain.ReceptionAmendment{
+		ReceptionId: receptionId,
+		Reason:      input.Reason,
+		Items:       input.Items,
+		RequestedBy: actorId,
+	})
+}
+
+func (s *AmendmentUsecase) GetAmendments(ctx context.Context, scope domain.TenantScope, receptionId uuid.UUID) ([]domain.ReceptionAmendment, error) {
+	return s.repo.GetAmendments(ctx, scope, receptionId)
+}
+
+func (s *AmendmentUsecase) ReviewAmendment(ctx context.Context, scope domain.TenantScope, id uuid.UUID, reviewer string, approve bool, comment string) (domain.ReceptionAmendment, error) {
+	if approve {
+		return s.repo.ApplyAmendment(ctx, scope, id, reviewer, comment)
+	}
+	return s.repo.RejectAmendment(ctx, scope, id, reviewer, comment)
+}
+
+func (s *AmendmentUsecase) GetReceptionHistory(ctx context.Context, scope domain.TenantScope, receptionId uuid.UUID) ([]domain.ReceptionVersion, error) {
+	return s.repo.GetReceptionHistory(ctx, scope, receptionId)
+}
+
+// validateAmendmentItems проверяет, что у добавляемых товаров указан тип, а у
+// удаляемых — id, и что один товар не удаляется дважды.
+func validateAmendmentItems(items []domain.AmendmentItem) error {
+	removed := make(map[uuid.UUID]struct{})
+	for i, item := range items {
+		switch item.Action {
+		case domain.CorrectionAdd:
+			if item.Type == "" || item.ProductId != nil {
+				return fmt.Errorf("%w: позиция %d должна содержать только тип товара", ErrInvalidAmendment, i+1)
+			}
+		case domain.CorrectionRemove:
+			if item.ProductId == nil || item.Type != "" {
+				return fmt.Errorf("%w: позиция %d должна содержать только id товара", ErrInvalidAmendment, i+1)
+			}
+			if _, ok := removed[*item.ProductId]; ok {
+				return fmt.Errorf("%w: товар %s указан несколько раз", ErrInvalidAmendment, item.ProductId)
+			}
+			removed[*item.ProductId] = struct{}{}
+		default:
+			return fmt.Errorf("%w: неизвестное действие %q", ErrInvalidAmendment, item.Action)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package usecase
+
+import (
+	"context"
+	"github.com/bllooop/pvzservice/internal/domain"
+	"github.com/bllooop/pvzservice/internal/repository"
+)
+
+type AuditUsecase struct {
+	repo repository.Audit
+}
+
+func NewAuditUsecase(repo *repository.Repository) *AuditUsecase {
+	return &AuditUsecase{
+		repo: repo,
+	}
+}
+
+func (s *AuditUsecase) RecordAudit(ctx context.Context, entry domain.AuditEntry) error {
+	_, err := s.repo.AppendAudit(ctx, entry)
+	return err
+}
+
+func (s *AuditUsecase) GetAudit(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditEntry, error) {
+	return s.repo.GetAudit(ctx, filter)
+}
+
+func (s *AuditUsecase) VerifyAuditChain(ctx context.Context) (int, error) {
+	return s.repo.VerifyAuditChain(ctx)
+}
@@ -1,6 +1,7 @@
 package usecase
 
 import (
+	"context"
 	"errors"
 	"time"
 
@@ -12,58 +13,106 @@ import (
 )
 
 type AuthUsecase struct {
-	repo repository.Authorization
+	repo     repository.Authorization
+	resets   repository.PasswordReset
+	policy   PasswordPolicy
+	tokenTTL time.Duration
 }
 
-func NewAuthUsecase(repo *repository.Repository) *AuthUsecase {
+// NewAuthUsecase создает usecase авторизации. Токены действуют tokenTTL, при
+// нулевом значении — DefaultTokenTTL.
+func NewAuthUsecase(repo *repository.Repository, policy PasswordPolicy, tokenTTL time.Duration) *AuthUsecase {
+	if tokenTTL <= 0 {
+		tokenTTL = DefaultTokenTTL
+	}
 	return &AuthUsecase{
-		repo: repo,
+		repo:     repo,
+		resets:   repo,
+		policy:   policy,
+		tokenTTL: tokenTTL,
 	}
 }
 
 const (
 	salt       = "hjqrhjqw124617ajfhajs"
 	signingKey = "qrkjk#4#%35FSFJlja#4353KSFjH"
-	tokenTTL   = 12 * time.Hour
+)
+
+const DefaultTokenTTL = 12 * time.Hour
+
+var (
+	ErrUserDisabled       = errors.New("пользователь заблокирован")
+	ErrInvalidCredentials = errors.New("неккоретные данные")
+	ErrTokenRevoked       = errors.New("токен отозван, авторизуйтесь заново")
+	ErrTokenOutdated      = errors.New("токен выпущен устаревшей версией сервиса, авторизуйтесь заново")
 )
 
 type tokenClaims struct {
 	jwt.StandardClaims
 	UserRole int    `json:"user_role"`
 	UserId   string `json:"user_id"`
+	TenantId string `json:"tenant_id,omitempty"`
+	// Dummy записывается во все токены: токен без этого признака выпущен
+	// до его появления, и по нему нельзя отличить тестовый токен от обычного.
+	Dummy *bool `json:"dummy"`
 }
 
-func (s *AuthUsecase) CreateUser(user domain.User) (domain.User, error) {
+func (s *AuthUsecase) CreateUser(ctx context.Context, user domain.User) (domain.User, error) {
+	if err := s.policy.Validate(user.Password); err != nil {
+		return domain.User{}, err
+	}
 	var err error
+	if user.TenantId == "" {
+		user.TenantId = domain.DefaultTenant
+	}
 	user.Password, err = HashPassword(user.Password)
 	if err != nil {
 		return domain.User{}, err
 	}
-	return s.repo.CreateUser(user)
+	return s.repo.CreateUser(ctx, user)
 }
-func (s *AuthUsecase) SignUser(email, password string) (domain.User, error) {
-	user, err := s.repo.SignUser(email)
+func (s *AuthUsecase) SignUser(ctx context.Context, tenantId, email, password string) (domain.User, error) {
+	if tenantId == "" {
+		tenantId = domain.DefaultTenant
+	}
+	user, err := s.repo.SignUser(ctx, tenantId, email)
 	if err != nil {
 		return domain.User{}, err
 	}
 	if !verifyPassword(user.Password, password) {
-		return domain.User{}, errors.New("неккоретные данные")
+		return domain.User{}, ErrInvalidCredentials
+	}
+	if user.DisabledAt != nil {
+		return domain.User{}, ErrUserDisabled
+	}
+	if err := s.repo.UpdateLastLogin(ctx, user.Id); err != nil {
+		return domain.User{}, err
 	}
 	return user, nil
 }
-func (s *AuthUsecase) GenerateToken(userId uuid.UUID, userRole int) (string, error) {
+func (s *AuthUsecase) GenerateToken(userId uuid.UUID, userRole int, tenantId string) (string, error) {
+	return s.signToken(userId, userRole, tenantId, false)
+}
+
+func (s *AuthUsecase) GenerateDummyToken(userId uuid.UUID, userRole int, tenantId string) (string, error) {
+	return s.signToken(userId, userRole, tenantId, true)
+}
+
+func (s *AuthUsecase) signToken(userId uuid.UUID, userRole int, tenantId string, dummy bool) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, &tokenClaims{
 		jwt.StandardClaims{
-			ExpiresAt: time.Now().Add(tokenTTL).Unix(),
+			ExpiresAt: time.Now().Add(s.tokenTTL).Unix(),
 			IssuedAt:  time.Now().Unix(),
 		},
 		userRole,
 		userId.String(),
+		tenantId,
+		&dummy,
 	})
 	return token.SignedString([]byte(signingKey))
 }
 
-func (s *AuthUsecase) ParseToken(accessToken string) (string, int, error) {
+func (s *AuthUsecase) ParseToken(accessToken string) (domain.TokenClaims, error) {
 	token, err := jwt.ParseWithClaims(accessToken, &tokenClaims{}, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, errors.New("некорретный signing method")
@@ -72,15 +121,91 @@ func (s *AuthUsecase) ParseToken(accessToken string) (string, int, error) {
 		return []byte(signingKey), nil
 	})
 	if err != nil {
-		return "", 0, err
+		return domain.TokenClaims{}, err
 	}
 
 	claims, ok := token.Claims.(*tokenClaims)
 	if !ok {
-		return "", 0, errors.New("token claims не типа *tokenClaims")
+		return domain.TokenClaims{}, errors.New("token claims не типа *tokenClaims")
+	}
+	if claims.Dummy == nil {
+		return domain.TokenClaims{}, ErrTokenOutdated
+	}
+
+	// Токены, выданные до появления нескольких компаний, относятся к компании по умолчанию.
+	tenantId := claims.TenantId
+	if tenantId == "" {
+		tenantId = domain.DefaultTenant
+	}
+	return domain.TokenClaims{
+		UserId:   claims.UserId,
+		UserRole: claims.UserRole,
+		TenantId: tenantId,
+		IssuedAt: time.Unix(claims.IssuedAt, 0),
+		Dummy:    *claims.Dummy,
+	}, nil
+}
+
+func (s *AuthUsecase) CheckUserActive(ctx context.Context, claims domain.TokenClaims) error {
+	// У тестовых токенов нет учетной записи; в окружении prod они отклоняются
+	// до этой проверки.
+	if claims.Dummy {
+		return nil
 	}
+	id, err := uuid.Parse(claims.UserId)
+	if err != nil {
+		return err
+	}
+	status, err := s.repo.GetUserStatus(ctx, id)
+	if err != nil {
+		return err
+	}
+	if status.Disabled {
+		return ErrUserDisabled
+	}
+	if changedAfter(status.PasswordChangedAt, claims.IssuedAt) || changedAfter(status.RoleChangedAt, claims.IssuedAt) {
+		return ErrTokenRevoked
+	}
+	return nil
+}
+
+// changedAfter сообщает, что учетная запись изменилась после выпуска токена.
+// Время выпуска в токене хранится с точностью до секунды, поэтому токен,
+// выпущенный в ту же секунду, что и изменение, тоже считается устаревшим:
+// сессия, выполнившая изменение, должна авторизоваться заново.
+func changedAfter(changedAt *time.Time, issuedAt time.Time) bool {
+	return changedAt != nil && !changedAt.Truncate(time.Second).Before(issuedAt.Truncate(time.Second))
+}
+
+func (s *AuthUsecase) GetUsers(ctx context.Context, scope domain.TenantScope, input domain.GettingUsersParams) ([]domain.UserInfo, error) {
+	return s.repo.GetUsers(ctx, scope, input)
+}
+
+func (s *AuthUsecase) UpdateUser(ctx context.Context, scope domain.TenantScope, userId uuid.UUID, input domain.UpdateUserInput) (domain.UserInfo, error) {
+	if input.Password != nil {
+		if err := s.policy.Validate(*input.Password); err != nil {
+			return domain.UserInfo{}, err
+		}
+		hashed, err := HashPassword(*input.Password)
+		if err != nil {
+			return domain.UserInfo{}, err
+		}
+		input.Password = &hashed
+	}
+	user, err := s.repo.UpdateUser(ctx, scope, userId, input)
+	if err != nil {
+		return domain.UserInfo{}, err
+	}
+	if input.Password != nil {
+		if err := s.resets.InvalidateResetTokens(ctx, userId); err != nil {
+			return domain.UserInfo{}, err
+		}
+	}
+	return user, nil
+}
 
-	return claims.UserId, claims.UserRole, nil
+func (s *AuthUsecase) DisableUser(ctx context.Context, scope domain.TenantScope, userId uuid.UUID) (domain.UserInfo, error) {
+	return s.repo.DisableUser(ctx, scope, userId)
 }
 
 func HashPassword(password string) (string, error) {
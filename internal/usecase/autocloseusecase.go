@@ -0,0 +1,52 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/bllooop/pvzservice/internal/domain"
+	"github.com/bllooop/pvzservice/internal/repository"
+)
+
+// SystemActor — автор изменений, которые выполняет сам сервис, а не пользователь.
+const SystemActor = "system"
+
+var DefaultAutoClosePolicy = domain.AutoClosePolicy{
+	IdleAfter:   12 * time.Hour,
+	EmptyAction: domain.EmptyReceptionFlag,
+}
+
+type AutoCloseUsecase struct {
+	repo   repository.ReceptionAutoClose
+	policy domain.AutoClosePolicy
+	now    func() time.Time
+}
+
+func NewAutoCloseUsecase(repo *repository.Repository, policy domain.AutoClosePolicy) *AutoCloseUsecase {
+	if policy.IdleAfter <= 0 {
+		policy.IdleAfter = DefaultAutoClosePolicy.IdleAfter
+	}
+	if policy.EmptyAction != domain.EmptyReceptionCancel {
+		policy.EmptyAction = domain.EmptyReceptionFlag
+	}
+	return &AutoCloseUsecase{
+		repo:   repo,
+		policy: policy,
+		now:    time.Now,
+	}
+}
+
+// AutoCloseReceptions закрывает приемки с товарами, простаивающие дольше
+// IdleAfter, а пустые помечает или отменяет в зависимости от политики.
+// Каждое действие записывается в журнал аудита от имени системы в той же
+// транзакции.
+func (s *AutoCloseUsecase) AutoCloseReceptions(ctx context.Context) (domain.AutoCloseResult, error) {
+	now := s.now()
+	ctx = repository.WithAudit(ctx, domain.AuditEntry{
+		CreatedAt:  now,
+		ActorId:    SystemActor,
+		ActorRole:  SystemActor,
+		EntityType: "reception",
+	})
+	return s.repo.AutoCloseReceptions(ctx, s.policy, now)
+}
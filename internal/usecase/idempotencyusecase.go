@@ -0,0 +1,75 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/bllooop/pvzservice/internal/domain"
+	"github.com/bllooop/pvzservice/internal/repository"
+)
+
+const DefaultIdempotencyTTL = 24 * time.Hour
+
+var (
+	ErrIdempotencyKeyReused  = errors.New("ключ идемпотентности уже использован для другого запроса")
+	ErrIdempotencyInProgress = errors.New("запрос с этим ключом идемпотентности еще выполняется")
+)
+
+type IdempotencyUsecase struct {
+	repo repository.Idempotency
+	ttl  time.Duration
+	now  func() time.Time
+}
+
+func NewIdempotencyUsecase(repo *repository.Repository, ttl time.Duration) *IdempotencyUsecase {
+	if ttl <= 0 {
+		ttl = DefaultIdempotencyTTL
+	}
+	return &IdempotencyUsecase{
+		repo: repo,
+		ttl:  ttl,
+		now:  time.Now,
+	}
+}
+
+// BeginIdempotent резервирует ключ за запросом. Если по ключу уже сохранен
+// ответ на тот же запрос, он возвращается для повторной отправки; nil означает,
+// что запрос нужно выполнить и затем вызвать CompleteIdempotent.
+func (s *IdempotencyUsecase) BeginIdempotent(ctx context.Context, scope, key, requestHash string) (*domain.IdempotencyRecord, error) {
+	now := s.now()
+	record, reserved, err := s.repo.ReserveIdempotencyKey(ctx, domain.IdempotencyRecord{
+		Scope:       scope,
+		Key:         key,
+		RequestHash: requestHash,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(s.ttl),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if reserved {
+		return nil, nil
+	}
+	if record.RequestHash != requestHash {
+		return nil, ErrIdempotencyKeyReused
+	}
+	if record.StatusCode == 0 {
+		return nil, ErrIdempotencyInProgress
+	}
+	return &record, nil
+}
+
+func (s *IdempotencyUsecase) CompleteIdempotent(ctx context.Context, scope, key string, statusCode int, response []byte) error {
+	return s.repo.CompleteIdempotencyKey(ctx, scope, key, statusCode, response)
+}
+
+// ReleaseIdempotent снимает резерв с ключа, если запрос не удалось выполнить,
+// чтобы клиент мог повторить его с тем же ключом.
+func (s *IdempotencyUsecase) ReleaseIdempotent(ctx context.Context, scope, key string) error {
+	return s.repo.ReleaseIdempotencyKey(ctx, scope, key)
+}
+
+func (s *IdempotencyUsecase) PurgeExpiredIdempotency(ctx context.Context) (int64, error) {
+	return s.repo.DeleteExpiredIdempotencyKeys(ctx, s.now())
+}
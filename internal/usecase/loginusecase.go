@@ -0,0 +1,202 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bllooop/pvzservice/internal/domain"
+	"github.com/bllooop/pvzservice/internal/repository"
+)
+
+// LoginPolicy задает правила защиты входа от перебора паролей.
+type LoginPolicy struct {
+	// MaxFailures - число неудачных попыток для одной почты, после которого вход блокируется.
+	MaxFailures int
+	// MaxFailuresPerIP - то же для одного IP-адреса клиента.
+	MaxFailuresPerIP int
+	// FreeAttempts - число неудачных попыток без задержки.
+	FreeAttempts int
+	// BaseDelay удваивается с каждой неудачной попыткой сверх FreeAttempts, но не превышает MaxDelay.
+	BaseDelay    time.Duration
+	MaxDelay     time.Duration
+	LockDuration time.Duration
+	// FailureWindow - после такого времени без ошибок счетчик начинается заново.
+	FailureWindow time.Duration
+}
+
+var DefaultLoginPolicy = LoginPolicy{
+	MaxFailures:      5,
+	MaxFailuresPerIP: 20,
+	FreeAttempts:     3,
+	BaseDelay:        time.Second,
+	MaxDelay:         30 * time.Second,
+	LockDuration:     15 * time.Minute,
+	FailureWindow:    15 * time.Minute,
+}
+
+type LoginBlockedError struct {
+	RetryAfter time.Duration
+	Locked     bool
+}
+
+func (e *LoginBlockedError) Error() string {
+	if e.Locked {
+		return fmt.Sprintf("вход временно заблокирован, повторите через %s", e.RetryAfter.Round(time.Second))
+	}
+	return fmt.Sprintf("слишком частые попытки входа, повторите через %s", e.RetryAfter.Round(time.Second))
+}
+
+type LoginUsecase struct {
+	repo   repository.LoginAttempts
+	mu     sync.RWMutex
+	policy LoginPolicy
+	now    func() time.Time
+}
+
+func NewLoginUsecase(repo *repository.Repository, policy LoginPolicy) *LoginUsecase {
+	return &LoginUsecase{
+		repo:   repo,
+		policy: policy,
+		now:    time.Now,
+	}
+}
+
+// SetPolicy заменяет правила защиты входа без перезапуска сервиса. Уже
+// записанные неудачные попытки и блокировки сохраняются.
+func (s *LoginUsecase) SetPolicy(policy LoginPolicy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.policy = policy
+}
+
+func (s *LoginUsecase) currentPolicy() LoginPolicy {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.policy
+}
+
+// emailKey учитывает компанию: одна и та же почта в разных компаниях
+// принадлежит разным пользователям и блокируется независимо.
+func emailKey(tenantId, email string) string {
+	if tenantId == "" {
+		tenantId = domain.DefaultTenant
+	}
+	return "email:" + tenantId + ":" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+// CheckLogin проверяет, разрешена ли попытка входа, и сразу учитывает ее как
+// неудачную. Проверка и увеличение счетчика выполняются для каждого ключа
+// атомарно, поэтому параллельные запросы не обходят задержку и блокировку.
+// Счетчик IP-адреса проверяется первым, чтобы попытки с заблокированного
+// адреса не увеличивали счетчик чужой почты.
+func (s *LoginUsecase) CheckLogin(ctx context.Context, tenantId, email, ip string) error {
+	now := s.now()
+	policy := s.currentPolicy()
+	windowStart := now.Add(-policy.FailureWindow)
+	limits := []struct {
+		key   string
+		limit int
+	}{
+		{key: ipKey(ip), limit: policy.MaxFailuresPerIP},
+		{key: emailKey(tenantId, email), limit: policy.MaxFailures},
+	}
+	for _, l := range limits {
+		_, err := s.repo.UpdateLoginAttempt(ctx, l.key, func(attempt domain.LoginAttempt) (domain.LoginAttempt, error) {
+			if blocked := blockedUntil(attempt, now, policy); blocked != nil {
+				return attempt, blocked
+			}
+			if attempt.LastFailureAt != nil && attempt.LastFailureAt.Before(windowStart) {
+				attempt.Failures = 0
+			}
+			attempt.Failures++
+			attempt.LastFailureAt = &now
+			if l.limit > 0 && attempt.Failures >= l.limit {
+				lockedUntil := now.Add(policy.LockDuration)
+				attempt.LockedUntil = &lockedUntil
+			}
+			return attempt, nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RegisterLoginSuccess сбрасывает счетчик почты после успешной попытки.
+// Счетчик IP-адреса не сбрасывается, иначе успешные входы в свою учетную
+// запись позволяли бы перебирать пароли чужих почт без блокировки адреса:
+// из него только вычитается учтенная CheckLogin успешная попытка, а прежние
+// ошибки истекают по окну FailureWindow.
+func (s *LoginUsecase) RegisterLoginSuccess(ctx context.Context, tenantId, email, ip string) error {
+	if err := s.repo.ResetLoginAttempts(ctx, emailKey(tenantId, email)); err != nil {
+		return err
+	}
+	limit := s.currentPolicy().MaxFailuresPerIP
+	_, err := s.repo.UpdateLoginAttempt(ctx, ipKey(ip), func(attempt domain.LoginAttempt) (domain.LoginAttempt, error) {
+		if attempt.Failures > 0 {
+			attempt.Failures--
+		}
+		// Пока адрес заблокирован, попытки не принимаются, поэтому блокировка
+		// могла появиться только из-за этой же попытки.
+		if attempt.LockedUntil != nil && (limit <= 0 || attempt.Failures < limit) {
+			attempt.LockedUntil = nil
+		}
+		return attempt, nil
+	})
+	return err
+}
+
+func (s *LoginUsecase) UnlockLogin(ctx context.Context, tenantId, email, ip string) error {
+	if email == "" && ip == "" {
+		return fmt.Errorf("необходимо указать почту или IP-адрес")
+	}
+	if email != "" {
+		if err := s.repo.ResetLoginAttempts(ctx, emailKey(tenantId, email)); err != nil {
+			return err
+		}
+	}
+	if ip != "" {
+		if err := s.repo.ResetLoginAttempts(ctx, ipKey(ip)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// blockedUntil возвращает ошибку, если следующая попытка для attempt еще не
+// разрешена: ключ заблокирован или не истекла задержка после ошибки.
+func blockedUntil(attempt domain.LoginAttempt, now time.Time, policy LoginPolicy) *LoginBlockedError {
+	if attempt.LockedUntil != nil && attempt.LockedUntil.After(now) {
+		return &LoginBlockedError{RetryAfter: attempt.LockedUntil.Sub(now), Locked: true}
+	}
+	if attempt.LastFailureAt == nil {
+		return nil
+	}
+	if next := attempt.LastFailureAt.Add(delay(attempt.Failures, policy)); next.After(now) {
+		return &LoginBlockedError{RetryAfter: next.Sub(now)}
+	}
+	return nil
+}
+
+func delay(failures int, policy LoginPolicy) time.Duration {
+	extra := failures - policy.FreeAttempts
+	if extra <= 0 {
+		return 0
+	}
+	delay := policy.BaseDelay
+	for i := 1; i < extra && delay < policy.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > policy.MaxDelay {
+		delay = policy.MaxDelay
+	}
+	return delay
+}
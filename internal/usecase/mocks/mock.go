@@ -12,6 +12,7 @@ package mocks
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	domain "github.com/bllooop/pvzservice/internal/domain"
 	uuid "github.com/google/uuid"
@@ -42,44 +43,102 @@ func (m *MockAuthorization) EXPECT() *MockAuthorizationMockRecorder {
 	return m.recorder
 }
 
+// CheckUserActive mocks base method.
+func (m *MockAuthorization) CheckUserActive(ctx context.Context, claims domain.TokenClaims) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckUserActive", ctx, claims)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CheckUserActive indicates an expected call of CheckUserActive.
+func (mr *MockAuthorizationMockRecorder) CheckUserActive(ctx, claims any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckUserActive", reflect.TypeOf((*MockAuthorization)(nil).CheckUserActive), ctx, claims)
+}
+
 // CreateUser mocks base method.
-func (m *MockAuthorization) CreateUser(user domain.User) (domain.User, error) {
+func (m *MockAuthorization) CreateUser(ctx context.Context, user domain.User) (domain.User, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateUser", user)
+	ret := m.ctrl.Call(m, "CreateUser", ctx, user)
 	ret0, _ := ret[0].(domain.User)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CreateUser indicates an expected call of CreateUser.
-func (mr *MockAuthorizationMockRecorder) CreateUser(user any) *gomock.Call {
+func (mr *MockAuthorizationMockRecorder) CreateUser(ctx, user any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockAuthorization)(nil).CreateUser), ctx, user)
+}
+
+// DisableUser mocks base method.
+func (m *MockAuthorization) DisableUser(ctx context.Context, scope domain.TenantScope, userId uuid.UUID) (domain.UserInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DisableUser", ctx, scope, userId)
+	ret0, _ := ret[0].(domain.UserInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DisableUser indicates an expected call of DisableUser.
+func (mr *MockAuthorizationMockRecorder) DisableUser(ctx, scope, userId any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockAuthorization)(nil).CreateUser), user)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisableUser", reflect.TypeOf((*MockAuthorization)(nil).DisableUser), ctx, scope, userId)
+}
+
+// GenerateDummyToken mocks base method.
+func (m *MockAuthorization) GenerateDummyToken(userId uuid.UUID, userRole int, tenantId string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GenerateDummyToken", userId, userRole, tenantId)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GenerateDummyToken indicates an expected call of GenerateDummyToken.
+func (mr *MockAuthorizationMockRecorder) GenerateDummyToken(userId, userRole, tenantId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateDummyToken", reflect.TypeOf((*MockAuthorization)(nil).GenerateDummyToken), userId, userRole, tenantId)
 }
 
 // GenerateToken mocks base method.
-func (m *MockAuthorization) GenerateToken(userId uuid.UUID, userRole int) (string, error) {
+func (m *MockAuthorization) GenerateToken(userId uuid.UUID, userRole int, tenantId string) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GenerateToken", userId, userRole)
+	ret := m.ctrl.Call(m, "GenerateToken", userId, userRole, tenantId)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GenerateToken indicates an expected call of GenerateToken.
-func (mr *MockAuthorizationMockRecorder) GenerateToken(userId, userRole any) *gomock.Call {
+func (mr *MockAuthorizationMockRecorder) GenerateToken(userId, userRole, tenantId any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateToken", reflect.TypeOf((*MockAuthorization)(nil).GenerateToken), userId, userRole)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateToken", reflect.TypeOf((*MockAuthorization)(nil).GenerateToken), userId, userRole, tenantId)
+}
+
+// GetUsers mocks base method.
+func (m *MockAuthorization) GetUsers(ctx context.Context, scope domain.TenantScope, input domain.GettingUsersParams) ([]domain.UserInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUsers", ctx, scope, input)
+	ret0, _ := ret[0].([]domain.UserInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUsers indicates an expected call of GetUsers.
+func (mr *MockAuthorizationMockRecorder) GetUsers(ctx, scope, input any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUsers", reflect.TypeOf((*MockAuthorization)(nil).GetUsers), ctx, scope, input)
 }
 
 // ParseToken mocks base method.
-func (m *MockAuthorization) ParseToken(accessToken string) (string, int, error) {
+func (m *MockAuthorization) ParseToken(accessToken string) (domain.TokenClaims, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "ParseToken", accessToken)
-	ret0, _ := ret[0].(string)
-	ret1, _ := ret[1].(int)
-	ret2, _ := ret[2].(error)
-	return ret0, ret1, ret2
+	ret0, _ := ret[0].(domain.TokenClaims)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // ParseToken indicates an expected call of ParseToken.
@@ -89,18 +148,607 @@ func (mr *MockAuthorizationMockRecorder) ParseToken(accessToken any) *gomock.Cal
 }
 
 // SignUser mocks base method.
-func (m *MockAuthorization) SignUser(email, password string) (domain.User, error) {
+func (m *MockAuthorization) SignUser(ctx context.Context, tenantId, email, password string) (domain.User, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SignUser", email, password)
+	ret := m.ctrl.Call(m, "SignUser", ctx, tenantId, email, password)
 	ret0, _ := ret[0].(domain.User)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // SignUser indicates an expected call of SignUser.
-func (mr *MockAuthorizationMockRecorder) SignUser(email, password any) *gomock.Call {
+func (mr *MockAuthorizationMockRecorder) SignUser(ctx, tenantId, email, password any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SignUser", reflect.TypeOf((*MockAuthorization)(nil).SignUser), ctx, tenantId, email, password)
+}
+
+// UpdateUser mocks base method.
+func (m *MockAuthorization) UpdateUser(ctx context.Context, scope domain.TenantScope, userId uuid.UUID, input domain.UpdateUserInput) (domain.UserInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateUser", ctx, scope, userId, input)
+	ret0, _ := ret[0].(domain.UserInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateUser indicates an expected call of UpdateUser.
+func (mr *MockAuthorizationMockRecorder) UpdateUser(ctx, scope, userId, input any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUser", reflect.TypeOf((*MockAuthorization)(nil).UpdateUser), ctx, scope, userId, input)
+}
+
+// MockPasswordReset is a mock of PasswordReset interface.
+type MockPasswordReset struct {
+	ctrl     *gomock.Controller
+	recorder *MockPasswordResetMockRecorder
+	isgomock struct{}
+}
+
+// MockPasswordResetMockRecorder is the mock recorder for MockPasswordReset.
+type MockPasswordResetMockRecorder struct {
+	mock *MockPasswordReset
+}
+
+// NewMockPasswordReset creates a new mock instance.
+func NewMockPasswordReset(ctrl *gomock.Controller) *MockPasswordReset {
+	mock := &MockPasswordReset{ctrl: ctrl}
+	mock.recorder = &MockPasswordResetMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPasswordReset) EXPECT() *MockPasswordResetMockRecorder {
+	return m.recorder
+}
+
+// RequestPasswordReset mocks base method.
+func (m *MockPasswordReset) RequestPasswordReset(ctx context.Context, tenantId, email, ip string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RequestPasswordReset", ctx, tenantId, email, ip)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RequestPasswordReset indicates an expected call of RequestPasswordReset.
+func (mr *MockPasswordResetMockRecorder) RequestPasswordReset(ctx, tenantId, email, ip any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RequestPasswordReset", reflect.TypeOf((*MockPasswordReset)(nil).RequestPasswordReset), ctx, tenantId, email, ip)
+}
+
+// ResetPassword mocks base method.
+func (m *MockPasswordReset) ResetPassword(ctx context.Context, token, password string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetPassword", ctx, token, password)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResetPassword indicates an expected call of ResetPassword.
+func (mr *MockPasswordResetMockRecorder) ResetPassword(ctx, token, password any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetPassword", reflect.TypeOf((*MockPasswordReset)(nil).ResetPassword), ctx, token, password)
+}
+
+// MockLoginProtection is a mock of LoginProtection interface.
+type MockLoginProtection struct {
+	ctrl     *gomock.Controller
+	recorder *MockLoginProtectionMockRecorder
+	isgomock struct{}
+}
+
+// MockLoginProtectionMockRecorder is the mock recorder for MockLoginProtection.
+type MockLoginProtectionMockRecorder struct {
+	mock *MockLoginProtection
+}
+
+// NewMockLoginProtection creates a new mock instance.
+func NewMockLoginProtection(ctrl *gomock.Controller) *MockLoginProtection {
+	mock := &MockLoginProtection{ctrl: ctrl}
+	mock.recorder = &MockLoginProtectionMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLoginProtection) EXPECT() *MockLoginProtectionMockRecorder {
+	return m.recorder
+}
+
+// CheckLogin mocks base method.
+func (m *MockLoginProtection) CheckLogin(ctx context.Context, tenantId, email, ip string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckLogin", ctx, tenantId, email, ip)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CheckLogin indicates an expected call of CheckLogin.
+func (mr *MockLoginProtectionMockRecorder) CheckLogin(ctx, tenantId, email, ip any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckLogin", reflect.TypeOf((*MockLoginProtection)(nil).CheckLogin), ctx, tenantId, email, ip)
+}
+
+// RegisterLoginSuccess mocks base method.
+func (m *MockLoginProtection) RegisterLoginSuccess(ctx context.Context, tenantId, email, ip string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RegisterLoginSuccess", ctx, tenantId, email, ip)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RegisterLoginSuccess indicates an expected call of RegisterLoginSuccess.
+func (mr *MockLoginProtectionMockRecorder) RegisterLoginSuccess(ctx, tenantId, email, ip any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SignUser", reflect.TypeOf((*MockAuthorization)(nil).SignUser), email, password)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RegisterLoginSuccess", reflect.TypeOf((*MockLoginProtection)(nil).RegisterLoginSuccess), ctx, tenantId, email, ip)
+}
+
+// UnlockLogin mocks base method.
+func (m *MockLoginProtection) UnlockLogin(ctx context.Context, tenantId, email, ip string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnlockLogin", ctx, tenantId, email, ip)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UnlockLogin indicates an expected call of UnlockLogin.
+func (mr *MockLoginProtectionMockRecorder) UnlockLogin(ctx, tenantId, email, ip any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnlockLogin", reflect.TypeOf((*MockLoginProtection)(nil).UnlockLogin), ctx, tenantId, email, ip)
+}
+
+// MockAudit is a mock of Audit interface.
+type MockAudit struct {
+	ctrl     *gomock.Controller
+	recorder *MockAuditMockRecorder
+	isgomock struct{}
+}
+
+// MockAuditMockRecorder is the mock recorder for MockAudit.
+type MockAuditMockRecorder struct {
+	mock *MockAudit
+}
+
+// NewMockAudit creates a new mock instance.
+func NewMockAudit(ctrl *gomock.Controller) *MockAudit {
+	mock := &MockAudit{ctrl: ctrl}
+	mock.recorder = &MockAuditMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAudit) EXPECT() *MockAuditMockRecorder {
+	return m.recorder
+}
+
+// GetAudit mocks base method.
+func (m *MockAudit) GetAudit(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAudit", ctx, filter)
+	ret0, _ := ret[0].([]domain.AuditEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAudit indicates an expected call of GetAudit.
+func (mr *MockAuditMockRecorder) GetAudit(ctx, filter any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAudit", reflect.TypeOf((*MockAudit)(nil).GetAudit), ctx, filter)
+}
+
+// RecordAudit mocks base method.
+func (m *MockAudit) RecordAudit(ctx context.Context, entry domain.AuditEntry) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordAudit", ctx, entry)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecordAudit indicates an expected call of RecordAudit.
+func (mr *MockAuditMockRecorder) RecordAudit(ctx, entry any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordAudit", reflect.TypeOf((*MockAudit)(nil).RecordAudit), ctx, entry)
+}
+
+// VerifyAuditChain mocks base method.
+func (m *MockAudit) VerifyAuditChain(ctx context.Context) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyAuditChain", ctx)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// VerifyAuditChain indicates an expected call of VerifyAuditChain.
+func (mr *MockAuditMockRecorder) VerifyAuditChain(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyAuditChain", reflect.TypeOf((*MockAudit)(nil).VerifyAuditChain), ctx)
+}
+
+// MockIdempotency is a mock of Idempotency interface.
+type MockIdempotency struct {
+	ctrl     *gomock.Controller
+	recorder *MockIdempotencyMockRecorder
+	isgomock struct{}
+}
+
+// MockIdempotencyMockRecorder is the mock recorder for MockIdempotency.
+type MockIdempotencyMockRecorder struct {
+	mock *MockIdempotency
+}
+
+// NewMockIdempotency creates a new mock instance.
+func NewMockIdempotency(ctrl *gomock.Controller) *MockIdempotency {
+	mock := &MockIdempotency{ctrl: ctrl}
+	mock.recorder = &MockIdempotencyMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIdempotency) EXPECT() *MockIdempotencyMockRecorder {
+	return m.recorder
+}
+
+// BeginIdempotent mocks base method.
+func (m *MockIdempotency) BeginIdempotent(ctx context.Context, scope, key, requestHash string) (*domain.IdempotencyRecord, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BeginIdempotent", ctx, scope, key, requestHash)
+	ret0, _ := ret[0].(*domain.IdempotencyRecord)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BeginIdempotent indicates an expected call of BeginIdempotent.
+func (mr *MockIdempotencyMockRecorder) BeginIdempotent(ctx, scope, key, requestHash any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BeginIdempotent", reflect.TypeOf((*MockIdempotency)(nil).BeginIdempotent), ctx, scope, key, requestHash)
+}
+
+// CompleteIdempotent mocks base method.
+func (m *MockIdempotency) CompleteIdempotent(ctx context.Context, scope, key string, statusCode int, response []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CompleteIdempotent", ctx, scope, key, statusCode, response)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CompleteIdempotent indicates an expected call of CompleteIdempotent.
+func (mr *MockIdempotencyMockRecorder) CompleteIdempotent(ctx, scope, key, statusCode, response any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CompleteIdempotent", reflect.TypeOf((*MockIdempotency)(nil).CompleteIdempotent), ctx, scope, key, statusCode, response)
+}
+
+// PurgeExpiredIdempotency mocks base method.
+func (m *MockIdempotency) PurgeExpiredIdempotency(ctx context.Context) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeExpiredIdempotency", ctx)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeExpiredIdempotency indicates an expected call of PurgeExpiredIdempotency.
+func (mr *MockIdempotencyMockRecorder) PurgeExpiredIdempotency(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeExpiredIdempotency", reflect.TypeOf((*MockIdempotency)(nil).PurgeExpiredIdempotency), ctx)
+}
+
+// ReleaseIdempotent mocks base method.
+func (m *MockIdempotency) ReleaseIdempotent(ctx context.Context, scope, key string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseIdempotent", ctx, scope, key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReleaseIdempotent indicates an expected call of ReleaseIdempotent.
+func (mr *MockIdempotencyMockRecorder) ReleaseIdempotent(ctx, scope, key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseIdempotent", reflect.TypeOf((*MockIdempotency)(nil).ReleaseIdempotent), ctx, scope, key)
+}
+
+// MockAmendments is a mock of Amendments interface.
+type MockAmendments struct {
+	ctrl     *gomock.Controller
+	recorder *MockAmendmentsMockRecorder
+	isgomock struct{}
+}
+
+// MockAmendmentsMockRecorder is the mock recorder for MockAmendments.
+type MockAmendmentsMockRecorder struct {
+	mock *MockAmendments
+}
+
+// NewMockAmendments creates a new mock instance.
+func NewMockAmendments(ctrl *gomock.Controller) *MockAmendments {
+	mock := &MockAmendments{ctrl: ctrl}
+	mock.recorder = &MockAmendmentsMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAmendments) EXPECT() *MockAmendmentsMockRecorder {
+	return m.recorder
+}
+
+// GetAmendments mocks base method.
+func (m *MockAmendments) GetAmendments(ctx context.Context, scope domain.TenantScope, receptionId uuid.UUID) ([]domain.ReceptionAmendment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAmendments", ctx, scope, receptionId)
+	ret0, _ := ret[0].([]domain.ReceptionAmendment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAmendments indicates an expected call of GetAmendments.
+func (mr *MockAmendmentsMockRecorder) GetAmendments(ctx, scope, receptionId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAmendments", reflect.TypeOf((*MockAmendments)(nil).GetAmendments), ctx, scope, receptionId)
+}
+
+// GetReceptionHistory mocks base method.
+func (m *MockAmendments) GetReceptionHistory(ctx context.Context, scope domain.TenantScope, receptionId uuid.UUID) ([]domain.ReceptionVersion, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetReceptionHistory", ctx, scope, receptionId)
+	ret0, _ := ret[0].([]domain.ReceptionVersion)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetReceptionHistory indicates an expected call of GetReceptionHistory.
+func (mr *MockAmendmentsMockRecorder) GetReceptionHistory(ctx, scope, receptionId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReceptionHistory", reflect.TypeOf((*MockAmendments)(nil).GetReceptionHistory), ctx, scope, receptionId)
+}
+
+// RequestAmendment mocks base method.
+func (m *MockAmendments) RequestAmendment(ctx context.Context, scope domain.TenantScope, receptionId uuid.UUID, actorId string, input domain.AmendmentRequest) (domain.ReceptionAmendment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RequestAmendment", ctx, scope, receptionId, actorId, input)
+	ret0, _ := ret[0].(domain.ReceptionAmendment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RequestAmendment indicates an expected call of RequestAmendment.
+func (mr *MockAmendmentsMockRecorder) RequestAmendment(ctx, scope, receptionId, actorId, input any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RequestAmendment", reflect.TypeOf((*MockAmendments)(nil).RequestAmendment), ctx, scope, receptionId, actorId, input)
+}
+
+// ReviewAmendment mocks base method.
+func (m *MockAmendments) ReviewAmendment(ctx context.Context, scope domain.TenantScope, id uuid.UUID, reviewer string, approve bool, comment string) (domain.ReceptionAmendment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReviewAmendment", ctx, scope, id, reviewer, approve, comment)
+	ret0, _ := ret[0].(domain.ReceptionAmendment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReviewAmendment indicates an expected call of ReviewAmendment.
+func (mr *MockAmendmentsMockRecorder) ReviewAmendment(ctx, scope, id, reviewer, approve, comment any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReviewAmendment", reflect.TypeOf((*MockAmendments)(nil).ReviewAmendment), ctx, scope, id, reviewer, approve, comment)
+}
+
+// MockTransfers is a mock of Transfers interface.
+type MockTransfers struct {
+	ctrl     *gomock.Controller
+	recorder *MockTransfersMockRecorder
+	isgomock struct{}
+}
+
+// MockTransfersMockRecorder is the mock recorder for MockTransfers.
+type MockTransfersMockRecorder struct {
+	mock *MockTransfers
+}
+
+// NewMockTransfers creates a new mock instance.
+func NewMockTransfers(ctrl *gomock.Controller) *MockTransfers {
+	mock := &MockTransfers{ctrl: ctrl}
+	mock.recorder = &MockTransfersMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTransfers) EXPECT() *MockTransfersMockRecorder {
+	return m.recorder
+}
+
+// AcceptTransfer mocks base method.
+func (m *MockTransfers) AcceptTransfer(ctx context.Context, scope domain.TenantScope, transferId uuid.UUID, actorId string, at time.Time) (domain.ProductTransfer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AcceptTransfer", ctx, scope, transferId, actorId, at)
+	ret0, _ := ret[0].(domain.ProductTransfer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AcceptTransfer indicates an expected call of AcceptTransfer.
+func (mr *MockTransfersMockRecorder) AcceptTransfer(ctx, scope, transferId, actorId, at any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AcceptTransfer", reflect.TypeOf((*MockTransfers)(nil).AcceptTransfer), ctx, scope, transferId, actorId, at)
+}
+
+// GetTransfersInTransit mocks base method.
+func (m *MockTransfers) GetTransfersInTransit(ctx context.Context, scope domain.TenantScope, params domain.InTransitParams) ([]domain.ProductTransfer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTransfersInTransit", ctx, scope, params)
+	ret0, _ := ret[0].([]domain.ProductTransfer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTransfersInTransit indicates an expected call of GetTransfersInTransit.
+func (mr *MockTransfersMockRecorder) GetTransfersInTransit(ctx, scope, params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTransfersInTransit", reflect.TypeOf((*MockTransfers)(nil).GetTransfersInTransit), ctx, scope, params)
+}
+
+// ShipProduct mocks base method.
+func (m *MockTransfers) ShipProduct(ctx context.Context, scope domain.TenantScope, fromPvzId, productId uuid.UUID, actorId string, input domain.TransferRequest, at time.Time) (domain.ProductTransfer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ShipProduct", ctx, scope, fromPvzId, productId, actorId, input, at)
+	ret0, _ := ret[0].(domain.ProductTransfer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ShipProduct indicates an expected call of ShipProduct.
+func (mr *MockTransfersMockRecorder) ShipProduct(ctx, scope, fromPvzId, productId, actorId, input, at any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ShipProduct", reflect.TypeOf((*MockTransfers)(nil).ShipProduct), ctx, scope, fromPvzId, productId, actorId, input, at)
+}
+
+// MockReceptionAutoClose is a mock of ReceptionAutoClose interface.
+type MockReceptionAutoClose struct {
+	ctrl     *gomock.Controller
+	recorder *MockReceptionAutoCloseMockRecorder
+	isgomock struct{}
+}
+
+// MockReceptionAutoCloseMockRecorder is the mock recorder for MockReceptionAutoClose.
+type MockReceptionAutoCloseMockRecorder struct {
+	mock *MockReceptionAutoClose
+}
+
+// NewMockReceptionAutoClose creates a new mock instance.
+func NewMockReceptionAutoClose(ctrl *gomock.Controller) *MockReceptionAutoClose {
+	mock := &MockReceptionAutoClose{ctrl: ctrl}
+	mock.recorder = &MockReceptionAutoCloseMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockReceptionAutoClose) EXPECT() *MockReceptionAutoCloseMockRecorder {
+	return m.recorder
+}
+
+// AutoCloseReceptions mocks base method.
+func (m *MockReceptionAutoClose) AutoCloseReceptions(ctx context.Context) (domain.AutoCloseResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AutoCloseReceptions", ctx)
+	ret0, _ := ret[0].(domain.AutoCloseResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AutoCloseReceptions indicates an expected call of AutoCloseReceptions.
+func (mr *MockReceptionAutoCloseMockRecorder) AutoCloseReceptions(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AutoCloseReceptions", reflect.TypeOf((*MockReceptionAutoClose)(nil).AutoCloseReceptions), ctx)
+}
+
+// MockPvzCapacity is a mock of PvzCapacity interface.
+type MockPvzCapacity struct {
+	ctrl     *gomock.Controller
+	recorder *MockPvzCapacityMockRecorder
+	isgomock struct{}
+}
+
+// MockPvzCapacityMockRecorder is the mock recorder for MockPvzCapacity.
+type MockPvzCapacityMockRecorder struct {
+	mock *MockPvzCapacity
+}
+
+// NewMockPvzCapacity creates a new mock instance.
+func NewMockPvzCapacity(ctrl *gomock.Controller) *MockPvzCapacity {
+	mock := &MockPvzCapacity{ctrl: ctrl}
+	mock.recorder = &MockPvzCapacityMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPvzCapacity) EXPECT() *MockPvzCapacityMockRecorder {
+	return m.recorder
+}
+
+// CheckPvzLimits mocks base method.
+func (m *MockPvzCapacity) CheckPvzLimits(ctx context.Context, scope domain.TenantScope, pvzId uuid.UUID, at time.Time) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckPvzLimits", ctx, scope, pvzId, at)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CheckPvzLimits indicates an expected call of CheckPvzLimits.
+func (mr *MockPvzCapacityMockRecorder) CheckPvzLimits(ctx, scope, pvzId, at any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckPvzLimits", reflect.TypeOf((*MockPvzCapacity)(nil).CheckPvzLimits), ctx, scope, pvzId, at)
+}
+
+// GetPvzOccupancy mocks base method.
+func (m *MockPvzCapacity) GetPvzOccupancy(ctx context.Context, scope domain.TenantScope, pvzId *uuid.UUID) ([]domain.PvzOccupancy, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPvzOccupancy", ctx, scope, pvzId)
+	ret0, _ := ret[0].([]domain.PvzOccupancy)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPvzOccupancy indicates an expected call of GetPvzOccupancy.
+func (mr *MockPvzCapacityMockRecorder) GetPvzOccupancy(ctx, scope, pvzId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPvzOccupancy", reflect.TypeOf((*MockPvzCapacity)(nil).GetPvzOccupancy), ctx, scope, pvzId)
+}
+
+// GetPvzSchedule mocks base method.
+func (m *MockPvzCapacity) GetPvzSchedule(ctx context.Context, scope domain.TenantScope, pvzId uuid.UUID) (domain.PvzSchedule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPvzSchedule", ctx, scope, pvzId)
+	ret0, _ := ret[0].(domain.PvzSchedule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPvzSchedule indicates an expected call of GetPvzSchedule.
+func (mr *MockPvzCapacityMockRecorder) GetPvzSchedule(ctx, scope, pvzId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPvzSchedule", reflect.TypeOf((*MockPvzCapacity)(nil).GetPvzSchedule), ctx, scope, pvzId)
+}
+
+// GetPvzStock mocks base method.
+func (m *MockPvzCapacity) GetPvzStock(ctx context.Context) ([]domain.PvzStock, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPvzStock", ctx)
+	ret0, _ := ret[0].([]domain.PvzStock)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPvzStock indicates an expected call of GetPvzStock.
+func (mr *MockPvzCapacityMockRecorder) GetPvzStock(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPvzStock", reflect.TypeOf((*MockPvzCapacity)(nil).GetPvzStock), ctx)
+}
+
+// IssueProduct mocks base method.
+func (m *MockPvzCapacity) IssueProduct(ctx context.Context, scope domain.TenantScope, pvzId, productId uuid.UUID, at time.Time) (domain.Product, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IssueProduct", ctx, scope, pvzId, productId, at)
+	ret0, _ := ret[0].(domain.Product)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IssueProduct indicates an expected call of IssueProduct.
+func (mr *MockPvzCapacityMockRecorder) IssueProduct(ctx, scope, pvzId, productId, at any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IssueProduct", reflect.TypeOf((*MockPvzCapacity)(nil).IssueProduct), ctx, scope, pvzId, productId, at)
+}
+
+// SetPvzSchedule mocks base method.
+func (m *MockPvzCapacity) SetPvzSchedule(ctx context.Context, scope domain.TenantScope, pvzId uuid.UUID, schedule domain.PvzSchedule) (domain.PvzSchedule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetPvzSchedule", ctx, scope, pvzId, schedule)
+	ret0, _ := ret[0].(domain.PvzSchedule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetPvzSchedule indicates an expected call of SetPvzSchedule.
+func (mr *MockPvzCapacityMockRecorder) SetPvzSchedule(ctx, scope, pvzId, schedule any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPvzSchedule", reflect.TypeOf((*MockPvzCapacity)(nil).SetPvzSchedule), ctx, scope, pvzId, schedule)
 }
 
 // MockPvz is a mock of Pvz interface.
@@ -128,105 +776,181 @@ func (m *MockPvz) EXPECT() *MockPvzMockRecorder {
 }
 
 // AddProdToRecep mocks base method.
-func (m *MockPvz) AddProdToRecep(product domain.Product) (domain.Product, error) {
+func (m *MockPvz) AddProdToRecep(ctx context.Context, scope domain.TenantScope, product domain.Product) (domain.Product, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "AddProdToRecep", product)
+	ret := m.ctrl.Call(m, "AddProdToRecep", ctx, scope, product)
 	ret0, _ := ret[0].(domain.Product)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // AddProdToRecep indicates an expected call of AddProdToRecep.
-func (mr *MockPvzMockRecorder) AddProdToRecep(product any) *gomock.Call {
+func (mr *MockPvzMockRecorder) AddProdToRecep(ctx, scope, product any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddProdToRecep", reflect.TypeOf((*MockPvz)(nil).AddProdToRecep), product)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddProdToRecep", reflect.TypeOf((*MockPvz)(nil).AddProdToRecep), ctx, scope, product)
+}
+
+// ArchiveDecommissionedPvz mocks base method.
+func (m *MockPvz) ArchiveDecommissionedPvz(ctx context.Context) ([]domain.PVZ, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ArchiveDecommissionedPvz", ctx)
+	ret0, _ := ret[0].([]domain.PVZ)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ArchiveDecommissionedPvz indicates an expected call of ArchiveDecommissionedPvz.
+func (mr *MockPvzMockRecorder) ArchiveDecommissionedPvz(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ArchiveDecommissionedPvz", reflect.TypeOf((*MockPvz)(nil).ArchiveDecommissionedPvz), ctx)
 }
 
 // CloseReception mocks base method.
-func (m *MockPvz) CloseReception(closeRec uuid.UUID) (domain.ProductReception, error) {
+func (m *MockPvz) CloseReception(ctx context.Context, scope domain.TenantScope, closeRec uuid.UUID) (domain.ProductReception, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CloseReception", closeRec)
+	ret := m.ctrl.Call(m, "CloseReception", ctx, scope, closeRec)
 	ret0, _ := ret[0].(domain.ProductReception)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CloseReception indicates an expected call of CloseReception.
-func (mr *MockPvzMockRecorder) CloseReception(closeRec any) *gomock.Call {
+func (mr *MockPvzMockRecorder) CloseReception(ctx, scope, closeRec any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CloseReception", reflect.TypeOf((*MockPvz)(nil).CloseReception), closeRec)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CloseReception", reflect.TypeOf((*MockPvz)(nil).CloseReception), ctx, scope, closeRec)
 }
 
 // CreatePvz mocks base method.
-func (m *MockPvz) CreatePvz(pvz domain.PVZ) (domain.PVZ, error) {
+func (m *MockPvz) CreatePvz(ctx context.Context, scope domain.TenantScope, pvz domain.PVZ) (domain.PVZ, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreatePvz", pvz)
+	ret := m.ctrl.Call(m, "CreatePvz", ctx, scope, pvz)
 	ret0, _ := ret[0].(domain.PVZ)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CreatePvz indicates an expected call of CreatePvz.
-func (mr *MockPvzMockRecorder) CreatePvz(pvz any) *gomock.Call {
+func (mr *MockPvzMockRecorder) CreatePvz(ctx, scope, pvz any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePvz", reflect.TypeOf((*MockPvz)(nil).CreatePvz), pvz)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePvz", reflect.TypeOf((*MockPvz)(nil).CreatePvz), ctx, scope, pvz)
 }
 
 // CreateRecep mocks base method.
-func (m *MockPvz) CreateRecep(recep domain.ProductReception) (domain.ProductReception, error) {
+func (m *MockPvz) CreateRecep(ctx context.Context, scope domain.TenantScope, recep domain.ProductReception) (domain.ProductReception, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateRecep", recep)
+	ret := m.ctrl.Call(m, "CreateRecep", ctx, scope, recep)
 	ret0, _ := ret[0].(domain.ProductReception)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CreateRecep indicates an expected call of CreateRecep.
-func (mr *MockPvzMockRecorder) CreateRecep(recep any) *gomock.Call {
+func (mr *MockPvzMockRecorder) CreateRecep(ctx, scope, recep any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateRecep", reflect.TypeOf((*MockPvz)(nil).CreateRecep), recep)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateRecep", reflect.TypeOf((*MockPvz)(nil).CreateRecep), ctx, scope, recep)
 }
 
 // DeleteLastProduct mocks base method.
-func (m *MockPvz) DeleteLastProduct(delProd uuid.UUID) error {
+func (m *MockPvz) DeleteLastProduct(ctx context.Context, scope domain.TenantScope, input domain.ProductDeletion) (domain.Product, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteLastProduct", delProd)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "DeleteLastProduct", ctx, scope, input)
+	ret0, _ := ret[0].(domain.Product)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // DeleteLastProduct indicates an expected call of DeleteLastProduct.
-func (mr *MockPvzMockRecorder) DeleteLastProduct(delProd any) *gomock.Call {
+func (mr *MockPvzMockRecorder) DeleteLastProduct(ctx, scope, input any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteLastProduct", reflect.TypeOf((*MockPvz)(nil).DeleteLastProduct), ctx, scope, input)
+}
+
+// DeleteProduct mocks base method.
+func (m *MockPvz) DeleteProduct(ctx context.Context, scope domain.TenantScope, input domain.ProductDeletion) (domain.Product, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteProduct", ctx, scope, input)
+	ret0, _ := ret[0].(domain.Product)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteProduct indicates an expected call of DeleteProduct.
+func (mr *MockPvzMockRecorder) DeleteProduct(ctx, scope, input any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteLastProduct", reflect.TypeOf((*MockPvz)(nil).DeleteLastProduct), delProd)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteProduct", reflect.TypeOf((*MockPvz)(nil).DeleteProduct), ctx, scope, input)
 }
 
 // GetListOFpvz mocks base method.
-func (m *MockPvz) GetListOFpvz(ctx context.Context) ([]domain.PVZ, error) {
+func (m *MockPvz) GetListOFpvz(ctx context.Context, scope domain.TenantScope) ([]domain.PVZ, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetListOFpvz", ctx)
+	ret := m.ctrl.Call(m, "GetListOFpvz", ctx, scope)
 	ret0, _ := ret[0].([]domain.PVZ)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetListOFpvz indicates an expected call of GetListOFpvz.
-func (mr *MockPvzMockRecorder) GetListOFpvz(ctx any) *gomock.Call {
+func (mr *MockPvzMockRecorder) GetListOFpvz(ctx, scope any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetListOFpvz", reflect.TypeOf((*MockPvz)(nil).GetListOFpvz), ctx)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetListOFpvz", reflect.TypeOf((*MockPvz)(nil).GetListOFpvz), ctx, scope)
+}
+
+// GetNearestPvz mocks base method.
+func (m *MockPvz) GetNearestPvz(ctx context.Context, scope domain.TenantScope, params domain.NearestPvzParams) ([]domain.PvzDistance, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetNearestPvz", ctx, scope, params)
+	ret0, _ := ret[0].([]domain.PvzDistance)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetNearestPvz indicates an expected call of GetNearestPvz.
+func (mr *MockPvzMockRecorder) GetNearestPvz(ctx, scope, params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNearestPvz", reflect.TypeOf((*MockPvz)(nil).GetNearestPvz), ctx, scope, params)
 }
 
 // GetPvz mocks base method.
-func (m *MockPvz) GetPvz(input domain.GettingPvzParams) ([]domain.PvzSummary, error) {
+func (m *MockPvz) GetPvz(ctx context.Context, scope domain.TenantScope, input domain.GettingPvzParams) ([]domain.PvzSummary, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetPvz", input)
+	ret := m.ctrl.Call(m, "GetPvz", ctx, scope, input)
 	ret0, _ := ret[0].([]domain.PvzSummary)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetPvz indicates an expected call of GetPvz.
-func (mr *MockPvzMockRecorder) GetPvz(input any) *gomock.Call {
+func (mr *MockPvzMockRecorder) GetPvz(ctx, scope, input any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPvz", reflect.TypeOf((*MockPvz)(nil).GetPvz), ctx, scope, input)
+}
+
+// GetPvzReport mocks base method.
+func (m *MockPvz) GetPvzReport(ctx context.Context, scope domain.TenantScope, params domain.PvzReportParams) (domain.PvzReport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPvzReport", ctx, scope, params)
+	ret0, _ := ret[0].(domain.PvzReport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPvzReport indicates an expected call of GetPvzReport.
+func (mr *MockPvzMockRecorder) GetPvzReport(ctx, scope, params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPvzReport", reflect.TypeOf((*MockPvz)(nil).GetPvzReport), ctx, scope, params)
+}
+
+// UpdatePvz mocks base method.
+func (m *MockPvz) UpdatePvz(ctx context.Context, scope domain.TenantScope, pvzId uuid.UUID, input domain.PvzUpdate) (domain.PVZ, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePvz", ctx, scope, pvzId, input)
+	ret0, _ := ret[0].(domain.PVZ)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdatePvz indicates an expected call of UpdatePvz.
+func (mr *MockPvzMockRecorder) UpdatePvz(ctx, scope, pvzId, input any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPvz", reflect.TypeOf((*MockPvz)(nil).GetPvz), input)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePvz", reflect.TypeOf((*MockPvz)(nil).UpdatePvz), ctx, scope, pvzId, input)
 }
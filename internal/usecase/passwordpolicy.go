@@ -0,0 +1,89 @@
+package usecase
+
+import (
+	_ "embed"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+//go:embed banned_passwords.txt
+var bannedPasswordsList string
+
+var bannedPasswords = func() map[string]struct{} {
+	banned := make(map[string]struct{})
+	for _, line := range strings.Split(bannedPasswordsList, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			banned[strings.ToLower(line)] = struct{}{}
+		}
+	}
+	return banned
+}()
+
+// PasswordPolicy задает требования к паролям пользователей.
+type PasswordPolicy struct {
+	MinLength      int
+	RequireUpper   bool
+	RequireLower   bool
+	RequireDigit   bool
+	RequireSpecial bool
+	// CheckBanned запрещает пароли из встроенного списка распространенных паролей.
+	CheckBanned bool
+}
+
+var DefaultPasswordPolicy = PasswordPolicy{
+	MinLength:    8,
+	RequireUpper: true,
+	RequireLower: true,
+	RequireDigit: true,
+	CheckBanned:  true,
+}
+
+type PasswordPolicyError struct {
+	Reasons []string
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return "пароль не соответствует требованиям: " + strings.Join(e.Reasons, ", ")
+}
+
+func (p PasswordPolicy) Validate(password string) error {
+	var reasons []string
+	if utf8.RuneCountInString(password) < p.MinLength || password == "" {
+		reasons = append(reasons, "слишком короткий")
+	}
+	var hasUpper, hasLower, hasDigit, hasSpecial bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			hasSpecial = true
+		}
+	}
+	if p.RequireUpper && !hasUpper {
+		reasons = append(reasons, "нет заглавной буквы")
+	}
+	if p.RequireLower && !hasLower {
+		reasons = append(reasons, "нет строчной буквы")
+	}
+	if p.RequireDigit && !hasDigit {
+		reasons = append(reasons, "нет цифры")
+	}
+	if p.RequireSpecial && !hasSpecial {
+		reasons = append(reasons, "нет специального символа")
+	}
+	if p.CheckBanned {
+		if _, ok := bannedPasswords[strings.ToLower(password)]; ok {
+			reasons = append(reasons, "слишком распространенный")
+		}
+	}
+	if len(reasons) > 0 {
+		return &PasswordPolicyError{Reasons: reasons}
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bllooop/pvzservice/internal/domain"
+	"github.com/bllooop/pvzservice/internal/notifier"
+	"github.com/bllooop/pvzservice/internal/repository"
+	logger "github.com/bllooop/pvzservice/pkg/logging"
+)
+
+// ResetThrottle ограничивает частоту запросов сброса пароля, чтобы через
+// сервис нельзя было засыпать письмами чужой ящик.
+type ResetThrottle struct {
+	// MaxPerEmail - число запросов для одной почты за Window, 0 снимает ограничение.
+	MaxPerEmail int
+	// MaxPerIP - то же для одного IP-адреса клиента.
+	MaxPerIP int
+	Window   time.Duration `mapstructure:"throttleWindow"`
+}
+
+var DefaultResetThrottle = ResetThrottle{
+	MaxPerEmail: 3,
+	MaxPerIP:    20,
+	Window:      time.Hour,
+}
+
+type ResetThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ResetThrottledError) Error() string {
+	return fmt.Sprintf("слишком частые запросы сброса пароля, повторите через %s", e.RetryAfter.Round(time.Second))
+}
+
+type PasswordUsecase struct {
+	users    repository.Authorization
+	resets   repository.PasswordReset
+	attempts repository.LoginAttempts
+	policy   PasswordPolicy
+	tokenTTL time.Duration
+	throttle ResetThrottle
+	notifier notifier.Notifier
+	now      func() time.Time
+}
+
+func NewPasswordUsecase(repo *repository.Repository, policy PasswordPolicy, tokenTTL time.Duration, throttle ResetThrottle, n notifier.Notifier) *PasswordUsecase {
+	if n == nil {
+		n = notifier.NewLogNotifier()
+	}
+	return &PasswordUsecase{
+		users:    repo,
+		resets:   repo,
+		attempts: repo.LoginAttempts,
+		policy:   policy,
+		tokenTTL: tokenTTL,
+		throttle: throttle,
+		notifier: n,
+		now:      time.Now,
+	}
+}
+
+// RequestPasswordReset не сообщает, существует ли пользователь, чтобы по ответу
+// нельзя было перебирать зарегистрированные почты. Лимиты запросов
+// проверяются до поиска пользователя и одинаково действуют для любых почт.
+func (s *PasswordUsecase) RequestPasswordReset(ctx context.Context, tenantId, email, ip string) error {
+	now := s.now()
+	if err := s.checkThrottle(ctx, "reset:"+ipKey(ip), s.throttle.MaxPerIP, now); err != nil {
+		return err
+	}
+	if err := s.checkThrottle(ctx, "reset:"+emailKey(tenantId, email), s.throttle.MaxPerEmail, now); err != nil {
+		return err
+	}
+	user, err := s.users.SignUser(ctx, tenantId, email)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			logger.FromContext(ctx).Debug().Msg("Запрошен сброс пароля для несуществующего пользователя")
+			return nil
+		}
+		return err
+	}
+	if user.DisabledAt != nil {
+		return nil
+	}
+	token, err := newResetToken()
+	if err != nil {
+		return err
+	}
+	expiresAt := now.Add(s.tokenTTL)
+	if err := s.resets.CreateResetToken(ctx, user.Id, hashResetToken(token), expiresAt); err != nil {
+		return err
+	}
+	return s.notifier.SendPasswordReset(user.Email, token, expiresAt)
+}
+
+func (s *PasswordUsecase) ResetPassword(ctx context.Context, token, password string) error {
+	if err := s.policy.Validate(password); err != nil {
+		return err
+	}
+	hashed, err := HashPassword(password)
+	if err != nil {
+		return err
+	}
+	_, err = s.resets.ConsumeResetToken(ctx, hashResetToken(token), hashed)
+	return err
+}
+
+// checkThrottle атомарно учитывает запрос в счетчике key и отклоняет его,
+// если за окно throttle.Window уже было limit запросов. В LastFailureAt
+// хранится время последнего принятого запроса.
+func (s *PasswordUsecase) checkThrottle(ctx context.Context, key string, limit int, now time.Time) error {
+	if limit <= 0 || s.attempts == nil {
+		return nil
+	}
+	windowStart := now.Add(-s.throttle.Window)
+	_, err := s.attempts.UpdateLoginAttempt(ctx, key, func(attempt domain.LoginAttempt) (domain.LoginAttempt, error) {
+		if attempt.LastFailureAt == nil || attempt.LastFailureAt.Before(windowStart) {
+			attempt.Failures = 0
+		}
+		if attempt.Failures >= limit {
+			return attempt, &ResetThrottledError{RetryAfter: attempt.LastFailureAt.Add(s.throttle.Window).Sub(now)}
+		}
+		attempt.Failures++
+		attempt.LastFailureAt = &now
+		return attempt, nil
+	})
+	return err
+}
+
+func newResetToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func hashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,122 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bllooop/pvzservice/internal/domain"
+	"github.com/bllooop/pvzservice/internal/repository"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidSchedule = errors.New("некорректное расписание ПВЗ")
+	ErrPvzClosedNow    = repository.ErrPvzClosedNow
+	ErrPvzFull         = repository.ErrPvzFull
+)
+
+type PvzCapacityUsecase struct {
+	repo      repository.PvzCapacity
+	limitMode string
+}
+
+func NewPvzCapacityUsecase(repo *repository.Repository, limitMode string) *PvzCapacityUsecase {
+	if limitMode != domain.LimitWarn {
+		limitMode = domain.LimitReject
+	}
+	return &PvzCapacityUsecase{
+		repo:      repo,
+		limitMode: limitMode,
+	}
+}
+
+func (s *PvzCapacityUsecase) GetPvzSchedule(ctx context.Context, scope domain.TenantScope, pvzId uuid.UUID) (domain.PvzSchedule, error) {
+	return s.repo.GetPvzSchedule(ctx, scope, pvzId)
+}
+
+func (s *PvzCapacityUsecase) SetPvzSchedule(ctx context.Context, scope domain.TenantScope, pvzId uuid.UUID, schedule domain.PvzSchedule) (domain.PvzSchedule, error) {
+	if err := validateSchedule(schedule); err != nil {
+		return domain.PvzSchedule{}, err
+	}
+	if schedule.Week == nil {
+		schedule.Week = []domain.WorkingDay{}
+	}
+	if schedule.Holidays == nil {
+		schedule.Holidays = []domain.Holiday{}
+	}
+	return s.repo.SetPvzSchedule(ctx, scope, pvzId, schedule)
+}
+
+func (s *PvzCapacityUsecase) GetPvzOccupancy(ctx context.Context, scope domain.TenantScope, pvzId *uuid.UUID) ([]domain.PvzOccupancy, error) {
+	return s.repo.GetPvzOccupancy(ctx, scope, pvzId)
+}
+
+func (s *PvzCapacityUsecase) GetPvzStock(ctx context.Context) ([]domain.PvzStock, error) {
+	return s.repo.GetPvzStock(ctx)
+}
+
+func (s *PvzCapacityUsecase) IssueProduct(ctx context.Context, scope domain.TenantScope, pvzId, productId uuid.UUID, at time.Time) (domain.Product, error) {
+	return s.repo.IssueProduct(ctx, scope, pvzId, productId, at)
+}
+
+// CheckPvzLimits проверяет, что ПВЗ работает в момент at и не заполнен.
+// В режиме reject нарушение возвращается ошибкой, в режиме warn — списком
+// предупреждений, а операция продолжается. Заполненность в режиме reject
+// проверяет сама операция в своей транзакции (см. PvzUsecase), здесь она
+// считается только для предупреждений.
+func (s *PvzCapacityUsecase) CheckPvzLimits(ctx context.Context, scope domain.TenantScope, pvzId uuid.UUID, at time.Time) ([]string, error) {
+	schedule, err := s.repo.GetPvzSchedule(ctx, scope, pvzId)
+	if err != nil {
+		return nil, err
+	}
+	var violations []error
+	if !schedule.OpenAt(at) {
+		violations = append(violations, ErrPvzClosedNow)
+	}
+	if s.limitMode == domain.LimitWarn {
+		occupancy, err := s.repo.GetPvzOccupancy(ctx, scope, &pvzId)
+		if err != nil {
+			return nil, err
+		}
+		if len(occupancy) > 0 && occupancy[0].Full() {
+			violations = append(violations, ErrPvzFull)
+		}
+	}
+	if len(violations) == 0 {
+		return nil, nil
+	}
+	if s.limitMode == domain.LimitReject {
+		return nil, violations[0]
+	}
+	warnings := make([]string, 0, len(violations))
+	for _, violation := range violations {
+		warnings = append(warnings, violation.Error())
+	}
+	return warnings, nil
+}
+
+func validateSchedule(schedule domain.PvzSchedule) error {
+	weekdays := make(map[int]bool, len(schedule.Week))
+	for _, day := range schedule.Week {
+		if weekdays[day.Weekday] {
+			return fmt.Errorf("%w: день недели %d указан дважды", ErrInvalidSchedule, day.Weekday)
+		}
+		weekdays[day.Weekday] = true
+		if day.Closes <= day.Opens {
+			return fmt.Errorf("%w: время закрытия раньше открытия", ErrInvalidSchedule)
+		}
+	}
+	dates := make(map[string]bool, len(schedule.Holidays))
+	for _, holiday := range schedule.Holidays {
+		if dates[holiday.Date] {
+			return fmt.Errorf("%w: дата %s указана дважды", ErrInvalidSchedule, holiday.Date)
+		}
+		dates[holiday.Date] = true
+		if holiday.Opens != "" && holiday.Closes <= holiday.Opens {
+			return fmt.Errorf("%w: время закрытия раньше открытия", ErrInvalidSchedule)
+		}
+	}
+	return nil
+}
@@ -2,43 +2,147 @@ package usecase
 
 import (
 	"context"
+	"errors"
+	"math"
+	"time"
 
 	"github.com/bllooop/pvzservice/internal/domain"
 	"github.com/bllooop/pvzservice/internal/repository"
 	"github.com/google/uuid"
 )
 
+var (
+	ErrInvalidPvzUpdate = errors.New("некорректное изменение ПВЗ")
+	ErrInvalidGeoQuery  = errors.New("некорректные координаты или радиус поиска")
+	ErrInvalidReport    = errors.New("некорректный период отчета")
+)
+
+const (
+	DefaultNearestRadiusKm = 5.0
+	MaxNearestRadiusKm     = 100.0
+	DefaultNearestLimit    = 10
+	MaxNearestLimit        = 30
+	MaxReportDays          = 366
+)
+
 type PvzUsecase struct {
-	repo repository.Pvz
+	repo      repository.Pvz
+	limitMode string
 }
 
-func NewPvzUsecase(repo *repository.Repository) *PvzUsecase {
+func NewPvzUsecase(repo *repository.Repository, limitMode string) *PvzUsecase {
+	if limitMode != domain.LimitWarn {
+		limitMode = domain.LimitReject
+	}
 	return &PvzUsecase{
-		repo: repo,
+		repo:      repo,
+		limitMode: limitMode,
+	}
+}
+
+// limitCapacity в режиме reject поручает репозиторию отклонять приемку в
+// заполненный ПВЗ в той же транзакции, что и запись.
+func (s *PvzUsecase) limitCapacity(ctx context.Context) context.Context {
+	if s.limitMode == domain.LimitReject {
+		return repository.WithCapacityLimit(ctx)
+	}
+	return ctx
+}
+
+func (s *PvzUsecase) CreatePvz(ctx context.Context, scope domain.TenantScope, pvz domain.PVZ) (domain.PVZ, error) {
+	if pvz.Timezone == "" {
+		pvz.Timezone = domain.DefaultPvzTimezone
+	}
+	return s.repo.CreatePvz(ctx, scope, pvz)
+}
+
+// UpdatePvz проверяет согласованность изменения и передает его в репозиторий.
+// Дата окончания допустима только для временного закрытия.
+func (s *PvzUsecase) UpdatePvz(ctx context.Context, scope domain.TenantScope, pvzId uuid.UUID, input domain.PvzUpdate) (domain.PVZ, error) {
+	if input.City == nil && input.Address == nil && input.PostalCode == nil && input.WorkingHours == nil &&
+		input.Latitude == nil && input.Capacity == nil && input.Timezone == nil && input.Status == nil {
+		return domain.PVZ{}, ErrInvalidPvzUpdate
+	}
+	if input.Status == nil {
+		if input.EffectiveFrom != nil || input.EffectiveTo != nil {
+			return domain.PVZ{}, ErrInvalidPvzUpdate
+		}
+		return s.repo.UpdatePvz(ctx, scope, pvzId, input)
+	}
+	if input.EffectiveFrom == nil {
+		return domain.PVZ{}, ErrInvalidPvzUpdate
+	}
+	if input.EffectiveTo != nil {
+		if *input.Status != domain.PvzTemporarilyClosed || !input.EffectiveTo.After(*input.EffectiveFrom) {
+			return domain.PVZ{}, ErrInvalidPvzUpdate
+		}
 	}
+	return s.repo.UpdatePvz(ctx, scope, pvzId, input)
 }
 
-func (s *PvzUsecase) CreatePvz(pvz domain.PVZ) (domain.PVZ, error) {
-	return s.repo.CreatePvz(pvz)
+// ArchiveDecommissionedPvz архивирует ПВЗ, вывод из работы которых вступил в
+// силу. Изменения записываются в журнал аудита от имени системы.
+func (s *PvzUsecase) ArchiveDecommissionedPvz(ctx context.Context) ([]domain.PVZ, error) {
+	now := time.Now()
+	ctx = repository.WithAudit(ctx, domain.AuditEntry{
+		CreatedAt:  now,
+		ActorId:    SystemActor,
+		ActorRole:  SystemActor,
+		EntityType: "pvz",
+	})
+	return s.repo.ArchiveDecommissionedPvz(ctx, now)
 }
-func (s *PvzUsecase) GetPvz(input domain.GettingPvzParams) ([]domain.PvzSummary, error) {
-	return s.repo.GetPvz(input)
+func (s *PvzUsecase) GetNearestPvz(ctx context.Context, scope domain.TenantScope, params domain.NearestPvzParams) ([]domain.PvzDistance, error) {
+	if math.IsNaN(params.Latitude) || math.IsNaN(params.Longitude) || math.IsNaN(params.RadiusKm) ||
+		params.Latitude < -90 || params.Latitude > 90 || params.Longitude < -180 || params.Longitude > 180 ||
+		params.RadiusKm < 0 || params.RadiusKm > MaxNearestRadiusKm {
+		return nil, ErrInvalidGeoQuery
+	}
+	if params.RadiusKm == 0 {
+		params.RadiusKm = DefaultNearestRadiusKm
+	}
+	if params.Limit < 1 {
+		params.Limit = DefaultNearestLimit
+	} else if params.Limit > MaxNearestLimit {
+		params.Limit = MaxNearestLimit
+	}
+	return s.repo.GetNearestPvz(ctx, scope, params)
 }
-func (s *PvzUsecase) CreateRecep(recep domain.ProductReception) (domain.ProductReception, error) {
-	return s.repo.CreateRecep(recep)
+func (s *PvzUsecase) GetPvz(ctx context.Context, scope domain.TenantScope, input domain.GettingPvzParams) ([]domain.PvzSummary, error) {
+	return s.repo.GetPvz(ctx, scope, input)
 }
 
-func (s *PvzUsecase) AddProdToRecep(product domain.Product) (domain.Product, error) {
-	return s.repo.AddProdToRecep(product)
+// GetPvzReport проверяет период отчета: даты в формате YYYY-MM-DD, конец не
+// раньше начала и не больше MaxReportDays дней.
+func (s *PvzUsecase) GetPvzReport(ctx context.Context, scope domain.TenantScope, params domain.PvzReportParams) (domain.PvzReport, error) {
+	from, err := time.Parse("2006-01-02", params.From)
+	if err != nil {
+		return domain.PvzReport{}, ErrInvalidReport
+	}
+	to, err := time.Parse("2006-01-02", params.To)
+	if err != nil || to.Before(from) || to.Sub(from) >= MaxReportDays*24*time.Hour {
+		return domain.PvzReport{}, ErrInvalidReport
+	}
+	return s.repo.GetPvzReport(ctx, scope, params)
+}
+func (s *PvzUsecase) CreateRecep(ctx context.Context, scope domain.TenantScope, recep domain.ProductReception) (domain.ProductReception, error) {
+	return s.repo.CreateRecep(s.limitCapacity(ctx), scope, recep)
 }
 
-func (s *PvzUsecase) DeleteLastProduct(delProd uuid.UUID) error {
-	return s.repo.DeleteLastProduct(delProd)
+func (s *PvzUsecase) AddProdToRecep(ctx context.Context, scope domain.TenantScope, product domain.Product) (domain.Product, error) {
+	return s.repo.AddProdToRecep(s.limitCapacity(ctx), scope, product)
+}
+
+func (s *PvzUsecase) DeleteLastProduct(ctx context.Context, scope domain.TenantScope, input domain.ProductDeletion) (domain.Product, error) {
+	return s.repo.DeleteLastProduct(ctx, scope, input)
+}
+func (s *PvzUsecase) DeleteProduct(ctx context.Context, scope domain.TenantScope, input domain.ProductDeletion) (domain.Product, error) {
+	return s.repo.DeleteProduct(ctx, scope, input)
 }
-func (s *PvzUsecase) CloseReception(closeRec uuid.UUID) (domain.ProductReception, error) {
-	return s.repo.CloseReception(closeRec)
+func (s *PvzUsecase) CloseReception(ctx context.Context, scope domain.TenantScope, closeRec uuid.UUID) (domain.ProductReception, error) {
+	return s.repo.CloseReception(ctx, scope, closeRec)
 }
 
-func (s *PvzUsecase) GetListOFpvz(ctx context.Context) ([]domain.PVZ, error) {
-	return s.repo.GetListOFpvz(ctx)
+func (s *PvzUsecase) GetListOFpvz(ctx context.Context, scope domain.TenantScope) ([]domain.PVZ, error) {
+	return s.repo.GetListOFpvz(ctx, scope)
 }
@@ -0,0 +1,55 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/bllooop/pvzservice/internal/domain"
+	"github.com/bllooop/pvzservice/internal/repository"
+	"github.com/google/uuid"
+)
+
+var ErrTransferToSamePvz = errors.New("товар нельзя переместить в тот же ПВЗ")
+
+type TransferUsecase struct {
+	repo      repository.Transfers
+	limitMode string
+}
+
+func NewTransferUsecase(repo *repository.Repository, limitMode string) *TransferUsecase {
+	if limitMode != domain.LimitWarn {
+		limitMode = domain.LimitReject
+	}
+	return &TransferUsecase{
+		repo:      repo,
+		limitMode: limitMode,
+	}
+}
+
+func (s *TransferUsecase) ShipProduct(ctx context.Context, scope domain.TenantScope, fromPvzId, productId uuid.UUID, actorId string, input domain.TransferRequest, at time.Time) (domain.ProductTransfer, error) {
+	if input.ToPVZId == fromPvzId {
+		return domain.ProductTransfer{}, ErrTransferToSamePvz
+	}
+	return s.repo.ShipTransfer(ctx, scope, domain.ProductTransfer{
+		ProductId: productId,
+		FromPVZId: fromPvzId,
+		ToPVZId:   input.ToPVZId,
+		Reason:    input.Reason,
+		ShippedBy: actorId,
+		ShippedAt: at,
+	})
+}
+
+func (s *TransferUsecase) AcceptTransfer(ctx context.Context, scope domain.TenantScope, transferId uuid.UUID, actorId string, at time.Time) (domain.ProductTransfer, error) {
+	// Принятый товар занимает место в ПВЗ получения, поэтому в режиме reject
+	// действуют те же ограничения, что и при приемке товаров.
+	if s.limitMode == domain.LimitReject {
+		ctx = repository.WithCapacityLimit(ctx)
+	}
+	return s.repo.AcceptTransfer(ctx, scope, transferId, actorId, at)
+}
+
+func (s *TransferUsecase) GetTransfersInTransit(ctx context.Context, scope domain.TenantScope, params domain.InTransitParams) ([]domain.ProductTransfer, error) {
+	return s.repo.GetTransfersInTransit(ctx, scope, params)
+}
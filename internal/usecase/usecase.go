@@ -2,8 +2,10 @@ package usecase
 
 import (
 	"context"
+	"time"
 
 	"github.com/bllooop/pvzservice/internal/domain"
+	"github.com/bllooop/pvzservice/internal/notifier"
 	"github.com/bllooop/pvzservice/internal/repository"
 	"github.com/google/uuid"
 )
@@ -11,28 +13,116 @@ import (
 //go:generate mockgen -source=usecase.go -destination=mocks/mock.go -package=mocks
 //export PATH=$PATH:$(go env GOPATH)/bin
 type Authorization interface {
-	CreateUser(user domain.User) (domain.User, error)
-	SignUser(email, password string) (domain.User, error)
-	GenerateToken(userId uuid.UUID, userRole int) (string, error)
-	ParseToken(accessToken string) (string, int, error)
+	CreateUser(ctx context.Context, user domain.User) (domain.User, error)
+	SignUser(ctx context.Context, tenantId, email, password string) (domain.User, error)
+	GenerateToken(userId uuid.UUID, userRole int, tenantId string) (string, error)
+	GenerateDummyToken(userId uuid.UUID, userRole int, tenantId string) (string, error)
+	ParseToken(accessToken string) (domain.TokenClaims, error)
+	CheckUserActive(ctx context.Context, claims domain.TokenClaims) error
+	GetUsers(ctx context.Context, scope domain.TenantScope, input domain.GettingUsersParams) ([]domain.UserInfo, error)
+	UpdateUser(ctx context.Context, scope domain.TenantScope, userId uuid.UUID, input domain.UpdateUserInput) (domain.UserInfo, error)
+	DisableUser(ctx context.Context, scope domain.TenantScope, userId uuid.UUID) (domain.UserInfo, error)
+}
+type PasswordReset interface {
+	RequestPasswordReset(ctx context.Context, tenantId, email, ip string) error
+	ResetPassword(ctx context.Context, token, password string) error
+}
+type LoginProtection interface {
+	CheckLogin(ctx context.Context, tenantId, email, ip string) error
+	RegisterLoginSuccess(ctx context.Context, tenantId, email, ip string) error
+	UnlockLogin(ctx context.Context, tenantId, email, ip string) error
+}
+type Audit interface {
+	RecordAudit(ctx context.Context, entry domain.AuditEntry) error
+	GetAudit(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditEntry, error)
+	VerifyAuditChain(ctx context.Context) (int, error)
+}
+type Idempotency interface {
+	BeginIdempotent(ctx context.Context, scope, key, requestHash string) (*domain.IdempotencyRecord, error)
+	CompleteIdempotent(ctx context.Context, scope, key string, statusCode int, response []byte) error
+	ReleaseIdempotent(ctx context.Context, scope, key string) error
+	PurgeExpiredIdempotency(ctx context.Context) (int64, error)
+}
+type Amendments interface {
+	RequestAmendment(ctx context.Context, scope domain.TenantScope, receptionId uuid.UUID, actorId string, input domain.AmendmentRequest) (domain.ReceptionAmendment, error)
+	GetAmendments(ctx context.Context, scope domain.TenantScope, receptionId uuid.UUID) ([]domain.ReceptionAmendment, error)
+	ReviewAmendment(ctx context.Context, scope domain.TenantScope, id uuid.UUID, reviewer string, approve bool, comment string) (domain.ReceptionAmendment, error)
+	GetReceptionHistory(ctx context.Context, scope domain.TenantScope, receptionId uuid.UUID) ([]domain.ReceptionVersion, error)
+}
+type Transfers interface {
+	ShipProduct(ctx context.Context, scope domain.TenantScope, fromPvzId, productId uuid.UUID, actorId string, input domain.TransferRequest, at time.Time) (domain.ProductTransfer, error)
+	AcceptTransfer(ctx context.Context, scope domain.TenantScope, transferId uuid.UUID, actorId string, at time.Time) (domain.ProductTransfer, error)
+	GetTransfersInTransit(ctx context.Context, scope domain.TenantScope, params domain.InTransitParams) ([]domain.ProductTransfer, error)
+}
+type ReceptionAutoClose interface {
+	AutoCloseReceptions(ctx context.Context) (domain.AutoCloseResult, error)
+}
+type PvzCapacity interface {
+	GetPvzSchedule(ctx context.Context, scope domain.TenantScope, pvzId uuid.UUID) (domain.PvzSchedule, error)
+	SetPvzSchedule(ctx context.Context, scope domain.TenantScope, pvzId uuid.UUID, schedule domain.PvzSchedule) (domain.PvzSchedule, error)
+	GetPvzOccupancy(ctx context.Context, scope domain.TenantScope, pvzId *uuid.UUID) ([]domain.PvzOccupancy, error)
+	GetPvzStock(ctx context.Context) ([]domain.PvzStock, error)
+	IssueProduct(ctx context.Context, scope domain.TenantScope, pvzId, productId uuid.UUID, at time.Time) (domain.Product, error)
+	CheckPvzLimits(ctx context.Context, scope domain.TenantScope, pvzId uuid.UUID, at time.Time) ([]string, error)
 }
 type Pvz interface {
-	CreatePvz(pvz domain.PVZ) (domain.PVZ, error)
-	GetPvz(input domain.GettingPvzParams) ([]domain.PvzSummary, error)
-	CreateRecep(recep domain.ProductReception) (domain.ProductReception, error)
-	AddProdToRecep(product domain.Product) (domain.Product, error)
-	DeleteLastProduct(delProd uuid.UUID) error
-	CloseReception(closeRec uuid.UUID) (domain.ProductReception, error)
-	GetListOFpvz(ctx context.Context) ([]domain.PVZ, error)
+	CreatePvz(ctx context.Context, scope domain.TenantScope, pvz domain.PVZ) (domain.PVZ, error)
+	UpdatePvz(ctx context.Context, scope domain.TenantScope, pvzId uuid.UUID, input domain.PvzUpdate) (domain.PVZ, error)
+	ArchiveDecommissionedPvz(ctx context.Context) ([]domain.PVZ, error)
+	GetNearestPvz(ctx context.Context, scope domain.TenantScope, params domain.NearestPvzParams) ([]domain.PvzDistance, error)
+	GetPvz(ctx context.Context, scope domain.TenantScope, input domain.GettingPvzParams) ([]domain.PvzSummary, error)
+	GetPvzReport(ctx context.Context, scope domain.TenantScope, params domain.PvzReportParams) (domain.PvzReport, error)
+	CreateRecep(ctx context.Context, scope domain.TenantScope, recep domain.ProductReception) (domain.ProductReception, error)
+	AddProdToRecep(ctx context.Context, scope domain.TenantScope, product domain.Product) (domain.Product, error)
+	DeleteLastProduct(ctx context.Context, scope domain.TenantScope, input domain.ProductDeletion) (domain.Product, error)
+	DeleteProduct(ctx context.Context, scope domain.TenantScope, input domain.ProductDeletion) (domain.Product, error)
+	CloseReception(ctx context.Context, scope domain.TenantScope, closeRec uuid.UUID) (domain.ProductReception, error)
+	GetListOFpvz(ctx context.Context, scope domain.TenantScope) ([]domain.PVZ, error)
 }
 type Usecase struct {
 	Authorization
+	PasswordReset
+	LoginProtection
+	Audit
+	Idempotency
+	Amendments
+	Transfers
+	ReceptionAutoClose
+	PvzCapacity
 	Pvz
 }
 
-func NewUsecase(repo *repository.Repository) *Usecase {
+type Config struct {
+	Login          LoginPolicy
+	Password       PasswordPolicy
+	TokenTTL       time.Duration
+	ResetTokenTTL  time.Duration
+	ResetThrottle  ResetThrottle
+	Notifier       notifier.Notifier
+	IdempotencyTTL time.Duration
+	AutoClose      domain.AutoClosePolicy
+	PvzLimitMode   string
+}
+
+func NewUsecase(repo *repository.Repository, cfg Config) *Usecase {
 	return &Usecase{
-		Authorization: NewAuthUsecase(repo),
-		Pvz:           NewPvzUsecase(repo),
+		Authorization:      NewAuthUsecase(repo, cfg.Password, cfg.TokenTTL),
+		PasswordReset:      NewPasswordUsecase(repo, cfg.Password, cfg.ResetTokenTTL, cfg.ResetThrottle, cfg.Notifier),
+		LoginProtection:    NewLoginUsecase(repo, cfg.Login),
+		Audit:              NewAuditUsecase(repo),
+		Idempotency:        NewIdempotencyUsecase(repo, cfg.IdempotencyTTL),
+		Amendments:         NewAmendmentUsecase(repo),
+		Transfers:          NewTransferUsecase(repo, cfg.PvzLimitMode),
+		ReceptionAutoClose: NewAutoCloseUsecase(repo, cfg.AutoClose),
+		PvzCapacity:        NewPvzCapacityUsecase(repo, cfg.PvzLimitMode),
+		Pvz:                NewPvzUsecase(repo, cfg.PvzLimitMode),
+	}
+}
+
+// Reload применяет параметры, которые можно менять без перезапуска: правила
+// защиты входа.
+func (u *Usecase) Reload(cfg Config) {
+	if login, ok := u.LoginProtection.(*LoginUsecase); ok {
+		login.SetPolicy(cfg.Login)
 	}
 }
@@ -0,0 +1,37 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL,
+    actor_id varchar(64) NOT NULL,
+    actor_role varchar(32) NOT NULL,
+    action varchar(64) NOT NULL,
+    entity_type varchar(32) NOT NULL,
+    entity_id varchar(64) NOT NULL,
+    before_state JSON,
+    after_state JSON,
+    request_id varchar(128) NOT NULL DEFAULT '',
+    client_ip varchar(64) NOT NULL DEFAULT '',
+    prev_hash varchar(64) NOT NULL,
+    hash varchar(64) NOT NULL UNIQUE
+);
+CREATE INDEX IF NOT EXISTS idx_audit_log_entity ON audit_log(entity_type, entity_id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log(actor_id, created_at);
+
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_no_update
+    BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS audit_log_no_update ON audit_log;
+DROP FUNCTION IF EXISTS audit_log_append_only();
+DROP TABLE IF EXISTS audit_log;
+-- +goose StatementEnd
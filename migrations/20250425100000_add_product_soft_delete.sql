@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE product ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+CREATE TABLE IF NOT EXISTS product_corrections (
+    id BIGSERIAL PRIMARY KEY,
+    reception_id UUID NOT NULL REFERENCES product_reception(id) ON DELETE CASCADE,
+    product_id UUID NOT NULL REFERENCES product(id) ON DELETE CASCADE,
+    action varchar(32) NOT NULL,
+    reason varchar(32) NOT NULL,
+    comment TEXT NOT NULL DEFAULT '',
+    actor_id varchar(64) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS product_corrections_reception_idx ON product_corrections (reception_id, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS product_corrections;
+DELETE FROM product WHERE deleted_at IS NOT NULL;
+ALTER TABLE product DROP COLUMN IF EXISTS deleted_at;
+-- +goose StatementEnd
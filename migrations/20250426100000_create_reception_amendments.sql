@@ -0,0 +1,36 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS reception_amendments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    reception_id UUID NOT NULL REFERENCES product_reception(id) ON DELETE CASCADE,
+    pvz_id UUID NOT NULL REFERENCES pvz(id) ON DELETE CASCADE,
+    status varchar(16) NOT NULL DEFAULT 'pending',
+    reason TEXT NOT NULL,
+    items JSONB NOT NULL,
+    requested_by varchar(64) NOT NULL,
+    reviewed_by varchar(64),
+    review_comment TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    reviewed_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS reception_amendments_reception_idx ON reception_amendments (reception_id, created_at);
+
+CREATE TABLE IF NOT EXISTS reception_versions (
+    reception_id UUID NOT NULL REFERENCES product_reception(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    amendment_id UUID REFERENCES reception_amendments(id),
+    created_by varchar(64) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    snapshot JSONB NOT NULL,
+    PRIMARY KEY (reception_id, version)
+);
+
+ALTER TABLE product_corrections ADD COLUMN IF NOT EXISTS amendment_id UUID REFERENCES reception_amendments(id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE product_corrections DROP COLUMN IF EXISTS amendment_id;
+DROP TABLE IF EXISTS reception_versions;
+DROP TABLE IF EXISTS reception_amendments;
+-- +goose StatementEnd
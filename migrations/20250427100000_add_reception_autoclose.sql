@@ -0,0 +1,9 @@
+-- +goose NO TRANSACTION
+-- +goose Up
+ALTER TYPE reception_status_enum ADD VALUE IF NOT EXISTS 'cancelled';
+ALTER TABLE product_reception ADD COLUMN IF NOT EXISTS flagged_at TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS idx_product_reception_in_progress ON product_reception (date_received) WHERE status_reception = 'in_progress';
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_product_reception_in_progress;
+ALTER TABLE product_reception DROP COLUMN IF EXISTS flagged_at;
@@ -0,0 +1,46 @@
+-- +goose Up
+-- +goose StatementBegin
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'pvz_status_enum') THEN
+        CREATE TYPE pvz_status_enum AS ENUM ('active', 'temporarily_closed', 'decommissioned');
+    END IF;
+END; $$;
+
+ALTER TABLE pvz ADD COLUMN IF NOT EXISTS address TEXT NOT NULL DEFAULT '';
+ALTER TABLE pvz ADD COLUMN IF NOT EXISTS archived_at TIMESTAMPTZ;
+
+CREATE TABLE IF NOT EXISTS pvz_status_history (
+    id BIGSERIAL PRIMARY KEY,
+    pvz_id UUID NOT NULL REFERENCES pvz(id) ON DELETE RESTRICT,
+    status pvz_status_enum NOT NULL,
+    effective_from TIMESTAMPTZ NOT NULL,
+    effective_to TIMESTAMPTZ,
+    reason TEXT NOT NULL DEFAULT '',
+    actor_id varchar(64) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS pvz_status_history_pvz_idx ON pvz_status_history (pvz_id, effective_from DESC);
+
+-- ПВЗ больше не удаляются вместе с историей приемок, выведенные из работы архивируются.
+ALTER TABLE product_reception DROP CONSTRAINT IF EXISTS product_reception_pvz_id_fkey;
+ALTER TABLE product_reception ADD CONSTRAINT product_reception_pvz_id_fkey
+    FOREIGN KEY (pvz_id) REFERENCES pvz(id) ON DELETE RESTRICT;
+ALTER TABLE product DROP CONSTRAINT IF EXISTS product_pvz_id_fkey;
+ALTER TABLE product ADD CONSTRAINT product_pvz_id_fkey
+    FOREIGN KEY (pvz_id) REFERENCES pvz(id) ON DELETE RESTRICT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE product DROP CONSTRAINT IF EXISTS product_pvz_id_fkey;
+ALTER TABLE product ADD CONSTRAINT product_pvz_id_fkey
+    FOREIGN KEY (pvz_id) REFERENCES pvz(id) ON DELETE CASCADE;
+ALTER TABLE product_reception DROP CONSTRAINT IF EXISTS product_reception_pvz_id_fkey;
+ALTER TABLE product_reception ADD CONSTRAINT product_reception_pvz_id_fkey
+    FOREIGN KEY (pvz_id) REFERENCES pvz(id) ON DELETE CASCADE;
+DROP TABLE IF EXISTS pvz_status_history;
+ALTER TABLE pvz DROP COLUMN IF EXISTS archived_at;
+ALTER TABLE pvz DROP COLUMN IF EXISTS address;
+DROP TYPE IF EXISTS pvz_status_enum;
+-- +goose StatementEnd
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE pvz ADD COLUMN IF NOT EXISTS postal_code varchar(6) NOT NULL DEFAULT '';
+ALTER TABLE pvz ADD COLUMN IF NOT EXISTS working_hours TEXT NOT NULL DEFAULT '';
+ALTER TABLE pvz ADD COLUMN IF NOT EXISTS latitude DOUBLE PRECISION;
+ALTER TABLE pvz ADD COLUMN IF NOT EXISTS longitude DOUBLE PRECISION;
+ALTER TABLE pvz ADD CONSTRAINT pvz_coordinates_check CHECK (
+    (latitude IS NULL AND longitude IS NULL)
+    OR (latitude BETWEEN -90 AND 90 AND longitude BETWEEN -180 AND 180)
+);
+CREATE INDEX IF NOT EXISTS pvz_coordinates_idx ON pvz (latitude, longitude) WHERE latitude IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS pvz_coordinates_idx;
+ALTER TABLE pvz DROP CONSTRAINT IF EXISTS pvz_coordinates_check;
+ALTER TABLE pvz DROP COLUMN IF EXISTS longitude;
+ALTER TABLE pvz DROP COLUMN IF EXISTS latitude;
+ALTER TABLE pvz DROP COLUMN IF EXISTS working_hours;
+ALTER TABLE pvz DROP COLUMN IF EXISTS postal_code;
+-- +goose StatementEnd
@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE pvz ADD COLUMN IF NOT EXISTS capacity INTEGER CHECK (capacity > 0);
+ALTER TABLE product ADD COLUMN IF NOT EXISTS issued_at TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS product_stored_idx ON product (pvz_id) WHERE deleted_at IS NULL AND issued_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS pvz_working_hours (
+    pvz_id UUID NOT NULL REFERENCES pvz(id) ON DELETE RESTRICT,
+    weekday SMALLINT NOT NULL CHECK (weekday BETWEEN 1 AND 7),
+    opens_at TIME NOT NULL,
+    closes_at TIME NOT NULL CHECK (closes_at > opens_at),
+    PRIMARY KEY (pvz_id, weekday)
+);
+
+CREATE TABLE IF NOT EXISTS pvz_holidays (
+    pvz_id UUID NOT NULL REFERENCES pvz(id) ON DELETE RESTRICT,
+    day DATE NOT NULL,
+    opens_at TIME,
+    closes_at TIME,
+    reason TEXT NOT NULL DEFAULT '',
+    PRIMARY KEY (pvz_id, day),
+    CHECK ((opens_at IS NULL AND closes_at IS NULL) OR closes_at > opens_at)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS pvz_holidays;
+DROP TABLE IF EXISTS pvz_working_hours;
+DROP INDEX IF EXISTS product_stored_idx;
+ALTER TABLE product DROP COLUMN IF EXISTS issued_at;
+ALTER TABLE pvz DROP COLUMN IF EXISTS capacity;
+-- +goose StatementEnd
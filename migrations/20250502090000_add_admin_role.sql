@@ -0,0 +1,8 @@
+-- +goose NO TRANSACTION
+-- Новое значение перечисления нельзя использовать в той же транзакции, где оно
+-- добавлено, поэтому роль admin добавляется отдельной миграцией без транзакции.
+-- +goose Up
+ALTER TYPE role_enum ADD VALUE IF NOT EXISTS 'admin';
+
+-- +goose Down
+-- Значение из перечисления PostgreSQL удалить нельзя, роль admin остается.
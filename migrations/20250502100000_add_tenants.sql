@@ -0,0 +1,75 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS tenants (
+    id varchar(64) PRIMARY KEY CHECK (id ~ '^[a-z0-9][a-z0-9_-]*$'),
+    name TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+INSERT INTO tenants (id, name) VALUES ('default', 'Компания по умолчанию') ON CONFLICT (id) DO NOTHING;
+
+ALTER TABLE userlist ADD COLUMN IF NOT EXISTS tenant_id varchar(64) NOT NULL DEFAULT 'default' REFERENCES tenants(id);
+ALTER TABLE pvz ADD COLUMN IF NOT EXISTS tenant_id varchar(64) NOT NULL DEFAULT 'default' REFERENCES tenants(id);
+ALTER TABLE product_reception ADD COLUMN IF NOT EXISTS tenant_id varchar(64) NOT NULL DEFAULT 'default' REFERENCES tenants(id);
+ALTER TABLE product ADD COLUMN IF NOT EXISTS tenant_id varchar(64) NOT NULL DEFAULT 'default' REFERENCES tenants(id);
+ALTER TABLE audit_log ADD COLUMN IF NOT EXISTS tenant_id varchar(64) NOT NULL DEFAULT '';
+
+-- Почта уникальна в пределах компании: один и тот же адрес может быть у сотрудников разных перевозчиков.
+ALTER TABLE userlist DROP CONSTRAINT IF EXISTS userlist_email_key;
+CREATE UNIQUE INDEX IF NOT EXISTS userlist_tenant_email_key ON userlist (tenant_id, email);
+
+-- Приемки и товары не могут ссылаться на ПВЗ другой компании.
+CREATE UNIQUE INDEX IF NOT EXISTS pvz_id_tenant_key ON pvz (id, tenant_id);
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'product_reception_pvz_tenant_fkey') THEN
+        ALTER TABLE product_reception ADD CONSTRAINT product_reception_pvz_tenant_fkey
+            FOREIGN KEY (pvz_id, tenant_id) REFERENCES pvz (id, tenant_id);
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'product_pvz_tenant_fkey') THEN
+        ALTER TABLE product ADD CONSTRAINT product_pvz_tenant_fkey
+            FOREIGN KEY (pvz_id, tenant_id) REFERENCES pvz (id, tenant_id);
+    END IF;
+END; $$;
+
+CREATE INDEX IF NOT EXISTS idx_pvz_tenant ON pvz (tenant_id);
+CREATE INDEX IF NOT EXISTS idx_product_reception_tenant ON product_reception (tenant_id);
+CREATE INDEX IF NOT EXISTS idx_product_tenant ON product (tenant_id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_tenant ON audit_log (tenant_id, id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- Без компаний почта снова должна быть уникальной во всей базе. Если один
+-- адрес уже есть в нескольких компаниях, откат прерывается до каких-либо
+-- изменений: такие учетные записи нужно сначала объединить или удалить.
+DO $$
+DECLARE
+    duplicates integer;
+BEGIN
+    SELECT COUNT(*) INTO duplicates FROM (SELECT email FROM userlist GROUP BY email HAVING COUNT(*) > 1) d;
+    IF duplicates > 0 THEN
+        RAISE EXCEPTION 'откат невозможен: % адресов почты используются в нескольких компаниях', duplicates;
+    END IF;
+END; $$;
+
+DROP INDEX IF EXISTS idx_audit_log_tenant;
+DROP INDEX IF EXISTS idx_product_tenant;
+DROP INDEX IF EXISTS idx_product_reception_tenant;
+DROP INDEX IF EXISTS idx_pvz_tenant;
+ALTER TABLE product DROP CONSTRAINT IF EXISTS product_pvz_tenant_fkey;
+ALTER TABLE product_reception DROP CONSTRAINT IF EXISTS product_reception_pvz_tenant_fkey;
+DROP INDEX IF EXISTS pvz_id_tenant_key;
+DROP INDEX IF EXISTS userlist_tenant_email_key;
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'userlist_email_key') THEN
+        ALTER TABLE userlist ADD CONSTRAINT userlist_email_key UNIQUE (email);
+    END IF;
+END; $$;
+ALTER TABLE audit_log DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE product DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE product_reception DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE pvz DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE userlist DROP COLUMN IF EXISTS tenant_id;
+DROP TABLE IF EXISTS tenants;
+-- +goose StatementEnd
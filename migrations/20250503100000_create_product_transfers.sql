@@ -0,0 +1,34 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS product_transfers (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    product_id UUID NOT NULL REFERENCES product(id),
+    type_product product_type_enum NOT NULL,
+    from_pvz_id UUID NOT NULL,
+    to_pvz_id UUID NOT NULL,
+    tenant_id varchar(64) NOT NULL REFERENCES tenants(id),
+    status varchar(16) NOT NULL DEFAULT 'in_transit',
+    reason TEXT NOT NULL DEFAULT '',
+    shipped_by varchar(64) NOT NULL,
+    shipped_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    received_product_id UUID REFERENCES product(id),
+    received_by varchar(64),
+    received_at TIMESTAMPTZ,
+    CHECK (from_pvz_id <> to_pvz_id),
+    -- ПВЗ отправления и получения принадлежат одной компании.
+    FOREIGN KEY (from_pvz_id, tenant_id) REFERENCES pvz (id, tenant_id),
+    FOREIGN KEY (to_pvz_id, tenant_id) REFERENCES pvz (id, tenant_id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS product_transfers_active_product_key ON product_transfers (product_id) WHERE status = 'in_transit';
+CREATE INDEX IF NOT EXISTS product_transfers_in_transit_idx ON product_transfers (tenant_id, shipped_at) WHERE status = 'in_transit';
+
+ALTER TABLE product ADD COLUMN IF NOT EXISTS transferred_at TIMESTAMPTZ;
+ALTER TABLE product_corrections ADD COLUMN IF NOT EXISTS transfer_id UUID REFERENCES product_transfers(id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE product_corrections DROP COLUMN IF EXISTS transfer_id;
+ALTER TABLE product DROP COLUMN IF EXISTS transferred_at;
+DROP TABLE IF EXISTS product_transfers;
+-- +goose StatementEnd
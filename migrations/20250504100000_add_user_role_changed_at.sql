@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE userlist ADD COLUMN IF NOT EXISTS role_changed_at TIMESTAMPTZ;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE userlist DROP COLUMN IF EXISTS role_changed_at;
+-- +goose StatementEnd
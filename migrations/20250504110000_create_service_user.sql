@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Служебная учетная запись для внутренних вызовов gRPC. Пароль не является
+-- хешем bcrypt, поэтому войти под ней нельзя.
+INSERT INTO tenants (id, name) VALUES ('system', 'Служебные учетные записи') ON CONFLICT (id) DO NOTHING;
+INSERT INTO userlist (id, email, password, role, tenant_id)
+VALUES ('00000000-0000-0000-0000-000000000001', 'service@pvzservice.internal', '!', 'admin', 'system')
+ON CONFLICT (id) DO NOTHING;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM userlist WHERE id = '00000000-0000-0000-0000-000000000001';
+DELETE FROM tenants WHERE id = 'system';
+-- +goose StatementEnd
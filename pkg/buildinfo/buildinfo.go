@@ -0,0 +1,43 @@
+package buildinfo
+
+import "runtime/debug"
+
+// GitSHA и BuildTime задаются при сборке:
+//
+//	go build -ldflags "-X github.com/bllooop/pvzservice/pkg/buildinfo.GitSHA=$(git rev-parse HEAD) \
+//	  -X github.com/bllooop/pvzservice/pkg/buildinfo.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var (
+	GitSHA    = ""
+	BuildTime = ""
+)
+
+// Info — сведения о сборке сервиса.
+type Info struct {
+	GitSHA    string `json:"gitSha"`
+	BuildTime string `json:"buildTime"`
+	GoVersion string `json:"goVersion"`
+}
+
+// Get возвращает сведения о сборке. Если они не заданы через -ldflags, берутся
+// данные системы контроля версий, которые go build записывает в бинарник.
+func Get() Info {
+	info := Info{GitSHA: GitSHA, BuildTime: BuildTime}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		info.GoVersion = bi.GoVersion
+		for _, s := range bi.Settings {
+			switch {
+			case s.Key == "vcs.revision" && info.GitSHA == "":
+				info.GitSHA = s.Value
+			case s.Key == "vcs.time" && info.BuildTime == "":
+				info.BuildTime = s.Value
+			}
+		}
+	}
+	if info.GitSHA == "" {
+		info.GitSHA = "unknown"
+	}
+	if info.BuildTime == "" {
+		info.BuildTime = "unknown"
+	}
+	return info
+}
@@ -1,9 +1,107 @@
 package logging
 
 import (
+	"context"
+	"fmt"
+	"io"
 	"os"
 
 	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
 )
 
-var Log = zerolog.New(os.Stdout).Level(zerolog.TraceLevel)
+const (
+	FormatJSON    = "json"
+	FormatConsole = "console"
+)
+
+var Log = New(os.Stdout, zerolog.TraceLevel, FormatJSON)
+
+// Config задает уровень (trace, debug, info, warn, error) и формат (json или
+// console) логов.
+type Config struct {
+	Level  string
+	Format string
+}
+
+// New создает логгер, все записи которого проходят через Redactor.
+func New(w io.Writer, level zerolog.Level, format string) zerolog.Logger {
+	if format == FormatConsole {
+		w = zerolog.ConsoleWriter{Out: w, NoColor: true}
+	}
+	return zerolog.New(NewRedactor(w)).Level(level).With().Timestamp().Logger().Hook(TraceHook{})
+}
+
+// Configure заменяет глобальный логгер логгером с уровнем и форматом из cfg.
+// Пустые поля оставляют значения по умолчанию: trace и json.
+// Уровень задается глобально, чтобы его можно было менять через SetLevel.
+func Configure(cfg Config) error {
+	if err := ValidateConfig(cfg); err != nil {
+		return err
+	}
+	Log = New(os.Stdout, zerolog.TraceLevel, cfg.Format)
+	return SetLevel(cfg.Level)
+}
+
+// SetLevel меняет уровень всех логгеров, в том числе логгеров запросов, уже
+// сохраненных в контексте. Безопасен для вызова во время работы сервиса.
+func SetLevel(level string) error {
+	parsed, err := parseLevel(level)
+	if err != nil {
+		return err
+	}
+	zerolog.SetGlobalLevel(parsed)
+	return nil
+}
+
+// ValidateConfig проверяет уровень и формат без изменения логгера.
+func ValidateConfig(cfg Config) error {
+	if _, err := parseLevel(cfg.Level); err != nil {
+		return err
+	}
+	switch cfg.Format {
+	case "", FormatJSON, FormatConsole:
+		return nil
+	default:
+		return fmt.Errorf("неизвестный формат логов %q, допустимы json, console", cfg.Format)
+	}
+}
+
+func parseLevel(level string) (zerolog.Level, error) {
+	if level == "" {
+		return zerolog.TraceLevel, nil
+	}
+	parsed, err := zerolog.ParseLevel(level)
+	if err != nil || parsed == zerolog.NoLevel {
+		return zerolog.NoLevel, fmt.Errorf("неизвестный уровень логирования %q", level)
+	}
+	return parsed, nil
+}
+
+type ctxKey struct{}
+
+// WithLogger сохраняет в ctx логгер запроса.
+func WithLogger(ctx context.Context, l zerolog.Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, &l)
+}
+
+// FromContext возвращает логгер запроса из ctx, а если его нет — глобальный
+// логгер.
+func FromContext(ctx context.Context) *zerolog.Logger {
+	if l, ok := ctx.Value(ctxKey{}).(*zerolog.Logger); ok {
+		return l
+	}
+	return &Log
+}
+
+// TraceHook добавляет в запись trace_id и span_id, если событию передан
+// контекст с активным спаном через Ctx.
+type TraceHook struct{}
+
+func (TraceHook) Run(e *zerolog.Event, _ zerolog.Level, _ string) {
+	spanCtx := trace.SpanContextFromContext(e.GetCtx())
+	if !spanCtx.IsValid() {
+		return
+	}
+	e.Str("trace_id", spanCtx.TraceID().String()).Str("span_id", spanCtx.SpanID().String())
+}
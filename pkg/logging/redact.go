@@ -0,0 +1,66 @@
+package logging
+
+import (
+	"io"
+	"regexp"
+)
+
+const redacted = "***"
+
+// redactRules скрывают секреты в уже сформированной записи: значения полей с
+// паролями и токенами, пароль в строке подключения к базе, Bearer-токены и
+// JWT в тексте сообщений.
+var redactRules = []struct {
+	re   *regexp.Regexp
+	repl string
+}{
+	{
+		re:   regexp.MustCompile(`(?i)("[a-z_]*(?:password|passwd|secret|token|authorization|dsn|conn)[a-z_]*"\s*:\s*)"(?:[^"\\]|\\.)*"`),
+		repl: `${1}"` + redacted + `"`,
+	},
+	{
+		re:   regexp.MustCompile(`(?i)\b([a-z][a-z0-9+.-]*://[^:/@\s"]+:)[^@\s"]+@`),
+		repl: `${1}` + redacted + `@`,
+	},
+	{
+		re:   regexp.MustCompile(`(?i)(password|пароль|token|токен)(\s*[:=]\s*)[^\s",]+`),
+		repl: `${1}${2}` + redacted,
+	},
+	{
+		re:   regexp.MustCompile(`(?i)\b(bearer\s+)[a-z0-9._~+/=-]+`),
+		repl: `${1}` + redacted,
+	},
+	{
+		re:   regexp.MustCompile(`\beyJ[A-Za-z0-9_-]*\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`),
+		repl: redacted,
+	},
+}
+
+// Redactor пропускает через redactRules каждую запись перед записью в w.
+// zerolog передает в Write ровно одну запись за вызов.
+type Redactor struct {
+	w io.Writer
+}
+
+func NewRedactor(w io.Writer) *Redactor {
+	return &Redactor{w: w}
+}
+
+func (r *Redactor) Write(p []byte) (int, error) {
+	out := p
+	for _, rule := range redactRules {
+		out = rule.re.ReplaceAll(out, []byte(rule.repl))
+	}
+	if _, err := r.w.Write(out); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Redact скрывает секреты в s по тем же правилам, что и Redactor.
+func Redact(s string) string {
+	for _, rule := range redactRules {
+		s = rule.re.ReplaceAllString(s, rule.repl)
+	}
+	return s
+}
@@ -0,0 +1,95 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactor(t *testing.T) {
+	tests := []struct {
+		name    string
+		log     func(l zerolog.Logger)
+		hidden  string
+		visible string
+	}{
+		{
+			name:    "Поле password",
+			log:     func(l zerolog.Logger) { l.Info().Str("password", "Qwerty123!").Msg("вход") },
+			hidden:  "Qwerty123!",
+			visible: `"password":"***"`,
+		},
+		{
+			name:    "Поле с токеном",
+			log:     func(l zerolog.Logger) { l.Info().Str("reset_token", "abc\"def").Msg("") },
+			hidden:  "abc",
+			visible: `"reset_token":"***"`,
+		},
+		{
+			name:    "Строка подключения",
+			log:     func(l zerolog.Logger) { l.Debug().Msg("postgres://postgres:s3cr3t@db:5432/postgres?sslmode=disable") },
+			hidden:  "s3cr3t",
+			visible: "postgres://postgres:***@db:5432",
+		},
+		{
+			name:    "Пароль в тексте",
+			log:     func(l zerolog.Logger) { l.Debug().Msgf("почта: %s, пароль: %s", "a@b.ru", "Qwerty123!") },
+			hidden:  "Qwerty123!",
+			visible: "пароль: ***",
+		},
+		{
+			name:    "Bearer-токен",
+			log:     func(l zerolog.Logger) { l.Error().Msg("заголовок Bearer abc.def.ghi отклонен") },
+			hidden:  "abc.def.ghi",
+			visible: "Bearer ***",
+		},
+		{
+			name:    "JWT в ошибке",
+			log:     func(l zerolog.Logger) { l.Error().Msg("token is expired: eyJhbGciOi.eyJ1c2VyIjoxfQ.c2lnbmF0dXJl") },
+			hidden:  "eyJhbGciOi",
+			visible: "***",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tt.log(zerolog.New(NewRedactor(&buf)))
+			assert.NotContains(t, buf.String(), tt.hidden)
+			assert.Contains(t, buf.String(), tt.visible)
+		})
+	}
+}
+
+func TestRedactor_keepsOtherFields(t *testing.T) {
+	var buf bytes.Buffer
+	l := zerolog.New(NewRedactor(&buf))
+	l.Info().Str("email", "a@b.ru").Str("content", "обувь").Msg("Создали пользователя")
+	assert.Equal(t, `{"level":"info","email":"a@b.ru","content":"обувь","message":"Создали пользователя"}`, strings.TrimSpace(buf.String()))
+}
+
+func TestConfigure(t *testing.T) {
+	defer func(l zerolog.Logger, level zerolog.Level) {
+		Log = l
+		zerolog.SetGlobalLevel(level)
+	}(Log, zerolog.GlobalLevel())
+	assert.NoError(t, Configure(Config{Level: "info", Format: FormatConsole}))
+	assert.Equal(t, zerolog.InfoLevel, zerolog.GlobalLevel())
+	assert.NoError(t, SetLevel("warn"))
+	assert.Equal(t, zerolog.WarnLevel, zerolog.GlobalLevel())
+	assert.Error(t, SetLevel("verbose"))
+	assert.Error(t, Configure(Config{Level: "verbose"}))
+	assert.Error(t, Configure(Config{Format: "xml"}))
+}
+
+func TestFromContext(t *testing.T) {
+	assert.Same(t, &Log, FromContext(context.Background()))
+
+	var buf bytes.Buffer
+	l := zerolog.New(&buf).With().Str("request_id", "r1").Logger()
+	FromContext(WithLogger(context.Background(), l)).Info().Msg("")
+	assert.Contains(t, buf.String(), `"request_id":"r1"`)
+}
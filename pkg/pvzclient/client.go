@@ -0,0 +1,192 @@
+// Package pvzclient — клиент gRPC сервиса PVZService для встраивания в другие
+// сервисы: подставляет токен авторизации, ограничивает время вызова,
+// повторяет вызовы при временной недоступности и возвращает типизированные
+// ошибки.
+package pvzclient
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	pb "github.com/bllooop/pvzservice/grpcpvz"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	DefaultTimeout     = 5 * time.Second
+	DefaultMaxAttempts = 3
+	DefaultBaseBackoff = 100 * time.Millisecond
+	DefaultMaxBackoff  = 2 * time.Second
+)
+
+// TokenSource возвращает токен, с которым выполняется очередной вызов.
+// Вызывается перед каждой попыткой, поэтому может обновлять истекший токен.
+type TokenSource func(ctx context.Context) (string, error)
+
+// StaticToken — TokenSource с постоянным токеном.
+func StaticToken(token string) TokenSource {
+	return func(context.Context) (string, error) {
+		return token, nil
+	}
+}
+
+// Config задает адрес сервиса и параметры вызовов. Нулевые значения
+// заменяются значениями Default*.
+type Config struct {
+	// Address — host:port gRPC сервера.
+	Address string
+	// Token — источник токена авторизации. Если не задан, вызовы выполняются
+	// без заголовка authorization.
+	Token TokenSource
+	// Timeout ограничивает одну попытку вызова. Общее время ограничено
+	// контекстом вызывающей стороны.
+	Timeout time.Duration
+	// MaxAttempts — число попыток, включая первую.
+	MaxAttempts int
+	// BaseBackoff и MaxBackoff задают экспоненциальную паузу между попытками.
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// Insecure отключает TLS. Без него используются системные сертификаты.
+	Insecure bool
+	// DialOptions дополняют параметры подключения, например собственными
+	// учетными данными TLS.
+	DialOptions []grpc.DialOption
+}
+
+// Client — клиент PVZService. Безопасен для одновременного использования.
+type Client struct {
+	conn *grpc.ClientConn
+	api  pb.PVZServiceClient
+	cfg  Config
+}
+
+// New создает клиента. Соединение устанавливается при первом вызове.
+func New(cfg Config) (*Client, error) {
+	if cfg.Address == "" {
+		return nil, errors.New("pvzclient: не указан адрес сервиса")
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = DefaultMaxAttempts
+	}
+	if cfg.BaseBackoff <= 0 {
+		cfg.BaseBackoff = DefaultBaseBackoff
+	}
+	if cfg.MaxBackoff < cfg.BaseBackoff {
+		cfg.MaxBackoff = max(DefaultMaxBackoff, cfg.BaseBackoff)
+	}
+	transport := credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})
+	if cfg.Insecure {
+		transport = insecure.NewCredentials()
+	}
+	opts := append([]grpc.DialOption{
+		grpc.WithTransportCredentials(transport),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+	}, cfg.DialOptions...)
+	conn, err := grpc.NewClient(cfg.Address, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("pvzclient: ошибка подключения к %s: %w", cfg.Address, err)
+	}
+	return &Client{conn: conn, api: pb.NewPVZServiceClient(conn), cfg: cfg}, nil
+}
+
+// Close закрывает соединение.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// GetPVZList возвращает ПВЗ компании, которой принадлежит токен.
+func (c *Client) GetPVZList(ctx context.Context) ([]*pb.PVZ, error) {
+	var resp *pb.GetPVZListResponse
+	err := c.invoke(ctx, "GetPVZList", func(ctx context.Context) (err error) {
+		resp, err = c.api.GetPVZList(ctx, &pb.GetPVZListRequest{})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.GetPvzs(), nil
+}
+
+// GetNearestPVZ возвращает ПВЗ в радиусе от точки, ближайшие первыми.
+func (c *Client) GetNearestPVZ(ctx context.Context, req *pb.GetNearestPVZRequest) ([]*pb.NearestPVZ, error) {
+	var resp *pb.GetNearestPVZResponse
+	err := c.invoke(ctx, "GetNearestPVZ", func(ctx context.Context) (err error) {
+		resp, err = c.api.GetNearestPVZ(ctx, req)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.GetPvzs(), nil
+}
+
+// invoke выполняет call с токеном и таймаутом попытки, повторяя его при
+// временных ошибках. Методы PVZService только читают данные, поэтому
+// повтор безопасен.
+func (c *Client) invoke(ctx context.Context, method string, call func(ctx context.Context) error) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = c.attempt(ctx, call)
+		if err == nil {
+			return nil
+		}
+		if attempt >= c.cfg.MaxAttempts || ctx.Err() != nil || !retryable(err) {
+			return newError(method, err, attempt)
+		}
+		timer := time.NewTimer(c.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return newError(method, status.FromContextError(ctx.Err()).Err(), attempt)
+		case <-timer.C:
+		}
+	}
+}
+
+func (c *Client) attempt(ctx context.Context, call func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+	defer cancel()
+	if c.cfg.Token != nil {
+		token, err := c.cfg.Token(ctx)
+		if err != nil {
+			return status.Errorf(codes.Unauthenticated, "не удалось получить токен: %v", err)
+		}
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+	}
+	return call(ctx)
+}
+
+// backoff возвращает паузу перед попыткой attempt+1: экспоненциально
+// растущую со случайным разбросом, чтобы клиенты не повторяли вызовы
+// одновременно.
+func (c *Client) backoff(attempt int) time.Duration {
+	d := c.cfg.BaseBackoff << (attempt - 1)
+	if d <= 0 || d > c.cfg.MaxBackoff {
+		d = c.cfg.MaxBackoff
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// retryable — временные ошибки: сервис недоступен, перегружен или попытка
+// не уложилась в свой таймаут.
+func retryable(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.ResourceExhausted, codes.DeadlineExceeded:
+		return true
+	default:
+		return false
+	}
+}
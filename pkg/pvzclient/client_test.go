@@ -0,0 +1,163 @@
+package pvzclient
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pb "github.com/bllooop/pvzservice/grpcpvz"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type fakeServer struct {
+	pb.UnimplementedPVZServiceServer
+	calls atomic.Int32
+	list  func(ctx context.Context, call int32) (*pb.GetPVZListResponse, error)
+}
+
+func (s *fakeServer) GetPVZList(ctx context.Context, _ *pb.GetPVZListRequest) (*pb.GetPVZListResponse, error) {
+	return s.list(ctx, s.calls.Add(1))
+}
+
+func newTestClient(t *testing.T, srv *fakeServer, cfg Config) *Client {
+	lis := bufconn.Listen(1 << 20)
+	grpcServer := grpc.NewServer()
+	pb.RegisterPVZServiceServer(grpcServer, srv)
+	go grpcServer.Serve(lis)
+	t.Cleanup(grpcServer.Stop)
+
+	cfg.Address = "passthrough:///bufnet"
+	cfg.Insecure = true
+	if cfg.BaseBackoff == 0 {
+		cfg.BaseBackoff = time.Millisecond
+	}
+	cfg.DialOptions = append(cfg.DialOptions, grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return lis.DialContext(ctx)
+	}))
+	client, err := New(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestClient_GetPVZList(t *testing.T) {
+	testTable := []struct {
+		name          string
+		cfg           Config
+		list          func(ctx context.Context, call int32) (*pb.GetPVZListResponse, error)
+		expectedPvzs  int
+		expectedCalls int32
+		expectedErr   error
+	}{
+		{
+			name: "Ok с токеном",
+			cfg:  Config{Token: StaticToken("t1")},
+			list: func(ctx context.Context, _ int32) (*pb.GetPVZListResponse, error) {
+				md, _ := metadata.FromIncomingContext(ctx)
+				if got := md.Get("authorization"); len(got) != 1 || got[0] != "Bearer t1" {
+					return nil, status.Error(codes.Unauthenticated, "Пустой заголовок авторизации")
+				}
+				return &pb.GetPVZListResponse{Pvzs: []*pb.PVZ{{Id: "1"}, {Id: "2"}}}, nil
+			},
+			expectedPvzs:  2,
+			expectedCalls: 1,
+		},
+		{
+			name: "Повтор после недоступности",
+			list: func(_ context.Context, call int32) (*pb.GetPVZListResponse, error) {
+				if call < 3 {
+					return nil, status.Error(codes.Unavailable, "перезапуск")
+				}
+				return &pb.GetPVZListResponse{Pvzs: []*pb.PVZ{{Id: "1"}}}, nil
+			},
+			expectedPvzs:  1,
+			expectedCalls: 3,
+		},
+		{
+			name: "Попытки исчерпаны",
+			cfg:  Config{MaxAttempts: 2},
+			list: func(context.Context, int32) (*pb.GetPVZListResponse, error) {
+				return nil, status.Error(codes.Unavailable, "перезапуск")
+			},
+			expectedCalls: 2,
+			expectedErr:   ErrUnavailable,
+		},
+		{
+			name: "Ошибка авторизации не повторяется",
+			list: func(context.Context, int32) (*pb.GetPVZListResponse, error) {
+				return nil, status.Error(codes.Unauthenticated, "Пустой заголовок авторизации")
+			},
+			expectedCalls: 1,
+			expectedErr:   ErrUnauthenticated,
+		},
+		{
+			name: "Таймаут попытки",
+			cfg:  Config{Timeout: 20 * time.Millisecond, MaxAttempts: 2},
+			list: func(ctx context.Context, _ int32) (*pb.GetPVZListResponse, error) {
+				<-ctx.Done()
+				return nil, status.FromContextError(ctx.Err()).Err()
+			},
+			expectedCalls: 2,
+			expectedErr:   ErrTimeout,
+		},
+		{
+			name: "Ошибка получения токена",
+			cfg: Config{Token: func(context.Context) (string, error) {
+				return "", errors.New("хранилище недоступно")
+			}},
+			list: func(context.Context, int32) (*pb.GetPVZListResponse, error) {
+				return &pb.GetPVZListResponse{}, nil
+			},
+			expectedCalls: 0,
+			expectedErr:   ErrUnauthenticated,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			srv := &fakeServer{list: testCase.list}
+			client := newTestClient(t, srv, testCase.cfg)
+
+			pvzs, err := client.GetPVZList(context.Background())
+
+			assert.Equal(t, testCase.expectedCalls, srv.calls.Load())
+			if testCase.expectedErr != nil {
+				assert.ErrorIs(t, err, testCase.expectedErr)
+				var clientErr *Error
+				assert.ErrorAs(t, err, &clientErr)
+				assert.Equal(t, "GetPVZList", clientErr.Method)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Len(t, pvzs, testCase.expectedPvzs)
+		})
+	}
+}
+
+func TestClient_canceled(t *testing.T) {
+	srv := &fakeServer{list: func(context.Context, int32) (*pb.GetPVZListResponse, error) {
+		return nil, status.Error(codes.Unavailable, "перезапуск")
+	}}
+	client := newTestClient(t, srv, Config{BaseBackoff: time.Hour, MaxBackoff: time.Hour})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := client.GetPVZList(ctx)
+
+	assert.ErrorIs(t, err, ErrTimeout)
+	assert.Equal(t, int32(1), srv.calls.Load())
+}
+
+func TestNew_withoutAddress(t *testing.T) {
+	_, err := New(Config{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,80 @@
+package pvzclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Ошибки, по которым вызывающая сторона различает ответы сервиса через
+// errors.Is.
+var (
+	ErrUnauthenticated  = errors.New("pvzclient: требуется авторизация")
+	ErrPermissionDenied = errors.New("pvzclient: доступ запрещен")
+	ErrInvalidArgument  = errors.New("pvzclient: неверный запрос")
+	ErrNotFound         = errors.New("pvzclient: не найдено")
+	ErrConflict         = errors.New("pvzclient: конфликт запросов")
+	ErrRateLimited      = errors.New("pvzclient: превышен лимит запросов")
+	ErrUnavailable      = errors.New("pvzclient: сервис недоступен")
+	ErrTimeout          = errors.New("pvzclient: превышено время ожидания")
+	ErrInternal         = errors.New("pvzclient: внутренняя ошибка сервиса")
+)
+
+// Error — ошибка вызова метода сервиса. Code и Message берутся из статуса
+// gRPC, Kind — одна из ошибок Err* или context.Canceled, если вызов отменила
+// вызывающая сторона.
+type Error struct {
+	Method   string
+	Code     codes.Code
+	Message  string
+	Attempts int
+	Kind     error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s (%s, попыток: %d)", e.Method, e.Message, e.Code, e.Attempts)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
+
+// newError переводит ошибку gRPC в *Error.
+func newError(method string, err error, attempts int) error {
+	st := status.Convert(err)
+	return &Error{
+		Method:   method,
+		Code:     st.Code(),
+		Message:  st.Message(),
+		Attempts: attempts,
+		Kind:     kindOf(st.Code()),
+	}
+}
+
+func kindOf(code codes.Code) error {
+	switch code {
+	case codes.Unauthenticated:
+		return ErrUnauthenticated
+	case codes.PermissionDenied:
+		return ErrPermissionDenied
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return ErrInvalidArgument
+	case codes.NotFound:
+		return ErrNotFound
+	case codes.Aborted, codes.AlreadyExists:
+		return ErrConflict
+	case codes.ResourceExhausted:
+		return ErrRateLimited
+	case codes.Unavailable:
+		return ErrUnavailable
+	case codes.DeadlineExceeded:
+		return ErrTimeout
+	case codes.Canceled:
+		return context.Canceled
+	default:
+		return ErrInternal
+	}
+}
@@ -0,0 +1,72 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	logger "github.com/bllooop/pvzservice/pkg/logging"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestInit_fileExporter(t *testing.T) {
+	testTable := []struct {
+		name      string
+		ratio     float64
+		wantSpans bool
+	}{
+		{name: "Все трассы", ratio: 1, wantSpans: true},
+		{name: "Запись отключена", ratio: 0},
+	}
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "traces.json")
+			shutdown, err := Init(context.Background(), Config{Exporter: ExporterFile, File: path, SampleRatio: testCase.ratio})
+			require.NoError(t, err)
+
+			_, span := otel.Tracer("test").Start(context.Background(), "GET /pvz")
+			span.End()
+			require.NoError(t, shutdown(context.Background()))
+
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+			if !testCase.wantSpans {
+				assert.Empty(t, data)
+				return
+			}
+			assert.Contains(t, string(data), `"Name":"GET /pvz"`)
+			assert.Contains(t, string(data), `"Value":"pvzservice"`)
+		})
+	}
+}
+
+func TestInit_unknownExporter(t *testing.T) {
+	_, err := Init(context.Background(), Config{Exporter: "jaeger"})
+	assert.Error(t, err)
+}
+
+func TestTraceHook(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	ctx, span := provider.Tracer("test").Start(context.Background(), "request")
+	defer span.End()
+
+	var buf bytes.Buffer
+	log := zerolog.New(&buf).Hook(logger.TraceHook{})
+	log.Info().Ctx(ctx).Msg("с контекстом")
+	log.Info().Msg("без контекста")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[0], `"trace_id":"`+span.SpanContext().TraceID().String()+`"`)
+	assert.Contains(t, lines[0], `"span_id":"`+span.SpanContext().SpanID().String()+`"`)
+	assert.NotContains(t, lines[1], "trace_id")
+}
@@ -1,6 +1,9 @@
 package prometheus
 
-import "github.com/prometheus/client_golang/prometheus"
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
 
 var (
 	HTTPRequestTotal = prometheus.NewCounterVec(
@@ -36,12 +39,143 @@ var (
 			Help: "Суммарное количество добавленных товаров",
 		},
 	)
+	LoginAttemptsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "login_attempts_total",
+			Help: "Количество попыток входа по результату",
+		},
+		[]string{"result"},
+	)
+	ReceptionAutoCloseTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "reception_autoclose_total",
+			Help: "Количество приемок, обработанных автозакрытием, по действию",
+		},
+		[]string{"action"},
+	)
+	AuditWriteFailures = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "audit_write_failures_total",
+			Help: "Количество операций, которые не удалось записать в журнал аудита",
+		},
+	)
+	ReceptionDuration = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "reception_duration_seconds",
+			Help:    "Время от открытия до закрытия приемки",
+			Buckets: []float64{60, 300, 900, 1800, 3600, 7200, 14400, 28800, 43200, 86400},
+		},
+	)
+	ProductsPerReception = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "reception_products",
+			Help:    "Количество товаров в закрытой приемке",
+			Buckets: []float64{1, 5, 10, 25, 50, 100, 250, 500, 1000},
+		},
+	)
+	GRPCRequestTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "grpc_server_handled_total",
+			Help: "Количество обработанных gRPC вызовов по методу и коду ответа",
+		},
+		[]string{"method", "code"},
+	)
+	GRPCRequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "grpc_server_handling_seconds",
+			Help:    "Продолжительность gRPC вызовов",
+			Buckets: []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1.0, 2.5, 5.0, 10.0},
+		},
+		[]string{"method", "code"},
+	)
+	DBQueryDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "db_query_duration_seconds",
+			Help:    "Продолжительность методов репозитория, обращающихся к базе",
+			Buckets: []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1.0, 2.5},
+		},
+		[]string{"method"},
+	)
+	DBQueriesCancelledTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "db_queries_cancelled_total",
+			Help: "Количество запросов к базе, прерванных отменой запроса клиента (canceled) или таймаутом (timeout), по методу",
+		},
+		[]string{"method", "reason"},
+	)
+	DBReadsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "db_reads_total",
+			Help: "Количество чтений, направленных на реплику или основную базу, по методу",
+		},
+		[]string{"method", "target"},
+	)
+	ReplicaLagSeconds = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "db_replica_lag_seconds",
+			Help: "Отставание реплики базы данных при последней проверке",
+		},
+	)
+	ReplicaUp = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "db_replica_up",
+			Help: "1, если чтения направляются на реплику, 0 - если реплика недоступна или отстает",
+		},
+	)
+	CacheRequestsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cache_requests_total",
+			Help: "Количество обращений к кэшу чтений по методу и результату (hit, miss)",
+		},
+		[]string{"method", "result"},
+	)
+	RateLimitedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "rate_limited_requests_total",
+			Help: "Количество запросов, отклоненных ограничением частоты, по протоколу и группе маршрутов",
+		},
+		[]string{"transport", "group"},
+	)
 )
 
+// Registry — реестр метрик сервиса, который отдается по /metrics. Метрики не
+// регистрируются в глобальном реестре prometheus, поэтому тесты могут
+// собрать их в собственный реестр через Register.
+var Registry = prometheus.NewRegistry()
+
 func init() {
-	prometheus.MustRegister(HTTPRequestTotal)
-	prometheus.MustRegister(HTTPRequestDuration)
-	prometheus.MustRegister(NumOfCreatedPVZ)
-	prometheus.MustRegister(NumOfCreatedRecep)
-	prometheus.MustRegister(NumOfAddedProducts)
+	Registry.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	if err := Register(Registry); err != nil {
+		panic(err)
+	}
+}
+
+// Register регистрирует метрики сервиса в reg.
+func Register(reg prometheus.Registerer) error {
+	for _, c := range []prometheus.Collector{
+		HTTPRequestTotal,
+		HTTPRequestDuration,
+		NumOfCreatedPVZ,
+		NumOfCreatedRecep,
+		NumOfAddedProducts,
+		LoginAttemptsTotal,
+		AuditWriteFailures,
+		ReceptionAutoCloseTotal,
+		ReceptionDuration,
+		ProductsPerReception,
+		GRPCRequestTotal,
+		GRPCRequestDuration,
+		DBQueryDuration,
+		DBQueriesCancelledTotal,
+		DBReadsTotal,
+		ReplicaLagSeconds,
+		ReplicaUp,
+		CacheRequestsTotal,
+		RateLimitedTotal,
+	} {
+		if err := reg.Register(c); err != nil {
+			return err
+		}
+	}
+	return nil
 }
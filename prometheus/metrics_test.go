@@ -0,0 +1,65 @@
+package prometheus
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bllooop/pvzservice/internal/domain"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegister(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	require.NoError(t, Register(reg))
+
+	NumOfCreatedPVZ.Inc()
+	count, err := testutil.GatherAndCount(reg, "created_pvz_amount_total")
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	count, err = testutil.GatherAndCount(prometheus.DefaultGatherer, "created_pvz_amount_total")
+	require.NoError(t, err)
+	assert.Zero(t, count, "метрики не должны попадать в глобальный реестр")
+}
+
+func TestStockCollector(t *testing.T) {
+	pvzId := uuid.MustParse("3fa85f64-5717-4562-b3fc-2c963f66afa6")
+	collector := NewStockCollector(func(ctx context.Context) ([]domain.PvzStock, error) {
+		return []domain.PvzStock{{PVZId: pvzId, City: "Москва", OpenReceptions: 1, ProductsHeld: 42}}, nil
+	}, time.Second)
+	expected := `
+# HELP pvz_open_receptions Количество открытых приемок в ПВЗ
+# TYPE pvz_open_receptions gauge
+pvz_open_receptions{city="Москва",pvz_id="3fa85f64-5717-4562-b3fc-2c963f66afa6"} 1
+# HELP pvz_products_held Количество товаров, находящихся в ПВЗ: принятых, не выданных и не перемещенных
+# TYPE pvz_products_held gauge
+pvz_products_held{city="Москва",pvz_id="3fa85f64-5717-4562-b3fc-2c963f66afa6"} 42
+`
+	assert.NoError(t, testutil.CollectAndCompare(collector, strings.NewReader(expected)))
+}
+
+func TestStockCollector_error(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(NewStockCollector(func(ctx context.Context) ([]domain.PvzStock, error) {
+		return nil, errors.New("нет соединения")
+	}, time.Second))
+	_, err := reg.Gather()
+	assert.Error(t, err)
+}
+
+func TestStockCollector_timeout(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(NewStockCollector(func(ctx context.Context) ([]domain.PvzStock, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}, 10*time.Millisecond))
+	_, err := reg.Gather()
+	assert.ErrorContains(t, err, context.DeadlineExceeded.Error())
+}
@@ -0,0 +1,58 @@
+package prometheus
+
+import (
+	"context"
+	"time"
+
+	"github.com/bllooop/pvzservice/internal/domain"
+	logger "github.com/bllooop/pvzservice/pkg/logging"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	openReceptionsDesc = prometheus.NewDesc(
+		"pvz_open_receptions",
+		"Количество открытых приемок в ПВЗ",
+		[]string{"pvz_id", "city"}, nil,
+	)
+	productsHeldDesc = prometheus.NewDesc(
+		"pvz_products_held",
+		"Количество товаров, находящихся в ПВЗ: принятых, не выданных и не перемещенных",
+		[]string{"pvz_id", "city"}, nil,
+	)
+)
+
+// StockCollector отдает текущие остатки ПВЗ. Значения читаются из базы при
+// каждом сборе метрик, поэтому совпадают на всех экземплярах сервиса. Чтение
+// ограничено timeout, чтобы медленная база не задерживала отдачу остальных
+// метрик.
+type StockCollector struct {
+	source  func(ctx context.Context) ([]domain.PvzStock, error)
+	timeout time.Duration
+}
+
+func NewStockCollector(source func(ctx context.Context) ([]domain.PvzStock, error), timeout time.Duration) *StockCollector {
+	return &StockCollector{source: source, timeout: timeout}
+}
+
+func (c *StockCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- openReceptionsDesc
+	ch <- productsHeldDesc
+}
+
+func (c *StockCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	stock, err := c.source(ctx)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Ошибка получения остатков ПВЗ для метрик")
+		ch <- prometheus.NewInvalidMetric(openReceptionsDesc, err)
+		ch <- prometheus.NewInvalidMetric(productsHeldDesc, err)
+		return
+	}
+	for _, s := range stock {
+		pvzId := s.PVZId.String()
+		ch <- prometheus.MustNewConstMetric(openReceptionsDesc, prometheus.GaugeValue, float64(s.OpenReceptions), pvzId, s.City)
+		ch <- prometheus.MustNewConstMetric(productsHeldDesc, prometheus.GaugeValue, float64(s.ProductsHeld), pvzId, s.City)
+	}
+}